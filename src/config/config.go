@@ -21,6 +21,18 @@ type Config struct {
 	AdminEmail            string
 	AdminPass             string
 	FrontendURL           string
+	AppointmentNotice     string // Minimum notice for client cancel/reschedule, as a duration ("24h")
+	PurchaseApprovalLimit string // Purchase total above which only an admin can approve the order
+	IssuerNIT             string // Tax ID of the salon as invoice issuer, without check digit
+	IssuerName            string // Registered business name printed on invoices
+	IssuerAddress         string
+	IssuerCity            string
+	IssuerCityCode        string // DANE municipality code
+	IssuerDepartment      string
+	IssuerDepartmentCode  string // DANE department code
+	IssuerPhone           string
+	IssuerEmail           string
+	EInvoiceEnvironment   string // 1 production, 2 testing
 	DBHost                string
 	DBPort                string
 	DBName                string
@@ -71,6 +83,18 @@ func LoadConfig() error {
 		AdminEmail:            getEnv("ADMIN_EMAIL", "admin@bebacoiffure.com"),
 		AdminPass:             getEnv("ADMIN_PASSWORD", "admin123"),
 		FrontendURL:           getEnv("FRONTEND_URL", "http://localhost:5173"),
+		AppointmentNotice:     getEnv("APPOINTMENT_NOTICE", "24h"),
+		PurchaseApprovalLimit: getEnv("PURCHASE_APPROVAL_LIMIT", "1000000"),
+		IssuerNIT:             getEnv("ISSUER_NIT", ""),
+		IssuerName:            getEnv("ISSUER_NAME", "Beba Coiffure"),
+		IssuerAddress:         getEnv("ISSUER_ADDRESS", ""),
+		IssuerCity:            getEnv("ISSUER_CITY", "Bogotá, D.C."),
+		IssuerCityCode:        getEnv("ISSUER_CITY_CODE", "11001"),
+		IssuerDepartment:      getEnv("ISSUER_DEPARTMENT", "Bogotá"),
+		IssuerDepartmentCode:  getEnv("ISSUER_DEPARTMENT_CODE", "11"),
+		IssuerPhone:           getEnv("ISSUER_PHONE", ""),
+		IssuerEmail:           getEnv("ISSUER_EMAIL", ""),
+		EInvoiceEnvironment:   getEnv("EINVOICE_ENVIRONMENT", "2"),
 		DBHost:                dbHost,
 		DBPort:                dbPort,
 		DBName:                dbName,
@@ -1,9 +1,16 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"salon/config"
+	"salon/models"
 	"salon/services"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +30,9 @@ const (
 	ErrAppointmentNotFound        = "Appointment not found"
 	ErrInvalidDateFormat          = "Invalid date format. Use YYYY-MM-DD"
 	ErrInvalidTimeFormat          = "Invalid time format. Use HH:MM"
+	ErrEmployeeDoubleBooked       = "The employee already has an appointment that overlaps this time"
+	ErrClientDoubleBooked         = "The client already has an appointment that overlaps this time"
+	ErrAppointmentClosed          = "Appointment is already closed and cannot be modified"
 )
 
 type AppointmentController struct {
@@ -71,7 +81,7 @@ func (ac *AppointmentController) GetAppointments(c *gin.Context) {
 			EmpID:    cita.EmpID,
 			SerID:    cita.SerID,
 			CliID:    cita.CliID,
-			Estado:   "Programada", // Default status since it's not in the database
+			Estado:   cita.CitEstado,
 		}
 		appointments = append(appointments, appointment)
 	}
@@ -104,7 +114,7 @@ func (ac *AppointmentController) GetAppointment(c *gin.Context) {
 		EmpID:    cita.EmpID,
 		SerID:    cita.SerID,
 		CliID:    cita.CliID,
-		Estado:   "Programada",
+		Estado:   cita.CitEstado,
 	}
 
 	c.JSON(http.StatusOK, gin.H{"appointment": appointment})
@@ -156,6 +166,9 @@ func (ac *AppointmentController) CreateAppointment(c *gin.Context) {
 	}
 
 	err = ac.dbService.InsertarCita(req.CitFecha, req.CitHora, req.EmpID, req.SerID, req.CliID)
+	if respondAppointmentConflict(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedCreateAppointment})
 		return
@@ -169,11 +182,29 @@ func (ac *AppointmentController) CreateAppointment(c *gin.Context) {
 			EmpID:    req.EmpID,
 			SerID:    req.SerID,
 			CliID:    req.CliID,
-			Estado:   "Programada",
+			Estado:   EstadoProgramada,
 		},
 	})
 }
 
+// respondAppointmentConflict writes a 409 with the overlapping cit_id when err is a booking conflict
+func respondAppointmentConflict(c *gin.Context, err error) bool {
+	var conflicto *services.CitaConflictoError
+	if !errors.As(err, &conflicto) {
+		return false
+	}
+
+	message := ErrClientDoubleBooked
+	if conflicto.MismoEmpleado {
+		message = ErrEmployeeDoubleBooked
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":              message,
+		"conflicting_cit_id": conflicto.CitID,
+	})
+	return true
+}
+
 // UpdateAppointment updates an existing appointment
 func (ac *AppointmentController) UpdateAppointment(c *gin.Context) {
 	id := c.Param("id")
@@ -189,6 +220,16 @@ func (ac *AppointmentController) UpdateAppointment(c *gin.Context) {
 		return
 	}
 
+	cita, err := ac.dbService.BuscarCitaPorID(uint(appointmentID))
+	if err != nil || cita.CitID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrAppointmentNotFound})
+		return
+	}
+	if isFinalAppointmentStatus(cita.CitEstado) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrAppointmentClosed, "estado": cita.CitEstado})
+		return
+	}
+
 	// Validate date format
 	_, err = time.Parse(DateFormat, req.CitFecha)
 	if err != nil {
@@ -204,6 +245,9 @@ func (ac *AppointmentController) UpdateAppointment(c *gin.Context) {
 	}
 
 	err = ac.dbService.ActualizarCita(uint(appointmentID), req.CitFecha, req.CitHora, req.EmpID, req.SerID, req.CliID)
+	if respondAppointmentConflict(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedUpdateAppointment})
 		return
@@ -216,7 +260,7 @@ func (ac *AppointmentController) UpdateAppointment(c *gin.Context) {
 		EmpID:    req.EmpID,
 		SerID:    req.SerID,
 		CliID:    req.CliID,
-		Estado:   "Programada",
+		Estado:   cita.CitEstado,
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -243,6 +287,181 @@ func (ac *AppointmentController) DeleteAppointment(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Appointment deleted successfully"})
 }
 
+// ============= STATUS LIFECYCLE =============
+
+// Appointment statuses stored in CITA.cit_estado
+const (
+	EstadoProgramada = services.EstadoCitaProgramada
+	EstadoConfirmada = services.EstadoCitaConfirmada
+	EstadoEnCurso    = services.EstadoCitaEnCurso
+	EstadoCompletada = services.EstadoCitaCompletada
+	EstadoCancelada  = services.EstadoCitaCancelada
+	EstadoNoAsistio  = services.EstadoCitaNoAsistio
+)
+
+const (
+	ErrFailedUpdateAppointmentStatus = "Failed to update appointment status"
+	ErrFailedRetrieveStatusHistory   = "Failed to retrieve appointment status history"
+	ErrInvalidAppointmentStatus      = "Invalid status. Use Programada, Confirmada, En Curso, Completada, Cancelada or No Asistio"
+	ErrStatusTransitionNotAllowed    = "Status transition not allowed"
+	ErrNotEnoughStockForRecipe       = "Not enough stock of the products the service's recipe uses"
+)
+
+// transicionesEstadoCita lists the statuses reachable from each status;
+// Completada, Cancelada and No Asistio are final. sp_cambiar_estado_cita enforces the same rules.
+var transicionesEstadoCita = map[string][]string{
+	EstadoProgramada: {EstadoConfirmada, EstadoEnCurso, EstadoCancelada, EstadoNoAsistio},
+	EstadoConfirmada: {EstadoEnCurso, EstadoCancelada, EstadoNoAsistio},
+	EstadoEnCurso:    {EstadoCompletada},
+	EstadoCompletada: {},
+	EstadoCancelada:  {},
+	EstadoNoAsistio:  {},
+}
+
+type AppointmentStatusRequest struct {
+	Estado        string `json:"estado" binding:"required"`
+	Observaciones string `json:"observaciones"`
+}
+
+// UpdateAppointmentStatus moves an appointment through its status lifecycle
+func (ac *AppointmentController) UpdateAppointmentStatus(c *gin.Context) {
+	appointmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidAppointmentID})
+		return
+	}
+
+	var req AppointmentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	estado, ok := normalizeAppointmentStatus(req.Estado)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidAppointmentStatus})
+		return
+	}
+
+	cita, err := ac.dbService.BuscarCitaPorID(uint(appointmentID))
+	if err != nil || cita.CitID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrAppointmentNotFound})
+		return
+	}
+
+	ac.changeAppointmentStatus(c, cita, estado, req.Observaciones)
+}
+
+// changeAppointmentStatus validates the transition, persists it and writes the response
+func (ac *AppointmentController) changeAppointmentStatus(c *gin.Context, cita *models.Cita, estado, observaciones string) {
+	if !canTransitionAppointment(cita.CitEstado, estado) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   ErrStatusTransitionNotAllowed,
+			"from":    cita.CitEstado,
+			"to":      estado,
+			"allowed": transicionesEstadoCita[cita.CitEstado],
+		})
+		return
+	}
+
+	err := ac.dbService.CambiarEstadoCita(cita.CitID, estado, observaciones, c.GetString("user_email"))
+	if errors.Is(err, services.ErrTransicionCitaNoPermitida) {
+		// The appointment changed status after it was read; report the status it has now
+		actual := cita.CitEstado
+		if recargada, err := ac.dbService.BuscarCitaPorID(cita.CitID); err == nil && recargada.CitID != 0 {
+			actual = recargada.CitEstado
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   ErrStatusTransitionNotAllowed,
+			"from":    actual,
+			"to":      estado,
+			"allowed": transicionesEstadoCita[actual],
+		})
+		return
+	}
+	if errors.Is(err, services.ErrStockInsuficienteReceta) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrNotEnoughStockForRecipe})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedUpdateAppointmentStatus})
+		return
+	}
+
+	historial, err := ac.dbService.ListarHistorialEstadoCita(cita.CitID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveStatusHistory})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Appointment status updated successfully",
+		"appointment": AppointmentResponse{
+			CitID:    cita.CitID,
+			CitFecha: cita.CitFecha.Format(DateFormat),
+			CitHora:  cita.CitHora,
+			EmpID:    cita.EmpID,
+			SerID:    cita.SerID,
+			CliID:    cita.CliID,
+			Estado:   estado,
+		},
+		"history": historial,
+	})
+}
+
+// GetAppointmentStatusHistory returns every status transition of an appointment with its timestamp
+func (ac *AppointmentController) GetAppointmentStatusHistory(c *gin.Context) {
+	appointmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidAppointmentID})
+		return
+	}
+
+	cita, err := ac.dbService.BuscarCitaPorID(uint(appointmentID))
+	if err != nil || cita.CitID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrAppointmentNotFound})
+		return
+	}
+
+	historial, err := ac.dbService.ListarHistorialEstadoCita(cita.CitID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveStatusHistory})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cit_id":  cita.CitID,
+		"estado":  cita.CitEstado,
+		"history": historial,
+		"total":   len(historial),
+	})
+}
+
+// normalizeAppointmentStatus matches the requested status case-insensitively against the known statuses
+func normalizeAppointmentStatus(estado string) (string, bool) {
+	estado = strings.TrimSpace(estado)
+	for known := range transicionesEstadoCita {
+		if strings.EqualFold(known, estado) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+func canTransitionAppointment(from, to string) bool {
+	for _, allowed := range transicionesEstadoCita[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func isFinalAppointmentStatus(estado string) bool {
+	next, known := transicionesEstadoCita[estado]
+	return known && len(next) == 0
+}
+
 // GetAppointmentsByEmployee returns appointments for a specific employee
 func (ac *AppointmentController) GetAppointmentsByEmployee(c *gin.Context) {
 	id := c.Param("emp_id")
@@ -268,7 +487,7 @@ func (ac *AppointmentController) GetAppointmentsByEmployee(c *gin.Context) {
 			EmpID:    cita.EmpID,
 			SerID:    cita.SerID,
 			CliID:    cita.CliID,
-			Estado:   "Programada",
+			Estado:   cita.CitEstado,
 		}
 		appointments = append(appointments, appointment)
 	}
@@ -307,7 +526,7 @@ func (ac *AppointmentController) GetAppointmentsByClient(c *gin.Context) {
 				EmpID:    cita.EmpID,
 				SerID:    cita.SerID,
 				CliID:    cita.CliID,
-				Estado:   "Programada",
+				Estado:   cita.CitEstado,
 			}
 			appointments = append(appointments, appointment)
 		}
@@ -356,6 +575,398 @@ func (ac *AppointmentController) GetMyAppointments(c *gin.Context) {
 	})
 }
 
+// ============= EMPLOYEE SELF-SERVICE =============
+
+const (
+	ErrEmployeeProfileNotFound = "Employee profile not found"
+	ErrNotYourAppointment      = "Appointment is not assigned to you"
+)
+
+// GetMyEmployeeAppointments returns the appointments assigned to the authenticated employee
+func (ac *AppointmentController) GetMyEmployeeAppointments(c *gin.Context) {
+	empID, ok := ac.currentEmployeeID(c)
+	if !ok {
+		return
+	}
+
+	citas, err := ac.dbService.VerCitasEmpleado(empID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveAppointments})
+		return
+	}
+
+	appointments := toAppointmentResponses(citas, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"appointments": appointments,
+		"total":        len(appointments),
+		"employee_id":  empID,
+	})
+}
+
+// GetMyTodayAppointments returns the authenticated employee's appointments for today, earliest first
+func (ac *AppointmentController) GetMyTodayAppointments(c *gin.Context) {
+	empID, ok := ac.currentEmployeeID(c)
+	if !ok {
+		return
+	}
+
+	citas, err := ac.dbService.VerCitasEmpleado(empID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveAppointments})
+		return
+	}
+
+	today := time.Now().Format(DateFormat)
+	appointments := toAppointmentResponses(citas, func(cita models.Cita) bool {
+		return cita.CitFecha.Format(DateFormat) == today
+	})
+	sort.Slice(appointments, func(i, j int) bool {
+		return appointments[i].CitHora < appointments[j].CitHora
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"appointments": appointments,
+		"total":        len(appointments),
+		"employee_id":  empID,
+		"date":         today,
+	})
+}
+
+// GetMyEmployeeHistory returns the service history notes of the authenticated employee's appointments
+func (ac *AppointmentController) GetMyEmployeeHistory(c *gin.Context) {
+	empID, ok := ac.currentEmployeeID(c)
+	if !ok {
+		return
+	}
+
+	historial, err := ac.dbService.VerHistorialEmpleado(empID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveAppointments})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history":     historial,
+		"total":       len(historial),
+		"employee_id": empID,
+	})
+}
+
+// UpdateMyAppointmentStatus lets an employee change the status of an appointment assigned to them
+func (ac *AppointmentController) UpdateMyAppointmentStatus(c *gin.Context) {
+	empID, ok := ac.currentEmployeeID(c)
+	if !ok {
+		return
+	}
+
+	appointmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidAppointmentID})
+		return
+	}
+
+	var req AppointmentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	estado, valid := normalizeAppointmentStatus(req.Estado)
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidAppointmentStatus})
+		return
+	}
+
+	cita, err := ac.dbService.BuscarCitaPorID(uint(appointmentID))
+	if err != nil || cita.CitID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrAppointmentNotFound})
+		return
+	}
+	if cita.EmpID != empID {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrNotYourAppointment})
+		return
+	}
+
+	ac.changeAppointmentStatus(c, cita, estado, req.Observaciones)
+}
+
+// currentEmployeeID resolves the emp_id of the authenticated employee from the JWT claims.
+// Login stores the emp_id as user_id for employees; the lookup confirms the profile still exists.
+func (ac *AppointmentController) currentEmployeeID(c *gin.Context) (uint, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, false
+	}
+
+	empID, ok := userID.(uint)
+	if !ok || empID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, false
+	}
+
+	employee, err := ac.dbService.BuscarEmpleadoPorID(empID)
+	if err != nil || employee.EmpID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrEmployeeProfileNotFound})
+		return 0, false
+	}
+
+	return employee.EmpID, true
+}
+
+// toAppointmentResponses converts appointments to the response format, keeping only those
+// accepted by include (all of them when include is nil)
+func toAppointmentResponses(citas []models.Cita, include func(models.Cita) bool) []AppointmentResponse {
+	appointments := []AppointmentResponse{}
+	for _, cita := range citas {
+		if include != nil && !include(cita) {
+			continue
+		}
+		appointments = append(appointments, AppointmentResponse{
+			CitID:    cita.CitID,
+			CitFecha: cita.CitFecha.Format(DateFormat),
+			CitHora:  cita.CitHora,
+			EmpID:    cita.EmpID,
+			SerID:    cita.SerID,
+			CliID:    cita.CliID,
+			Estado:   cita.CitEstado,
+		})
+	}
+	return appointments
+}
+
+// ============= CLIENT SELF-SERVICE =============
+
+// DefaultAppointmentNotice is the minimum notice used when APPOINTMENT_NOTICE is unset or invalid
+const DefaultAppointmentNotice = 24 * time.Hour
+
+const (
+	ErrNotYourClientAppointment   = "Appointment does not belong to you"
+	ErrAppointmentNotPending      = "Only scheduled or confirmed appointments can be changed"
+	ErrAppointmentAlreadyStarted  = "Appointment time has already passed"
+	ErrRescheduleNoticeTooShort   = "Appointments can no longer be rescheduled this close to their start time"
+	ErrRescheduleToPast           = "New appointment time must be in the future"
+	ErrOutsideWorkingHours        = "The employee is not working for the whole service at the requested time"
+	ErrFailedCancelAppointment    = "Failed to cancel appointment"
+	ErrFailedRetrieveCancellation = "Failed to retrieve late cancellations"
+)
+
+type CancelAppointmentRequest struct {
+	Motivo string `json:"motivo"`
+}
+
+type RescheduleAppointmentRequest struct {
+	CitFecha string `json:"cit_fecha" binding:"required"` // Format: YYYY-MM-DD
+	CitHora  string `json:"cit_hora" binding:"required"`  // Format: HH:MM
+	EmpID    uint   `json:"emp_id"`                       // Optional - keeps the current employee when omitted
+}
+
+// CancelMyAppointment lets a client cancel one of their own appointments. Cancelling inside the
+// minimum notice period is still allowed, but the appointment is flagged as a late cancellation.
+func (ac *AppointmentController) CancelMyAppointment(c *gin.Context) {
+	cita, ok := ac.resolveClientAppointment(c)
+	if !ok {
+		return
+	}
+
+	var req CancelAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start, err := appointmentStart(cita)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedCancelAppointment})
+		return
+	}
+	if !start.After(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrAppointmentAlreadyStarted})
+		return
+	}
+
+	notice := appointmentNotice()
+	late := time.Until(start) < notice
+
+	if err := ac.dbService.CancelarCitaCliente(cita.CitID, cita.CliID, late, req.Motivo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedCancelAppointment})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Appointment cancelled successfully",
+		"appointment": AppointmentResponse{
+			CitID:    cita.CitID,
+			CitFecha: cita.CitFecha.Format(DateFormat),
+			CitHora:  cita.CitHora,
+			EmpID:    cita.EmpID,
+			SerID:    cita.SerID,
+			CliID:    cita.CliID,
+			Estado:   EstadoCancelada,
+		},
+		"late_cancellation": late,
+		"notice":            notice.String(),
+	})
+}
+
+// RescheduleMyAppointment lets a client move one of their own appointments, as long as
+// the current booking is still outside the minimum notice period
+func (ac *AppointmentController) RescheduleMyAppointment(c *gin.Context) {
+	cita, ok := ac.resolveClientAppointment(c)
+	if !ok {
+		return
+	}
+
+	var req RescheduleAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	newDate, err := time.ParseInLocation(DateFormat, req.CitFecha, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDateFormat})
+		return
+	}
+	newTime, err := time.Parse(TimeFormat, req.CitHora)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidTimeFormat})
+		return
+	}
+	newStart := newDate.Add(time.Duration(newTime.Hour())*time.Hour + time.Duration(newTime.Minute())*time.Minute)
+	if !newStart.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrRescheduleToPast})
+		return
+	}
+
+	start, err := appointmentStart(cita)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedUpdateAppointment})
+		return
+	}
+	notice := appointmentNotice()
+	if time.Until(start) < notice {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    ErrRescheduleNoticeTooShort,
+			"notice":   notice.String(),
+			"deadline": start.Add(-notice).Format(time.RFC3339),
+		})
+		return
+	}
+
+	empID := cita.EmpID
+	if req.EmpID != 0 {
+		empID = req.EmpID
+	}
+
+	fits, err := ac.fitsWorkingHours(empID, cita.SerID, newDate, req.CitHora)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedUpdateAppointment})
+		return
+	}
+	if !fits {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrOutsideWorkingHours, "emp_id": empID})
+		return
+	}
+
+	err = ac.dbService.ActualizarCita(cita.CitID, req.CitFecha, req.CitHora, empID, cita.SerID, cita.CliID)
+	if respondAppointmentConflict(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedUpdateAppointment})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Appointment rescheduled successfully",
+		"appointment": AppointmentResponse{
+			CitID:    cita.CitID,
+			CitFecha: req.CitFecha,
+			CitHora:  req.CitHora,
+			EmpID:    empID,
+			SerID:    cita.SerID,
+			CliID:    cita.CliID,
+			Estado:   cita.CitEstado,
+		},
+	})
+}
+
+// GetLateCancellations lists the appointments clients cancelled inside the minimum notice period
+func (ac *AppointmentController) GetLateCancellations(c *gin.Context) {
+	citas, err := ac.dbService.ListarCancelacionesTardias()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveCancellation})
+		return
+	}
+
+	appointments := toAppointmentResponses(citas, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"appointments": appointments,
+		"total":        len(appointments),
+	})
+}
+
+// resolveClientAppointment loads the :id appointment and checks that it belongs to the
+// authenticated client and has not been closed or started yet
+func (ac *AppointmentController) resolveClientAppointment(c *gin.Context) (*models.Cita, bool) {
+	userEmail, exists := c.Get("user_email")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	client, err := ac.dbService.BuscarClientePorCorreo(userEmail.(string))
+	if err != nil || client.CliID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrClientProfileNotFound})
+		return nil, false
+	}
+
+	appointmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidAppointmentID})
+		return nil, false
+	}
+
+	cita, err := ac.dbService.BuscarCitaPorID(uint(appointmentID))
+	if err != nil || cita.CitID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrAppointmentNotFound})
+		return nil, false
+	}
+	if cita.CliID != client.CliID {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrNotYourClientAppointment})
+		return nil, false
+	}
+	if cita.CitEstado != EstadoProgramada && cita.CitEstado != EstadoConfirmada {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrAppointmentNotPending, "estado": cita.CitEstado})
+		return nil, false
+	}
+
+	return cita, true
+}
+
+// appointmentStart combines the appointment date and time into a local timestamp
+func appointmentStart(cita *models.Cita) (time.Time, error) {
+	minutes, err := parseClock(cita.CitHora)
+	if err != nil {
+		return time.Time{}, err
+	}
+	day := time.Date(cita.CitFecha.Year(), cita.CitFecha.Month(), cita.CitFecha.Day(), 0, 0, 0, 0, time.Local)
+	return day.Add(time.Duration(minutes) * time.Minute), nil
+}
+
+// appointmentNotice returns the configured minimum notice for client changes
+func appointmentNotice() time.Duration {
+	if config.AppConfig == nil || config.AppConfig.AppointmentNotice == "" {
+		return DefaultAppointmentNotice
+	}
+	notice, err := time.ParseDuration(config.AppConfig.AppointmentNotice)
+	if err != nil || notice < 0 {
+		return DefaultAppointmentNotice
+	}
+	return notice
+}
+
 // GetAppointmentsByDate returns appointments for a specific date
 func (ac *AppointmentController) GetAppointmentsByDate(c *gin.Context) {
 	dateStr := c.Param("date")
@@ -385,7 +996,7 @@ func (ac *AppointmentController) GetAppointmentsByDate(c *gin.Context) {
 				EmpID:    cita.EmpID,
 				SerID:    cita.SerID,
 				CliID:    cita.CliID,
-				Estado:   "Programada",
+				Estado:   cita.CitEstado,
 			}
 			appointments = append(appointments, appointment)
 		}
@@ -397,3 +1008,389 @@ func (ac *AppointmentController) GetAppointmentsByDate(c *gin.Context) {
 		"date":         dateStr,
 	})
 }
+
+// ============= CHECKOUT =============
+
+const (
+	ErrFailedCheckout             = "Failed to create invoice from appointments"
+	ErrAppointmentNotCompleted    = "Only completed appointments can be invoiced"
+	ErrAppointmentAlreadyInvoiced = "Appointment has already been invoiced"
+	ErrCheckoutMixedClients       = "All appointments must belong to the same client"
+	ErrCheckoutRepeatedService    = "Each service can only be invoiced once per invoice"
+)
+
+type CheckoutRequest struct {
+	CitIDs []uint `json:"cit_ids"` // Optional - other completed appointments of the same client to bill together
+	Fecha  string `json:"fecha"`   // Optional - defaults to today (YYYY-MM-DD)
+	Hora   string `json:"hora"`    // Optional - defaults to now (HH:MM)
+}
+
+// CheckoutAppointment creates an invoice from one or more completed appointments of the same
+// client, applying active promotions and linking the appointments to the new invoice
+func (ac *AppointmentController) CheckoutAppointment(c *gin.Context) {
+	appointmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidAppointmentID})
+		return
+	}
+
+	var req CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if req.Fecha == "" {
+		req.Fecha = now.Format(DateFormat)
+	} else if _, err := time.Parse(DateFormat, req.Fecha); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDateFormat})
+		return
+	}
+	if req.Hora == "" {
+		req.Hora = now.Format(TimeFormat)
+	} else if _, err := time.Parse(TimeFormat, req.Hora); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidTimeFormat})
+		return
+	}
+
+	citIDs := []uint{uint(appointmentID)}
+	seen := map[uint]bool{uint(appointmentID): true}
+	for _, citID := range req.CitIDs {
+		if !seen[citID] {
+			seen[citID] = true
+			citIDs = append(citIDs, citID)
+		}
+	}
+
+	factura, err := ac.dbService.FacturarCitas(citIDs, req.Fecha, req.Hora, c.GetString("user_email"))
+	var noFacturable *services.CitaNoFacturableError
+	if errors.As(err, &noFacturable) {
+		respondCheckoutError(c, noFacturable)
+		return
+	}
+	var noAplicable *services.PromocionNoAplicableError
+	if errors.As(err, &noAplicable) {
+		respondPromotionNotApplicable(c, noAplicable)
+		return
+	}
+	if respondNumberingError(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrStockInsuficienteReceta) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrNotEnoughStockForRecipe})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedCheckout})
+		return
+	}
+
+	lineas, err := ac.dbService.ListarLineasFactura(factura.FacID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveDetails})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Invoice created from appointments successfully",
+		"invoice": factura,
+		"lines":   lineas,
+		"cit_ids": citIDs,
+	})
+}
+
+func respondCheckoutError(c *gin.Context, err *services.CitaNoFacturableError) {
+	switch err.Motivo {
+	case services.MotivoCitaNoEncontrada:
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrAppointmentNotFound, "cit_id": err.CitID})
+	case services.MotivoCitaYaFacturada:
+		c.JSON(http.StatusConflict, gin.H{"error": ErrAppointmentAlreadyInvoiced, "cit_id": err.CitID, "fac_id": err.FacID})
+	case services.MotivoCitaNoCompletada:
+		c.JSON(http.StatusConflict, gin.H{"error": ErrAppointmentNotCompleted, "cit_id": err.CitID})
+	case services.MotivoCitaOtroCliente:
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrCheckoutMixedClients, "cit_id": err.CitID})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrCheckoutRepeatedService, "cit_id": err.CitID})
+	}
+}
+
+// ============= AVAILABILITY =============
+
+const (
+	// SlotIntervalMinutes is the spacing between candidate start times offered to clients
+	SlotIntervalMinutes = 15
+	// DefaultServiceDuration is used for services without an estimated duration
+	DefaultServiceDuration = 60
+)
+
+const (
+	ErrFailedRetrieveAvailability = "Failed to retrieve availability"
+	ErrInvalidServiceIDParam      = "A valid ser_id is required"
+	ErrServiceNotFoundForBooking  = "Service not found"
+	ErrPastDate                   = "Date must be today or later"
+)
+
+// diasPorWeekday maps time.Weekday to the day names stored in HORARIO_EMPLEADO
+var diasPorWeekday = [...]string{"Domingo", "Lunes", "Martes", "Miercoles", "Jueves", "Viernes", "Sabado"}
+
+type EmployeeAvailability struct {
+	EmpID       uint     `json:"emp_id"`
+	EmpNombre   string   `json:"emp_nombre"`
+	EmpApellido string   `json:"emp_apellido"`
+	Slots       []string `json:"slots"`
+}
+
+// timeRange is a half-open [Start, End) interval expressed in minutes since midnight
+type timeRange struct {
+	Start int
+	End   int
+}
+
+func (r timeRange) overlaps(other timeRange) bool {
+	return r.Start < other.End && other.Start < r.End
+}
+
+// GetAvailability returns the free start slots for a service on a date, optionally for a single employee
+func (ac *AppointmentController) GetAvailability(c *gin.Context) {
+	serID, err := strconv.ParseUint(c.Query("ser_id"), 10, 32)
+	if err != nil || serID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidServiceIDParam})
+		return
+	}
+
+	dateStr := c.Query("date")
+	date, err := time.ParseInLocation(DateFormat, dateStr, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDateFormat})
+		return
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if date.Before(today) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrPastDate})
+		return
+	}
+
+	var empID *uint
+	if empParam := c.Query("emp_id"); empParam != "" {
+		id, err := strconv.ParseUint(empParam, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
+			return
+		}
+		value := uint(id)
+		empID = &value
+	}
+
+	servicio, err := ac.dbService.BuscarServicioPorID(uint(serID))
+	if err != nil || servicio.SerID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrServiceNotFoundForBooking})
+		return
+	}
+	duracion := serviceDuration(servicio.SerDuracionEstimada)
+
+	workingHours, err := ac.loadWorkingHours(date, empID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveAvailability})
+		return
+	}
+
+	citas, err := ac.dbService.ListarCitasPorFecha(dateStr, empID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveAvailability})
+		return
+	}
+
+	empleados, err := ac.dbService.GetEmpleados()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveAvailability})
+		return
+	}
+
+	// Slots that already started are not offered when searching today
+	earliest := 0
+	if date.Equal(today) {
+		earliest = now.Hour()*60 + now.Minute()
+	}
+
+	busy := buildBusyRanges(citas)
+
+	availability := []EmployeeAvailability{}
+	for _, empleado := range empleados {
+		windows, ok := workingHours[empleado.EmpID]
+		if !ok {
+			continue
+		}
+		slots := freeSlots(windows, busy[empleado.EmpID], duracion, earliest)
+		if len(slots) == 0 {
+			continue
+		}
+		availability = append(availability, EmployeeAvailability{
+			EmpID:       empleado.EmpID,
+			EmpNombre:   empleado.EmpNombre,
+			EmpApellido: empleado.EmpApellido,
+			Slots:       slots,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":         dateStr,
+		"ser_id":       servicio.SerID,
+		"duration":     duracion,
+		"availability": availability,
+		"total":        len(availability),
+	})
+}
+
+// loadWorkingHours reads the weekly shifts and the exceptions of a date and returns the working
+// windows per employee, optionally for a single employee
+func (ac *AppointmentController) loadWorkingHours(date time.Time, empID *uint) (map[uint][]timeRange, error) {
+	horarios, err := ac.dbService.ListarHorariosPorDia(diasPorWeekday[date.Weekday()], empID)
+	if err != nil {
+		return nil, err
+	}
+
+	excepciones, err := ac.dbService.ListarExcepcionesPorFecha(date.Format(DateFormat), empID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildWorkingHours(horarios, excepciones), nil
+}
+
+// fitsWorkingHours reports whether the employee works during the whole service starting at
+// hora on date, using the same shifts and exceptions as the availability search
+func (ac *AppointmentController) fitsWorkingHours(empID, serID uint, date time.Time, hora string) (bool, error) {
+	servicio, err := ac.dbService.BuscarServicioPorID(serID)
+	if err != nil {
+		return false, err
+	}
+	start, err := parseClock(hora)
+	if err != nil {
+		return false, err
+	}
+
+	workingHours, err := ac.loadWorkingHours(date, &empID)
+	if err != nil {
+		return false, err
+	}
+
+	slot := timeRange{Start: start, End: start + serviceDuration(servicio.SerDuracionEstimada)}
+	for _, window := range workingHours[empID] {
+		if window.Start <= slot.Start && slot.End <= window.End {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// buildWorkingHours returns the working windows per employee for a single date. Full-day
+// exceptions remove the employee from the day and special-hours exceptions replace the
+// regular weekly shifts.
+func buildWorkingHours(horarios []models.HorarioEmpleado, excepciones []models.HorarioExcepcion) map[uint][]timeRange {
+	windows := make(map[uint][]timeRange)
+	for _, horario := range horarios {
+		start, errStart := parseClock(horario.HorHoraEntrada)
+		end, errEnd := parseClock(horario.HorHoraSalida)
+		if errStart != nil || errEnd != nil || start >= end {
+			continue
+		}
+		windows[horario.EmpID] = append(windows[horario.EmpID], timeRange{Start: start, End: end})
+	}
+
+	special := make(map[uint][]timeRange)
+	dayOff := make(map[uint]bool)
+	for _, excepcion := range excepciones {
+		if excepcion.HexTipo != ExceptionTypeSpecialHours {
+			dayOff[excepcion.EmpID] = true
+			continue
+		}
+		if excepcion.HexHoraEntrada == nil || excepcion.HexHoraSalida == nil {
+			continue
+		}
+		start, errStart := parseClock(*excepcion.HexHoraEntrada)
+		end, errEnd := parseClock(*excepcion.HexHoraSalida)
+		if errStart != nil || errEnd != nil || start >= end {
+			continue
+		}
+		special[excepcion.EmpID] = append(special[excepcion.EmpID], timeRange{Start: start, End: end})
+	}
+
+	for empID, ranges := range special {
+		windows[empID] = ranges
+	}
+	for empID := range dayOff {
+		delete(windows, empID)
+	}
+	return windows
+}
+
+// buildBusyRanges groups the booked intervals of the day per employee
+func buildBusyRanges(citas []models.CitaAgenda) map[uint][]timeRange {
+	busy := make(map[uint][]timeRange)
+	for _, cita := range citas {
+		start, err := parseClock(cita.CitHora)
+		if err != nil {
+			continue
+		}
+		busy[cita.EmpID] = append(busy[cita.EmpID], timeRange{
+			Start: start,
+			End:   start + serviceDuration(cita.SerDuracionEstimada),
+		})
+	}
+	return busy
+}
+
+// freeSlots lists every start time, at SlotIntervalMinutes spacing, where a service of the
+// given duration fits inside a working window without overlapping a booked range
+func freeSlots(windows []timeRange, busy []timeRange, duration, earliest int) []string {
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start < windows[j].Start })
+
+	var slots []string
+	seen := make(map[int]bool)
+	for _, window := range windows {
+		for start := window.Start; start+duration <= window.End; start += SlotIntervalMinutes {
+			if start < earliest || seen[start] {
+				continue
+			}
+			candidate := timeRange{Start: start, End: start + duration}
+			free := true
+			for _, booked := range busy {
+				if candidate.overlaps(booked) {
+					free = false
+					break
+				}
+			}
+			if free {
+				seen[start] = true
+				slots = append(slots, formatClock(start))
+			}
+		}
+	}
+	return slots
+}
+
+func serviceDuration(minutes int) int {
+	if minutes <= 0 {
+		return DefaultServiceDuration
+	}
+	return minutes
+}
+
+// parseClock converts a TIME value (HH:MM or HH:MM:SS) to minutes since midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04:05", value)
+	if err != nil {
+		t, err = time.Parse(TimeFormat, value)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
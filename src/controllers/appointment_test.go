@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"salon/models"
+)
+
+func TestBuildWorkingHours(t *testing.T) {
+	hora := func(valor string) *string { return &valor }
+
+	tests := []struct {
+		name        string
+		horarios    []models.HorarioEmpleado
+		excepciones []models.HorarioExcepcion
+		want        map[uint][]timeRange
+	}{
+		{
+			name: "regular and split shifts",
+			horarios: []models.HorarioEmpleado{
+				{EmpID: 1, HorHoraEntrada: "09:00:00", HorHoraSalida: "13:00:00"},
+				{EmpID: 1, HorHoraEntrada: "14:00:00", HorHoraSalida: "18:00:00"},
+				{EmpID: 2, HorHoraEntrada: "10:00", HorHoraSalida: "16:30"},
+			},
+			want: map[uint][]timeRange{
+				1: {{Start: 540, End: 780}, {Start: 840, End: 1080}},
+				2: {{Start: 600, End: 990}},
+			},
+		},
+		{
+			name: "shifts that end before they start are ignored",
+			horarios: []models.HorarioEmpleado{
+				{EmpID: 1, HorHoraEntrada: "18:00:00", HorHoraSalida: "09:00:00"},
+				{EmpID: 2, HorHoraEntrada: "09:00:00", HorHoraSalida: "09:00:00"},
+			},
+			want: map[uint][]timeRange{},
+		},
+		{
+			name: "a day off removes the employee",
+			horarios: []models.HorarioEmpleado{
+				{EmpID: 1, HorHoraEntrada: "09:00:00", HorHoraSalida: "18:00:00"},
+				{EmpID: 2, HorHoraEntrada: "09:00:00", HorHoraSalida: "18:00:00"},
+			},
+			excepciones: []models.HorarioExcepcion{{EmpID: 1, HexTipo: "Vacaciones"}},
+			want: map[uint][]timeRange{
+				2: {{Start: 540, End: 1080}},
+			},
+		},
+		{
+			name: "special hours replace the regular shifts",
+			horarios: []models.HorarioEmpleado{
+				{EmpID: 1, HorHoraEntrada: "09:00:00", HorHoraSalida: "18:00:00"},
+			},
+			excepciones: []models.HorarioExcepcion{
+				{EmpID: 1, HexTipo: ExceptionTypeSpecialHours, HexHoraEntrada: hora("12:00:00"), HexHoraSalida: hora("15:00:00")},
+				{EmpID: 3, HexTipo: ExceptionTypeSpecialHours, HexHoraEntrada: hora("08:00:00"), HexHoraSalida: hora("10:00:00")},
+			},
+			want: map[uint][]timeRange{
+				1: {{Start: 720, End: 900}},
+				3: {{Start: 480, End: 600}},
+			},
+		},
+		{
+			name: "a day off wins over special hours",
+			horarios: []models.HorarioEmpleado{
+				{EmpID: 1, HorHoraEntrada: "09:00:00", HorHoraSalida: "18:00:00"},
+			},
+			excepciones: []models.HorarioExcepcion{
+				{EmpID: 1, HexTipo: ExceptionTypeSpecialHours, HexHoraEntrada: hora("12:00:00"), HexHoraSalida: hora("15:00:00")},
+				{EmpID: 1, HexTipo: "Festivo"},
+			},
+			want: map[uint][]timeRange{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildWorkingHours(tt.horarios, tt.excepciones); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildWorkingHours() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFreeSlots(t *testing.T) {
+	tests := []struct {
+		name     string
+		windows  []timeRange
+		busy     []timeRange
+		duration int
+		earliest int
+		want     []string
+	}{
+		{
+			name:     "slots around a booked hour",
+			windows:  []timeRange{{Start: 540, End: 720}},
+			busy:     []timeRange{{Start: 600, End: 660}},
+			duration: 60,
+			want:     []string{"09:00", "11:00"},
+		},
+		{
+			name:     "nothing before the earliest start",
+			windows:  []timeRange{{Start: 540, End: 720}},
+			busy:     []timeRange{{Start: 600, End: 660}},
+			duration: 60,
+			earliest: 600,
+			want:     []string{"11:00"},
+		},
+		{
+			name:     "the service must end inside the window",
+			windows:  []timeRange{{Start: 540, End: 600}},
+			duration: 45,
+			want:     []string{"09:00", "09:15"},
+		},
+		{
+			name:     "overlapping windows do not repeat a start",
+			windows:  []timeRange{{Start: 570, End: 660}, {Start: 540, End: 600}},
+			duration: 30,
+			want:     []string{"09:00", "09:15", "09:30", "09:45", "10:00", "10:15", "10:30"},
+		},
+		{
+			name:     "fully booked",
+			windows:  []timeRange{{Start: 540, End: 660}},
+			busy:     []timeRange{{Start: 540, End: 600}, {Start: 600, End: 660}},
+			duration: 30,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := freeSlots(tt.windows, tt.busy, tt.duration, tt.earliest); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("freeSlots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceDuration(t *testing.T) {
+	tests := []struct {
+		minutes int
+		want    int
+	}{
+		{45, 45},
+		{0, DefaultServiceDuration},
+		{-10, DefaultServiceDuration},
+	}
+
+	for _, tt := range tests {
+		if got := serviceDuration(tt.minutes); got != tt.want {
+			t.Errorf("serviceDuration(%d) = %d, want %d", tt.minutes, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"salon/models"
 	"salon/services"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -27,6 +28,12 @@ const (
 	ErrFailedUpdateProfile   = "Failed to update profile"
 )
 
+const (
+	ErrClientNotFound       = "Client not found"
+	ErrInvalidDocumentType  = "Invalid document type, expected CC, CE, NIT, PP or TI"
+	ErrFailedUpdateDocument = "Failed to update client document"
+)
+
 type ClientController struct {
 	dbService *services.DatabaseService
 }
@@ -157,6 +164,51 @@ func (cc *ClientController) DeleteClient(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Client deleted successfully"})
 }
 
+// ClientDocumentRequest is the identification document of a client, required on electronic invoices
+type ClientDocumentRequest struct {
+	TipoDocumento string `json:"cli_tipo_documento" binding:"required"`
+	Documento     string `json:"cli_documento" binding:"required,max=20,numeric"`
+}
+
+// UpdateClientDocument records the identification document of a client (admin only)
+func (cc *ClientController) UpdateClientDocument(c *gin.Context) {
+	var clientID uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &clientID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidClientID})
+		return
+	}
+
+	var req ClientDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.TipoDocumento = strings.ToUpper(strings.TrimSpace(req.TipoDocumento))
+	if !services.EsTipoDocumento(req.TipoDocumento) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDocumentType})
+		return
+	}
+
+	client, err := cc.dbService.BuscarClientePorID(clientID)
+	if err != nil || client.CliID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrClientNotFound})
+		return
+	}
+
+	if err := cc.dbService.ActualizarDocumentoCliente(clientID, req.TipoDocumento, req.Documento); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedUpdateDocument})
+		return
+	}
+
+	client.CliTipoDocumento = &req.TipoDocumento
+	client.CliDocumento = &req.Documento
+	client.CliPassword = "" // Don't return password
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Client document updated successfully",
+		"client":  client,
+	})
+}
+
 // GetProfile gets the authenticated client's own profile
 func (cc *ClientController) GetProfile(c *gin.Context) {
 	// Get user information from context (set by AuthMiddleware)
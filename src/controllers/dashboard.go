@@ -3,6 +3,7 @@ package controllers
 import (
 	"net/http"
 	"salon/services"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -95,6 +96,14 @@ func (dc *DashboardController) GetInventoryMetrics(c *gin.Context) {
 		metrics["productos_bajos"] = prodBajos.ProductosBajos
 	}
 
+	// Products below their minimum stock, with the suggested order quantity
+	reorden, err := dc.dbService.GetProductosReorden()
+	if err != nil {
+		metrics["productos_reorden"] = []interface{}{}
+	} else {
+		metrics["productos_reorden"] = reorden
+	}
+
 	// Get total products
 	totalProd, err := dc.dbService.GetTotalProductos()
 	if err != nil {
@@ -109,6 +118,7 @@ func (dc *DashboardController) GetInventoryMetrics(c *gin.Context) {
 		metrics["valor_inventario"] = 0.0
 	} else {
 		metrics["valor_inventario"] = valorInv.ValorTotal
+		metrics["valor_inventario_venta"] = valorInv.ValorVenta
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -117,6 +127,80 @@ func (dc *DashboardController) GetInventoryMetrics(c *gin.Context) {
 	})
 }
 
+// GetInventoryValuation handles GET /dashboard/inventory/valuation - stock valued at weighted average cost
+func (dc *DashboardController) GetInventoryValuation(c *gin.Context) {
+	productos, err := dc.dbService.ReporteValorizacionInventario()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve inventory valuation",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	valorCosto, valorVenta := 0.0, 0.0
+	for _, producto := range productos {
+		valorCosto += producto.ValorCosto
+		valorVenta += producto.ValorVenta
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"productos":   productos,
+			"valor_costo": valorCosto,
+			"valor_venta": valorVenta,
+		},
+	})
+}
+
+// GetInventoryCostOfGoods handles GET /dashboard/inventory/cogs - cost of the stock consumed by services,
+// sold or wasted between ?desde= and ?hasta= (YYYY-MM-DD, default current month)
+func (dc *DashboardController) GetInventoryCostOfGoods(c *gin.Context) {
+	now := time.Now()
+	desde := c.DefaultQuery("desde", time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02"))
+	hasta := c.DefaultQuery("hasta", now.Format("2006-01-02"))
+	for _, fecha := range []string{desde, hasta} {
+		if _, err := time.Parse("2006-01-02", fecha); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid date format. Use YYYY-MM-DD",
+			})
+			return
+		}
+	}
+
+	salidas, err := dc.dbService.ReporteCostoSalidas(desde, hasta)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve cost of goods",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Service consumption and retail sales are cost of goods; waste is reported apart
+	costoPorTipo := make(map[string]float64)
+	costoVentas := 0.0
+	for _, salida := range salidas {
+		costoPorTipo[salida.MovTipo] += salida.Costo
+		if salida.MovTipo != services.MovimientoMerma {
+			costoVentas += salida.Costo
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"desde":          desde,
+			"hasta":          hasta,
+			"detalle":        salidas,
+			"costo_por_tipo": costoPorTipo,
+			"costo_ventas":   costoVentas,
+			"merma":          costoPorTipo[services.MovimientoMerma],
+		},
+	})
+}
+
 // GetServicesMetrics handles GET /dashboard/services - uses views
 func (dc *DashboardController) GetServicesMetrics(c *gin.Context) {
 	metrics := make(map[string]interface{})
@@ -5,6 +5,8 @@ import (
 	"salon/models"
 	"salon/services"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -161,3 +163,445 @@ func (ec *SPEmployeeController) DeleteEmployee(c *gin.Context) {
 		"message": "Employee deleted successfully",
 	})
 }
+
+// ============= EMPLOYEE SCHEDULE MANAGEMENT =============
+
+const (
+	ErrInvalidEmployeeID         = "Invalid employee ID"
+	ErrEmployeeNotFound          = "Employee not found"
+	ErrInvalidScheduleID         = "Invalid schedule ID"
+	ErrScheduleNotFound          = "Schedule not found"
+	ErrInvalidDayOfWeek          = "Invalid day of week. Use Lunes, Martes, Miercoles, Jueves, Viernes, Sabado or Domingo"
+	ErrInvalidScheduleHours      = "Entry time must be before exit time"
+	ErrInvalidExceptionID        = "Invalid schedule exception ID"
+	ErrScheduleExceptionNotFound = "Schedule exception not found"
+	ErrInvalidExceptionType      = "Invalid exception type. Use Descanso, Festivo, Vacaciones or Horario Especial"
+	ErrSpecialHoursRequired      = "Entry and exit times are required for Horario Especial exceptions"
+)
+
+// ExceptionTypeSpecialHours marks an exception that replaces the regular shift with custom
+// hours instead of taking the whole day off.
+const ExceptionTypeSpecialHours = "Horario Especial"
+
+// diasSemana maps accepted day names to the canonical value stored in HORARIO_EMPLEADO
+var diasSemana = map[string]string{
+	"lunes":     "Lunes",
+	"martes":    "Martes",
+	"miercoles": "Miercoles",
+	"miércoles": "Miercoles",
+	"jueves":    "Jueves",
+	"viernes":   "Viernes",
+	"sabado":    "Sabado",
+	"sábado":    "Sabado",
+	"domingo":   "Domingo",
+}
+
+var tiposExcepcion = map[string]bool{
+	"Descanso":                true,
+	"Festivo":                 true,
+	"Vacaciones":              true,
+	ExceptionTypeSpecialHours: true,
+}
+
+type ScheduleRequest struct {
+	HorDiaSemana   string `json:"hor_dia_semana" binding:"required"`
+	HorHoraEntrada string `json:"hor_hora_entrada" binding:"required"` // Format: HH:MM
+	HorHoraSalida  string `json:"hor_hora_salida" binding:"required"`  // Format: HH:MM
+}
+
+type ScheduleExceptionRequest struct {
+	HexFecha       string  `json:"hex_fecha" binding:"required"` // Format: YYYY-MM-DD
+	HexTipo        string  `json:"hex_tipo" binding:"required"`
+	HexHoraEntrada *string `json:"hex_hora_entrada"` // Only for Horario Especial
+	HexHoraSalida  *string `json:"hex_hora_salida"`  // Only for Horario Especial
+	HexMotivo      string  `json:"hex_motivo"`
+}
+
+type ScheduleExceptionResponse struct {
+	HexID          uint    `json:"hex_id"`
+	HexFecha       string  `json:"hex_fecha"`
+	HexTipo        string  `json:"hex_tipo"`
+	HexHoraEntrada *string `json:"hex_hora_entrada"`
+	HexHoraSalida  *string `json:"hex_hora_salida"`
+	HexMotivo      string  `json:"hex_motivo"`
+	EmpID          uint    `json:"emp_id"`
+}
+
+// GetSchedule handles GET /employees/:id/schedule - returns the weekly schedule and exceptions
+func (ec *SPEmployeeController) GetSchedule(c *gin.Context) {
+	empID, ok := ec.resolveEmployee(c)
+	if !ok {
+		return
+	}
+
+	horarios, err := ec.dbService.ListarHorariosEmpleado(empID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve schedule",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	excepciones, err := ec.dbService.ListarExcepcionesEmpleado(empID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve schedule exceptions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"emp_id":     empID,
+		"schedule":   horarios,
+		"exceptions": toScheduleExceptionResponses(excepciones),
+	})
+}
+
+// CreateSchedule handles POST /employees/:id/schedule
+func (ec *SPEmployeeController) CreateSchedule(c *gin.Context) {
+	empID, ok := ec.resolveEmployee(c)
+	if !ok {
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	dia, errMsg := validateScheduleRequest(req)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	err := ec.dbService.InsertarHorario(dia, req.HorHoraEntrada, req.HorHoraSalida, empID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create schedule",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Schedule created successfully",
+	})
+}
+
+// UpdateSchedule handles PUT /employees/:id/schedule/:hor_id
+func (ec *SPEmployeeController) UpdateSchedule(c *gin.Context) {
+	horario, ok := ec.resolveSchedule(c)
+	if !ok {
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	dia, errMsg := validateScheduleRequest(req)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	err := ec.dbService.ActualizarHorario(horario.HorID, dia, req.HorHoraEntrada, req.HorHoraSalida)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update schedule",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Schedule updated successfully",
+	})
+}
+
+// DeleteSchedule handles DELETE /employees/:id/schedule/:hor_id
+func (ec *SPEmployeeController) DeleteSchedule(c *gin.Context) {
+	horario, ok := ec.resolveSchedule(c)
+	if !ok {
+		return
+	}
+
+	if err := ec.dbService.EliminarHorario(horario.HorID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete schedule",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Schedule deleted successfully",
+	})
+}
+
+// GetScheduleExceptions handles GET /employees/:id/schedule/exceptions
+func (ec *SPEmployeeController) GetScheduleExceptions(c *gin.Context) {
+	empID, ok := ec.resolveEmployee(c)
+	if !ok {
+		return
+	}
+
+	excepciones, err := ec.dbService.ListarExcepcionesEmpleado(empID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve schedule exceptions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"emp_id":     empID,
+		"exceptions": toScheduleExceptionResponses(excepciones),
+	})
+}
+
+// CreateScheduleException handles POST /employees/:id/schedule/exceptions - registers a day off,
+// holiday or special hours for a specific date
+func (ec *SPEmployeeController) CreateScheduleException(c *gin.Context) {
+	empID, ok := ec.resolveEmployee(c)
+	if !ok {
+		return
+	}
+
+	var req ScheduleExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if errMsg := validateScheduleExceptionRequest(&req); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	err := ec.dbService.InsertarExcepcion(req.HexFecha, req.HexTipo, req.HexHoraEntrada, req.HexHoraSalida, req.HexMotivo, empID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create schedule exception",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Schedule exception created successfully",
+	})
+}
+
+// UpdateScheduleException handles PUT /employees/:id/schedule/exceptions/:hex_id
+func (ec *SPEmployeeController) UpdateScheduleException(c *gin.Context) {
+	excepcion, ok := ec.resolveScheduleException(c)
+	if !ok {
+		return
+	}
+
+	var req ScheduleExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if errMsg := validateScheduleExceptionRequest(&req); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	err := ec.dbService.ActualizarExcepcion(excepcion.HexID, req.HexFecha, req.HexTipo, req.HexHoraEntrada, req.HexHoraSalida, req.HexMotivo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update schedule exception",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Schedule exception updated successfully",
+	})
+}
+
+// DeleteScheduleException handles DELETE /employees/:id/schedule/exceptions/:hex_id
+func (ec *SPEmployeeController) DeleteScheduleException(c *gin.Context) {
+	excepcion, ok := ec.resolveScheduleException(c)
+	if !ok {
+		return
+	}
+
+	if err := ec.dbService.EliminarExcepcion(excepcion.HexID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete schedule exception",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Schedule exception deleted successfully",
+	})
+}
+
+// resolveEmployee parses the :id parameter and checks the employee exists, writing the
+// error response when it does not
+func (ec *SPEmployeeController) resolveEmployee(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidEmployeeID})
+		return 0, false
+	}
+
+	employee, err := ec.dbService.BuscarEmpleadoPorID(uint(id))
+	if err != nil || employee.EmpID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrEmployeeNotFound})
+		return 0, false
+	}
+
+	return employee.EmpID, true
+}
+
+// resolveSchedule loads the schedule entry in :hor_id and checks it belongs to the employee in :id
+func (ec *SPEmployeeController) resolveSchedule(c *gin.Context) (*models.HorarioEmpleado, bool) {
+	empID, ok := ec.resolveEmployee(c)
+	if !ok {
+		return nil, false
+	}
+
+	horID, err := strconv.ParseUint(c.Param("hor_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidScheduleID})
+		return nil, false
+	}
+
+	horario, err := ec.dbService.BuscarHorarioPorID(uint(horID))
+	if err != nil || horario.EmpID != empID {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrScheduleNotFound})
+		return nil, false
+	}
+
+	return horario, true
+}
+
+// resolveScheduleException loads the exception in :hex_id and checks it belongs to the employee in :id
+func (ec *SPEmployeeController) resolveScheduleException(c *gin.Context) (*models.HorarioExcepcion, bool) {
+	empID, ok := ec.resolveEmployee(c)
+	if !ok {
+		return nil, false
+	}
+
+	hexID, err := strconv.ParseUint(c.Param("hex_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidExceptionID})
+		return nil, false
+	}
+
+	excepcion, err := ec.dbService.BuscarExcepcionPorID(uint(hexID))
+	if err != nil || excepcion.EmpID != empID {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrScheduleExceptionNotFound})
+		return nil, false
+	}
+
+	return excepcion, true
+}
+
+// validateScheduleRequest returns the canonical day name, or an error message when the request is invalid
+func validateScheduleRequest(req ScheduleRequest) (string, string) {
+	dia, ok := diasSemana[strings.ToLower(strings.TrimSpace(req.HorDiaSemana))]
+	if !ok {
+		return "", ErrInvalidDayOfWeek
+	}
+
+	entrada, err := time.Parse(TimeFormat, req.HorHoraEntrada)
+	if err != nil {
+		return "", ErrInvalidTimeFormat
+	}
+	salida, err := time.Parse(TimeFormat, req.HorHoraSalida)
+	if err != nil {
+		return "", ErrInvalidTimeFormat
+	}
+	if !entrada.Before(salida) {
+		return "", ErrInvalidScheduleHours
+	}
+
+	return dia, ""
+}
+
+// validateScheduleExceptionRequest checks the request and clears the hours for full-day exceptions
+func validateScheduleExceptionRequest(req *ScheduleExceptionRequest) string {
+	if _, err := time.Parse(DateFormat, req.HexFecha); err != nil {
+		return ErrInvalidDateFormat
+	}
+
+	if !tiposExcepcion[req.HexTipo] {
+		return ErrInvalidExceptionType
+	}
+
+	if req.HexTipo != ExceptionTypeSpecialHours {
+		req.HexHoraEntrada = nil
+		req.HexHoraSalida = nil
+		return ""
+	}
+
+	if req.HexHoraEntrada == nil || req.HexHoraSalida == nil {
+		return ErrSpecialHoursRequired
+	}
+	entrada, err := time.Parse(TimeFormat, *req.HexHoraEntrada)
+	if err != nil {
+		return ErrInvalidTimeFormat
+	}
+	salida, err := time.Parse(TimeFormat, *req.HexHoraSalida)
+	if err != nil {
+		return ErrInvalidTimeFormat
+	}
+	if !entrada.Before(salida) {
+		return ErrInvalidScheduleHours
+	}
+
+	return ""
+}
+
+func toScheduleExceptionResponses(excepciones []models.HorarioExcepcion) []ScheduleExceptionResponse {
+	responses := make([]ScheduleExceptionResponse, 0, len(excepciones))
+	for _, excepcion := range excepciones {
+		responses = append(responses, ScheduleExceptionResponse{
+			HexID:          excepcion.HexID,
+			HexFecha:       excepcion.HexFecha.Format(DateFormat),
+			HexTipo:        excepcion.HexTipo,
+			HexHoraEntrada: excepcion.HexHoraEntrada,
+			HexHoraSalida:  excepcion.HexHoraSalida,
+			HexMotivo:      excepcion.HexMotivo,
+			EmpID:          excepcion.EmpID,
+		})
+	}
+	return responses
+}
@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"salon/models"
 	"salon/services"
@@ -82,13 +84,21 @@ func (ic *InventoryController) CreateProduct(c *gin.Context) {
 		return
 	}
 
+	// prod_costo_promedio is the unit cost of the initial stock
+	if product.ProdCostoPromedio < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Product cost cannot be negative",
+		})
+		return
+	}
+
 	// Set default quantity if not provided
 	if product.ProdCantidadDisponible < 0 {
 		product.ProdCantidadDisponible = 0
 	}
 
 	// Use stored procedure to create product (it also creates inventory entry)
-	err := ic.dbService.InsertProducto(product)
+	err := ic.dbService.InsertProducto(product, c.GetString("user_email"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create product",
@@ -115,14 +125,18 @@ func (ic *InventoryController) UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	var product models.Product
-	if err := c.ShouldBindJSON(&product); err != nil {
+	var request struct {
+		models.Product
+		Motivo string `json:"motivo"` // Reason recorded when the quantity changes
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request data",
 			"details": err.Error(),
 		})
 		return
 	}
+	product := request.Product
 
 	// Set the product ID from URL parameter
 	product.ProdID = uint(productID)
@@ -149,8 +163,8 @@ func (ic *InventoryController) UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	// Use stored procedure to update product (it also updates inventory)
-	err = ic.dbService.UpdateProducto(product)
+	// Use stored procedure to update product (a quantity change is recorded as a stock adjustment)
+	err = ic.dbService.UpdateProducto(product, c.GetString("user_email"), request.Motivo)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update product",
@@ -199,6 +213,7 @@ func (ic *InventoryController) CreateInventory(c *gin.Context) {
 		ProdID        uint   `json:"prod_id" binding:"required"`
 		Cantidad      int    `json:"cantidad" binding:"required"`
 		Observaciones string `json:"observaciones"`
+		Motivo        string `json:"motivo"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -219,7 +234,8 @@ func (ic *InventoryController) CreateInventory(c *gin.Context) {
 	// Use current date
 	fecha := time.Now().Format("2006-01-02")
 
-	err := ic.dbService.CrearInventario(fecha, request.ProdID, request.Cantidad, request.Observaciones)
+	err := ic.dbService.CrearInventario(fecha, request.ProdID, request.Cantidad, request.Observaciones,
+		c.GetString("user_email"), request.Motivo)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create inventory entry",
@@ -248,6 +264,7 @@ func (ic *InventoryController) UpdateInventory(c *gin.Context) {
 	var request struct {
 		Cantidad      int    `json:"cantidad" binding:"required"`
 		Observaciones string `json:"observaciones"`
+		Motivo        string `json:"motivo"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -268,7 +285,9 @@ func (ic *InventoryController) UpdateInventory(c *gin.Context) {
 	// Use current date
 	fecha := time.Now().Format("2006-01-02")
 
-	err = ic.dbService.ActualizarInventario(uint(invID), fecha, request.Cantidad, request.Observaciones)
+	// The difference with the current stock is recorded as an adjustment movement
+	err = ic.dbService.ActualizarInventario(uint(invID), fecha, request.Cantidad, request.Observaciones,
+		c.GetString("user_email"), request.Motivo)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update inventory",
@@ -308,3 +327,710 @@ func (ic *InventoryController) DeleteInventory(c *gin.Context) {
 		"message": "Inventory entry deleted successfully",
 	})
 }
+
+// ============= STOCK MOVEMENTS =============
+
+// manualMovementTypes are the movements that can be registered by hand; purchases, service
+// consumption and sales are recorded by their own flows
+var manualMovementTypes = map[string]bool{
+	services.MovimientoAjuste:     true,
+	services.MovimientoMerma:      true,
+	services.MovimientoDevolucion: true,
+}
+
+// RegisterMovement appends a manual movement (adjustment, waste or return) to a product's stock ledger
+func (ic *InventoryController) RegisterMovement(c *gin.Context) {
+	var request struct {
+		ProdID        uint     `json:"prod_id" binding:"required"`
+		Tipo          string   `json:"tipo" binding:"required"`
+		Cantidad      int      `json:"cantidad" binding:"required"` // Signed change; for Merma the units lost
+		Motivo        string   `json:"motivo" binding:"required"`
+		Referencia    string   `json:"referencia"`
+		CostoUnitario *float64 `json:"costo_unitario" binding:"omitempty,min=0"` // Unit cost of units coming in; defaults to the average cost
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !manualMovementTypes[request.Tipo] {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid movement type",
+			"allowed": []string{services.MovimientoAjuste, services.MovimientoMerma, services.MovimientoDevolucion},
+		})
+		return
+	}
+
+	// Waste always takes stock out, whatever sign was sent
+	cantidad := request.Cantidad
+	if request.Tipo == services.MovimientoMerma && cantidad > 0 {
+		cantidad = -cantidad
+	}
+
+	producto, err := ic.dbService.BuscarProductoPorID(request.ProdID)
+	if err != nil || producto.ProdID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	if producto.ProdCantidadDisponible+cantidad < 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "Not enough stock for this movement",
+			"available": producto.ProdCantidadDisponible,
+		})
+		return
+	}
+
+	// Only incoming units can bring their own cost; outgoing units leave at the average cost
+	costoUnitario := request.CostoUnitario
+	if cantidad < 0 {
+		costoUnitario = nil
+	}
+
+	err = ic.dbService.RegistrarMovimientoInventario(request.ProdID, request.Tipo, cantidad,
+		c.GetString("user_email"), request.Motivo, request.Referencia, costoUnitario)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to register stock movement",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":  true,
+		"message":  "Stock movement registered successfully",
+		"prod_id":  request.ProdID,
+		"cantidad": cantidad,
+		"stock":    producto.ProdCantidadDisponible + cantidad,
+	})
+}
+
+// GetProductMovements returns a product's stock ledger, optionally limited with ?desde= and ?hasta= (YYYY-MM-DD)
+func (ic *InventoryController) GetProductMovements(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	desde, hasta := c.Query("desde"), c.Query("hasta")
+	for _, fecha := range []string{desde, hasta} {
+		if fecha == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", fecha); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid date format. Use YYYY-MM-DD",
+			})
+			return
+		}
+	}
+
+	producto, err := ic.dbService.BuscarProductoPorID(uint(productID))
+	if err != nil || producto.ProdID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	movimientos, err := ic.dbService.ListarMovimientosProducto(uint(productID), desde, hasta)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve stock movements",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	entradas, salidas := 0, 0
+	for _, movimiento := range movimientos {
+		if movimiento.MovCantidad > 0 {
+			entradas += movimiento.MovCantidad
+		} else {
+			salidas -= movimiento.MovCantidad
+		}
+	}
+
+	response := gin.H{
+		"success":       true,
+		"product":       producto,
+		"movements":     movimientos,
+		"total":         len(movimientos),
+		"units_in":      entradas,
+		"units_out":     salidas,
+		"current_stock": producto.ProdCantidadDisponible,
+	}
+	// Stock before the first and after the last movement of the range
+	if len(movimientos) > 0 {
+		response["opening_stock"] = movimientos[0].MovSaldo - movimientos[0].MovCantidad
+		response["closing_stock"] = movimientos[len(movimientos)-1].MovSaldo
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ============= REORDER POINTS =============
+
+// UpdateReorderSettings sets a product's minimum stock, usual order quantity and preferred supplier
+func (ic *InventoryController) UpdateReorderSettings(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var request struct {
+		ProdStockMinimo     int   `json:"prod_stock_minimo" binding:"min=0"`
+		ProdCantidadReorden int   `json:"prod_cantidad_reorden" binding:"min=0"`
+		ProvID              *uint `json:"prov_id"` // null clears the preferred supplier
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	producto, err := ic.dbService.BuscarProductoPorID(uint(productID))
+	if err != nil || producto.ProdID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	if request.ProvID != nil {
+		proveedores, err := ic.dbService.ObtenerProveedores()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to retrieve suppliers",
+				"details": err.Error(),
+			})
+			return
+		}
+		existe := false
+		for _, proveedor := range proveedores {
+			if proveedor.ProvID == *request.ProvID {
+				existe = true
+				break
+			}
+		}
+		if !existe {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Supplier not found",
+			})
+			return
+		}
+	}
+
+	err = ic.dbService.ActualizarReordenProducto(uint(productID), request.ProdStockMinimo, request.ProdCantidadReorden, request.ProvID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update reorder settings",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	producto.ProdStockMinimo = request.ProdStockMinimo
+	producto.ProdCantidadReorden = request.ProdCantidadReorden
+	producto.ProvID = request.ProvID
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reorder settings updated successfully",
+		"product": producto,
+	})
+}
+
+// UpdateProductTax sets the VAT rate of a product; invoices already billed keep their rate
+func (ic *InventoryController) UpdateProductTax(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var request TaxRateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	producto, err := ic.dbService.BuscarProductoPorID(uint(productID))
+	if err != nil || producto.ProdID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	if err := ic.dbService.ActualizarIvaProducto(uint(productID), *request.IvaPorcentaje); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   ErrFailedUpdateTaxRate,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	producto.ProdIvaPorcentaje = *request.IvaPorcentaje
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Product VAT rate updated successfully",
+		"product": producto,
+	})
+}
+
+// GetLowStock lists the products below their minimum stock with the suggested order quantity
+func (ic *InventoryController) GetLowStock(c *gin.Context) {
+	productos, err := ic.dbService.GetProductosReorden()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve low stock products",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	costoEstimado := 0.0
+	sinProveedor := 0
+	for _, producto := range productos {
+		costoEstimado += float64(producto.CantidadSugerida) * producto.CostoEstimado
+		if producto.ProvID == nil {
+			sinProveedor++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"products":         productos,
+		"total":            len(productos),
+		"estimated_cost":   costoEstimado,
+		"without_supplier": sinProveedor,
+	})
+}
+
+// CreateReorderPurchases turns the low-stock suggestions into draft purchases grouped by preferred supplier
+func (ic *InventoryController) CreateReorderPurchases(c *gin.Context) {
+	var request struct {
+		CopMetodoPago  string `json:"cop_metodo_pago" binding:"required"`
+		GasID          uint   `json:"gas_id" binding:"required"`
+		CopFechaCompra string `json:"cop_fecha_compra"` // Defaults to today
+		ProdIDs        []uint `json:"prod_ids"`         // Limit the order to these products
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	fecha := time.Now().Format("2006-01-02")
+	if request.CopFechaCompra != "" {
+		if _, err := time.Parse("2006-01-02", request.CopFechaCompra); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid date format. Use YYYY-MM-DD",
+			})
+			return
+		}
+		fecha = request.CopFechaCompra
+	}
+
+	productos, err := ic.dbService.GetProductosReorden()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve low stock products",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	seleccionados := make(map[uint]bool, len(request.ProdIDs))
+	for _, prodID := range request.ProdIDs {
+		seleccionados[prodID] = true
+	}
+
+	pedir := make([]models.ProductoReorden, 0, len(productos))
+	omitidos := make([]models.ProductoReorden, 0)
+	for _, producto := range productos {
+		if len(seleccionados) > 0 && !seleccionados[producto.ProdID] {
+			continue
+		}
+		if producto.CantidadSugerida <= 0 {
+			continue
+		}
+		if producto.ProvID == nil {
+			omitidos = append(omitidos, producto)
+			continue
+		}
+		pedir = append(pedir, producto)
+	}
+
+	if len(pedir) == 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":            "There are no suggested quantities with a preferred supplier to order",
+			"without_supplier": omitidos,
+		})
+		return
+	}
+
+	compras, err := ic.dbService.CrearComprasReorden(fecha, request.CopMetodoPago, request.GasID, pedir)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create purchases",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":          true,
+		"message":          "Draft purchases created successfully",
+		"purchases":        compras,
+		"total":            len(compras),
+		"without_supplier": omitidos,
+	})
+}
+
+// ============= STOCK COUNTS =============
+
+// StockCountSummary totals the variances of a count session
+type StockCountSummary struct {
+	Productos         int     `json:"productos"`
+	Contados          int     `json:"contados"`
+	ConDiferencia     int     `json:"con_diferencia"`
+	ValorFaltante     float64 `json:"valor_faltante"`
+	ValorSobrante     float64 `json:"valor_sobrante"`
+	ValorNeto         float64 `json:"valor_neto"`
+	UnidadesFaltantes int     `json:"unidades_faltantes"`
+	UnidadesSobrantes int     `json:"unidades_sobrantes"`
+}
+
+// OpenStockCount opens a count session; the system stock of every product is frozen at this moment
+func (ic *InventoryController) OpenStockCount(c *gin.Context) {
+	var request struct {
+		Observaciones string `json:"observaciones"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	conteos, err := ic.dbService.ListarConteosInventario()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve stock counts",
+			"details": err.Error(),
+		})
+		return
+	}
+	for _, conteo := range conteos {
+		if conteo.ConEstado == services.EstadoConteoAbierto {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":  "There is already an open stock count",
+				"con_id": conteo.ConID,
+			})
+			return
+		}
+	}
+
+	conteo, err := ic.dbService.AbrirConteoInventario(c.GetString("user_email"), request.Observaciones)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to open stock count",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Stock count opened successfully",
+		"count":   conteo,
+	})
+}
+
+// GetStockCounts lists the count sessions
+func (ic *InventoryController) GetStockCounts(c *gin.Context) {
+	conteos, err := ic.dbService.ListarConteosInventario()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve stock counts",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"counts":  conteos,
+		"total":   len(conteos),
+	})
+}
+
+// GetStockCount returns a count session with the variance of each product and its monetary value
+func (ic *InventoryController) GetStockCount(c *gin.Context) {
+	conteo, ok := ic.findStockCount(c)
+	if !ok {
+		return
+	}
+
+	diferencias, err := ic.dbService.ListarDiferenciasConteo(conteo.ConID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve stock count variances",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   conteo,
+		"lines":   diferencias,
+		"summary": summarizeStockCount(diferencias),
+	})
+}
+
+// SubmitStockCount records the quantities counted by the current user; several employees can count
+// the same product (their quantities are added) and counting a product again replaces your own quantity
+func (ic *InventoryController) SubmitStockCount(c *gin.Context) {
+	var request struct {
+		Lineas []struct {
+			ProdID   uint `json:"prod_id" binding:"required"`
+			Cantidad *int `json:"cantidad" binding:"required,min=0"`
+		} `json:"lineas" binding:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	conteo, ok := ic.findStockCount(c)
+	if !ok {
+		return
+	}
+	if conteo.ConEstado != services.EstadoConteoAbierto {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "The stock count is not open",
+			"con_estado": conteo.ConEstado,
+		})
+		return
+	}
+
+	diferencias, err := ic.dbService.ListarDiferenciasConteo(conteo.ConID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve stock count variances",
+			"details": err.Error(),
+		})
+		return
+	}
+	enConteo := make(map[uint]bool, len(diferencias))
+	for _, diferencia := range diferencias {
+		enConteo[diferencia.ProdID] = true
+	}
+
+	conteos := make([]services.ConteoParams, 0, len(request.Lineas))
+	vistos := make(map[uint]bool, len(request.Lineas))
+	for _, linea := range request.Lineas {
+		if !enConteo[linea.ProdID] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "The product is not part of this stock count",
+				"prod_id": linea.ProdID,
+			})
+			return
+		}
+		if vistos[linea.ProdID] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Each product can only appear once in a submission",
+				"prod_id": linea.ProdID,
+			})
+			return
+		}
+		vistos[linea.ProdID] = true
+		conteos = append(conteos, services.ConteoParams{ProdID: linea.ProdID, Cantidad: *linea.Cantidad})
+	}
+
+	if err := ic.dbService.RegistrarConteo(conteo.ConID, c.GetString("user_email"), conteos); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to record stock count",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Stock count recorded successfully",
+		"con_id":  conteo.ConID,
+		"lines":   len(conteos),
+	})
+}
+
+// ApproveStockCount posts the variances of an open count as stock adjustments in one step
+func (ic *InventoryController) ApproveStockCount(c *gin.Context) {
+	conteo, ok := ic.findStockCount(c)
+	if !ok {
+		return
+	}
+	if conteo.ConEstado != services.EstadoConteoAbierto {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "The stock count is not open",
+			"con_estado": conteo.ConEstado,
+		})
+		return
+	}
+
+	diferencias, err := ic.dbService.ListarDiferenciasConteo(conteo.ConID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve stock count variances",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Variances are applied to the current stock, which may have moved since the count was opened
+	productos, err := ic.dbService.GetProductos()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve products",
+			"details": err.Error(),
+		})
+		return
+	}
+	stock := make(map[uint]int, len(productos))
+	for _, producto := range productos {
+		stock[producto.ProdID] = producto.ProdCantidadDisponible
+	}
+	for _, diferencia := range diferencias {
+		if diferencia.Diferencia != nil && stock[diferencia.ProdID]+*diferencia.Diferencia < 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":     "The adjustment would leave the stock negative",
+				"prod_id":   diferencia.ProdID,
+				"available": stock[diferencia.ProdID],
+			})
+			return
+		}
+	}
+
+	if err := ic.dbService.AprobarConteoInventario(conteo.ConID, c.GetString("user_email")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to approve stock count",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Stock count approved and adjustments posted successfully",
+		"con_id":  conteo.ConID,
+		"summary": summarizeStockCount(diferencias),
+	})
+}
+
+// CancelStockCount discards an open count without touching the stock
+func (ic *InventoryController) CancelStockCount(c *gin.Context) {
+	conteo, ok := ic.findStockCount(c)
+	if !ok {
+		return
+	}
+	if conteo.ConEstado != services.EstadoConteoAbierto {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "The stock count is not open",
+			"con_estado": conteo.ConEstado,
+		})
+		return
+	}
+
+	if err := ic.dbService.CancelarConteoInventario(conteo.ConID, c.GetString("user_email")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to cancel stock count",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Stock count cancelled successfully",
+	})
+}
+
+// findStockCount loads the count session of the :id parameter, answering 400/404 when it cannot
+func (ic *InventoryController) findStockCount(c *gin.Context) (*models.ConteoInventario, bool) {
+	countID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid stock count ID",
+		})
+		return nil, false
+	}
+
+	conteo, err := ic.dbService.BuscarConteoInventario(uint(countID))
+	if err != nil || conteo.ConID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Stock count not found",
+		})
+		return nil, false
+	}
+	return conteo, true
+}
+
+// summarizeStockCount totals the counted products and the value of the shortages and overages
+func summarizeStockCount(diferencias []models.DiferenciaConteo) StockCountSummary {
+	resumen := StockCountSummary{Productos: len(diferencias)}
+	for _, diferencia := range diferencias {
+		if diferencia.CantidadContada == nil {
+			continue
+		}
+		resumen.Contados++
+		if diferencia.Diferencia == nil || *diferencia.Diferencia == 0 {
+			continue
+		}
+		resumen.ConDiferencia++
+		valor := 0.0
+		if diferencia.ValorDiferencia != nil {
+			valor = *diferencia.ValorDiferencia
+		}
+		if *diferencia.Diferencia < 0 {
+			resumen.UnidadesFaltantes -= *diferencia.Diferencia
+			resumen.ValorFaltante -= valor
+		} else {
+			resumen.UnidadesSobrantes += *diferencia.Diferencia
+			resumen.ValorSobrante += valor
+		}
+		resumen.ValorNeto += valor
+	}
+	return resumen
+}
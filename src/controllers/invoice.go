@@ -1,11 +1,16 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"salon/config"
 	"salon/models"
 	"salon/services"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,6 +30,67 @@ const (
 	ErrFailedRemoveService          = "Failed to remove service from invoice"
 )
 
+const (
+	ErrInvoiceWithoutLines       = "An invoice needs at least one service or product"
+	ErrProductNotFoundForInvoice = "Product not found"
+	ErrNotEnoughStockForInvoice  = "Not enough stock for this product"
+	ErrFailedRetrieveProducts    = "Failed to retrieve invoice products"
+	ErrFailedAddProduct          = "Failed to add product to invoice"
+	ErrFailedRemoveProduct       = "Failed to remove product from invoice"
+)
+
+const (
+	ErrInvalidInvoiceLineID       = "Invalid invoice line ID"
+	ErrServiceNotFoundForInvoice  = "Service not found"
+	ErrEmployeeNotFoundForInvoice = "Employee not found"
+)
+
+const (
+	ErrInvalidPaymentMethod    = "Invalid payment method"
+	ErrInvoiceAlreadyPaid      = "Invoice is already paid"
+	ErrPaymentExceedsBalance   = "Payment exceeds the invoice balance"
+	ErrFailedRegisterPayment   = "Failed to register payment"
+	ErrFailedRetrievePayments  = "Failed to retrieve invoice payments"
+	ErrFailedRetrieveBalances  = "Failed to retrieve outstanding balances"
+	ErrInvalidClientIDForQuery = "Invalid client ID"
+)
+
+const (
+	ErrInvoiceNotEditable        = "Issued invoices cannot be modified; void the invoice or issue a credit note"
+	ErrInvoiceNotIssued          = "The invoice is not issued"
+	ErrInvoiceAlreadyIssued      = "Invoice is already issued"
+	ErrInvoiceCannotBeVoided     = "The invoice cannot be voided"
+	ErrInvoiceFullyCredited      = "The invoice has nothing left to credit"
+	ErrCreditNoteWithoutLines    = "A credit note needs lines or completa=true"
+	ErrCreditNoteLineNotFound    = "Invoice line not found"
+	ErrCreditNoteLineTarget      = "Each line needs either dfs_id or prod_id"
+	ErrCreditNoteExceedsLine     = "Credited quantity exceeds the quantity left on the invoice line"
+	ErrInvalidRefundMethod       = "Invalid refund method"
+	ErrFailedIssueInvoice        = "Failed to issue invoice"
+	ErrFailedVoidInvoice         = "Failed to void invoice"
+	ErrFailedCreateCreditNote    = "Failed to create credit note"
+	ErrFailedRetrieveCreditNotes = "Failed to retrieve credit notes"
+)
+
+const (
+	ErrNoActiveResolution         = "There is no active invoice numbering resolution"
+	ErrResolutionNotValidForDate  = "Today's date is outside the validity dates of the numbering resolution"
+	ErrResolutionRangeExhausted   = "The numbering resolution has no consecutive numbers left"
+	ErrInvalidResolutionID        = "Invalid resolution ID"
+	ErrResolutionNotFound         = "Resolution not found"
+	ErrInvalidResolutionRange     = "rango_hasta must be greater than or equal to rango_desde"
+	ErrInvalidResolutionDates     = "fecha_hasta must not be before fecha_desde"
+	ErrFailedCreateResolution     = "Failed to create numbering resolution"
+	ErrFailedRetrieveResolutions  = "Failed to retrieve numbering resolutions"
+	ErrFailedActivateResolution   = "Failed to activate numbering resolution"
+	ErrFailedRetrieveInvoiceTaxes = "Failed to retrieve invoice taxes"
+)
+
+const (
+	ErrIssuerNotConfigured      = "The invoice issuer tax ID (ISSUER_NIT) is not configured"
+	ErrFailedGenerateInvoiceXML = "Failed to generate electronic invoice"
+)
+
 type InvoiceController struct {
 	dbService *services.DatabaseService
 }
@@ -35,12 +101,40 @@ func NewInvoiceController(dbService *services.DatabaseService) *InvoiceControlle
 	}
 }
 
+// InvoiceServiceRequest represents a service line of an invoice
+type InvoiceServiceRequest struct {
+	SerID    uint  `json:"ser_id" binding:"required"`
+	Cantidad int   `json:"cantidad" binding:"omitempty,min=1"` // Defaults to 1
+	EmpID    *uint `json:"emp_id"`                             // Employee who performed the service
+}
+
+// params converts the request to the service line parameters, defaulting the quantity to 1
+func (r InvoiceServiceRequest) params() services.ServicioFacturaParams {
+	cantidad := r.Cantidad
+	if cantidad == 0 {
+		cantidad = 1
+	}
+	return services.ServicioFacturaParams{SerID: r.SerID, Cantidad: cantidad, EmpID: r.EmpID}
+}
+
+// InvoiceProductRequest represents a retail product sold on an invoice
+type InvoiceProductRequest struct {
+	ProdID    uint    `json:"prod_id" binding:"required"`
+	Cantidad  int     `json:"cantidad" binding:"required,min=1"`
+	Descuento float64 `json:"descuento" binding:"min=0"` // Discount on the whole line
+}
+
 // CreateInvoiceRequest represents the request to create a new invoice
 type CreateInvoiceRequest struct {
-	CliID     uint   `json:"cli_id" binding:"required"`
-	Fecha     string `json:"fecha" binding:"required"`
-	Hora      string `json:"hora" binding:"required"`
-	Servicios []uint `json:"servicios" binding:"required,min=1"` // List of service IDs
+	CliID     uint                    `json:"cli_id" binding:"required"`
+	Fecha     string                  `json:"fecha" binding:"required"`
+	Hora      string                  `json:"hora" binding:"required"`
+	Servicios []uint                  `json:"servicios"`                // List of service IDs, one line each (IDs may repeat)
+	Productos []InvoiceProductRequest `json:"productos" binding:"dive"` // Retail products sold at the counter
+	Codigo    string                  `json:"codigo"`                   // Optional coupon code
+	Borrador  bool                    `json:"borrador"`                 // Keep the invoice as an editable draft instead of issuing it
+
+	LineasServicio []InvoiceServiceRequest `json:"lineas_servicio" binding:"dive"` // Service lines with quantity and employee
 }
 
 // InvoiceDetailResponse represents the complete invoice with details
@@ -52,6 +146,25 @@ type InvoiceDetailResponse struct {
 	CliID     uint    `json:"cli_id"`
 	CliNombre string  `json:"cli_nombre"`
 	Servicios string  `json:"servicios"` // Comma-separated service names
+	Productos string  `json:"productos"` // Comma-separated products sold, with their quantities
+
+	FacPagado     float64 `json:"fac_pagado"`
+	Saldo         float64 `json:"saldo"`
+	FacEstadoPago string  `json:"fac_estado_pago"`
+
+	FacEstado      string  `json:"fac_estado"` // Borrador, Emitida or Anulada
+	FacAcreditado  float64 `json:"fac_acreditado"`
+	FacReembolsado float64 `json:"fac_reembolsado"`
+
+	FacNumero   *string `json:"fac_numero"`   // Legal number, assigned when the invoice is issued
+	FacSubtotal float64 `json:"fac_subtotal"` // Total before VAT
+	FacImpuesto float64 `json:"fac_impuesto"` // VAT included in the total
+
+	Impuestos       []models.ImpuestoFactura      `json:"impuestos,omitempty"`        // VAT grouped by rate
+	Lineas          []models.LineaFactura         `json:"lineas,omitempty"`           // Lines with the applied promotion discounts
+	LineasProductos []models.LineaProductoFactura `json:"lineas_productos,omitempty"` // Retail product lines
+	Pagos           []models.ClientPayment        `json:"pagos,omitempty"`            // Payments applied to the invoice
+	NotasCredito    []models.NotaCredito          `json:"notas_credito,omitempty"`    // Voids and refunds of the invoice
 }
 
 // CreateInvoice creates a new invoice with its details
@@ -62,44 +175,113 @@ func (ic *InvoiceController) CreateInvoice(c *gin.Context) {
 		return
 	}
 
-	// First, create the invoice with initial total of 0 (will be updated by stored procedure)
-	err := ic.dbService.InsertarFacturaSinTotal(req.Fecha, req.Hora, req.CliID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedCreateInvoice})
+	if len(req.Servicios) == 0 && len(req.LineasServicio) == 0 && len(req.Productos) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvoiceWithoutLines})
 		return
 	}
 
-	// Get the created invoice to get its ID
-	createdInvoice, err := ic.dbService.ObtenerUltimaFactura()
+	servicios := services.ServiciosUnitarios(req.Servicios)
+	for _, linea := range req.LineasServicio {
+		servicios = append(servicios, linea.params())
+	}
+
+	productos := make([]services.ProductoFacturaParams, 0, len(req.Productos))
+	for _, producto := range req.Productos {
+		productos = append(productos, services.ProductoFacturaParams{
+			ProdID:    producto.ProdID,
+			Cantidad:  producto.Cantidad,
+			Descuento: producto.Descuento,
+		})
+	}
+
+	// The invoice, its lines, the promotion usage counters and the stock of the products sold
+	// are updated in one transaction; the invoice is issued unless a draft is requested
+	factura, err := ic.dbService.CrearFacturaServicios(req.CliID, req.Fecha, req.Hora, servicios, productos,
+		normalizePromotionCode(req.Codigo), c.GetString("user_email"), req.Borrador)
+	var noAplicable *services.PromocionNoAplicableError
+	if errors.As(err, &noAplicable) {
+		respondPromotionNotApplicable(c, noAplicable)
+		return
+	}
+	if errors.Is(err, services.ErrServicioNoEncontrado) || errors.Is(err, services.ErrEmpleadoNoEncontrado) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ic.respondProductError(c, err) {
+		return
+	}
+	if respondNumberingError(c, err) {
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveInvoice})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedCreateInvoice})
 		return
 	}
 
-	// Add each service to the invoice details (stored procedure will calculate total)
-	for _, servicioID := range req.Servicios {
-		err = ic.dbService.InsertarDetalleFactura(createdInvoice.FacID, servicioID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("Failed to add service %d to invoice", servicioID),
-			})
-			return
-		}
+	lineas, err := ic.dbService.ListarLineasFactura(factura.FacID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveDetails})
+		return
 	}
 
-	// Get the updated invoice (total should be calculated by stored procedure)
-	updatedInvoice, err := ic.dbService.BuscarFacturaPorID(createdInvoice.FacID)
+	lineasProductos, err := ic.dbService.ListarProductosFactura(factura.FacID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveInvoice})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveProducts})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Invoice created successfully",
-		"invoice": updatedInvoice,
+		"message":  "Invoice created successfully",
+		"invoice":  factura,
+		"lines":    lineas,
+		"products": lineasProductos,
 	})
 }
 
+// respondProductError writes the response for an unknown product or a sale above the available
+// stock and reports whether err was one of them
+func (ic *InvoiceController) respondProductError(c *gin.Context, err error) bool {
+	if errors.Is(err, services.ErrProductoNoEncontrado) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrProductNotFoundForInvoice, "details": err.Error()})
+		return true
+	}
+	var sinStock *services.StockInsuficienteError
+	if errors.As(err, &sinStock) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     ErrNotEnoughStockForInvoice,
+			"prod_id":   sinStock.ProdID,
+			"available": sinStock.Disponible,
+			"requested": sinStock.Solicitado,
+		})
+		return true
+	}
+	return false
+}
+
+// respondNumberingError writes the response for an invoice that cannot be issued because there is
+// no numbering resolution for it and reports whether err was one of those errors
+func respondNumberingError(c *gin.Context, err error) bool {
+	if errors.Is(err, services.ErrSinResolucionFacturacion) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrNoActiveResolution})
+		return true
+	}
+	if errors.Is(err, services.ErrRangoFacturacionAgotado) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrResolutionRangeExhausted})
+		return true
+	}
+	var noVigente *services.ResolucionNoVigenteError
+	if errors.As(err, &noVigente) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      ErrResolutionNotValidForDate,
+			"res_numero": noVigente.ResNumero,
+			"desde":      noVigente.Desde.Format("2006-01-02"),
+			"hasta":      noVigente.Hasta.Format("2006-01-02"),
+		})
+		return true
+	}
+	return false
+}
+
 // GetInvoices returns all invoices with basic information
 func (ic *InvoiceController) GetInvoices(c *gin.Context) {
 	facturas, err := ic.dbService.ListarFacturas()
@@ -188,14 +370,59 @@ func (ic *InvoiceController) buildInvoiceDetailResponse(factura *models.FacturaS
 	// Build services string
 	serviciosStr := ic.buildServicesString(detalles, servicios)
 
+	// Get the lines with their promotion discounts
+	lineas, err := ic.dbService.ListarLineasFactura(factura.FacID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrFailedRetrieveDetails)
+	}
+
+	// Get the retail product lines
+	lineasProductos, err := ic.dbService.ListarProductosFactura(factura.FacID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrFailedRetrieveProducts)
+	}
+
+	// Get the payments applied to the invoice
+	pagos, err := ic.dbService.ListarPagosFactura(factura.FacID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrFailedRetrievePayments)
+	}
+
+	// Get the voids and refunds of the invoice
+	notas, err := ic.dbService.ListarNotasCreditoFactura(factura.FacID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrFailedRetrieveCreditNotes)
+	}
+
+	// Get the VAT breakdown by rate
+	impuestos, err := ic.dbService.ResumenImpuestosFactura(factura.FacID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrFailedRetrieveInvoiceTaxes)
+	}
+
 	response := &InvoiceDetailResponse{
-		FacID:     factura.FacID,
-		FacTotal:  factura.FacTotal,
-		FacFecha:  factura.FacFecha.Format("2006-01-02"),
-		FacHora:   factura.FacHora,
-		CliID:     factura.CliID,
-		CliNombre: clienteName,
-		Servicios: serviciosStr,
+		FacID:           factura.FacID,
+		FacTotal:        factura.FacTotal,
+		FacFecha:        factura.FacFecha.Format("2006-01-02"),
+		FacHora:         factura.FacHora,
+		CliID:           factura.CliID,
+		CliNombre:       clienteName,
+		Servicios:       serviciosStr,
+		Productos:       ic.buildProductsString(lineasProductos),
+		FacPagado:       factura.FacPagado,
+		Saldo:           invoiceBalance(factura),
+		FacEstadoPago:   factura.FacEstadoPago,
+		FacEstado:       factura.FacEstado,
+		FacAcreditado:   factura.FacAcreditado,
+		FacReembolsado:  factura.FacReembolsado,
+		FacNumero:       factura.FacNumero,
+		FacSubtotal:     factura.FacSubtotal,
+		FacImpuesto:     factura.FacImpuesto,
+		Impuestos:       impuestos,
+		Lineas:          lineas,
+		LineasProductos: lineasProductos,
+		Pagos:           pagos,
+		NotasCredito:    notas,
 	}
 
 	return response, nil
@@ -207,7 +434,11 @@ func (ic *InvoiceController) buildServicesString(detalles []models.DetalleFactur
 	for _, detalle := range detalles {
 		for _, servicio := range servicios {
 			if servicio.SerID == detalle.SerID {
-				serviceNames = append(serviceNames, servicio.SerNombre)
+				if detalle.DfsCantidad > 1 {
+					serviceNames = append(serviceNames, fmt.Sprintf("%d x %s", detalle.DfsCantidad, servicio.SerNombre))
+				} else {
+					serviceNames = append(serviceNames, servicio.SerNombre)
+				}
 				break
 			}
 		}
@@ -223,6 +454,15 @@ func (ic *InvoiceController) buildServicesString(detalles []models.DetalleFactur
 	return serviciosStr
 }
 
+// Helper method to build products string ("2 x Shampoo, 1 x Tinte")
+func (ic *InvoiceController) buildProductsString(lineas []models.LineaProductoFactura) string {
+	var productNames []string
+	for _, linea := range lineas {
+		productNames = append(productNames, fmt.Sprintf("%d x %s", linea.DfpCantidad, linea.ProdNombre))
+	}
+	return strings.Join(productNames, ", ")
+}
+
 // GetAllInvoicesWithDetails returns all invoices with complete information
 func (ic *InvoiceController) GetAllInvoicesWithDetails(c *gin.Context) {
 	// Get all required data
@@ -284,6 +524,11 @@ func (ic *InvoiceController) processInvoiceWithDetails(factura models.FacturaSer
 	// Build services string
 	serviciosStr := ic.buildServicesString(detalles, servicios)
 
+	lineasProductos, err := ic.dbService.ListarProductosFactura(factura.FacID)
+	if err != nil {
+		return nil // Skip this invoice if we can't get its products
+	}
+
 	return &InvoiceDetailResponse{
 		FacID:     factura.FacID,
 		FacTotal:  factura.FacTotal,
@@ -292,6 +537,19 @@ func (ic *InvoiceController) processInvoiceWithDetails(factura models.FacturaSer
 		CliID:     factura.CliID,
 		CliNombre: clienteName,
 		Servicios: serviciosStr,
+		Productos: ic.buildProductsString(lineasProductos),
+
+		FacPagado:     factura.FacPagado,
+		Saldo:         invoiceBalance(&factura),
+		FacEstadoPago: factura.FacEstadoPago,
+
+		FacEstado:      factura.FacEstado,
+		FacAcreditado:  factura.FacAcreditado,
+		FacReembolsado: factura.FacReembolsado,
+
+		FacNumero:   factura.FacNumero,
+		FacSubtotal: factura.FacSubtotal,
+		FacImpuesto: factura.FacImpuesto,
 	}
 }
 
@@ -305,7 +563,8 @@ func (ic *InvoiceController) findClientName(cliID uint, clientes []models.Client
 	return ""
 }
 
-// AddServiceToInvoice adds a single service to an existing invoice
+// AddServiceToInvoice adds a service line to an existing invoice; a service already on the
+// invoice gets a new line of its own
 func (ic *InvoiceController) AddServiceToInvoice(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -314,16 +573,32 @@ func (ic *InvoiceController) AddServiceToInvoice(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		SerID uint `json:"ser_id" binding:"required"`
-	}
+	var req InvoiceServiceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// The stored procedure will automatically calculate the new total
-	err = ic.dbService.InsertarDetalleFactura(uint(id), req.SerID)
+	if !ic.requireDraftInvoice(c, uint(id)) {
+		return
+	}
+
+	servicio, err := ic.dbService.BuscarServicioPorID(req.SerID)
+	if err != nil || servicio.SerID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrServiceNotFoundForInvoice})
+		return
+	}
+
+	if req.EmpID != nil {
+		empleado, err := ic.dbService.BuscarEmpleadoPorID(*req.EmpID)
+		if err != nil || empleado.EmpID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrEmployeeNotFoundForInvoice})
+			return
+		}
+	}
+
+	// The promotion rules pick the line's discount and the total is recalculated
+	err = ic.dbService.InsertarDetalleFactura(uint(id), req.params())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedAddService})
 		return
@@ -332,7 +607,43 @@ func (ic *InvoiceController) AddServiceToInvoice(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Service added to invoice successfully"})
 }
 
-// UpdateInvoice updates an existing invoice
+// AddProductToInvoice sells a retail product on an existing invoice, deducting it from stock
+func (ic *InvoiceController) AddProductToInvoice(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return
+	}
+
+	var req InvoiceProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !ic.requireDraftInvoice(c, uint(id)) {
+		return
+	}
+
+	// The stored procedure records the sale in the stock ledger and recalculates the total
+	err = ic.dbService.InsertarProductoFactura(uint(id), services.ProductoFacturaParams{
+		ProdID:    req.ProdID,
+		Cantidad:  req.Cantidad,
+		Descuento: req.Descuento,
+	}, c.GetString("user_email"))
+	if ic.respondProductError(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedAddProduct})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product added to invoice successfully"})
+}
+
+// UpdateInvoice updates a draft invoice
 func (ic *InvoiceController) UpdateInvoice(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -342,17 +653,21 @@ func (ic *InvoiceController) UpdateInvoice(c *gin.Context) {
 	}
 
 	var req struct {
-		FacTotal float64 `json:"fac_total" binding:"required"`
-		FacFecha string  `json:"fac_fecha" binding:"required"`
-		FacHora  string  `json:"fac_hora" binding:"required"`
-		CliID    uint    `json:"cli_id" binding:"required"`
+		FacFecha string `json:"fac_fecha" binding:"required"`
+		FacHora  string `json:"fac_hora" binding:"required"`
+		CliID    uint   `json:"cli_id" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = ic.dbService.ActualizarFactura(uint(id), req.FacTotal, req.FacFecha, req.FacHora, req.CliID)
+	if !ic.requireDraftInvoice(c, uint(id)) {
+		return
+	}
+
+	// The total, subtotal and VAT are recalculated from the lines
+	err = ic.dbService.ActualizarFactura(uint(id), req.FacFecha, req.FacHora, req.CliID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedUpdateInvoice})
 		return
@@ -361,7 +676,7 @@ func (ic *InvoiceController) UpdateInvoice(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Invoice updated successfully"})
 }
 
-// DeleteInvoice deletes an invoice and its details
+// DeleteInvoice deletes a draft invoice and its details; issued invoices are voided instead
 func (ic *InvoiceController) DeleteInvoice(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -370,7 +685,12 @@ func (ic *InvoiceController) DeleteInvoice(c *gin.Context) {
 		return
 	}
 
-	err = ic.dbService.EliminarFactura(uint(id))
+	if !ic.requireDraftInvoice(c, uint(id)) {
+		return
+	}
+
+	// Products sold on the invoice go back to stock
+	err = ic.dbService.EliminarFactura(uint(id), c.GetString("user_email"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedDeleteInvoice})
 		return
@@ -388,6 +708,10 @@ func (ic *InvoiceController) RemoveServiceFromInvoice(c *gin.Context) {
 		return
 	}
 
+	if !ic.requireDraftInvoice(c, uint(id)) {
+		return
+	}
+
 	// The stored procedure will automatically recalculate the total
 	err = ic.dbService.EliminarDetalleFactura(uint(id))
 	if err != nil {
@@ -398,6 +722,86 @@ func (ic *InvoiceController) RemoveServiceFromInvoice(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Service removed from invoice successfully"})
 }
 
+// RemoveServiceLineFromInvoice removes a single service line, identified by its dfs_id
+func (ic *InvoiceController) RemoveServiceLineFromInvoice(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return
+	}
+
+	dfsID, err := strconv.ParseUint(c.Param("dfs_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceLineID})
+		return
+	}
+
+	if !ic.requireDraftInvoice(c, uint(id)) {
+		return
+	}
+
+	// The stored procedure gives back the promotion use and recalculates the total
+	err = ic.dbService.EliminarLineaFactura(uint(id), uint(dfsID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRemoveService})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Service line removed from invoice successfully"})
+}
+
+// RemoveProductFromInvoice removes a product line from an invoice and returns its units to stock
+func (ic *InvoiceController) RemoveProductFromInvoice(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return
+	}
+
+	prodID, err := strconv.ParseUint(c.Param("prod_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidProductID})
+		return
+	}
+
+	if !ic.requireDraftInvoice(c, uint(id)) {
+		return
+	}
+
+	// The stored procedure records the return in the stock ledger and recalculates the total
+	err = ic.dbService.EliminarProductoFactura(uint(id), uint(prodID), c.GetString("user_email"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRemoveProduct})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product removed from invoice successfully"})
+}
+
+// GetInvoiceAppointments returns the appointments billed by an invoice
+func (ic *InvoiceController) GetInvoiceAppointments(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return
+	}
+
+	citas, err := ic.dbService.ListarCitasFactura(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveDetails})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"appointments": citas,
+		"total":        len(citas),
+		"fac_id":       id,
+	})
+}
+
 // GetMyInvoices returns invoices for the authenticated client user
 func (ic *InvoiceController) GetMyInvoices(c *gin.Context) {
 	// Get client ID from JWT token
@@ -425,3 +829,557 @@ func (ic *InvoiceController) GetMyInvoices(c *gin.Context) {
 		"total":    len(invoices),
 	})
 }
+
+// ============= PAYMENTS =============
+
+// InvoicePaymentRequest represents one tender of a payment
+type InvoicePaymentRequest struct {
+	Metodo     string  `json:"metodo" binding:"required"`     // Efectivo, Tarjeta, Transferencia or Billetera Digital
+	Monto      float64 `json:"monto" binding:"required,gt=0"` // Amount handed over; cash above the balance returns change
+	Entidad    string  `json:"entidad"`                       // Card network, bank or wallet (Nequi, Daviplata...)
+	Referencia string  `json:"referencia"`                    // Voucher, transfer or wallet transaction number
+}
+
+// RegisterInvoicePaymentsRequest represents a payment of an invoice, split across one or more tenders
+type RegisterInvoicePaymentsRequest struct {
+	Pagos []InvoicePaymentRequest `json:"pagos" binding:"required,min=1,dive"`
+}
+
+// invoiceBalance returns what is still owed on an invoice, net of credit notes and refunds
+func invoiceBalance(factura *models.FacturaServicio) float64 {
+	return services.SaldoFactura(factura)
+}
+
+// RegisterInvoicePayments applies a payment to the invoice balance. Several tenders can be combined
+// (split payment) and the balance may be paid partially; cash returns change when it exceeds the balance.
+func (ic *InvoiceController) RegisterInvoicePayments(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return
+	}
+
+	var req RegisterInvoicePaymentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pagos := make([]services.PagoClienteParams, 0, len(req.Pagos))
+	for _, pago := range req.Pagos {
+		if !services.EsMetodoPago(pago.Metodo) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPaymentMethod, "metodo": pago.Metodo})
+			return
+		}
+		pagos = append(pagos, services.PagoClienteParams{
+			Metodo:     pago.Metodo,
+			Monto:      pago.Monto,
+			Entidad:    strings.TrimSpace(pago.Entidad),
+			Referencia: strings.TrimSpace(pago.Referencia),
+		})
+	}
+
+	registrados, err := ic.dbService.RegistrarPagosFactura(uint(id), pagos, c.GetString("user_email"))
+	if errors.Is(err, services.ErrFacturaNoEncontrada) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrInvoiceNotFound})
+		return
+	}
+	if errors.Is(err, services.ErrFacturaNoEmitida) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrInvoiceNotIssued})
+		return
+	}
+	if errors.Is(err, services.ErrFacturaPagada) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrInvoiceAlreadyPaid})
+		return
+	}
+	var excede *services.PagoExcedeSaldoError
+	if errors.As(err, &excede) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   ErrPaymentExceedsBalance,
+			"metodo":  excede.Metodo,
+			"monto":   excede.Monto,
+			"balance": excede.Saldo,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRegisterPayment})
+		return
+	}
+
+	factura, err := ic.dbService.BuscarFacturaPorID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveInvoice})
+		return
+	}
+
+	var cambio float64
+	for _, pago := range registrados {
+		cambio += pago.PagCliCambio
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Payment registered successfully",
+		"payments": registrados,
+		"change":   cambio,
+		"invoice":  factura,
+		"balance":  invoiceBalance(factura),
+	})
+}
+
+// GetInvoicePayments returns the payments applied to an invoice and its balance
+func (ic *InvoiceController) GetInvoicePayments(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return
+	}
+
+	factura, err := ic.dbService.BuscarFacturaPorID(uint(id))
+	if err != nil || factura.FacID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrInvoiceNotFound})
+		return
+	}
+
+	pagos, err := ic.dbService.ListarPagosFactura(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrievePayments})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"payments":        pagos,
+		"total":           len(pagos),
+		"fac_id":          factura.FacID,
+		"fac_total":       factura.FacTotal,
+		"fac_acreditado":  factura.FacAcreditado,
+		"fac_pagado":      factura.FacPagado,
+		"fac_reembolsado": factura.FacReembolsado,
+		"fac_estado_pago": factura.FacEstadoPago,
+		"balance":         invoiceBalance(factura),
+	})
+}
+
+// GetOutstandingBalances lists the invoices with an unpaid balance, oldest first, optionally for
+// one client (?cli_id=)
+func (ic *InvoiceController) GetOutstandingBalances(c *gin.Context) {
+	var cliID *uint
+	if valor := c.Query("cli_id"); valor != "" {
+		id, err := strconv.ParseUint(valor, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidClientIDForQuery})
+			return
+		}
+		cliente := uint(id)
+		cliID = &cliente
+	}
+
+	saldos, err := ic.dbService.ReporteSaldosPendientes(cliID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveBalances})
+		return
+	}
+
+	var total float64
+	clientes := make(map[uint]bool)
+	for _, saldo := range saldos {
+		total += saldo.Saldo
+		clientes[saldo.CliID] = true
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invoices":          saldos,
+		"total":             len(saldos),
+		"clients":           len(clientes),
+		"total_outstanding": math.Round(total*100) / 100,
+	})
+}
+
+// ============= ISSUING, VOIDS AND CREDIT NOTES =============
+
+// requireDraftInvoice answers 404/409 and returns false unless the invoice exists and is still a draft
+func (ic *InvoiceController) requireDraftInvoice(c *gin.Context, facID uint) bool {
+	factura, err := ic.dbService.BuscarFacturaPorID(facID)
+	if err != nil || factura.FacID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrInvoiceNotFound})
+		return false
+	}
+	if factura.FacEstado != services.EstadoFacturaBorrador {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrInvoiceNotEditable, "fac_estado": factura.FacEstado})
+		return false
+	}
+	return true
+}
+
+// IssueInvoice issues a draft invoice; after that it can only be voided or refunded with credit notes
+func (ic *InvoiceController) IssueInvoice(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return
+	}
+
+	factura, err := ic.dbService.EmitirFactura(uint(id))
+	if errors.Is(err, services.ErrFacturaNoEncontrada) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrInvoiceNotFound})
+		return
+	}
+	if errors.Is(err, services.ErrFacturaNoBorrador) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrInvoiceAlreadyIssued})
+		return
+	}
+	if errors.Is(err, services.ErrFacturaSinLineas) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrInvoiceWithoutLines})
+		return
+	}
+	if respondNumberingError(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedIssueInvoice})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invoice issued successfully",
+		"invoice": factura,
+	})
+}
+
+// VoidInvoiceRequest represents the reason for voiding an invoice
+type VoidInvoiceRequest struct {
+	Motivo string `json:"motivo" binding:"required,max=255"`
+}
+
+// VoidInvoice voids an invoice issued today with a credit note for all of its lines. Products go
+// back to stock and what the client paid is refunded; the invoice is kept as Anulada.
+func (ic *InvoiceController) VoidInvoice(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return
+	}
+
+	var req VoidInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	nota, err := ic.dbService.AnularFactura(uint(id), strings.TrimSpace(req.Motivo), c.GetString("user_email"))
+	if errors.Is(err, services.ErrFacturaNoEncontrada) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrInvoiceNotFound})
+		return
+	}
+	var noAnulable *services.FacturaNoAnulableError
+	if errors.As(err, &noAnulable) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrInvoiceCannotBeVoided, "reason": noAnulable.Motivo})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedVoidInvoice})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Invoice voided successfully",
+		"credit_note": nota,
+		"refund":      nota.NcReembolso,
+	})
+}
+
+// CreditNoteLineRequest represents a credited line: a service line (dfs_id) or a product sold on
+// the invoice (prod_id)
+type CreditNoteLineRequest struct {
+	DfsID    uint `json:"dfs_id"`
+	ProdID   uint `json:"prod_id"`
+	Cantidad int  `json:"cantidad" binding:"required,min=1"`
+}
+
+// CreateCreditNoteRequest represents a partial or full refund of an issued invoice
+type CreateCreditNoteRequest struct {
+	Motivo          string                  `json:"motivo" binding:"required,max=255"`
+	MetodoReembolso string                  `json:"metodo_reembolso"`      // How money is given back, defaults to Efectivo
+	Completa        bool                    `json:"completa"`              // Credit everything not credited yet
+	Lineas          []CreditNoteLineRequest `json:"lineas" binding:"dive"` // Lines to credit when not completa
+}
+
+// CreateCreditNote refunds part or all of an issued invoice. Each line is credited in proportion to
+// its net total, returned products go back to stock and any amount already paid above the new
+// balance is refunded to the client.
+func (ic *InvoiceController) CreateCreditNote(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return
+	}
+
+	var req CreateCreditNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.MetodoReembolso != "" && !services.EsMetodoPago(req.MetodoReembolso) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRefundMethod, "metodo_reembolso": req.MetodoReembolso})
+		return
+	}
+
+	var lineas []services.LineaNotaCreditoParams
+	if !req.Completa {
+		if len(req.Lineas) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrCreditNoteWithoutLines})
+			return
+		}
+		for _, linea := range req.Lineas {
+			if (linea.DfsID == 0) == (linea.ProdID == 0) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": ErrCreditNoteLineTarget})
+				return
+			}
+			lineas = append(lineas, services.LineaNotaCreditoParams{
+				DfsID:    linea.DfsID,
+				ProdID:   linea.ProdID,
+				Cantidad: linea.Cantidad,
+			})
+		}
+	}
+
+	nota, err := ic.dbService.CrearNotaCredito(uint(id), strings.TrimSpace(req.Motivo), req.MetodoReembolso,
+		lineas, c.GetString("user_email"))
+	if errors.Is(err, services.ErrFacturaNoEncontrada) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrInvoiceNotFound})
+		return
+	}
+	if errors.Is(err, services.ErrFacturaNoEmitida) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrInvoiceNotIssued})
+		return
+	}
+	if errors.Is(err, services.ErrFacturaAcreditada) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrInvoiceFullyCredited})
+		return
+	}
+	if errors.Is(err, services.ErrLineaFacturaNoEncontrada) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrCreditNoteLineNotFound, "details": err.Error()})
+		return
+	}
+	var excede *services.CantidadAcreditadaExcedeError
+	if errors.As(err, &excede) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     ErrCreditNoteExceedsLine,
+			"dfs_id":    excede.DfsID,
+			"prod_id":   excede.ProdID,
+			"available": excede.Disponible,
+			"requested": excede.Solicitado,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedCreateCreditNote})
+		return
+	}
+
+	factura, err := ic.dbService.BuscarFacturaPorID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveInvoice})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Credit note created successfully",
+		"credit_note": nota,
+		"refund":      nota.NcReembolso,
+		"invoice":     factura,
+		"balance":     invoiceBalance(factura),
+	})
+}
+
+// GetCreditNotes returns the voids and refunds of an invoice with their lines
+func (ic *InvoiceController) GetCreditNotes(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return
+	}
+
+	factura, err := ic.dbService.BuscarFacturaPorID(uint(id))
+	if err != nil || factura.FacID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrInvoiceNotFound})
+		return
+	}
+
+	notas, err := ic.dbService.ListarNotasCreditoFactura(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveCreditNotes})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"credit_notes":    notas,
+		"total":           len(notas),
+		"fac_id":          factura.FacID,
+		"fac_estado":      factura.FacEstado,
+		"fac_total":       factura.FacTotal,
+		"fac_acreditado":  factura.FacAcreditado,
+		"fac_reembolsado": factura.FacReembolsado,
+		"balance":         invoiceBalance(factura),
+	})
+}
+
+// ============= NUMBERING RESOLUTIONS =============
+
+// CreateResolutionRequest represents an authorized numbering range for issued invoices
+type CreateResolutionRequest struct {
+	Numero       string `json:"res_numero" binding:"required,max=50"`
+	Prefijo      string `json:"res_prefijo" binding:"max=10"`
+	RangoDesde   int    `json:"res_rango_desde" binding:"required,min=1"`
+	RangoHasta   int    `json:"res_rango_hasta" binding:"required,min=1"`
+	FechaDesde   string `json:"res_fecha_desde" binding:"required"`
+	FechaHasta   string `json:"res_fecha_hasta" binding:"required"`
+	Activa       bool   `json:"res_activa"`                          // Number new invoices with this resolution from now on
+	ClaveTecnica string `json:"res_clave_tecnica" binding:"max=100"` // Technical key of the resolution, used in the CUFE
+}
+
+// GetResolutions lists the numbering resolutions with the consecutive numbers they have left
+func (ic *InvoiceController) GetResolutions(c *gin.Context) {
+	resoluciones, err := ic.dbService.ListarResolucionesFacturacion()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveResolutions})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resolutions": resoluciones,
+		"total":       len(resoluciones),
+	})
+}
+
+// CreateResolution registers a numbering resolution; an active one replaces the current resolution
+func (ic *InvoiceController) CreateResolution(c *gin.Context) {
+	var req CreateResolutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.RangoHasta < req.RangoDesde {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidResolutionRange})
+		return
+	}
+
+	desde, err := time.Parse("2006-01-02", req.FechaDesde)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDateFormat})
+		return
+	}
+	hasta, err := time.Parse("2006-01-02", req.FechaHasta)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDateFormat})
+		return
+	}
+	if hasta.Before(desde) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidResolutionDates})
+		return
+	}
+
+	resolucion, err := ic.dbService.CrearResolucionFacturacion(services.ResolucionFacturacionParams{
+		Numero:       strings.TrimSpace(req.Numero),
+		Prefijo:      strings.TrimSpace(req.Prefijo),
+		RangoDesde:   req.RangoDesde,
+		RangoHasta:   req.RangoHasta,
+		FechaDesde:   req.FechaDesde,
+		FechaHasta:   req.FechaHasta,
+		Activa:       req.Activa,
+		ClaveTecnica: strings.TrimSpace(req.ClaveTecnica),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedCreateResolution})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Numbering resolution created successfully",
+		"resolution": resolucion,
+	})
+}
+
+// ActivateResolution makes a resolution the one that numbers new invoices
+func (ic *InvoiceController) ActivateResolution(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("res_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidResolutionID})
+		return
+	}
+
+	resolucion, err := ic.dbService.BuscarResolucionFacturacion(uint(id))
+	if err != nil || resolucion.ResID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrResolutionNotFound})
+		return
+	}
+
+	if err := ic.dbService.ActivarResolucionFacturacion(resolucion.ResID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedActivateResolution})
+		return
+	}
+	resolucion.ResActiva = true
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Numbering resolution activated successfully",
+		"resolution": resolucion,
+	})
+}
+
+// ============= ELECTRONIC INVOICE =============
+
+// emisorFactura returns the salon's issuer data for electronic invoices from the configuration
+func emisorFactura() services.EmisorFactura {
+	if config.AppConfig == nil {
+		return services.EmisorFactura{}
+	}
+	return services.EmisorFactura{
+		NIT:                config.AppConfig.IssuerNIT,
+		RazonSocial:        config.AppConfig.IssuerName,
+		Direccion:          config.AppConfig.IssuerAddress,
+		Ciudad:             config.AppConfig.IssuerCity,
+		CodigoCiudad:       config.AppConfig.IssuerCityCode,
+		Departamento:       config.AppConfig.IssuerDepartment,
+		CodigoDepartamento: config.AppConfig.IssuerDepartmentCode,
+		Telefono:           config.AppConfig.IssuerPhone,
+		Correo:             config.AppConfig.IssuerEmail,
+		Ambiente:           config.AppConfig.EInvoiceEnvironment,
+	}
+}
+
+// GetInvoiceXML downloads an issued invoice as a UBL 2.1 electronic invoice with its CUFE
+func (ic *InvoiceController) GetInvoiceXML(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return
+	}
+
+	factura, err := ic.dbService.GenerarFacturaElectronica(uint(id), emisorFactura())
+	switch {
+	case errors.Is(err, services.ErrFacturaNoEncontrada):
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrInvoiceNotFound})
+		return
+	case errors.Is(err, services.ErrFacturaNoEmitida):
+		c.JSON(http.StatusConflict, gin.H{"error": ErrInvoiceNotIssued})
+		return
+	case errors.Is(err, services.ErrEmisorSinNIT):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrIssuerNotConfigured})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedGenerateInvoiceXML})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", factura.Numero+".xml"))
+	c.Header("X-CUFE", factura.CUFE)
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", factura.XML)
+}
@@ -0,0 +1,665 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"salon/models"
+	"salon/services"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ErrInvalidPaperSize    = "Invalid paper size, expected letter or a4"
+	ErrInvalidReceiptWidth = "Invalid receipt width, expected 58 or 80"
+)
+
+// Paper sizes of the printed invoice, in PDF points
+var tamanosPapel = map[string][2]float64{
+	"letter": {612, 792},
+	"a4":     {595.28, 841.89},
+}
+
+// Characters per line of 58 and 80 mm thermal printers (font A)
+var anchosRecibo = map[string]int{
+	"58": 32,
+	"80": 48,
+}
+
+// Brand color of the printed invoice header
+var colorMarca = [3]float64{0.45, 0.18, 0.40}
+
+// GetInvoicePDF renders the invoice as a printable PDF (?size=letter|a4, letter by default)
+func (ic *InvoiceController) GetInvoicePDF(c *gin.Context) {
+	tamano, ok := tamanosPapel[strings.ToLower(c.DefaultQuery("size", "letter"))]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPaperSize})
+		return
+	}
+
+	detalle, ok := ic.invoiceDetailForPrint(c)
+	if !ok {
+		return
+	}
+
+	pdf := renderInvoicePDF(detalle, emisorFactura(), tamano[0], tamano[1])
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", invoiceFileName(detalle)+".pdf"))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// GetInvoiceReceipt renders the invoice as ESC/POS commands for a thermal printer (?width=58|80, 80 by default)
+func (ic *InvoiceController) GetInvoiceReceipt(c *gin.Context) {
+	ancho := c.DefaultQuery("width", "80")
+	columnas, ok := anchosRecibo[strings.TrimSuffix(ancho, "mm")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidReceiptWidth})
+		return
+	}
+
+	detalle, ok := ic.invoiceDetailForPrint(c)
+	if !ok {
+		return
+	}
+
+	recibo := renderInvoiceReceipt(detalle, emisorFactura(), columnas)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		fmt.Sprintf("%s-%smm.bin", invoiceFileName(detalle), strings.TrimSuffix(ancho, "mm"))))
+	c.Data(http.StatusOK, "application/octet-stream", recibo)
+}
+
+// invoiceDetailForPrint loads the invoice of the :id param with its details, answering the request on errors
+func (ic *InvoiceController) invoiceDetailForPrint(c *gin.Context) (*InvoiceDetailResponse, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidInvoiceID})
+		return nil, false
+	}
+
+	factura, err := ic.dbService.BuscarFacturaPorID(uint(id))
+	if err != nil || factura.FacID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrInvoiceNotFound})
+		return nil, false
+	}
+
+	detalle, err := ic.buildInvoiceDetailResponse(factura)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return detalle, true
+}
+
+// invoiceFileName names printed invoices after their legal number, or their ID while they are drafts
+func invoiceFileName(detalle *InvoiceDetailResponse) string {
+	if detalle.FacNumero != nil {
+		return *detalle.FacNumero
+	}
+	return fmt.Sprintf("factura-%d", detalle.FacID)
+}
+
+// invoiceTitle returns the document title and number line of a printed invoice
+func invoiceTitle(detalle *InvoiceDetailResponse) (string, string) {
+	if detalle.FacNumero == nil {
+		return "BORRADOR", "Sin número - no válido como factura"
+	}
+	return "FACTURA DE VENTA", "N° " + *detalle.FacNumero
+}
+
+// issuerLines returns the issuer identification printed at the top of invoices
+func issuerLines(emisor services.EmisorFactura) []string {
+	var lineas []string
+	if emisor.NIT != "" {
+		lineas = append(lineas, fmt.Sprintf("NIT %s-%s", emisor.NIT, services.DigitoVerificacion(emisor.NIT)))
+	}
+	if emisor.Direccion != "" {
+		lineas = append(lineas, emisor.Direccion)
+	}
+	if emisor.Ciudad != "" {
+		lineas = append(lineas, emisor.Ciudad)
+	}
+	var contacto []string
+	if emisor.Telefono != "" {
+		contacto = append(contacto, "Tel. "+emisor.Telefono)
+	}
+	if emisor.Correo != "" {
+		contacto = append(contacto, emisor.Correo)
+	}
+	if len(contacto) > 0 {
+		lineas = append(lineas, strings.Join(contacto, " - "))
+	}
+	return lineas
+}
+
+// paymentLabel describes a payment as its method and entity ("Tarjeta Visa")
+func paymentLabel(pago models.ClientPayment) string {
+	if pago.PagCliEntidad != nil && *pago.PagCliEntidad != "" {
+		return pago.PagCliMetodo + " " + *pago.PagCliEntidad
+	}
+	return pago.PagCliMetodo
+}
+
+// formatoPesos formats an amount as Colombian pesos ("$45.000", "$15.966,39")
+func formatoPesos(valor float64) string {
+	signo := ""
+	if valor < 0 {
+		signo = "-"
+		valor = -valor
+	}
+	centavos := int64(math.Round(valor * 100))
+	enteros := strconv.FormatInt(centavos/100, 10)
+
+	var texto strings.Builder
+	texto.WriteString(signo + "$")
+	for i, digito := range enteros {
+		if i > 0 && (len(enteros)-i)%3 == 0 {
+			texto.WriteByte('.')
+		}
+		texto.WriteRune(digito)
+	}
+	if resto := centavos % 100; resto != 0 {
+		fmt.Fprintf(&texto, ",%02d", resto)
+	}
+	return texto.String()
+}
+
+// ============= PDF INVOICE =============
+
+// renderInvoicePDF lays out the invoice on pages of the given size: header, client, lines, totals,
+// payments and credit notes
+func renderInvoicePDF(detalle *InvoiceDetailResponse, emisor services.EmisorFactura, ancho, alto float64) []byte {
+	const margen = 40.0
+	pdf := nuevoPDF(ancho, alto)
+	util := ancho - 2*margen
+
+	// Columns of the lines table, by their right edge
+	colCantidad := margen + util*0.56
+	colPrecio := margen + util*0.70
+	colDescuento := margen + util*0.83
+	colIva := margen + util*0.89
+	colTotal := margen + util
+	anchoDescripcion := util * 0.50
+
+	// Brand band with the issuer name and the document title
+	titulo, numero := invoiceTitle(detalle)
+	pdf.color(colorMarca[0], colorMarca[1], colorMarca[2])
+	pdf.rectangulo(0, alto-70, ancho, 70)
+	pdf.color(1, 1, 1)
+	pdf.texto(margen, alto-44, 18, true, emisor.RazonSocial)
+	pdf.textoDerecha(ancho-margen, alto-38, 14, true, titulo)
+	pdf.textoDerecha(ancho-margen, alto-55, 10, false, numero)
+	pdf.color(0, 0, 0)
+
+	y := alto - 92
+	yEmisor := y
+	for _, linea := range issuerLines(emisor) {
+		pdf.texto(margen, yEmisor, 9, false, linea)
+		yEmisor -= 12
+	}
+	yDatos := y
+	datos := []string{
+		fmt.Sprintf("Fecha: %s %s", detalle.FacFecha, detalle.FacHora),
+		"Estado: " + detalle.FacEstado,
+		"Pago: " + detalle.FacEstadoPago,
+	}
+	for _, dato := range datos {
+		pdf.textoDerecha(ancho-margen, yDatos, 9, false, dato)
+		yDatos -= 12
+	}
+	y = math.Min(yEmisor, yDatos) - 10
+
+	pdf.texto(margen, y, 10, true, "Cliente")
+	pdf.texto(margen+50, y, 10, false, detalle.CliNombre)
+	y -= 24
+
+	encabezado := func() {
+		pdf.color(0.92, 0.92, 0.92)
+		pdf.rectangulo(margen, y-4, util, 16)
+		pdf.color(0, 0, 0)
+		pdf.texto(margen+4, y, 9, true, "Descripción")
+		pdf.textoDerecha(colCantidad, y, 9, true, "Cant.")
+		pdf.textoDerecha(colPrecio, y, 9, true, "Precio")
+		pdf.textoDerecha(colDescuento, y, 9, true, "Descuento")
+		pdf.textoDerecha(colIva, y, 9, true, "IVA")
+		pdf.textoDerecha(colTotal-4, y, 9, true, "Total")
+		y -= 18
+	}
+	// espacio starts a new page when the next block does not fit
+	espacio := func(necesario float64, conEncabezado bool) {
+		if y-necesario < margen+20 {
+			pdf.nuevaPagina()
+			y = alto - margen
+			if conEncabezado {
+				encabezado()
+			}
+		}
+	}
+	linea := func(descripcion string, notas []string, cantidad int, precio, descuento, iva, total float64) {
+		espacio(14+float64(len(notas))*10, true)
+		pdf.texto(margen+4, y, 9, false, recortarTexto(descripcion, 9, anchoDescripcion))
+		pdf.textoDerecha(colCantidad, y, 9, false, strconv.Itoa(cantidad))
+		pdf.textoDerecha(colPrecio, y, 9, false, formatoPesos(precio))
+		if descuento > 0 {
+			pdf.textoDerecha(colDescuento, y, 9, false, formatoPesos(-descuento))
+		}
+		pdf.textoDerecha(colIva, y, 9, false, strconv.FormatFloat(iva, 'f', -1, 64)+"%")
+		pdf.textoDerecha(colTotal-4, y, 9, false, formatoPesos(total))
+		y -= 10
+		pdf.color(0.4, 0.4, 0.4)
+		for _, nota := range notas {
+			pdf.texto(margen+12, y, 7.5, false, recortarTexto(nota, 7.5, anchoDescripcion-8))
+			y -= 10
+		}
+		pdf.color(0, 0, 0)
+		y -= 4
+	}
+
+	encabezado()
+	for _, l := range detalle.Lineas {
+		var notas []string
+		if l.ProNombre != nil {
+			notas = append(notas, "Promoción: "+*l.ProNombre)
+		}
+		if l.EmpNombre != nil {
+			notas = append(notas, "Atendió: "+*l.EmpNombre)
+		}
+		linea(l.SerNombre, notas, l.DfsCantidad, l.DfsPrecioUnitario, l.DfsDescuento, l.IvaPorcentaje, l.TotalLinea)
+	}
+	for _, l := range detalle.LineasProductos {
+		linea(l.ProdNombre, nil, l.DfpCantidad, l.DfpPrecioUnitario, l.DfpDescuento, l.IvaPorcentaje, l.TotalLinea)
+	}
+	pdf.linea(margen, y+6, ancho-margen, y+6)
+	y -= 10
+
+	// Totals, right aligned under the lines
+	etiquetas := margen + util*0.55
+	total := func(etiqueta string, valor float64, negrita bool) {
+		espacio(14, false)
+		pdf.texto(etiquetas, y, 9, negrita, etiqueta)
+		pdf.textoDerecha(colTotal-4, y, 9, negrita, formatoPesos(valor))
+		y -= 14
+	}
+	total("Subtotal", detalle.FacSubtotal, false)
+	for _, impuesto := range detalle.Impuestos {
+		if impuesto.IvaPorcentaje > 0 {
+			total(fmt.Sprintf("IVA %s%% sobre %s", strconv.FormatFloat(impuesto.IvaPorcentaje, 'f', -1, 64),
+				formatoPesos(impuesto.Base)), impuesto.Impuesto, false)
+		}
+	}
+	total("Total", detalle.FacTotal, true)
+	if detalle.FacAcreditado > 0 {
+		total("Notas crédito", -detalle.FacAcreditado, false)
+	}
+	total("Pagado", detalle.FacPagado, false)
+	if detalle.FacReembolsado > 0 {
+		total("Reembolsado", detalle.FacReembolsado, false)
+	}
+	total("Saldo", detalle.Saldo, true)
+
+	if len(detalle.Pagos) > 0 {
+		y -= 10
+		espacio(30, false)
+		pdf.texto(margen, y, 10, true, "Pagos")
+		y -= 14
+		for _, pago := range detalle.Pagos {
+			espacio(12, false)
+			pdf.texto(margen+4, y, 9, false, pago.PagCliFecha.Format("2006-01-02 15:04"))
+			pdf.texto(margen+util*0.22, y, 9, false, paymentLabel(pago))
+			if pago.PagCliReferencia != nil {
+				pdf.texto(margen+util*0.50, y, 9, false, "Ref. "+*pago.PagCliReferencia)
+			}
+			if pago.PagCliCambio > 0 {
+				pdf.textoDerecha(colDescuento, y, 9, false, "Cambio "+formatoPesos(pago.PagCliCambio))
+			}
+			pdf.textoDerecha(colTotal-4, y, 9, false, formatoPesos(pago.PagCliMonto))
+			y -= 12
+		}
+	}
+
+	if len(detalle.NotasCredito) > 0 {
+		y -= 10
+		espacio(30, false)
+		pdf.texto(margen, y, 10, true, "Notas crédito")
+		y -= 14
+		for _, nota := range detalle.NotasCredito {
+			espacio(12, false)
+			pdf.texto(margen+4, y, 9, false, nota.NcFecha.Format("2006-01-02"))
+			pdf.texto(margen+util*0.22, y, 9, false,
+				recortarTexto(fmt.Sprintf("%s #%d: %s", nota.NcTipo, nota.NcID, nota.NcMotivo), 9, util*0.55))
+			pdf.textoDerecha(colTotal-4, y, 9, false, formatoPesos(-nota.NcTotal))
+			y -= 12
+		}
+	}
+
+	pie := "Gracias por su visita"
+	if detalle.FacEstado == services.EstadoFacturaAnulada {
+		pie = "FACTURA ANULADA - " + pie
+	}
+	pdf.color(0.4, 0.4, 0.4)
+	pdf.texto((ancho-anchoTexto(pie, 8))/2, margen/2, 8, false, pie)
+
+	return pdf.bytes()
+}
+
+// documentoPDF writes a PDF with the standard Helvetica fonts, so no font has to be embedded
+type documentoPDF struct {
+	ancho, alto float64
+	paginas     []*bytes.Buffer
+	actual      *bytes.Buffer
+}
+
+func nuevoPDF(ancho, alto float64) *documentoPDF {
+	pdf := &documentoPDF{ancho: ancho, alto: alto}
+	pdf.nuevaPagina()
+	return pdf
+}
+
+func (p *documentoPDF) nuevaPagina() {
+	p.actual = &bytes.Buffer{}
+	p.paginas = append(p.paginas, p.actual)
+}
+
+// color sets the fill color used by rectangles and text
+func (p *documentoPDF) color(r, g, b float64) {
+	fmt.Fprintf(p.actual, "%.3f %.3f %.3f rg\n", r, g, b)
+}
+
+func (p *documentoPDF) texto(x, y, tamano float64, negrita bool, texto string) {
+	fuente := "F1"
+	if negrita {
+		fuente = "F2"
+	}
+	fmt.Fprintf(p.actual, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", fuente, tamano, x, y, textoPDF(texto))
+}
+
+func (p *documentoPDF) textoDerecha(derecha, y, tamano float64, negrita bool, texto string) {
+	p.texto(derecha-anchoTexto(texto, tamano), y, tamano, negrita, texto)
+}
+
+func (p *documentoPDF) rectangulo(x, y, ancho, alto float64) {
+	fmt.Fprintf(p.actual, "%.2f %.2f %.2f %.2f re f\n", x, y, ancho, alto)
+}
+
+func (p *documentoPDF) linea(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(p.actual, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// bytes writes the catalog, the page tree, both fonts and every page with its content stream
+func (p *documentoPDF) bytes() []byte {
+	var salida bytes.Buffer
+	var posiciones []int
+	objeto := func(contenido string) {
+		posiciones = append(posiciones, salida.Len())
+		fmt.Fprintf(&salida, "%d 0 obj\n%s\nendobj\n", len(posiciones), contenido)
+	}
+
+	salida.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	paginas := make([]string, len(p.paginas))
+	for i := range p.paginas {
+		paginas[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objeto("<< /Type /Catalog /Pages 2 0 R >>")
+	objeto(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(paginas, " "), len(paginas)))
+	objeto("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	objeto("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, contenido := range p.paginas {
+		objeto(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", p.ancho, p.alto, 6+2*i))
+		objeto(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", contenido.Len(), contenido.String()))
+	}
+
+	inicioXref := salida.Len()
+	fmt.Fprintf(&salida, "xref\n0 %d\n0000000000 65535 f \n", len(posiciones)+1)
+	for _, posicion := range posiciones {
+		fmt.Fprintf(&salida, "%010d 00000 n \n", posicion)
+	}
+	fmt.Fprintf(&salida, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(posiciones)+1, inicioXref)
+	return salida.Bytes()
+}
+
+// textoPDF encodes text as a WinAnsi PDF string; Latin-1 letters keep their code and other
+// characters are replaced
+func textoPDF(texto string) string {
+	var salida strings.Builder
+	for _, r := range texto {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			salida.WriteByte('\\')
+			salida.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			salida.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			salida.WriteByte(byte(r))
+		case r == '–' || r == '—':
+			salida.WriteByte('-')
+		default:
+			salida.WriteByte('?')
+		}
+	}
+	return salida.String()
+}
+
+// Helvetica glyph widths for the printable ASCII characters, in thousandths of the font size
+var anchosHelvetica = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+// anchoTexto approximates the width of a Helvetica text; accented letters count as a lowercase letter
+func anchoTexto(texto string, tamano float64) float64 {
+	total := 0
+	for _, r := range texto {
+		if r >= 0x20 && r < 0x7f {
+			total += anchosHelvetica[r-0x20]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * tamano / 1000
+}
+
+// recortarTexto shortens a text with an ellipsis so it fits the given width
+func recortarTexto(texto string, tamano, ancho float64) string {
+	if anchoTexto(texto, tamano) <= ancho {
+		return texto
+	}
+	runas := []rune(texto)
+	for len(runas) > 0 && anchoTexto(string(runas)+"...", tamano) > ancho {
+		runas = runas[:len(runas)-1]
+	}
+	return string(runas) + "..."
+}
+
+// ============= THERMAL RECEIPT =============
+
+// ESC/POS commands used by the receipt
+var (
+	escposIniciar       = []byte{0x1b, '@'}
+	escposTablaPC850    = []byte{0x1b, 't', 2}
+	escposCentrar       = []byte{0x1b, 'a', 1}
+	escposIzquierda     = []byte{0x1b, 'a', 0}
+	escposNegrita       = []byte{0x1b, 'E', 1}
+	escposNormal        = []byte{0x1b, 'E', 0}
+	escposDobleAlto     = []byte{0x1d, '!', 0x01}
+	escposTamanoNormal  = []byte{0x1d, '!', 0x00}
+	escposAvanzarYCorte = []byte{0x1d, 'V', 66, 3} // Feed three lines and make a partial cut
+)
+
+// renderInvoiceReceipt writes the invoice as an ESC/POS receipt of the given characters per line
+func renderInvoiceReceipt(detalle *InvoiceDetailResponse, emisor services.EmisorFactura, columnas int) []byte {
+	r := &reciboTermico{columnas: columnas}
+	r.comando(escposIniciar, escposTablaPC850, escposCentrar)
+
+	r.comando(escposNegrita, escposDobleAlto)
+	r.lineas(emisor.RazonSocial)
+	r.comando(escposTamanoNormal, escposNormal)
+	for _, linea := range issuerLines(emisor) {
+		r.lineas(linea)
+	}
+	r.separador()
+
+	titulo, numero := invoiceTitle(detalle)
+	r.comando(escposNegrita)
+	r.lineas(titulo)
+	r.comando(escposNormal)
+	r.lineas(numero)
+	r.lineas(detalle.FacFecha + " " + detalle.FacHora)
+	r.comando(escposIzquierda)
+	r.lineas("Cliente: " + detalle.CliNombre)
+	r.separador()
+
+	item := func(descripcion string, cantidad int, precio, descuento float64, motivo string) {
+		r.lineas(descripcion)
+		r.columnasTexto(fmt.Sprintf("  %d x %s", cantidad, formatoPesos(precio)), formatoPesos(float64(cantidad)*precio))
+		if descuento > 0 {
+			r.columnasTexto("  "+motivo, formatoPesos(-descuento))
+		}
+	}
+	for _, l := range detalle.Lineas {
+		motivo := "Descuento"
+		if l.ProNombre != nil {
+			motivo = *l.ProNombre
+		}
+		item(l.SerNombre, l.DfsCantidad, l.DfsPrecioUnitario, l.DfsDescuento, motivo)
+	}
+	for _, l := range detalle.LineasProductos {
+		item(l.ProdNombre, l.DfpCantidad, l.DfpPrecioUnitario, l.DfpDescuento, "Descuento")
+	}
+	r.separador()
+
+	r.columnasTexto("Subtotal", formatoPesos(detalle.FacSubtotal))
+	for _, impuesto := range detalle.Impuestos {
+		if impuesto.IvaPorcentaje > 0 {
+			r.columnasTexto("IVA "+strconv.FormatFloat(impuesto.IvaPorcentaje, 'f', -1, 64)+"%", formatoPesos(impuesto.Impuesto))
+		}
+	}
+	r.comando(escposNegrita, escposDobleAlto)
+	r.columnasTexto("TOTAL", formatoPesos(detalle.FacTotal))
+	r.comando(escposTamanoNormal, escposNormal)
+
+	if len(detalle.Pagos) > 0 {
+		r.separador()
+		for _, pago := range detalle.Pagos {
+			r.columnasTexto(paymentLabel(pago), formatoPesos(pago.PagCliMonto))
+			if pago.PagCliCambio > 0 {
+				r.columnasTexto("  Recibido", formatoPesos(pago.PagCliRecibido))
+				r.columnasTexto("  Cambio", formatoPesos(pago.PagCliCambio))
+			}
+		}
+	}
+	if detalle.FacAcreditado > 0 {
+		r.columnasTexto("Notas crédito", formatoPesos(-detalle.FacAcreditado))
+	}
+	if detalle.FacReembolsado > 0 {
+		r.columnasTexto("Reembolsado", formatoPesos(detalle.FacReembolsado))
+	}
+	r.comando(escposNegrita)
+	r.columnasTexto("Saldo", formatoPesos(detalle.Saldo))
+	r.comando(escposNormal)
+	r.separador()
+
+	r.comando(escposCentrar)
+	if detalle.FacEstado == services.EstadoFacturaAnulada {
+		r.comando(escposNegrita)
+		r.lineas("*** FACTURA ANULADA ***")
+		r.comando(escposNormal)
+	}
+	r.lineas("Gracias por su visita")
+	r.comando(escposAvanzarYCorte)
+	return r.salida.Bytes()
+}
+
+// reciboTermico writes text for a thermal printer using the PC850 code page
+type reciboTermico struct {
+	columnas int
+	salida   bytes.Buffer
+}
+
+func (r *reciboTermico) comando(comandos ...[]byte) {
+	for _, comando := range comandos {
+		r.salida.Write(comando)
+	}
+}
+
+// lineas prints a text wrapped at word boundaries to the receipt width
+func (r *reciboTermico) lineas(texto string) {
+	for _, linea := range envolverTexto(texto, r.columnas) {
+		r.escribir(linea + "\n")
+	}
+}
+
+// columnasTexto prints a label and an amount aligned to both edges, or on two lines when they do not fit
+func (r *reciboTermico) columnasTexto(izquierda, derecha string) {
+	espacio := r.columnas - utf8.RuneCountInString(izquierda) - utf8.RuneCountInString(derecha)
+	if espacio < 1 {
+		r.lineas(izquierda)
+		espacio = max(r.columnas-utf8.RuneCountInString(derecha), 0)
+		izquierda = ""
+	}
+	r.escribir(izquierda + strings.Repeat(" ", espacio) + derecha + "\n")
+}
+
+func (r *reciboTermico) separador() {
+	r.escribir(strings.Repeat("-", r.columnas) + "\n")
+}
+
+func (r *reciboTermico) escribir(texto string) {
+	for _, c := range texto {
+		switch {
+		case c < 0x80:
+			r.salida.WriteByte(byte(c))
+		default:
+			if codigo, ok := caracteresPC850[c]; ok {
+				r.salida.WriteByte(codigo)
+			} else {
+				r.salida.WriteByte('?')
+			}
+		}
+	}
+}
+
+// Spanish characters of the PC850 code page
+var caracteresPC850 = map[rune]byte{
+	'á': 0xa0, 'é': 0x82, 'í': 0xa1, 'ó': 0xa2, 'ú': 0xa3, 'ü': 0x81, 'ñ': 0xa4,
+	'Á': 0xb5, 'É': 0x90, 'Í': 0xd6, 'Ó': 0xe0, 'Ú': 0xe9, 'Ü': 0x9a, 'Ñ': 0xa5,
+	'¿': 0xa8, '¡': 0xad, '°': 0xf8, 'º': 0xa7,
+}
+
+// envolverTexto splits a text into lines of at most the given characters, cutting long words
+func envolverTexto(texto string, columnas int) []string {
+	var lineas []string
+	actual := ""
+	for _, palabra := range strings.Fields(texto) {
+		for utf8.RuneCountInString(palabra) > columnas {
+			if actual != "" {
+				lineas = append(lineas, actual)
+				actual = ""
+			}
+			runas := []rune(palabra)
+			lineas = append(lineas, string(runas[:columnas]))
+			palabra = string(runas[columnas:])
+		}
+		switch {
+		case actual == "":
+			actual = palabra
+		case utf8.RuneCountInString(actual)+1+utf8.RuneCountInString(palabra) <= columnas:
+			actual += " " + palabra
+		default:
+			lineas = append(lineas, actual)
+			actual = palabra
+		}
+	}
+	if actual != "" || len(lineas) == 0 {
+		lineas = append(lineas, actual)
+	}
+	return lineas
+}
@@ -1,9 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"salon/services"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -57,43 +60,102 @@ func (pc *PromotionController) GetPromotionByID(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"promotion": promotion})
 }
 
-// CreatePromotion creates a new promotion
-func (pc *PromotionController) CreatePromotion(c *gin.Context) {
-	var req struct {
-		Nombre      string  `json:"pro_nombre" binding:"required"`
-		Descripcion string  `json:"pro_descripcion"`
-		FechaInicio string  `json:"pro_fecha_inicio" binding:"required"`
-		FechaFin    string  `json:"pro_fecha_fin" binding:"required"`
-		Descuento   float64 `json:"pro_descuento_porcentaje" binding:"required"`
-		SerID       uint    `json:"ser_id" binding:"required"`
-		Usos        int     `json:"pro_usos"`
-	}
+// PromotionRequest is the body accepted to create or update a promotion
+type PromotionRequest struct {
+	Nombre           string   `json:"pro_nombre" binding:"required"`
+	Descripcion      string   `json:"pro_descripcion"`
+	FechaInicio      string   `json:"pro_fecha_inicio" binding:"required"`
+	FechaFin         string   `json:"pro_fecha_fin" binding:"required"`
+	Descuento        float64  `json:"pro_descuento_porcentaje"`
+	SerID            uint     `json:"ser_id" binding:"required"`
+	Usos             int      `json:"pro_usos"`
+	TipoDescuento    string   `json:"pro_tipo_descuento"`   // Porcentaje (default) or Valor Fijo
+	DescuentoValor   *float64 `json:"pro_descuento_valor"`  // Required for Valor Fijo
+	MaxUsos          *int     `json:"pro_max_usos"`         // Total uses allowed, omit for unlimited
+	MaxUsosCliente   *int     `json:"pro_max_usos_cliente"` // Uses allowed per client, omit for unlimited
+	MontoMinimo      *float64 `json:"pro_monto_minimo"`     // Minimum invoice subtotal
+	Codigo           string   `json:"pro_codigo"`           // Coupon code, the promotion only applies when redeemed
+	ServiciosPaquete []uint   `json:"servicios_paquete"`    // Extra services required on the invoice (bundle)
+}
 
+// bindPromotionRequest validates the body and converts it to stored procedure parameters;
+// on failure it writes the 400 response and returns false
+func bindPromotionRequest(c *gin.Context) (services.PromocionParams, bool) {
+	var req PromotionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return services.PromocionParams{}, false
 	}
 
 	// Validate dates
 	if req.FechaInicio >= req.FechaFin {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Start date must be before end date"})
-		return
+		return services.PromocionParams{}, false
 	}
 
-	// Validate discount percentage
-	if req.Descuento < 0 || req.Descuento > 100 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Discount percentage must be between 0 and 100"})
-		return
+	if req.TipoDescuento == "" {
+		req.TipoDescuento = services.TipoDescuentoPorcentaje
+	}
+	switch req.TipoDescuento {
+	case services.TipoDescuentoPorcentaje:
+		// Validate discount percentage
+		if req.Descuento <= 0 || req.Descuento > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Discount percentage must be between 0 and 100"})
+			return services.PromocionParams{}, false
+		}
+		req.DescuentoValor = nil
+	case services.TipoDescuentoValorFijo:
+		if req.DescuentoValor == nil || *req.DescuentoValor <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "pro_descuento_valor must be greater than 0 for fixed-amount promotions"})
+			return services.PromocionParams{}, false
+		}
+		req.Descuento = 0
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pro_tipo_descuento must be Porcentaje or Valor Fijo"})
+		return services.PromocionParams{}, false
+	}
+
+	if (req.MaxUsos != nil && *req.MaxUsos < 1) || (req.MaxUsosCliente != nil && *req.MaxUsosCliente < 1) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Usage limits must be at least 1"})
+		return services.PromocionParams{}, false
+	}
+	if req.MontoMinimo != nil && *req.MontoMinimo < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pro_monto_minimo cannot be negative"})
+		return services.PromocionParams{}, false
 	}
 
 	params := services.PromocionParams{
-		Nombre:      req.Nombre,
-		Descripcion: req.Descripcion,
-		FechaInicio: req.FechaInicio,
-		FechaFin:    req.FechaFin,
-		Descuento:   req.Descuento,
-		SerID:       req.SerID,
-		Usos:        req.Usos,
+		Nombre:           req.Nombre,
+		Descripcion:      req.Descripcion,
+		FechaInicio:      req.FechaInicio,
+		FechaFin:         req.FechaFin,
+		Descuento:        req.Descuento,
+		SerID:            req.SerID,
+		Usos:             req.Usos,
+		TipoDescuento:    req.TipoDescuento,
+		DescuentoValor:   req.DescuentoValor,
+		MaxUsos:          req.MaxUsos,
+		MaxUsosCliente:   req.MaxUsosCliente,
+		MontoMinimo:      req.MontoMinimo,
+		ServiciosPaquete: req.ServiciosPaquete,
+	}
+	if codigo := normalizePromotionCode(req.Codigo); codigo != "" {
+		params.Codigo = &codigo
+	}
+
+	return params, true
+}
+
+// normalizePromotionCode makes coupon codes case-insensitive
+func normalizePromotionCode(codigo string) string {
+	return strings.ToUpper(strings.TrimSpace(codigo))
+}
+
+// CreatePromotion creates a new promotion
+func (pc *PromotionController) CreatePromotion(c *gin.Context) {
+	params, ok := bindPromotionRequest(c)
+	if !ok {
+		return
 	}
 
 	if err := pc.dbService.CrearPromocion(params); err != nil {
@@ -115,43 +177,11 @@ func (pc *PromotionController) UpdatePromotion(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Nombre      string  `json:"pro_nombre" binding:"required"`
-		Descripcion string  `json:"pro_descripcion"`
-		FechaInicio string  `json:"pro_fecha_inicio" binding:"required"`
-		FechaFin    string  `json:"pro_fecha_fin" binding:"required"`
-		Descuento   float64 `json:"pro_descuento_porcentaje" binding:"required"`
-		SerID       uint    `json:"ser_id" binding:"required"`
-		Usos        int     `json:"pro_usos"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Validate dates
-	if req.FechaInicio >= req.FechaFin {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Start date must be before end date"})
+	params, ok := bindPromotionRequest(c)
+	if !ok {
 		return
 	}
 
-	// Validate discount percentage
-	if req.Descuento < 0 || req.Descuento > 100 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Discount percentage must be between 0 and 100"})
-		return
-	}
-
-	params := services.PromocionParams{
-		Nombre:      req.Nombre,
-		Descripcion: req.Descripcion,
-		FechaInicio: req.FechaInicio,
-		FechaFin:    req.FechaFin,
-		Descuento:   req.Descuento,
-		SerID:       req.SerID,
-		Usos:        req.Usos,
-	}
-
 	if err := pc.dbService.ActualizarPromocion(promotionID, params); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update promotion"})
 		return
@@ -178,3 +208,75 @@ func (pc *PromotionController) DeletePromotion(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Promotion deleted successfully"})
 }
+
+// ============= PROMOTION VALIDATION =============
+
+// Messages explaining why a promotion does not apply, by services.MotivoPromocion* reason
+var promotionReasonMessages = map[string]string{
+	services.MotivoPromocionNoEncontrada:       "Promotion code not found",
+	services.MotivoPromocionNoVigente:          "Promotion is not active on the invoice date",
+	services.MotivoPromocionLimiteUsos:         "Promotion has reached its maximum number of uses",
+	services.MotivoPromocionLimiteCliente:      "Client has reached the maximum uses of this promotion",
+	services.MotivoPromocionServiciosFaltantes: "Invoice does not include all the services required by the promotion",
+	services.MotivoPromocionMontoMinimo:        "Invoice subtotal is below the promotion minimum",
+}
+
+// ValidatePromotionRequest describes a draft invoice to check a coupon code against
+type ValidatePromotionRequest struct {
+	Codigo    string `json:"codigo" binding:"required"`
+	CliID     uint   `json:"cli_id" binding:"required"`
+	Servicios []uint `json:"servicios" binding:"required,min=1"`
+	Fecha     string `json:"fecha"` // Invoice date, defaults to today
+}
+
+// ValidatePromotion reports whether a coupon code applies to a draft invoice and, if not, why
+func (pc *PromotionController) ValidatePromotion(c *gin.Context) {
+	var req ValidatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Fecha == "" {
+		req.Fecha = time.Now().Format(DateFormat)
+	} else if _, err := time.Parse(DateFormat, req.Fecha); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDateFormat})
+		return
+	}
+
+	evaluacion, err := pc.dbService.EvaluarPromociones(req.CliID, req.Fecha, req.Servicios, normalizePromotionCode(req.Codigo))
+	if errors.Is(err, services.ErrServicioNoEncontrado) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate promotion"})
+		return
+	}
+
+	cupon := evaluacion.Cupon
+	c.JSON(http.StatusOK, gin.H{
+		"valid":     cupon.Aplicable,
+		"reason":    cupon.Motivo,
+		"message":   promotionReasonMessages[cupon.Motivo],
+		"promotion": cupon.Promocion,
+		"discount":  cupon.Descuento,
+		"subtotal":  evaluacion.Subtotal,
+		"total":     evaluacion.Total,
+		"lines":     evaluacion.Lineas,
+	})
+}
+
+// respondPromotionNotApplicable writes the response for a coupon that cannot be redeemed;
+// usage limits are conflicts because they can change between validation and invoicing
+func respondPromotionNotApplicable(c *gin.Context, err *services.PromocionNoAplicableError) {
+	status := http.StatusBadRequest
+	if err.Motivo == services.MotivoPromocionLimiteUsos || err.Motivo == services.MotivoPromocionLimiteCliente {
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"error":  promotionReasonMessages[err.Motivo],
+		"reason": err.Motivo,
+		"pro_id": err.ProID,
+	})
+}
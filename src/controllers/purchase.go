@@ -1,7 +1,11 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"salon/config"
+	"salon/models"
 	"salon/services"
 	"strconv"
 	"time"
@@ -22,6 +26,27 @@ const (
 	ErrFailedDeleteDetail      = "Failed to delete purchase detail"
 )
 
+const (
+	ErrDuplicatePurchaseProduct = "Each product can only appear once in a purchase"
+	ErrFailedReceivePurchase    = "Failed to receive purchase"
+	ErrFailedRetrieveReceipts   = "Failed to retrieve purchase receipts"
+	ErrProductNotInPurchase     = "The product is not part of this purchase"
+	ErrPurchaseAlreadyReceived  = "Purchase has already been fully received"
+	ErrDuplicateReceiptProduct  = "Each product can only appear once in a receipt"
+)
+
+const (
+	ErrInvalidPurchaseStatus      = "Invalid purchase status"
+	ErrInvalidSupplierFilter      = "Invalid supplier ID"
+	ErrPurchaseNotEditable        = "Only draft purchases can be modified"
+	ErrPurchaseNotReceivable      = "Only sent purchases can be received"
+	ErrPurchaseTransition         = "The purchase cannot change to the requested status"
+	ErrPurchaseWithoutLines       = "A purchase without lines cannot be approved"
+	ErrPurchaseApprovalAdminOnly  = "Purchases above the approval limit must be approved by an admin"
+	ErrFailedChangePurchaseStatus = "Failed to change purchase status"
+	ErrPurchaseNotDeletable       = "Only draft or cancelled purchases can be deleted; use POST /api/purchases/:id/cancel instead"
+)
+
 type PurchaseManagementController struct {
 	dbService *services.DatabaseService
 }
@@ -47,16 +72,31 @@ type PurchaseDetailRequest struct {
 }
 
 type UpdatePurchaseRequest struct {
-	CopFechaCompra string  `json:"cop_fecha_compra" binding:"required"`
-	CopTotalCompra float64 `json:"cop_total_compra" binding:"required,min=0"`
-	CopMetodoPago  string  `json:"cop_metodo_pago" binding:"required"`
-	ProvID         uint    `json:"prov_id" binding:"required"`
-	GasID          uint    `json:"gas_id" binding:"required"`
+	CopFechaCompra string `json:"cop_fecha_compra" binding:"required"`
+	CopMetodoPago  string `json:"cop_metodo_pago" binding:"required"`
+	ProvID         uint   `json:"prov_id" binding:"required"`
+	GasID          uint   `json:"gas_id" binding:"required"`
 }
 
-// GetPurchases returns all purchases with their details
+// GetPurchases returns all purchases with their details, optionally filtered by ?estado= and ?prov_id=
 func (pmc *PurchaseManagementController) GetPurchases(c *gin.Context) {
-	compras, err := pmc.dbService.ListarCompras()
+	estado := c.Query("estado")
+	if estado != "" && !services.EsEstadoCompra(estado) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPurchaseStatus})
+		return
+	}
+
+	var provID uint64
+	if prov := c.Query("prov_id"); prov != "" {
+		var err error
+		provID, err = strconv.ParseUint(prov, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidSupplierFilter})
+			return
+		}
+	}
+
+	compras, err := pmc.dbService.ListarCompras(estado, uint(provID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrievePurchases})
 		return
@@ -92,7 +132,13 @@ func (pmc *PurchaseManagementController) GetPurchase(c *gin.Context) {
 
 	compra.Detalles = detalles
 
-	c.JSON(http.StatusOK, gin.H{"purchase": compra})
+	historial, err := pmc.dbService.ListarHistorialEstadoCompra(uint(purchaseID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get purchase history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purchase": compra, "history": historial})
 }
 
 // CreatePurchase creates a new purchase with its details
@@ -110,51 +156,39 @@ func (pmc *PurchaseManagementController) CreatePurchase(c *gin.Context) {
 		return
 	}
 
-	// Calculate total from details
-	var total float64
-	// for _, detalle := range req.Detalles {
-	// 	total += detalle.DecPrecioUnitario * float64(detalle.DecCantidad)
-	// }
+	detalles := make([]services.DetalleCompraParams, 0, len(req.Detalles))
+	productos := make(map[uint]bool)
+	for _, detalle := range req.Detalles {
+		if productos[detalle.ProdID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrDuplicatePurchaseProduct, "prod_id": detalle.ProdID})
+			return
+		}
+		productos[detalle.ProdID] = true
+		detalles = append(detalles, services.DetalleCompraParams{
+			ProdID:         detalle.ProdID,
+			Cantidad:       detalle.DecCantidad,
+			PrecioUnitario: detalle.DecPrecioUnitario,
+		})
+	}
 
-	// Create the purchase first
-	err = pmc.dbService.InsertarCompra(
+	// Header and lines are saved in one transaction, nothing is left behind if a line fails
+	compra, err := pmc.dbService.CrearCompra(
 		fechaCompra.Format("2006-01-02"),
-		total,
 		req.CopMetodoPago,
 		req.ProvID,
 		req.GasID,
+		detalles,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedCreatePurchase})
 		return
 	}
 
-	// Get the last inserted purchase ID
-	ultimaCompra, err := pmc.dbService.ObtenerUltimaCompra()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get created purchase"})
-		return
-	}
-
-	// Insert purchase details
-	for _, detalle := range req.Detalles {
-		err = pmc.dbService.InsertarDetalleCompra(
-			ultimaCompra.ComID,
-			detalle.ProdID,
-			detalle.DecCantidad,
-			detalle.DecPrecioUnitario,
-		)
-		if err != nil {
-			// If detail insertion fails, we should ideally rollback the purchase
-			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedCreateDetail})
-			return
-		}
-	}
-
 	c.JSON(http.StatusCreated, gin.H{
 		"message":     "Purchase created successfully",
-		"purchase_id": ultimaCompra.ComID,
-		"total":       total,
+		"purchase_id": compra.ComID,
+		"total":       compra.CopTotalCompra,
+		"purchase":    compra,
 	})
 }
 
@@ -173,10 +207,13 @@ func (pmc *PurchaseManagementController) UpdatePurchase(c *gin.Context) {
 		return
 	}
 
+	if !pmc.requireDraftPurchase(c, uint(purchaseID)) {
+		return
+	}
+
 	err = pmc.dbService.ActualizarCompra(
 		uint(purchaseID),
 		req.CopFechaCompra,
-		req.CopTotalCompra,
 		req.CopMetodoPago,
 		req.ProvID,
 		req.GasID,
@@ -189,7 +226,7 @@ func (pmc *PurchaseManagementController) UpdatePurchase(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Purchase updated successfully"})
 }
 
-// DeletePurchase deletes a purchase and all its details
+// DeletePurchase deletes a draft or cancelled purchase and all its details
 func (pmc *PurchaseManagementController) DeletePurchase(c *gin.Context) {
 	id := c.Param("id")
 	purchaseID, err := strconv.ParseUint(id, 10, 32)
@@ -198,7 +235,15 @@ func (pmc *PurchaseManagementController) DeletePurchase(c *gin.Context) {
 		return
 	}
 
+	if !pmc.requireDeletablePurchase(c, uint(purchaseID)) {
+		return
+	}
+
 	err = pmc.dbService.EliminarCompra(uint(purchaseID))
+	if errors.Is(err, services.ErrCompraNoEliminable) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrPurchaseNotDeletable})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedDeletePurchase})
 		return
@@ -224,6 +269,10 @@ func (pmc *PurchaseManagementController) AddPurchaseDetail(c *gin.Context) {
 		return
 	}
 
+	if !pmc.requireDraftPurchase(c, uint(purchaseID)) {
+		return
+	}
+
 	err = pmc.dbService.InsertarDetalleCompra(
 		uint(purchaseID),
 		req.ProdID,
@@ -261,6 +310,10 @@ func (pmc *PurchaseManagementController) UpdatePurchaseDetail(c *gin.Context) {
 		return
 	}
 
+	if !pmc.requireDraftPurchase(c, uint(purchaseID)) {
+		return
+	}
+
 	err = pmc.dbService.ActualizarDetalleCompra(
 		uint(purchaseID),
 		uint(productID),
@@ -292,6 +345,10 @@ func (pmc *PurchaseManagementController) DeletePurchaseDetail(c *gin.Context) {
 		return
 	}
 
+	if !pmc.requireDraftPurchase(c, uint(purchaseID)) {
+		return
+	}
+
 	err = pmc.dbService.EliminarDetalleCompra(uint(purchaseID), uint(productID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedDeleteDetail})
@@ -322,3 +379,363 @@ func (pmc *PurchaseManagementController) GetPurchaseDetails(c *gin.Context) {
 		"total":       len(detalles),
 	})
 }
+
+// ============= GOODS RECEIPT =============
+
+// Receipt status of a purchase line
+const (
+	ReceiptStatusPending  = "Pendiente"
+	ReceiptStatusPartial  = "Parcial"
+	ReceiptStatusComplete = "Completa"
+	ReceiptStatusExcess   = "Excedente"
+)
+
+// ReceivePurchaseRequest lists the quantities delivered; without lines every pending quantity is received
+type ReceivePurchaseRequest struct {
+	Lineas        []ReceiveLineRequest `json:"lineas" binding:"dive"`
+	Observaciones string               `json:"observaciones"`
+}
+
+type ReceiveLineRequest struct {
+	ProdID        uint     `json:"prod_id" binding:"required"`
+	Cantidad      int      `json:"cantidad" binding:"required,min=1"`
+	CostoUnitario *float64 `json:"costo_unitario" binding:"omitempty,min=0"` // Defaults to the ordered unit price
+	Observaciones string   `json:"observaciones"`
+}
+
+// PurchaseReceiptLine compares what was ordered with what has been received for a purchase line
+type PurchaseReceiptLine struct {
+	ProdID              uint     `json:"prod_id"`
+	DecCantidad         int      `json:"dec_cantidad"`
+	DecCantidadRecibida int      `json:"dec_cantidad_recibida"`
+	Pendiente           int      `json:"pendiente"`
+	Estado              string   `json:"estado"`
+	DecPrecioUnitario   float64  `json:"dec_precio_unitario"`
+	CostoRecibido       *float64 `json:"costo_recibido,omitempty"` // Unit cost of this delivery
+	Discrepancias       []string `json:"discrepancias,omitempty"`
+}
+
+// ReceivePurchase records a full or partial delivery of a purchase, increments stock and reports
+// quantity and cost discrepancies against the order
+func (pmc *PurchaseManagementController) ReceivePurchase(c *gin.Context) {
+	purchaseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPurchaseID})
+		return
+	}
+
+	var req ReceivePurchaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	compra, err := pmc.dbService.BuscarCompraPorID(uint(purchaseID))
+	if err != nil || compra.ComID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrPurchaseNotFound})
+		return
+	}
+	if !services.PuedeRecibirCompra(compra.CopEstado) {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrPurchaseNotReceivable, "cop_estado": compra.CopEstado})
+		return
+	}
+
+	detalles, err := pmc.dbService.ListarDetallesCompra(uint(purchaseID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get purchase details"})
+		return
+	}
+	ordenados := make(map[uint]models.DetalleCompra, len(detalles))
+	for _, detalle := range detalles {
+		ordenados[detalle.ProdID] = detalle
+	}
+
+	recepciones := make([]services.RecepcionParams, 0, len(detalles))
+	if len(req.Lineas) == 0 {
+		// Full receipt: everything still pending arrives at the ordered price
+		for _, detalle := range detalles {
+			if pendiente := detalle.DecCantidad - detalle.DecCantidadRecibida; pendiente > 0 {
+				recepciones = append(recepciones, services.RecepcionParams{
+					ProdID:        detalle.ProdID,
+					Cantidad:      pendiente,
+					CostoUnitario: detalle.DecPrecioUnitario,
+					Observaciones: req.Observaciones,
+				})
+			}
+		}
+		if len(recepciones) == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": ErrPurchaseAlreadyReceived})
+			return
+		}
+	}
+
+	vistos := make(map[uint]bool)
+	for _, linea := range req.Lineas {
+		detalle, ok := ordenados[linea.ProdID]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrProductNotInPurchase, "prod_id": linea.ProdID})
+			return
+		}
+		if vistos[linea.ProdID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrDuplicateReceiptProduct, "prod_id": linea.ProdID})
+			return
+		}
+		vistos[linea.ProdID] = true
+
+		recepcion := services.RecepcionParams{
+			ProdID:        linea.ProdID,
+			Cantidad:      linea.Cantidad,
+			CostoUnitario: detalle.DecPrecioUnitario,
+			Observaciones: linea.Observaciones,
+		}
+		if linea.CostoUnitario != nil {
+			recepcion.CostoUnitario = *linea.CostoUnitario
+		}
+		if recepcion.Observaciones == "" {
+			recepcion.Observaciones = req.Observaciones
+		}
+		recepciones = append(recepciones, recepcion)
+	}
+
+	actualizados, err := pmc.dbService.RecibirCompra(uint(purchaseID), c.GetString("user_email"), recepciones)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedReceivePurchase})
+		return
+	}
+
+	costos := make(map[uint]float64, len(recepciones))
+	for _, recepcion := range recepciones {
+		costos[recepcion.ProdID] = recepcion.CostoUnitario
+	}
+
+	lineas := make([]PurchaseReceiptLine, 0, len(actualizados))
+	completa := true
+	discrepancias := 0
+	for _, detalle := range actualizados {
+		linea := buildReceiptLine(detalle)
+		if costo, ok := costos[detalle.ProdID]; ok {
+			linea.CostoRecibido = &costo
+			if costo != detalle.DecPrecioUnitario {
+				linea.Discrepancias = append(linea.Discrepancias, "costo")
+			}
+		}
+		if linea.Estado != ReceiptStatusComplete && linea.Estado != ReceiptStatusExcess {
+			completa = false
+		}
+		if len(linea.Discrepancias) > 0 {
+			discrepancias++
+		}
+		lineas = append(lineas, linea)
+	}
+
+	estado := services.EstadoCompraParcialmenteRecibida
+	if completa {
+		estado = services.EstadoCompraRecibida
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Purchase received successfully",
+		"purchase_id":    purchaseID,
+		"cop_estado":     estado,
+		"fully_received": completa,
+		"discrepancies":  discrepancias,
+		"lines":          lineas,
+	})
+}
+
+// GetPurchaseReceipts returns the deliveries recorded for a purchase and the status of each line
+func (pmc *PurchaseManagementController) GetPurchaseReceipts(c *gin.Context) {
+	purchaseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPurchaseID})
+		return
+	}
+
+	recepciones, err := pmc.dbService.ListarRecepcionesCompra(uint(purchaseID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveReceipts})
+		return
+	}
+
+	detalles, err := pmc.dbService.ListarDetallesCompra(uint(purchaseID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveReceipts})
+		return
+	}
+
+	lineas := make([]PurchaseReceiptLine, 0, len(detalles))
+	for _, detalle := range detalles {
+		lineas = append(lineas, buildReceiptLine(detalle))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"purchase_id": purchaseID,
+		"receipts":    recepciones,
+		"lines":       lineas,
+	})
+}
+
+// buildReceiptLine computes the receipt status of a line; receiving more than ordered is a discrepancy
+func buildReceiptLine(detalle models.DetalleCompra) PurchaseReceiptLine {
+	linea := PurchaseReceiptLine{
+		ProdID:              detalle.ProdID,
+		DecCantidad:         detalle.DecCantidad,
+		DecCantidadRecibida: detalle.DecCantidadRecibida,
+		DecPrecioUnitario:   detalle.DecPrecioUnitario,
+	}
+
+	switch {
+	case detalle.DecCantidadRecibida == 0:
+		linea.Estado = ReceiptStatusPending
+	case detalle.DecCantidadRecibida < detalle.DecCantidad:
+		linea.Estado = ReceiptStatusPartial
+	case detalle.DecCantidadRecibida == detalle.DecCantidad:
+		linea.Estado = ReceiptStatusComplete
+	default:
+		linea.Estado = ReceiptStatusExcess
+		linea.Discrepancias = append(linea.Discrepancias, "cantidad")
+	}
+	if pendiente := detalle.DecCantidad - detalle.DecCantidadRecibida; pendiente > 0 {
+		linea.Pendiente = pendiente
+	}
+
+	return linea
+}
+
+// ============= PURCHASE ORDER WORKFLOW =============
+
+// DefaultPurchaseApprovalLimit is used when PURCHASE_APPROVAL_LIMIT is unset or invalid
+const DefaultPurchaseApprovalLimit = 1000000.0
+
+// PurchaseStatusRequest is the optional body of the workflow endpoints
+type PurchaseStatusRequest struct {
+	Observaciones string `json:"observaciones"`
+}
+
+// ApprovePurchase approves a draft purchase; above the approval limit only an admin can approve it
+func (pmc *PurchaseManagementController) ApprovePurchase(c *gin.Context) {
+	pmc.changePurchaseStatus(c, services.EstadoCompraAprobada)
+}
+
+// SendPurchase marks an approved purchase as sent to the supplier
+func (pmc *PurchaseManagementController) SendPurchase(c *gin.Context) {
+	pmc.changePurchaseStatus(c, services.EstadoCompraEnviada)
+}
+
+// CancelPurchase cancels a purchase that has not received any goods yet
+func (pmc *PurchaseManagementController) CancelPurchase(c *gin.Context) {
+	pmc.changePurchaseStatus(c, services.EstadoCompraCancelada)
+}
+
+// ClosePurchase closes a received purchase, or a partially received one whose remaining goods will not arrive
+func (pmc *PurchaseManagementController) ClosePurchase(c *gin.Context) {
+	pmc.changePurchaseStatus(c, services.EstadoCompraCerrada)
+}
+
+// changePurchaseStatus validates and applies a workflow transition, recording the user who made it
+func (pmc *PurchaseManagementController) changePurchaseStatus(c *gin.Context, estado string) {
+	purchaseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPurchaseID})
+		return
+	}
+
+	var req PurchaseStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	compra, err := pmc.dbService.BuscarCompraPorID(uint(purchaseID))
+	if err != nil || compra.ComID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrPurchaseNotFound})
+		return
+	}
+	if !services.PuedeCambiarEstadoCompra(compra.CopEstado, estado) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      ErrPurchaseTransition,
+			"cop_estado": compra.CopEstado,
+			"requested":  estado,
+		})
+		return
+	}
+
+	if estado == services.EstadoCompraAprobada {
+		detalles, err := pmc.dbService.ListarDetallesCompra(uint(purchaseID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get purchase details"})
+			return
+		}
+		if len(detalles) == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": ErrPurchaseWithoutLines})
+			return
+		}
+
+		total := 0.0
+		for _, detalle := range detalles {
+			total += float64(detalle.DecCantidad) * detalle.DecPrecioUnitario
+		}
+		limite := purchaseApprovalLimit()
+		if total > limite && c.GetString("user_type") != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":          ErrPurchaseApprovalAdminOnly,
+				"total":          total,
+				"approval_limit": limite,
+			})
+			return
+		}
+	}
+
+	err = pmc.dbService.CambiarEstadoCompra(uint(purchaseID), estado, c.GetString("user_email"), req.Observaciones)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedChangePurchaseStatus})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Purchase status updated successfully",
+		"purchase_id": purchaseID,
+		"cop_estado":  estado,
+	})
+}
+
+// requireDraftPurchase answers 404/409 and returns false unless the purchase exists and is still a draft
+func (pmc *PurchaseManagementController) requireDraftPurchase(c *gin.Context, purchaseID uint) bool {
+	compra, err := pmc.dbService.BuscarCompraPorID(purchaseID)
+	if err != nil || compra.ComID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrPurchaseNotFound})
+		return false
+	}
+	if compra.CopEstado != services.EstadoCompraBorrador {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrPurchaseNotEditable, "cop_estado": compra.CopEstado})
+		return false
+	}
+	return true
+}
+
+// requireDeletablePurchase answers 404/409 and returns false unless the purchase exists and is a draft
+// or cancelled; once approved it may have received goods, so it has to be cancelled instead
+func (pmc *PurchaseManagementController) requireDeletablePurchase(c *gin.Context, purchaseID uint) bool {
+	compra, err := pmc.dbService.BuscarCompraPorID(purchaseID)
+	if err != nil || compra.ComID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrPurchaseNotFound})
+		return false
+	}
+	if compra.CopEstado != services.EstadoCompraBorrador && compra.CopEstado != services.EstadoCompraCancelada {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrPurchaseNotDeletable, "cop_estado": compra.CopEstado})
+		return false
+	}
+	return true
+}
+
+// purchaseApprovalLimit returns the configured purchase total that requires admin approval
+func purchaseApprovalLimit() float64 {
+	if config.AppConfig == nil || config.AppConfig.PurchaseApprovalLimit == "" {
+		return DefaultPurchaseApprovalLimit
+	}
+	limite, err := strconv.ParseFloat(config.AppConfig.PurchaseApprovalLimit, 64)
+	if err != nil || limite < 0 {
+		return DefaultPurchaseApprovalLimit
+	}
+	return limite
+}
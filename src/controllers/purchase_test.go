@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"salon/models"
+)
+
+func TestBuildReceiptLine(t *testing.T) {
+	tests := []struct {
+		name          string
+		ordenada      int
+		recibida      int
+		estado        string
+		pendiente     int
+		discrepancias []string
+	}{
+		{"nothing received", 10, 0, ReceiptStatusPending, 10, nil},
+		{"partial delivery", 10, 4, ReceiptStatusPartial, 6, nil},
+		{"complete delivery", 10, 10, ReceiptStatusComplete, 0, nil},
+		{"more than ordered", 10, 12, ReceiptStatusExcess, 0, []string{"cantidad"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linea := buildReceiptLine(models.DetalleCompra{
+				ComID:               1,
+				ProdID:              7,
+				DecCantidad:         tt.ordenada,
+				DecCantidadRecibida: tt.recibida,
+				DecPrecioUnitario:   12500,
+			})
+
+			if linea.Estado != tt.estado {
+				t.Errorf("estado = %q, want %q", linea.Estado, tt.estado)
+			}
+			if linea.Pendiente != tt.pendiente {
+				t.Errorf("pendiente = %d, want %d", linea.Pendiente, tt.pendiente)
+			}
+			if !reflect.DeepEqual(linea.Discrepancias, tt.discrepancias) {
+				t.Errorf("discrepancias = %v, want %v", linea.Discrepancias, tt.discrepancias)
+			}
+			if linea.ProdID != 7 || linea.DecCantidad != tt.ordenada || linea.DecCantidadRecibida != tt.recibida ||
+				linea.DecPrecioUnitario != 12500 || linea.CostoRecibido != nil {
+				t.Errorf("line copied from the order = %+v", linea)
+			}
+		})
+	}
+}
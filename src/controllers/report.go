@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"salon/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ErrInvalidReportDates = "Invalid date format. Use YYYY-MM-DD"
+	ErrInvalidReportRange = "to must not be before from"
+	ErrInvalidReportGroup = "Invalid group_by, expected month or week"
+	ErrFailedBuildPnL     = "Failed to build profit and loss report"
+)
+
+type ReportController struct {
+	dbService *services.DatabaseService
+}
+
+func NewReportController(dbService *services.DatabaseService) *ReportController {
+	return &ReportController{dbService: dbService}
+}
+
+// GetProfitAndLoss handles GET /reports/pnl - revenue, cost of goods, payroll, other expenses by
+// type and net profit between ?from= and ?to= (YYYY-MM-DD, default current month), per ?group_by=
+// month or week, compared with the previous period of the same length
+func (rc *ReportController) GetProfitAndLoss(c *gin.Context) {
+	now := time.Now()
+	from := c.DefaultQuery("from", time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02"))
+	to := c.DefaultQuery("to", now.Format("2006-01-02"))
+
+	desde, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidReportDates})
+		return
+	}
+	hasta, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidReportDates})
+		return
+	}
+
+	reporte, err := rc.dbService.EstadoResultados(desde, hasta, c.DefaultQuery("group_by", services.AgrupacionMes))
+	switch {
+	case errors.Is(err, services.ErrAgrupacionInvalida):
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidReportGroup})
+		return
+	case errors.Is(err, services.ErrRangoFechasInvalido):
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidReportRange})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   ErrFailedBuildPnL,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reporte,
+	})
+}
@@ -18,6 +18,20 @@ const (
 	ErrServiceNotFound        = "Service not found"
 )
 
+const (
+	ErrFailedRetrieveServiceProducts = "Failed to retrieve service products"
+	ErrFailedSaveServiceProduct      = "Failed to save service product"
+	ErrFailedDeleteServiceProduct    = "Failed to remove service product"
+	ErrInvalidProductIDParam         = "Invalid product ID"
+	ErrProductNotFoundForService     = "Product not found"
+	ErrServiceProductNotFound        = "The service does not use this product"
+	ErrServiceProductExists          = "The service already uses this product"
+)
+
+const (
+	ErrFailedUpdateTaxRate = "Failed to update VAT rate"
+)
+
 type ServiceManagementController struct {
 	dbService *services.DatabaseService
 }
@@ -138,6 +152,41 @@ func (smc *ServiceManagementController) UpdateService(c *gin.Context) {
 	})
 }
 
+// TaxRateRequest represents the VAT rate included in the price of a service or product
+type TaxRateRequest struct {
+	IvaPorcentaje *float64 `json:"iva_porcentaje" binding:"required,min=0,max=100"` // 0 for excluded items
+}
+
+// UpdateServiceTax sets the VAT rate of a service; invoices already billed keep their rate
+func (smc *ServiceManagementController) UpdateServiceTax(c *gin.Context) {
+	serviceID, ok := smc.resolveService(c)
+	if !ok {
+		return
+	}
+
+	var req TaxRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := smc.dbService.ActualizarIvaServicio(serviceID, *req.IvaPorcentaje); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedUpdateTaxRate})
+		return
+	}
+
+	servicio, err := smc.dbService.BuscarServicioPorID(serviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveServices})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Service VAT rate updated successfully",
+		"service": servicio,
+	})
+}
+
 // DeleteService deletes a service
 func (smc *ServiceManagementController) DeleteService(c *gin.Context) {
 	id := c.Param("id")
@@ -233,3 +282,198 @@ func (smc *ServiceManagementController) GetServiceStats(c *gin.Context) {
 		"categories":     categoryList,
 	})
 }
+
+// ============= SERVICE PRODUCTS (RECIPE) =============
+
+// ServiceProductRequest is the amount of a product consumed each time a service is performed
+type ServiceProductRequest struct {
+	ProdID            uint `json:"prod_id"`
+	PruCantidadUsada  int  `json:"pru_cantidad_usada" binding:"required,min=1"` // Amount used (ml, g...)
+	PruBotellasUsadas int  `json:"pru_botellas_usadas" binding:"min=0"`         // Stock units deducted per service
+}
+
+// GetServiceProducts returns the products a service consumes
+func (smc *ServiceManagementController) GetServiceProducts(c *gin.Context) {
+	serviceID, ok := smc.resolveService(c)
+	if !ok {
+		return
+	}
+
+	productos, err := smc.dbService.ListarProductosServicio(serviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveServiceProducts})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ser_id":   serviceID,
+		"products": productos,
+		"total":    len(productos),
+	})
+}
+
+// AddServiceProduct adds a product to a service's recipe
+func (smc *ServiceManagementController) AddServiceProduct(c *gin.Context) {
+	serviceID, ok := smc.resolveService(c)
+	if !ok {
+		return
+	}
+
+	var req ServiceProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ProdID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidProductIDParam})
+		return
+	}
+
+	productos, err := smc.dbService.GetProductos()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedSaveServiceProduct})
+		return
+	}
+	found := false
+	for _, producto := range productos {
+		if producto.ProdID == req.ProdID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrProductNotFoundForService})
+		return
+	}
+
+	if exists, err := smc.findServiceProduct(serviceID, req.ProdID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedSaveServiceProduct})
+		return
+	} else if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrServiceProductExists})
+		return
+	}
+
+	productoUsado := models.ProductoUsado{
+		SerID:             serviceID,
+		ProdID:            req.ProdID,
+		PruCantidadUsada:  req.PruCantidadUsada,
+		PruBotellasUsadas: req.PruBotellasUsadas,
+	}
+	if err := smc.dbService.InsertarProductoUsado(productoUsado); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedSaveServiceProduct})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Product added to service successfully",
+		"product": productoUsado,
+	})
+}
+
+// UpdateServiceProduct changes the amount of a product a service consumes
+func (smc *ServiceManagementController) UpdateServiceProduct(c *gin.Context) {
+	serviceID, ok := smc.resolveService(c)
+	if !ok {
+		return
+	}
+	productID, err := strconv.ParseUint(c.Param("prod_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidProductIDParam})
+		return
+	}
+
+	var req ServiceProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if exists, err := smc.findServiceProduct(serviceID, uint(productID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedSaveServiceProduct})
+		return
+	} else if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrServiceProductNotFound})
+		return
+	}
+
+	productoUsado := models.ProductoUsado{
+		SerID:             serviceID,
+		ProdID:            uint(productID),
+		PruCantidadUsada:  req.PruCantidadUsada,
+		PruBotellasUsadas: req.PruBotellasUsadas,
+	}
+	if err := smc.dbService.ActualizarProductoUsado(productoUsado); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedSaveServiceProduct})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Service product updated successfully",
+		"product": productoUsado,
+	})
+}
+
+// RemoveServiceProduct removes a product from a service's recipe
+func (smc *ServiceManagementController) RemoveServiceProduct(c *gin.Context) {
+	serviceID, ok := smc.resolveService(c)
+	if !ok {
+		return
+	}
+	productID, err := strconv.ParseUint(c.Param("prod_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidProductIDParam})
+		return
+	}
+
+	if exists, err := smc.findServiceProduct(serviceID, uint(productID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedDeleteServiceProduct})
+		return
+	} else if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrServiceProductNotFound})
+		return
+	}
+
+	if err := smc.dbService.EliminarProductoUsado(serviceID, uint(productID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedDeleteServiceProduct})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product removed from service successfully"})
+}
+
+// resolveService parses the :id parameter and checks the service exists;
+// on failure it writes the error response and returns false
+func (smc *ServiceManagementController) resolveService(c *gin.Context) (uint, bool) {
+	serviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidServiceID})
+		return 0, false
+	}
+
+	servicio, err := smc.dbService.BuscarServicioPorID(uint(serviceID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrFailedRetrieveServices})
+		return 0, false
+	}
+	if servicio.SerID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrServiceNotFound})
+		return 0, false
+	}
+
+	return uint(serviceID), true
+}
+
+// findServiceProduct reports whether the product is part of the service's recipe
+func (smc *ServiceManagementController) findServiceProduct(serviceID, productID uint) (bool, error) {
+	productos, err := smc.dbService.ListarProductosServicio(serviceID)
+	if err != nil {
+		return false, err
+	}
+	for _, producto := range productos {
+		if producto.ProdID == productID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
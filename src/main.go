@@ -38,7 +38,7 @@ func main() {
 	// CORS middleware - temporarily allow all origins for debugging
 	corsConfig := cors.Config{
 		AllowOrigins:     []string{"http://localhost:5174"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: false, // still false, since you're not using cookies
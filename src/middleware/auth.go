@@ -14,7 +14,7 @@ import (
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Email    string `json:"email"`
-	UserType string `json:"user_type"` // "admin", "employee", "client"
+	UserType string `json:"user_type"` // "admin", "empleado", "cliente"
 	Role     string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
@@ -76,7 +76,7 @@ func AdminOnlyMiddleware() gin.HandlerFunc {
 func EmployeeOrAdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userType, exists := c.Get("user_type")
-		if !exists || (userType != "admin" && userType != "employee") {
+		if !exists || (userType != "admin" && userType != "employee" && userType != "empleado") {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Employee or admin access required"})
 			c.Abort()
 			return
@@ -85,6 +85,24 @@ func EmployeeOrAdminMiddleware() gin.HandlerFunc {
 	}
 }
 
+func EmployeeOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userType, exists := c.Get("user_type")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		if userType != "employee" && userType != "empleado" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only employees can access their appointments"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 func ClientOnlyMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userType, exists := c.Get("user_type")
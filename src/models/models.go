@@ -34,6 +34,34 @@ func (Employee) TableName() string {
 	return "EMPLEADO"
 }
 
+// HorarioEmpleado represents the employee weekly schedule table (matches database schema exactly)
+type HorarioEmpleado struct {
+	HorID          uint   `json:"hor_id" gorm:"primaryKey;autoIncrement;column:hor_id"`
+	HorDiaSemana   string `json:"hor_dia_semana" gorm:"not null;column:hor_dia_semana"`
+	HorHoraEntrada string `json:"hor_hora_entrada" gorm:"not null;column:hor_hora_entrada"`
+	HorHoraSalida  string `json:"hor_hora_salida" gorm:"not null;column:hor_hora_salida"`
+	EmpID          uint   `json:"emp_id" gorm:"not null;column:emp_id"`
+}
+
+func (HorarioEmpleado) TableName() string {
+	return "HORARIO_EMPLEADO"
+}
+
+// HorarioExcepcion represents days off, holidays and special hours for an employee (matches database schema exactly)
+type HorarioExcepcion struct {
+	HexID          uint      `json:"hex_id" gorm:"primaryKey;autoIncrement;column:hex_id"`
+	HexFecha       time.Time `json:"hex_fecha" gorm:"not null;column:hex_fecha"`
+	HexTipo        string    `json:"hex_tipo" gorm:"not null;column:hex_tipo"`
+	HexHoraEntrada *string   `json:"hex_hora_entrada" gorm:"column:hex_hora_entrada"`
+	HexHoraSalida  *string   `json:"hex_hora_salida" gorm:"column:hex_hora_salida"`
+	HexMotivo      string    `json:"hex_motivo" gorm:"column:hex_motivo"`
+	EmpID          uint      `json:"emp_id" gorm:"not null;column:emp_id"`
+}
+
+func (HorarioExcepcion) TableName() string {
+	return "HORARIO_EXCEPCION"
+}
+
 // Client represents the clients table (matches database schema exactly)
 type Client struct {
 	CliID       uint   `json:"cli_id" gorm:"primaryKey;autoIncrement;column:cli_id"`
@@ -42,6 +70,9 @@ type Client struct {
 	CliTelefono string `json:"cli_telefono" gorm:"column:cli_telefono"`
 	CliCorreo   string `json:"cli_correo" gorm:"not null;column:cli_correo"`
 	CliPassword string `json:"cli_password" gorm:"-"`
+
+	CliTipoDocumento *string `json:"cli_tipo_documento" gorm:"column:cli_tipo_documento"` // CC, CE, NIT, PP or TI
+	CliDocumento     *string `json:"cli_documento" gorm:"column:cli_documento"`           // Identifies the client on electronic invoices
 	// Password is not stored in the database, but used for authentication
 }
 
@@ -57,6 +88,7 @@ type Service struct {
 	SerPrecioUnitario   float64 `json:"ser_precio_unitario" gorm:"not null;column:ser_precio_unitario"`
 	SerCategoria        string  `json:"ser_categoria" gorm:"not null;column:ser_categoria"`
 	SerDuracionEstimada int     `json:"ser_duracion_estimada" gorm:"default:60;column:ser_duracion_estimada"`
+	SerIvaPorcentaje    float64 `json:"ser_iva_porcentaje" gorm:"not null;default:19;column:ser_iva_porcentaje"` // VAT rate included in the price
 }
 
 func (Service) TableName() string {
@@ -70,12 +102,51 @@ type Product struct {
 	ProdDescripcion        string  `json:"prod_descripcion" gorm:"column:prod_descripcion"`
 	ProdCantidadDisponible int     `json:"prod_cantidad_disponible" gorm:"not null;column:prod_cantidad_disponible"`
 	ProdPrecioUnitario     float64 `json:"prod_precio_unitario" gorm:"not null;column:prod_precio_unitario"`
+	ProdCostoPromedio      float64 `json:"prod_costo_promedio" gorm:"not null;default:0;column:prod_costo_promedio"`     // Weighted average unit cost
+	ProdStockMinimo        int     `json:"prod_stock_minimo" gorm:"not null;default:5;column:prod_stock_minimo"`         // Reorder point
+	ProdCantidadReorden    int     `json:"prod_cantidad_reorden" gorm:"not null;default:0;column:prod_cantidad_reorden"` // Usual order quantity
+	ProvID                 *uint   `json:"prov_id" gorm:"column:prov_id"`                                                // Preferred supplier
+	ProdIvaPorcentaje      float64 `json:"prod_iva_porcentaje" gorm:"not null;default:19;column:prod_iva_porcentaje"`    // VAT rate included in the price
 }
 
 func (Product) TableName() string {
 	return "PRODUCTO"
 }
 
+// ProductoReorden is a product below its minimum stock with the suggested order quantity (vw_productos_reorden)
+type ProductoReorden struct {
+	ProdID                 uint    `json:"prod_id" gorm:"column:prod_id"`
+	ProdNombre             string  `json:"prod_nombre" gorm:"column:prod_nombre"`
+	ProdCantidadDisponible int     `json:"prod_cantidad_disponible" gorm:"column:prod_cantidad_disponible"`
+	ProdStockMinimo        int     `json:"prod_stock_minimo" gorm:"column:prod_stock_minimo"`
+	ProdCantidadReorden    int     `json:"prod_cantidad_reorden" gorm:"column:prod_cantidad_reorden"`
+	ProvID                 *uint   `json:"prov_id" gorm:"column:prov_id"`
+	ProvNombre             *string `json:"prov_nombre" gorm:"column:prov_nombre"`
+	EnPedido               int     `json:"en_pedido" gorm:"column:en_pedido"`           // Pending in open purchases
+	CostoEstimado          float64 `json:"costo_estimado" gorm:"column:costo_estimado"` // Last purchase price
+	CantidadSugerida       int     `json:"cantidad_sugerida" gorm:"column:cantidad_sugerida"`
+}
+
+// ProductoUsado is one product of a service's recipe (PRODUCTO_USADO); pru_botellas_usadas units
+// are deducted from stock each time the service is performed
+type ProductoUsado struct {
+	SerID             uint `json:"ser_id" gorm:"primaryKey;column:ser_id"`
+	ProdID            uint `json:"prod_id" gorm:"primaryKey;column:prod_id"`
+	PruCantidadUsada  int  `json:"pru_cantidad_usada" gorm:"not null;column:pru_cantidad_usada"`
+	PruBotellasUsadas int  `json:"pru_botellas_usadas" gorm:"not null;column:pru_botellas_usadas"`
+}
+
+func (ProductoUsado) TableName() string {
+	return "PRODUCTO_USADO"
+}
+
+// ProductoServicio is a recipe line with the product information from sp_listar_productos_servicio
+type ProductoServicio struct {
+	ProductoUsado
+	ProdNombre             string `json:"prod_nombre" gorm:"column:prod_nombre"`
+	ProdCantidadDisponible int    `json:"prod_cantidad_disponible" gorm:"column:prod_cantidad_disponible"`
+}
+
 // Inventory represents the inventory table (matches database schema exactly)
 type Inventory struct {
 	InvID                 uint      `json:"inv_id" gorm:"primaryKey;autoIncrement;column:inv_id"`
@@ -90,6 +161,58 @@ func (Inventory) TableName() string {
 	return "INVENTARIO"
 }
 
+// MovimientoInventario is an entry of the append-only stock ledger
+type MovimientoInventario struct {
+	MovID            uint      `json:"mov_id" gorm:"primaryKey;autoIncrement;column:mov_id"`
+	ProdID           uint      `json:"prod_id" gorm:"not null;column:prod_id"`
+	MovFecha         time.Time `json:"mov_fecha" gorm:"column:mov_fecha"`
+	MovTipo          string    `json:"mov_tipo" gorm:"not null;column:mov_tipo"`
+	MovCantidad      int       `json:"mov_cantidad" gorm:"not null;column:mov_cantidad"`             // Positive in, negative out
+	MovSaldo         int       `json:"mov_saldo" gorm:"not null;column:mov_saldo"`                   // Stock after the movement
+	MovCostoUnitario float64   `json:"mov_costo_unitario" gorm:"not null;column:mov_costo_unitario"` // Purchase cost on receipts, average cost otherwise
+	MovCostoPromedio float64   `json:"mov_costo_promedio" gorm:"not null;column:mov_costo_promedio"` // Average cost after the movement
+	MovUsuario       *string   `json:"mov_usuario" gorm:"column:mov_usuario"`
+	MovMotivo        *string   `json:"mov_motivo" gorm:"column:mov_motivo"`
+	MovReferencia    *string   `json:"mov_referencia" gorm:"column:mov_referencia"`
+	ProdNombre       string    `json:"prod_nombre" gorm:"column:prod_nombre;->;-:migration"` // From sp_listar_movimientos_producto
+}
+
+func (MovimientoInventario) TableName() string {
+	return "MOVIMIENTO_INVENTARIO"
+}
+
+// ConteoInventario is a physical stock count session
+type ConteoInventario struct {
+	ConID             uint       `json:"con_id" gorm:"primaryKey;autoIncrement;column:con_id"`
+	ConFechaApertura  time.Time  `json:"con_fecha_apertura" gorm:"column:con_fecha_apertura"`
+	ConEstado         string     `json:"con_estado" gorm:"not null;default:Abierto;column:con_estado"`
+	ConAbiertoPor     *string    `json:"con_abierto_por" gorm:"column:con_abierto_por"`
+	ConCerradoPor     *string    `json:"con_cerrado_por" gorm:"column:con_cerrado_por"`
+	ConFechaCierre    *time.Time `json:"con_fecha_cierre" gorm:"column:con_fecha_cierre"`
+	ConObservaciones  *string    `json:"con_observaciones" gorm:"column:con_observaciones"`
+	ProductosContados int        `json:"productos_contados" gorm:"column:productos_contados;->;-:migration"` // From sp_listar_conteos_inventario
+}
+
+func (ConteoInventario) TableName() string {
+	return "CONTEO_INVENTARIO"
+}
+
+// DiferenciaConteo compares the counted quantity with the system stock when the product was last counted
+// (StockAlContar); DcoStockSistema is the stock frozen when the count was opened. CantidadContada is nil
+// while nobody has counted the product
+type DiferenciaConteo struct {
+	ConID            uint     `json:"con_id" gorm:"column:con_id"`
+	ProdID           uint     `json:"prod_id" gorm:"column:prod_id"`
+	ProdNombre       string   `json:"prod_nombre" gorm:"column:prod_nombre"`
+	DcoStockSistema  int      `json:"dco_stock_sistema" gorm:"column:dco_stock_sistema"`
+	DcoCostoUnitario float64  `json:"dco_costo_unitario" gorm:"column:dco_costo_unitario"`
+	CantidadContada  *int     `json:"cantidad_contada" gorm:"column:cantidad_contada"`
+	ContadoPor       *string  `json:"contado_por" gorm:"column:contado_por"`
+	StockAlContar    *int     `json:"stock_al_contar" gorm:"column:stock_al_contar"`
+	Diferencia       *int     `json:"diferencia" gorm:"column:diferencia"`
+	ValorDiferencia  *float64 `json:"valor_diferencia" gorm:"column:valor_diferencia"`
+}
+
 // Supplier represents the suppliers table (matches database schema exactly)
 type Supplier struct {
 	ProvID        uint   `json:"prov_id" gorm:"primaryKey;autoIncrement;column:prov_id"`
@@ -105,12 +228,15 @@ func (Supplier) TableName() string {
 
 // Purchase represents the purchases table (matches database schema)
 type Purchase struct {
-	ComID          uint      `json:"com_id" gorm:"primaryKey;autoIncrement;column:com_id"`
-	CopFechaCompra time.Time `json:"cop_fecha_compra" gorm:"not null;column:cop_fecha_compra"`
-	CopTotalCompra float64   `json:"cop_total_compra" gorm:"not null;column:cop_total_compra"`
-	CopMetodoPago  string    `json:"cop_metodo_pago" gorm:"not null;column:cop_metodo_pago"`
-	ProvID         uint      `json:"prov_id" gorm:"not null;column:prov_id"`
-	GasID          uint      `json:"gas_id" gorm:"not null;column:gas_id"`
+	ComID              uint       `json:"com_id" gorm:"primaryKey;autoIncrement;column:com_id"`
+	CopFechaCompra     time.Time  `json:"cop_fecha_compra" gorm:"not null;column:cop_fecha_compra"`
+	CopTotalCompra     float64    `json:"cop_total_compra" gorm:"not null;column:cop_total_compra"`
+	CopMetodoPago      string     `json:"cop_metodo_pago" gorm:"not null;column:cop_metodo_pago"`
+	ProvID             uint       `json:"prov_id" gorm:"not null;column:prov_id"`
+	GasID              uint       `json:"gas_id" gorm:"not null;column:gas_id"`
+	CopEstado          string     `json:"cop_estado" gorm:"not null;default:Borrador;column:cop_estado"`
+	CopAprobadaPor     *string    `json:"cop_aprobada_por" gorm:"column:cop_aprobada_por"`
+	CopFechaAprobacion *time.Time `json:"cop_fecha_aprobacion" gorm:"column:cop_fecha_aprobacion"`
 }
 
 func (Purchase) TableName() string {
@@ -119,16 +245,48 @@ func (Purchase) TableName() string {
 
 // DetalleCompra represents purchase details table (matches database schema)
 type DetalleCompra struct {
-	ComID             uint    `json:"com_id" gorm:"primaryKey;column:com_id"`
-	ProdID            uint    `json:"prod_id" gorm:"primaryKey;column:prod_id"`
-	DecCantidad       int     `json:"dec_cantidad" gorm:"not null;column:dec_cantidad"`
-	DecPrecioUnitario float64 `json:"dec_precio_unitario" gorm:"not null;column:dec_precio_unitario"`
+	ComID               uint    `json:"com_id" gorm:"primaryKey;column:com_id"`
+	ProdID              uint    `json:"prod_id" gorm:"primaryKey;column:prod_id"`
+	DecCantidad         int     `json:"dec_cantidad" gorm:"not null;column:dec_cantidad"`
+	DecPrecioUnitario   float64 `json:"dec_precio_unitario" gorm:"not null;column:dec_precio_unitario"`
+	DecCantidadRecibida int     `json:"dec_cantidad_recibida" gorm:"not null;default:0;column:dec_cantidad_recibida"` // Received so far
 }
 
 func (DetalleCompra) TableName() string {
 	return "DETALLE_COMPRA"
 }
 
+// RecepcionCompra is one delivery of a purchase line, with the unit cost actually received
+type RecepcionCompra struct {
+	RecID            uint      `json:"rec_id" gorm:"primaryKey;autoIncrement;column:rec_id"`
+	ComID            uint      `json:"com_id" gorm:"not null;column:com_id"`
+	ProdID           uint      `json:"prod_id" gorm:"not null;column:prod_id"`
+	RecFecha         time.Time `json:"rec_fecha" gorm:"column:rec_fecha"`
+	RecCantidad      int       `json:"rec_cantidad" gorm:"not null;column:rec_cantidad"`
+	RecCostoUnitario float64   `json:"rec_costo_unitario" gorm:"not null;column:rec_costo_unitario"`
+	RecObservaciones *string   `json:"rec_observaciones" gorm:"column:rec_observaciones"`
+	ProdNombre       string    `json:"prod_nombre" gorm:"column:prod_nombre;->;-:migration"` // From sp_listar_recepciones_compra
+}
+
+func (RecepcionCompra) TableName() string {
+	return "RECEPCION_COMPRA"
+}
+
+// HistorialEstadoCompra records every state change of a purchase order
+type HistorialEstadoCompra struct {
+	HcoID             uint      `json:"hco_id" gorm:"primaryKey;autoIncrement;column:hco_id"`
+	ComID             uint      `json:"com_id" gorm:"not null;column:com_id"`
+	HcoEstadoAnterior *string   `json:"hco_estado_anterior" gorm:"column:hco_estado_anterior"`
+	HcoEstadoNuevo    string    `json:"hco_estado_nuevo" gorm:"not null;column:hco_estado_nuevo"`
+	HcoFecha          time.Time `json:"hco_fecha" gorm:"column:hco_fecha"`
+	HcoUsuario        *string   `json:"hco_usuario" gorm:"column:hco_usuario"`
+	HcoObservaciones  *string   `json:"hco_observaciones" gorm:"column:hco_observaciones"`
+}
+
+func (HistorialEstadoCompra) TableName() string {
+	return "HISTORIAL_ESTADO_COMPRA"
+}
+
 type PurchaseWithProducts struct {
 	ComID          uint      `json:"com_id"`
 	CopFechaCompra time.Time `json:"cop_fecha_compra"`
@@ -136,19 +294,24 @@ type PurchaseWithProducts struct {
 	Productos      string    `json:"productos"`
 	CopTotalCompra float64   `json:"cop_total_compra"`
 	CopMetodoPago  string    `json:"cop_metodo_pago"`
+	CopEstado      string    `json:"cop_estado"`
+	ProvID         uint      `json:"prov_id"`
 }
 
 // PurchaseWithDetails represents a complete purchase with its details
 type PurchaseWithDetails struct {
-	ComID          uint            `json:"com_id"`
-	CopFechaCompra time.Time       `json:"cop_fecha_compra"`
-	CopTotalCompra float64         `json:"cop_total_compra"`
-	CopMetodoPago  string          `json:"cop_metodo_pago"`
-	ProvID         uint            `json:"prov_id"`
-	GasID          uint            `json:"gas_id"`
-	Proveedor      string          `json:"proveedor"`
-	Productos      string          `json:"productos"`
-	Detalles       []DetalleCompra `json:"detalles,omitempty" gorm:"-"`
+	ComID              uint            `json:"com_id"`
+	CopFechaCompra     time.Time       `json:"cop_fecha_compra"`
+	CopTotalCompra     float64         `json:"cop_total_compra"`
+	CopMetodoPago      string          `json:"cop_metodo_pago"`
+	ProvID             uint            `json:"prov_id"`
+	GasID              uint            `json:"gas_id"`
+	Proveedor          string          `json:"proveedor"`
+	Productos          string          `json:"productos"`
+	CopEstado          string          `json:"cop_estado"`
+	CopAprobadaPor     *string         `json:"cop_aprobada_por"`
+	CopFechaAprobacion *time.Time      `json:"cop_fecha_aprobacion"`
+	Detalles           []DetalleCompra `json:"detalles,omitempty" gorm:"-"`
 }
 
 // Payment represents employee salary payments (matches database schema)
@@ -188,6 +351,13 @@ type Promotion struct {
 	ProDescuentoPorcentaje float64   `json:"pro_descuento_porcentaje" gorm:"column:pro_descuento_porcentaje"`
 	SerID                  uint      `json:"ser_id" gorm:"not null;column:ser_id"`
 	ProUsos                int       `json:"pro_usos" gorm:"column:pro_usos"`
+	ProTipoDescuento       string    `json:"pro_tipo_descuento" gorm:"column:pro_tipo_descuento;default:Porcentaje"` // Porcentaje or Valor Fijo
+	ProDescuentoValor      *float64  `json:"pro_descuento_valor" gorm:"column:pro_descuento_valor"`                  // Amount off for Valor Fijo promotions
+	ProMaxUsos             *int      `json:"pro_max_usos" gorm:"column:pro_max_usos"`                                // Nil means unlimited
+	ProMaxUsosCliente      *int      `json:"pro_max_usos_cliente" gorm:"column:pro_max_usos_cliente"`                // Nil means unlimited
+	ProMontoMinimo         *float64  `json:"pro_monto_minimo" gorm:"column:pro_monto_minimo"`                        // Minimum invoice subtotal
+	ProCodigo              *string   `json:"pro_codigo" gorm:"column:pro_codigo"`                                    // Coupon code; coded promotions only apply when redeemed
+	ServiciosPaquete       *string   `json:"servicios_paquete" gorm:"column:servicios_paquete;->;-:migration"`       // Comma-separated extra ser_id required by a bundle
 }
 
 func (Promotion) TableName() string {
@@ -205,6 +375,21 @@ type PromotionWithService struct {
 	ProDescuentoPorcentaje float64 `json:"pro_descuento_porcentaje" gorm:"column:pro_descuento_porcentaje"`
 	ProUsos                int     `json:"pro_usos" gorm:"column:pro_usos"`
 	SerNombre              string  `json:"ser_nombre" gorm:"column:ser_nombre"`
+
+	ProTipoDescuento  string   `json:"pro_tipo_descuento" gorm:"column:pro_tipo_descuento"`
+	ProDescuentoValor *float64 `json:"pro_descuento_valor" gorm:"column:pro_descuento_valor"`
+	ProMaxUsos        *int     `json:"pro_max_usos" gorm:"column:pro_max_usos"`
+	ProMaxUsosCliente *int     `json:"pro_max_usos_cliente" gorm:"column:pro_max_usos_cliente"`
+	ProMontoMinimo    *float64 `json:"pro_monto_minimo" gorm:"column:pro_monto_minimo"`
+	ProCodigo         *string  `json:"pro_codigo" gorm:"column:pro_codigo"`
+	ServiciosPaquete  *string  `json:"servicios_paquete" gorm:"column:servicios_paquete"` // Comma-separated extra ser_id required by a bundle
+}
+
+// PromocionConReglas is a promotion with the usage data needed to evaluate its rules for a client
+// Matches sp_listar_promociones_vigentes and sp_buscar_promocion_por_codigo
+type PromocionConReglas struct {
+	Promotion   `gorm:"embedded"`
+	UsosCliente int `json:"usos_cliente" gorm:"column:usos_cliente"` // Invoices of the client that already used the promotion
 }
 
 // Views structs for dashboard metrics (used with stored procedures)
@@ -228,8 +413,73 @@ type TotalProductos struct {
 	TotalProductos int `json:"total_productos" gorm:"column:total_productos"`
 }
 
+// ValorInventario is the stock valued at weighted average cost (ValorVenta at sale price)
 type ValorInventario struct {
 	ValorTotal float64 `json:"valor_total" gorm:"column:valor_total"`
+	ValorVenta float64 `json:"valor_venta" gorm:"column:valor_venta"`
+}
+
+// ValorizacionProducto is the stock value of a product at average cost and at sale price
+type ValorizacionProducto struct {
+	ProdID                 uint    `json:"prod_id" gorm:"column:prod_id"`
+	ProdNombre             string  `json:"prod_nombre" gorm:"column:prod_nombre"`
+	ProdCantidadDisponible int     `json:"prod_cantidad_disponible" gorm:"column:prod_cantidad_disponible"`
+	ProdCostoPromedio      float64 `json:"prod_costo_promedio" gorm:"column:prod_costo_promedio"`
+	ValorCosto             float64 `json:"valor_costo" gorm:"column:valor_costo"`
+	ProdPrecioUnitario     float64 `json:"prod_precio_unitario" gorm:"column:prod_precio_unitario"`
+	ValorVenta             float64 `json:"valor_venta" gorm:"column:valor_venta"`
+}
+
+// CostoSalida is the cost of the units of a product that left stock for one movement type
+type CostoSalida struct {
+	ProdID     uint    `json:"prod_id" gorm:"column:prod_id"`
+	ProdNombre string  `json:"prod_nombre" gorm:"column:prod_nombre"`
+	MovTipo    string  `json:"mov_tipo" gorm:"column:mov_tipo"`
+	Unidades   int     `json:"unidades" gorm:"column:unidades"`
+	Costo      float64 `json:"costo" gorm:"column:costo"`
+}
+
+// ConceptoResultado is the amount of one line of the profit and loss report in a month or week
+type ConceptoResultado struct {
+	Periodo  time.Time `json:"periodo" gorm:"column:periodo"` // First day of the month or Monday of the week
+	Concepto string    `json:"concepto" gorm:"column:concepto"`
+	Detalle  *string   `json:"detalle" gorm:"column:detalle"` // Expense type (gas_tipo) of other expenses
+	Monto    float64   `json:"monto" gorm:"column:monto"`
+}
+
+// ResultadoPeriodo is the profit and loss of a period. Sales and credit notes exclude VAT (fac_subtotal).
+type ResultadoPeriodo struct {
+	Periodo       string             `json:"periodo"` // 2025-06, 2025-W24, or the range for totals
+	Desde         string             `json:"desde"`
+	Hasta         string             `json:"hasta"`
+	Ventas        float64            `json:"ventas"`
+	NotasCredito  float64            `json:"notas_credito"`
+	Ingresos      float64            `json:"ingresos"` // Sales minus credit notes
+	CostoVentas   float64            `json:"costo_ventas"`
+	Merma         float64            `json:"merma"`
+	UtilidadBruta float64            `json:"utilidad_bruta"`
+	Nomina        float64            `json:"nomina"`
+	Gastos        map[string]float64 `json:"gastos"` // Other expenses by gas_tipo
+	TotalGastos   float64            `json:"total_gastos"`
+	UtilidadNeta  float64            `json:"utilidad_neta"`
+	Compras       float64            `json:"compras"` // Inventory bought; its cost counts when the stock leaves
+}
+
+// CambioResultado compares an amount with the previous period; the percentage is nil when it was zero
+type CambioResultado struct {
+	Anterior   float64  `json:"anterior"`
+	Actual     float64  `json:"actual"`
+	Diferencia float64  `json:"diferencia"`
+	Porcentaje *float64 `json:"porcentaje"`
+}
+
+// EstadoResultados is the profit and loss report of a date range compared with the previous period
+type EstadoResultados struct {
+	Agrupacion      string                     `json:"agrupacion"` // month or week
+	Periodos        []ResultadoPeriodo         `json:"periodos"`
+	Total           ResultadoPeriodo           `json:"total"`
+	PeriodoAnterior ResultadoPeriodo           `json:"periodo_anterior"`
+	Variacion       map[string]CambioResultado `json:"variacion"` // ingresos, costo_ventas, nomina, total_gastos, utilidad_neta
 }
 
 type ServiciosTotales struct {
@@ -252,18 +502,35 @@ type TotalProveedores struct {
 
 // Cita represents appointments table (matches database schema exactly)
 type Cita struct {
-	CitID    uint      `json:"cit_id" gorm:"primaryKey;autoIncrement;column:cit_id"`
-	CitFecha time.Time `json:"cit_fecha" gorm:"not null;column:cit_fecha"`
-	CitHora  string    `json:"cit_hora" gorm:"not null;column:cit_hora"`
-	EmpID    uint      `json:"emp_id" gorm:"not null;column:emp_id"`
-	SerID    uint      `json:"ser_id" gorm:"not null;column:ser_id"`
-	CliID    uint      `json:"cli_id" gorm:"not null;column:cli_id"`
+	CitID                uint      `json:"cit_id" gorm:"primaryKey;autoIncrement;column:cit_id"`
+	CitFecha             time.Time `json:"cit_fecha" gorm:"not null;column:cit_fecha"`
+	CitHora              string    `json:"cit_hora" gorm:"not null;column:cit_hora"`
+	EmpID                uint      `json:"emp_id" gorm:"not null;column:emp_id"`
+	SerID                uint      `json:"ser_id" gorm:"not null;column:ser_id"`
+	CliID                uint      `json:"cli_id" gorm:"not null;column:cli_id"`
+	CitEstado            string    `json:"cit_estado" gorm:"not null;default:Programada;column:cit_estado"`
+	CitCancelacionTardia bool      `json:"cit_cancelacion_tardia" gorm:"not null;default:false;column:cit_cancelacion_tardia"`
+	FacID                *uint     `json:"fac_id" gorm:"column:fac_id"`
 }
 
 func (Cita) TableName() string {
 	return "CITA"
 }
 
+// HistorialEstadoCita records each status transition of an appointment
+type HistorialEstadoCita struct {
+	HecID             uint      `json:"hec_id" gorm:"primaryKey;autoIncrement;column:hec_id"`
+	HecEstadoAnterior *string   `json:"hec_estado_anterior" gorm:"column:hec_estado_anterior"`
+	HecEstadoNuevo    string    `json:"hec_estado_nuevo" gorm:"not null;column:hec_estado_nuevo"`
+	HecFecha          time.Time `json:"hec_fecha" gorm:"not null;column:hec_fecha"`
+	HecObservaciones  string    `json:"hec_observaciones" gorm:"column:hec_observaciones"`
+	CitID             uint      `json:"cit_id" gorm:"not null;column:cit_id"`
+}
+
+func (HistorialEstadoCita) TableName() string {
+	return "HISTORIAL_ESTADO_CITA"
+}
+
 // CitaConDetalles represents appointments with employee and service details
 type CitaConDetalles struct {
 	CitID             uint    `json:"cit_id" gorm:"column:cit_id"`
@@ -272,6 +539,7 @@ type CitaConDetalles struct {
 	EmpID             uint    `json:"emp_id" gorm:"column:emp_id"`
 	SerID             uint    `json:"ser_id" gorm:"column:ser_id"`
 	CliID             uint    `json:"cli_id" gorm:"column:cli_id"`
+	CitEstado         string  `json:"cit_estado" gorm:"column:cit_estado"`
 	EmpNombre         string  `json:"emp_nombre" gorm:"column:emp_nombre"`
 	EmpApellido       string  `json:"emp_apellido" gorm:"column:emp_apellido"`
 	EmpPuesto         string  `json:"emp_puesto" gorm:"column:emp_puesto"`
@@ -280,6 +548,18 @@ type CitaConDetalles struct {
 	SerPrecioUnitario float64 `json:"ser_precio_unitario" gorm:"column:ser_precio_unitario"`
 }
 
+// CitaAgenda represents an appointment with the estimated duration of its service,
+// used to check employee availability
+type CitaAgenda struct {
+	CitID               uint      `json:"cit_id" gorm:"column:cit_id"`
+	CitFecha            time.Time `json:"cit_fecha" gorm:"column:cit_fecha"`
+	CitHora             string    `json:"cit_hora" gorm:"column:cit_hora"`
+	EmpID               uint      `json:"emp_id" gorm:"column:emp_id"`
+	SerID               uint      `json:"ser_id" gorm:"column:ser_id"`
+	CliID               uint      `json:"cli_id" gorm:"column:cli_id"`
+	SerDuracionEstimada int       `json:"ser_duracion_estimada" gorm:"column:ser_duracion_estimada"`
+}
+
 // FacturaServicio represents service invoices table (matches database schema exactly)
 type FacturaServicio struct {
 	FacID    uint      `json:"fac_id" gorm:"primaryKey;autoIncrement;column:fac_id"`
@@ -287,6 +567,21 @@ type FacturaServicio struct {
 	FacFecha time.Time `json:"fac_fecha" gorm:"not null;column:fac_fecha"`
 	FacHora  string    `json:"fac_hora" gorm:"not null;column:fac_hora"`
 	CliID    uint      `json:"cli_id" gorm:"not null;column:cli_id"`
+
+	FacPagado     float64 `json:"fac_pagado" gorm:"not null;default:0;column:fac_pagado"`                   // Sum of the client payments applied
+	FacEstadoPago string  `json:"fac_estado_pago" gorm:"not null;default:Pendiente;column:fac_estado_pago"` // Pendiente, Parcial or Pagada
+
+	FacEstado       string     `json:"fac_estado" gorm:"not null;default:Borrador;column:fac_estado"` // Borrador, Emitida or Anulada
+	FacFechaEmision *time.Time `json:"fac_fecha_emision" gorm:"column:fac_fecha_emision"`
+	FacAcreditado   float64    `json:"fac_acreditado" gorm:"not null;default:0;column:fac_acreditado"`   // Sum of the credit notes
+	FacReembolsado  float64    `json:"fac_reembolsado" gorm:"not null;default:0;column:fac_reembolsado"` // Refunded to the client by credit notes
+
+	FacSubtotal    float64 `json:"fac_subtotal" gorm:"not null;default:0;column:fac_subtotal"` // Total before VAT
+	FacImpuesto    float64 `json:"fac_impuesto" gorm:"not null;default:0;column:fac_impuesto"` // VAT included in the total
+	ResID          *uint   `json:"res_id" gorm:"column:res_id"`                                // Numbering resolution used when issued
+	FacPrefijo     *string `json:"fac_prefijo" gorm:"column:fac_prefijo"`
+	FacConsecutivo *int    `json:"fac_consecutivo" gorm:"column:fac_consecutivo"`
+	FacNumero      *string `json:"fac_numero" gorm:"column:fac_numero"` // Legal number: prefix followed by the consecutive
 }
 
 func (FacturaServicio) TableName() string {
@@ -295,14 +590,71 @@ func (FacturaServicio) TableName() string {
 
 // DetalleFacturaServicio represents invoice details table (matches database schema exactly)
 type DetalleFacturaServicio struct {
-	FacID uint `json:"fac_id" gorm:"primaryKey;column:fac_id"`
-	SerID uint `json:"ser_id" gorm:"primaryKey;column:ser_id"`
+	DfsID             uint    `json:"dfs_id" gorm:"primaryKey;autoIncrement;column:dfs_id"`
+	FacID             uint    `json:"fac_id" gorm:"not null;column:fac_id"`
+	SerID             uint    `json:"ser_id" gorm:"not null;column:ser_id"`
+	DfsCantidad       int     `json:"dfs_cantidad" gorm:"not null;default:1;column:dfs_cantidad"`
+	DfsPrecioUnitario float64 `json:"dfs_precio_unitario" gorm:"not null;column:dfs_precio_unitario"` // Service price when it was invoiced
+	DfsDescuento      float64 `json:"dfs_descuento" gorm:"not null;column:dfs_descuento"`
+	ProID             *uint   `json:"pro_id" gorm:"column:pro_id"`
+	EmpID             *uint   `json:"emp_id" gorm:"column:emp_id"` // Employee who performed the service
+	DfsIvaPorcentaje  float64 `json:"dfs_iva_porcentaje" gorm:"not null;default:0;column:dfs_iva_porcentaje"`
 }
 
 func (DetalleFacturaServicio) TableName() string {
 	return "DETALLE_FACTURA_SERVICIO"
 }
 
+// DetalleFacturaProducto represents the retail product lines of an invoice (matches database schema exactly)
+type DetalleFacturaProducto struct {
+	FacID             uint    `json:"fac_id" gorm:"primaryKey;column:fac_id"`
+	ProdID            uint    `json:"prod_id" gorm:"primaryKey;column:prod_id"`
+	DfpCantidad       int     `json:"dfp_cantidad" gorm:"not null;column:dfp_cantidad"`
+	DfpPrecioUnitario float64 `json:"dfp_precio_unitario" gorm:"not null;column:dfp_precio_unitario"`
+	DfpDescuento      float64 `json:"dfp_descuento" gorm:"not null;column:dfp_descuento"`
+	DfpIvaPorcentaje  float64 `json:"dfp_iva_porcentaje" gorm:"not null;default:0;column:dfp_iva_porcentaje"`
+}
+
+func (DetalleFacturaProducto) TableName() string {
+	return "DETALLE_FACTURA_PRODUCTO"
+}
+
+// LineaFactura represents an invoice line with its service name, the promotion that discounted it
+// and the employee who performed it
+type LineaFactura struct {
+	DfsID             uint    `json:"dfs_id" gorm:"column:dfs_id"`
+	FacID             uint    `json:"fac_id" gorm:"column:fac_id"`
+	SerID             uint    `json:"ser_id" gorm:"column:ser_id"`
+	SerNombre         string  `json:"ser_nombre" gorm:"column:ser_nombre"`
+	DfsCantidad       int     `json:"dfs_cantidad" gorm:"column:dfs_cantidad"`
+	DfsPrecioUnitario float64 `json:"dfs_precio_unitario" gorm:"column:dfs_precio_unitario"`
+	DfsDescuento      float64 `json:"dfs_descuento" gorm:"column:dfs_descuento"`
+	ProID             *uint   `json:"pro_id" gorm:"column:pro_id"`
+	ProNombre         *string `json:"pro_nombre" gorm:"column:pro_nombre"`
+	EmpID             *uint   `json:"emp_id" gorm:"column:emp_id"`
+	EmpNombre         *string `json:"emp_nombre" gorm:"column:emp_nombre"`
+	TotalLinea        float64 `json:"total_linea" gorm:"column:total_linea"`
+	IvaPorcentaje     float64 `json:"iva_porcentaje" gorm:"column:iva_porcentaje"`
+	IvaLinea          float64 `json:"iva_linea" gorm:"column:iva_linea"` // VAT included in the line total
+
+	CantidadAcreditada int `json:"cantidad_acreditada" gorm:"column:cantidad_acreditada"` // Units already credited by credit notes
+}
+
+// LineaProductoFactura represents a retail product line of an invoice with its product name
+type LineaProductoFactura struct {
+	FacID             uint    `json:"fac_id" gorm:"column:fac_id"`
+	ProdID            uint    `json:"prod_id" gorm:"column:prod_id"`
+	ProdNombre        string  `json:"prod_nombre" gorm:"column:prod_nombre"`
+	DfpCantidad       int     `json:"dfp_cantidad" gorm:"column:dfp_cantidad"`
+	DfpPrecioUnitario float64 `json:"dfp_precio_unitario" gorm:"column:dfp_precio_unitario"`
+	DfpDescuento      float64 `json:"dfp_descuento" gorm:"column:dfp_descuento"`
+	TotalLinea        float64 `json:"total_linea" gorm:"column:total_linea"`
+	IvaPorcentaje     float64 `json:"iva_porcentaje" gorm:"column:iva_porcentaje"`
+	IvaLinea          float64 `json:"iva_linea" gorm:"column:iva_linea"` // VAT included in the line total
+
+	CantidadAcreditada int `json:"cantidad_acreditada" gorm:"column:cantidad_acreditada"` // Units already returned by credit notes
+}
+
 // InvoiceDetailResponse represents the complete invoice with details for client queries
 type InvoiceDetailResponse struct {
 	FacID     uint    `json:"fac_id" gorm:"column:fac_id"`
@@ -312,6 +664,18 @@ type InvoiceDetailResponse struct {
 	CliID     uint    `json:"cli_id" gorm:"column:cli_id"`
 	CliNombre string  `json:"cli_nombre" gorm:"column:cli_nombre"`
 	Servicios string  `json:"servicios" gorm:"column:servicios"`
+	Productos string  `json:"productos" gorm:"column:productos"`
+
+	FacPagado     float64 `json:"fac_pagado" gorm:"column:fac_pagado"`
+	FacEstadoPago string  `json:"fac_estado_pago" gorm:"column:fac_estado_pago"`
+
+	FacEstado      string  `json:"fac_estado" gorm:"column:fac_estado"`
+	FacAcreditado  float64 `json:"fac_acreditado" gorm:"column:fac_acreditado"`
+	FacReembolsado float64 `json:"fac_reembolsado" gorm:"column:fac_reembolsado"`
+
+	FacNumero   *string `json:"fac_numero" gorm:"column:fac_numero"`
+	FacSubtotal float64 `json:"fac_subtotal" gorm:"column:fac_subtotal"`
+	FacImpuesto float64 `json:"fac_impuesto" gorm:"column:fac_impuesto"`
 }
 
 // HistorialCita represents appointment history table (matches database schema exactly)
@@ -325,14 +689,96 @@ func (HistorialCita) TableName() string {
 	return "HISTORIAL_CITA"
 }
 
-// Additional structs for frontend compatibility
+// ClientPayment represents client payments against invoices table (matches database schema exactly)
 type ClientPayment struct {
-	PagCliID uint      `json:"pag_cli_id"`
-	Fecha    time.Time `json:"fecha"`
-	Monto    float64   `json:"monto"`
-	Metodo   string    `json:"metodo"`
-	Servicio string    `json:"servicio"`
-	Cliente  string    `json:"cliente"`
+	PagCliID         uint      `json:"pag_cli_id" gorm:"primaryKey;autoIncrement;column:pag_cli_id"`
+	FacID            uint      `json:"fac_id" gorm:"not null;column:fac_id"`
+	PagCliFecha      time.Time `json:"pag_cli_fecha" gorm:"not null;column:pag_cli_fecha"`
+	PagCliMetodo     string    `json:"pag_cli_metodo" gorm:"not null;column:pag_cli_metodo"`
+	PagCliEntidad    *string   `json:"pag_cli_entidad" gorm:"column:pag_cli_entidad"`       // Card network, bank or wallet
+	PagCliReferencia *string   `json:"pag_cli_referencia" gorm:"column:pag_cli_referencia"` // Voucher or transaction number
+	PagCliRecibido   float64   `json:"pag_cli_recibido" gorm:"not null;column:pag_cli_recibido"`
+	PagCliMonto      float64   `json:"pag_cli_monto" gorm:"not null;column:pag_cli_monto"` // Amount applied to the invoice
+	PagCliCambio     float64   `json:"pag_cli_cambio" gorm:"not null;default:0;column:pag_cli_cambio"`
+	PagCliUsuario    *string   `json:"pag_cli_usuario" gorm:"column:pag_cli_usuario"`
+}
+
+func (ClientPayment) TableName() string {
+	return "PAGO_CLIENTE"
+}
+
+// SaldoPendiente is an invoice with an outstanding balance
+type SaldoPendiente struct {
+	FacID          uint      `json:"fac_id" gorm:"column:fac_id"`
+	FacFecha       time.Time `json:"fac_fecha" gorm:"column:fac_fecha"`
+	CliID          uint      `json:"cli_id" gorm:"column:cli_id"`
+	CliNombre      string    `json:"cli_nombre" gorm:"column:cli_nombre"`
+	FacTotal       float64   `json:"fac_total" gorm:"column:fac_total"`
+	FacAcreditado  float64   `json:"fac_acreditado" gorm:"column:fac_acreditado"`
+	FacPagado      float64   `json:"fac_pagado" gorm:"column:fac_pagado"`
+	FacReembolsado float64   `json:"fac_reembolsado" gorm:"column:fac_reembolsado"`
+	Saldo          float64   `json:"saldo" gorm:"column:saldo"`
+	FacEstadoPago  string    `json:"fac_estado_pago" gorm:"column:fac_estado_pago"`
+	DiasPendiente  int       `json:"dias_pendiente" gorm:"column:dias_pendiente"`
+}
+
+// ResolucionFacturacion represents the invoice numbering resolutions table: an authorized prefix
+// and consecutive range valid between two dates
+type ResolucionFacturacion struct {
+	ResID                uint      `json:"res_id" gorm:"primaryKey;autoIncrement;column:res_id"`
+	ResNumero            string    `json:"res_numero" gorm:"not null;column:res_numero"`
+	ResPrefijo           string    `json:"res_prefijo" gorm:"not null;column:res_prefijo"`
+	ResRangoDesde        int       `json:"res_rango_desde" gorm:"not null;column:res_rango_desde"`
+	ResRangoHasta        int       `json:"res_rango_hasta" gorm:"not null;column:res_rango_hasta"`
+	ResConsecutivoActual *int      `json:"res_consecutivo_actual" gorm:"column:res_consecutivo_actual"` // Last number assigned
+	ResFechaDesde        time.Time `json:"res_fecha_desde" gorm:"not null;column:res_fecha_desde"`
+	ResFechaHasta        time.Time `json:"res_fecha_hasta" gorm:"not null;column:res_fecha_hasta"`
+	ResActiva            bool      `json:"res_activa" gorm:"not null;default:false;column:res_activa"` // Numbers new invoices
+	ResClaveTecnica      *string   `json:"res_clave_tecnica" gorm:"column:res_clave_tecnica"`          // Technical key used in the CUFE
+
+	ConsecutivosDisponibles int `json:"consecutivos_disponibles" gorm:"column:consecutivos_disponibles"`
+}
+
+func (ResolucionFacturacion) TableName() string {
+	return "RESOLUCION_FACTURACION"
+}
+
+// ImpuestoFactura is the VAT of an invoice grouped by rate
+type ImpuestoFactura struct {
+	IvaPorcentaje float64 `json:"iva_porcentaje" gorm:"column:iva_porcentaje"`
+	Base          float64 `json:"base" gorm:"column:base"`
+	Impuesto      float64 `json:"impuesto" gorm:"column:impuesto"`
+	Total         float64 `json:"total" gorm:"column:total"`
+}
+
+// NotaCredito represents the credit notes table: a void or a partial/full refund of an issued invoice
+type NotaCredito struct {
+	NcID              uint      `json:"nc_id" gorm:"primaryKey;autoIncrement;column:nc_id"`
+	FacID             uint      `json:"fac_id" gorm:"not null;column:fac_id"`
+	NcFecha           time.Time `json:"nc_fecha" gorm:"not null;column:nc_fecha"`
+	NcTipo            string    `json:"nc_tipo" gorm:"not null;column:nc_tipo"` // Anulación or Devolución
+	NcMotivo          string    `json:"nc_motivo" gorm:"not null;column:nc_motivo"`
+	NcTotal           float64   `json:"nc_total" gorm:"not null;default:0;column:nc_total"`         // Amount credited to the invoice
+	NcReembolso       float64   `json:"nc_reembolso" gorm:"not null;default:0;column:nc_reembolso"` // Amount given back to the client
+	NcMetodoReembolso *string   `json:"nc_metodo_reembolso" gorm:"column:nc_metodo_reembolso"`
+	NcUsuario         *string   `json:"nc_usuario" gorm:"column:nc_usuario"`
+
+	Lineas []LineaNotaCredito `json:"lineas,omitempty" gorm:"-"`
+}
+
+func (NotaCredito) TableName() string {
+	return "NOTA_CREDITO"
+}
+
+// LineaNotaCredito is a credit note line with the service or product it credits
+type LineaNotaCredito struct {
+	DncID       uint    `json:"dnc_id" gorm:"column:dnc_id"`
+	NcID        uint    `json:"nc_id" gorm:"column:nc_id"`
+	DfsID       *uint   `json:"dfs_id" gorm:"column:dfs_id"`   // Service line credited
+	ProdID      *uint   `json:"prod_id" gorm:"column:prod_id"` // Product returned
+	Descripcion string  `json:"descripcion" gorm:"column:descripcion"`
+	DncCantidad int     `json:"dnc_cantidad" gorm:"column:dnc_cantidad"`
+	DncValor    float64 `json:"dnc_valor" gorm:"column:dnc_valor"`
 }
 
 // PaymentWithEmployee represents payment data with employee information
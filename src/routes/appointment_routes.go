@@ -24,35 +24,43 @@ func SetupAppointmentRoutes(api *gin.RouterGroup, dbService *services.DatabaseSe
 	protectedAppointments.Use(middleware.AuthMiddleware())
 	{
 		// Routes accessible by all authenticated users (for creating appointments)
-		protectedAppointments.POST("", appointmentController.CreateAppointment) // Create appointment (clients can book their own)
+		protectedAppointments.POST("", appointmentController.CreateAppointment)           // Create appointment (clients can book their own)
+		protectedAppointments.GET("/availability", appointmentController.GetAvailability) // Free slots (?ser_id=&date=YYYY-MM-DD&emp_id=)
 
 		// Admin and employee routes for appointment management
 		adminAppointments := protectedAppointments.Group("")
 		adminAppointments.Use(middleware.EmployeeOrAdminMiddleware())
 		{
-			adminAppointments.GET("", appointmentController.GetAppointments)                            // Get all appointments
-			adminAppointments.GET("/:id", appointmentController.GetAppointment)                         // Get appointment by ID
-			adminAppointments.PUT("/:id", appointmentController.UpdateAppointment)                      // Update appointment
-			adminAppointments.DELETE("/:id", appointmentController.DeleteAppointment)                   // Delete appointment
-			adminAppointments.GET("/employee/:emp_id", appointmentController.GetAppointmentsByEmployee) // Get appointments by employee
-			adminAppointments.GET("/client/:cli_id", appointmentController.GetAppointmentsByClient)     // Get appointments by client
-			adminAppointments.GET("/date/:date", appointmentController.GetAppointmentsByDate)           // Get appointments by date (YYYY-MM-DD)
+			adminAppointments.GET("", appointmentController.GetAppointments)                                // Get all appointments
+			adminAppointments.GET("/:id", appointmentController.GetAppointment)                             // Get appointment by ID
+			adminAppointments.PUT("/:id", appointmentController.UpdateAppointment)                          // Update appointment
+			adminAppointments.DELETE("/:id", appointmentController.DeleteAppointment)                       // Delete appointment
+			adminAppointments.PATCH("/:id/status", appointmentController.UpdateAppointmentStatus)           // Move appointment to a new status
+			adminAppointments.GET("/:id/status-history", appointmentController.GetAppointmentStatusHistory) // Status transitions with timestamps
+			adminAppointments.GET("/late-cancellations", appointmentController.GetLateCancellations)        // Client cancellations inside the notice period
+			adminAppointments.POST("/:id/checkout", appointmentController.CheckoutAppointment)              // Invoice completed appointments of one client
+			adminAppointments.GET("/employee/:emp_id", appointmentController.GetAppointmentsByEmployee)     // Get appointments by employee
+			adminAppointments.GET("/client/:cli_id", appointmentController.GetAppointmentsByClient)         // Get appointments by client
+			adminAppointments.GET("/date/:date", appointmentController.GetAppointmentsByDate)               // Get appointments by date (YYYY-MM-DD)
 		}
 
 		// Client routes (for their own appointments)
 		clientAppointments := protectedAppointments.Group("/my")
 		clientAppointments.Use(middleware.ClientOnlyMiddleware())
 		{
-			clientAppointments.GET("", appointmentController.GetMyAppointments) // Get client's own appointments
+			clientAppointments.GET("", appointmentController.GetMyAppointments)                      // Get client's own appointments
+			clientAppointments.PATCH("/:id/cancel", appointmentController.CancelMyAppointment)       // Cancel own appointment (late ones are flagged)
+			clientAppointments.PUT("/:id/reschedule", appointmentController.RescheduleMyAppointment) // Move own appointment (outside the notice period)
 		}
 
-		// Employee routes (for their assigned appointments) - Future implementation
-		// employeeAppointments := protectedAppointments.Group("/employee")
-		// {
-		//     // These routes will be accessible to employees for their assigned appointments
-		//     // employeeAppointments.GET("/my", appointmentController.GetMyAppointments)     // Get employee's appointments
-		//     // employeeAppointments.PUT("/:id/status", appointmentController.UpdateAppointmentStatus) // Update appointment status
-		//     // employeeAppointments.GET("/today", appointmentController.GetTodayAppointments) // Get today's appointments
-		// }
+		// Employee routes (for their assigned appointments)
+		employeeAppointments := protectedAppointments.Group("/employee")
+		employeeAppointments.Use(middleware.EmployeeOnlyMiddleware())
+		{
+			employeeAppointments.GET("/my", appointmentController.GetMyEmployeeAppointments)           // Get employee's appointments
+			employeeAppointments.GET("/my/history", appointmentController.GetMyEmployeeHistory)        // Get employee's service history notes
+			employeeAppointments.GET("/today", appointmentController.GetMyTodayAppointments)           // Get today's appointments
+			employeeAppointments.PATCH("/:id/status", appointmentController.UpdateMyAppointmentStatus) // Update status of an assigned appointment
+		}
 	}
 }
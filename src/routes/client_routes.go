@@ -25,10 +25,11 @@ func SetupClientRoutes(api *gin.RouterGroup, dbService *services.DatabaseService
 		adminClients := protectedClients.Group("")
 		adminClients.Use(middleware.AdminOnlyMiddleware())
 		{
-			adminClients.GET("", clientController.GetClients)          // Get all clients
-			adminClients.POST("", clientController.CreateClient)       // Create client (admin)
-			adminClients.PUT("/:id", clientController.UpdateClient)    // Update client
-			adminClients.DELETE("/:id", clientController.DeleteClient) // Delete client
+			adminClients.GET("", clientController.GetClients)                        // Get all clients
+			adminClients.POST("", clientController.CreateClient)                     // Create client (admin)
+			adminClients.PUT("/:id", clientController.UpdateClient)                  // Update client
+			adminClients.DELETE("/:id", clientController.DeleteClient)               // Delete client
+			adminClients.PUT("/:id/document", clientController.UpdateClientDocument) // Set identification document for electronic invoices
 		}
 
 		// Client profile routes (for authenticated clients)
@@ -23,6 +23,8 @@ func SetupDashboardRoutes(api *gin.RouterGroup, dbService *services.DatabaseServ
 		protectedDashboard.GET("/appointments", dashboardController.GetAppointmentsMetrics) // Appointment metrics
 		protectedDashboard.GET("/financial", dashboardController.GetFinancialMetrics)       // Financial metrics
 		protectedDashboard.GET("/inventory", dashboardController.GetInventoryMetrics)       // Inventory metrics
+		protectedDashboard.GET("/inventory/valuation", dashboardController.GetInventoryValuation) // Stock valued at weighted average cost
+		protectedDashboard.GET("/inventory/cogs", dashboardController.GetInventoryCostOfGoods)    // Cost of goods consumed, sold or wasted
 		protectedDashboard.GET("/services", dashboardController.GetServicesMetrics)         // Service metrics
 		protectedDashboard.GET("/suppliers", dashboardController.GetSuppliersMetrics)       // Supplier metrics
 	}
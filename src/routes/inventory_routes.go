@@ -32,6 +32,32 @@ func SetupInventoryRoutes(api *gin.RouterGroup, dbService *services.DatabaseServ
 			adminInventory.POST("/products", inventoryController.CreateProduct)       // Create product
 			adminInventory.PUT("/products/:id", inventoryController.UpdateProduct)    // Update product
 			adminInventory.DELETE("/products/:id", inventoryController.DeleteProduct) // Delete product
+
+			// Reorder endpoints
+			adminInventory.PUT("/products/:id/reorder", inventoryController.UpdateReorderSettings)  // Set minimum stock, reorder quantity and preferred supplier
+			adminInventory.GET("/low-stock", inventoryController.GetLowStock)                       // Products below minimum with suggested quantities
+			adminInventory.POST("/low-stock/purchases", inventoryController.CreateReorderPurchases) // Create draft purchases grouped by supplier
+
+			// Tax endpoints
+			adminInventory.PUT("/products/:id/tax", inventoryController.UpdateProductTax) // Set the VAT rate included in the price
+
+			// Stock ledger endpoints
+			adminInventory.POST("/movements", inventoryController.RegisterMovement)                // Register adjustment, waste or return
+			adminInventory.GET("/products/:id/movements", inventoryController.GetProductMovements) // Product movement history (?desde=&hasta=)
+
+			// Stock count endpoints
+			adminInventory.GET("/counts", inventoryController.GetStockCounts)                // List count sessions
+			adminInventory.POST("/counts", inventoryController.OpenStockCount)               // Open a count session (freezes system stock)
+			adminInventory.GET("/counts/:id", inventoryController.GetStockCount)             // Count with variances and their value
+			adminInventory.POST("/counts/:id/entries", inventoryController.SubmitStockCount) // Submit counted quantities
+		}
+
+		// Admin only routes for approving stock counts
+		countApproval := protectedInventory.Group("")
+		countApproval.Use(middleware.AdminOnlyMiddleware())
+		{
+			countApproval.POST("/counts/:id/approve", inventoryController.ApproveStockCount) // Post variances as adjustments
+			countApproval.POST("/counts/:id/cancel", inventoryController.CancelStockCount)   // Discard an open count
 		}
 	}
 }
@@ -32,12 +32,37 @@ func SetupInvoiceRoutes(api *gin.RouterGroup, dbService *services.DatabaseServic
 			adminInvoices.POST("", invoiceController.CreateInvoice)       // Create new invoice with services
 			adminInvoices.GET("/:id", invoiceController.GetInvoiceByID)   // Get specific invoice (basic info)
 			adminInvoices.PUT("/:id", invoiceController.UpdateInvoice)    // Update invoice
-			adminInvoices.DELETE("/:id", invoiceController.DeleteInvoice) // Delete invoice
+			adminInvoices.DELETE("/:id", invoiceController.DeleteInvoice) // Delete a draft invoice
 
 			// Invoice details management
-			adminInvoices.GET("/:id/details", invoiceController.GetInvoiceDetails)            // Get invoice with full details
-			adminInvoices.POST("/:id/services", invoiceController.AddServiceToInvoice)        // Add service to existing invoice
-			adminInvoices.DELETE("/:id/services", invoiceController.RemoveServiceFromInvoice) // Remove service from invoice
+			adminInvoices.GET("/:id/details", invoiceController.GetInvoiceDetails)                        // Get invoice with full details
+			adminInvoices.POST("/:id/services", invoiceController.AddServiceToInvoice)                    // Add service to existing invoice
+			adminInvoices.DELETE("/:id/services", invoiceController.RemoveServiceFromInvoice)             // Remove all services from invoice
+			adminInvoices.DELETE("/:id/services/:dfs_id", invoiceController.RemoveServiceLineFromInvoice) // Remove a single service line
+			adminInvoices.POST("/:id/products", invoiceController.AddProductToInvoice)                    // Sell a retail product on the invoice (deducts stock)
+			adminInvoices.DELETE("/:id/products/:prod_id", invoiceController.RemoveProductFromInvoice)    // Remove a product line (returns it to stock)
+			adminInvoices.GET("/:id/appointments", invoiceController.GetInvoiceAppointments)              // Appointments billed by this invoice
+			adminInvoices.GET("/:id/payments", invoiceController.GetInvoicePayments)                      // Payments applied and balance
+			adminInvoices.POST("/:id/payments", invoiceController.RegisterInvoicePayments)                // Register a (split or partial) payment
+			adminInvoices.GET("/outstanding", invoiceController.GetOutstandingBalances)                   // Invoices with an unpaid balance (?cli_id=)
+
+			// Issuing, voids and credit notes (issued invoices are immutable)
+			adminInvoices.POST("/:id/issue", invoiceController.IssueInvoice)            // Issue a draft invoice
+			adminInvoices.POST("/:id/void", invoiceController.VoidInvoice)              // Void an invoice issued today (full credit note)
+			adminInvoices.GET("/:id/credit-notes", invoiceController.GetCreditNotes)    // Voids and refunds of the invoice
+			adminInvoices.POST("/:id/credit-notes", invoiceController.CreateCreditNote) // Partial or full refund (returns products to stock)
+
+			// Legal numbering (assigned when an invoice is issued)
+			adminInvoices.GET("/resolutions", invoiceController.GetResolutions)                       // Numbering resolutions with the numbers left
+			adminInvoices.POST("/resolutions", invoiceController.CreateResolution)                    // Register a prefix and range with its validity dates
+			adminInvoices.POST("/resolutions/:res_id/activate", invoiceController.ActivateResolution) // Number new invoices with this resolution
+
+			// Electronic invoice
+			adminInvoices.GET("/:id/xml", invoiceController.GetInvoiceXML) // UBL 2.1 document with CUFE of an issued invoice
+
+			// Printing
+			adminInvoices.GET("/:id/pdf", invoiceController.GetInvoicePDF)         // Printable invoice (?size=letter|a4)
+			adminInvoices.GET("/:id/receipt", invoiceController.GetInvoiceReceipt) // ESC/POS receipt (?width=58|80)
 
 			// Full invoice listing with details (main endpoint for frontend)
 			adminInvoices.GET("/details", invoiceController.GetAllInvoicesWithDetails) // Get all invoices with full details
@@ -49,6 +74,10 @@ func SetupInvoiceRoutes(api *gin.RouterGroup, dbService *services.DatabaseServic
 			employeeInvoices.GET("", invoiceController.GetInvoices)                       // View all invoices
 			employeeInvoices.GET("/:id", invoiceController.GetInvoiceByID)                // View specific invoice
 			employeeInvoices.GET("/:id/details", invoiceController.GetInvoiceDetails)     // View invoice details
+			employeeInvoices.GET("/:id/payments", invoiceController.GetInvoicePayments)   // View invoice payments
+			employeeInvoices.GET("/:id/credit-notes", invoiceController.GetCreditNotes)   // View invoice credit notes
+			employeeInvoices.GET("/:id/pdf", invoiceController.GetInvoicePDF)             // Print invoice
+			employeeInvoices.GET("/:id/receipt", invoiceController.GetInvoiceReceipt)     // Print thermal receipt
 			employeeInvoices.GET("/details", invoiceController.GetAllInvoicesWithDetails) // View all invoices with details
 		}
 
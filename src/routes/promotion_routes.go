@@ -18,6 +18,12 @@ func SetupPromotionRoutes(api *gin.RouterGroup, dbService *services.DatabaseServ
 	protectedPromotions.Use(middleware.AuthMiddleware())
 	{
 		protectedPromotions.GET("", promotionController.GetPromotions)          // Get all promotions
+		// Employee and admin routes for the front desk
+		frontDeskPromotions := protectedPromotions.Group("")
+		frontDeskPromotions.Use(middleware.EmployeeOrAdminMiddleware())
+		{
+			frontDeskPromotions.POST("/validate", promotionController.ValidatePromotion) // Check a coupon code against a draft invoice
+		}
 		// Admin only routes for promotion management
 		adminPromotions := protectedPromotions.Group("")
 		adminPromotions.Use(middleware.AdminOnlyMiddleware())
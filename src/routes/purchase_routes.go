@@ -27,19 +27,35 @@ func SetupPurchaseRoutes(api *gin.RouterGroup, dbService *services.DatabaseServi
 		api.GET("/purchases", purchaseController.GetPurchases)    // Get all purchases
 		protectedPurchases.GET("/:id", purchaseController.GetPurchase) // Get purchase by ID
 
+		// Purchase order routes for employees and admins (drafts can only be edited while in Borrador)
+		staffPurchases := protectedPurchases.Group("")
+		staffPurchases.Use(middleware.EmployeeOrAdminMiddleware())
+		{
+			staffPurchases.POST("", purchaseController.CreatePurchase)    // Create purchase (as a draft)
+			staffPurchases.PUT("/:id", purchaseController.UpdatePurchase) // Update draft purchase
+
+			// Purchase detail management routes
+			staffPurchases.GET("/:id/details", purchaseController.GetPurchaseDetails)              // Get purchase details
+			staffPurchases.POST("/:id/details", purchaseController.AddPurchaseDetail)              // Add detail to draft purchase
+			staffPurchases.PUT("/:id/details/:prodId", purchaseController.UpdatePurchaseDetail)    // Update draft purchase detail
+			staffPurchases.DELETE("/:id/details/:prodId", purchaseController.DeletePurchaseDetail) // Delete draft purchase detail
+
+			// Purchase order workflow routes
+			staffPurchases.POST("/:id/approve", purchaseController.ApprovePurchase) // Approve draft (admin only above the approval limit)
+			staffPurchases.POST("/:id/send", purchaseController.SendPurchase)       // Mark approved purchase as sent to the supplier
+			staffPurchases.POST("/:id/cancel", purchaseController.CancelPurchase)   // Cancel purchase before any goods arrive
+			staffPurchases.POST("/:id/close", purchaseController.ClosePurchase)     // Close received or partially received purchase
+		}
+
 		// Admin only routes for purchase management
 		adminPurchases := protectedPurchases.Group("")
 		adminPurchases.Use(middleware.AdminOnlyMiddleware())
 		{
-			adminPurchases.POST("", purchaseController.CreatePurchase)       // Create purchase
-			adminPurchases.PUT("/:id", purchaseController.UpdatePurchase)    // Update purchase
-			adminPurchases.DELETE("/:id", purchaseController.DeletePurchase) // Delete purchase
+			adminPurchases.DELETE("/:id", purchaseController.DeletePurchase) // Delete draft or cancelled purchase
 
-			// Purchase detail management routes
-			adminPurchases.GET("/:id/details", purchaseController.GetPurchaseDetails)              // Get purchase details
-			adminPurchases.POST("/:id/details", purchaseController.AddPurchaseDetail)              // Add detail to purchase
-			adminPurchases.PUT("/:id/details/:prodId", purchaseController.UpdatePurchaseDetail)    // Update purchase detail
-			adminPurchases.DELETE("/:id/details/:prodId", purchaseController.DeletePurchaseDetail) // Delete purchase detail
+			// Goods receipt routes
+			adminPurchases.POST("/:id/receive", purchaseController.ReceivePurchase)     // Receive purchase (full or per line), updates stock
+			adminPurchases.GET("/:id/receipts", purchaseController.GetPurchaseReceipts) // Get receipts and line status
 		}
 
 		// Employee routes (can view purchases)
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"salon/controllers"
+	"salon/middleware"
+	"salon/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupReportRoutes configures the financial report routes
+func SetupReportRoutes(api *gin.RouterGroup, dbService *services.DatabaseService) {
+	// Initialize report controller
+	reportController := controllers.NewReportController(dbService)
+
+	// Financial reports include payroll, so they are for admins only
+	protectedReports := api.Group("/reports")
+	protectedReports.Use(middleware.AuthMiddleware(), middleware.AdminOnlyMiddleware())
+	{
+		protectedReports.GET("/pnl", reportController.GetProfitAndLoss) // Profit and loss by month or week, compared with the previous period
+	}
+}
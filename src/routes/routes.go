@@ -42,6 +42,8 @@ func SetupRoutes(r *gin.Engine) {
 		protectedEmployees.Use(middleware.AuthMiddleware())
 		{
 			protectedEmployees.GET("", employeeController.GetEmployees)
+			protectedEmployees.GET("/:id/schedule", employeeController.GetSchedule)                      // Weekly schedule with exceptions
+			protectedEmployees.GET("/:id/schedule/exceptions", employeeController.GetScheduleExceptions) // Days off, holidays, special hours
 			adminEmployees := protectedEmployees.Group("")
 			adminEmployees.Use(middleware.AdminOnlyMiddleware())
 			{
@@ -49,6 +51,14 @@ func SetupRoutes(r *gin.Engine) {
 				adminEmployees.POST("", employeeController.CreateEmployee)
 				adminEmployees.PUT("/:id", employeeController.UpdateEmployee)
 				adminEmployees.DELETE("/:id", employeeController.DeleteEmployee)
+
+				// Employee schedule management
+				adminEmployees.POST("/:id/schedule", employeeController.CreateSchedule)
+				adminEmployees.PUT("/:id/schedule/:hor_id", employeeController.UpdateSchedule)
+				adminEmployees.DELETE("/:id/schedule/:hor_id", employeeController.DeleteSchedule)
+				adminEmployees.POST("/:id/schedule/exceptions", employeeController.CreateScheduleException)
+				adminEmployees.PUT("/:id/schedule/exceptions/:hex_id", employeeController.UpdateScheduleException)
+				adminEmployees.DELETE("/:id/schedule/exceptions/:hex_id", employeeController.DeleteScheduleException)
 			}
 		}
 
@@ -84,6 +94,9 @@ func SetupRoutes(r *gin.Engine) {
 
 		// Setup dashboard routes
 		SetupDashboardRoutes(api, dbService)
+
+		// Setup report routes
+		SetupReportRoutes(api, dbService)
 	}
 
 	// Protected routes
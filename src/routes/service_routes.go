@@ -33,9 +33,22 @@ func SetupServiceRoutes(api *gin.RouterGroup, dbService *services.DatabaseServic
 		adminServices := protectedServices.Group("")
 		adminServices.Use(middleware.AdminOnlyMiddleware())
 		{
-			adminServices.POST("", serviceController.CreateService)       // Create service
-			adminServices.PUT("/:id", serviceController.UpdateService)    // Update service
-			adminServices.DELETE("/:id", serviceController.DeleteService) // Delete service
+			adminServices.POST("", serviceController.CreateService)           // Create service
+			adminServices.PUT("/:id", serviceController.UpdateService)        // Update service
+			adminServices.DELETE("/:id", serviceController.DeleteService)     // Delete service
+			adminServices.PUT("/:id/tax", serviceController.UpdateServiceTax) // Set the VAT rate included in the price
+
+			// Products consumed by the service (deducted from stock when an appointment is completed)
+			adminServices.POST("/:id/products", serviceController.AddServiceProduct)               // Add product to service
+			adminServices.PUT("/:id/products/:prod_id", serviceController.UpdateServiceProduct)    // Update product usage
+			adminServices.DELETE("/:id/products/:prod_id", serviceController.RemoveServiceProduct) // Remove product from service
+		}
+
+		// Employee and admin routes
+		staffServices := protectedServices.Group("")
+		staffServices.Use(middleware.EmployeeOrAdminMiddleware())
+		{
+			staffServices.GET("/:id/products", serviceController.GetServiceProducts) // Get products used by a service
 		}
 
 		// Employee routes (can view and manage services they offer)
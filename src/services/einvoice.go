@@ -0,0 +1,660 @@
+package services
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"salon/models"
+)
+
+// UBL 2.1 namespaces of the invoice document
+const (
+	ublNamespaceInvoice = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	ublNamespaceCAC     = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	ublNamespaceCBC     = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+)
+
+// Codes of the DIAN technical annex used in the document
+const (
+	monedaFacturaElectronica = "COP"
+	tipoFacturaVenta         = "01" // Factura electrónica de venta
+	tipoOperacionEstandar    = "10"
+	unidadMedidaUnidad       = "94"
+	agenciaDIAN              = "195"
+	tributoIVA               = "01"
+	tributoINC               = "04"
+	tributoICA               = "03"
+	responsabilidadNoAplica  = "R-99-PN"
+	personaJuridica          = "1"
+	personaNatural           = "2"
+	formaPagoContado         = "1"
+	formaPagoCredito         = "2"
+	medioPagoOtro            = "ZZZ"
+)
+
+// Final consumer used for clients without an identification document
+const (
+	tipoDocumentoConsumidorFinal = "CC"
+	documentoConsumidorFinal     = "222222222222"
+	nombreConsumidorFinal        = "Consumidor final"
+)
+
+// Identification document types (CLIENTE.cli_tipo_documento) and their DIAN codes
+var tiposDocumentoDIAN = map[string]string{
+	"CC":  "13",
+	"CE":  "22",
+	"NIT": "31",
+	"PP":  "41",
+	"TI":  "12",
+}
+
+// Client payment methods and their DIAN payment means codes
+var mediosPagoDIAN = map[string]string{
+	MetodoPagoEfectivo:      "10",
+	MetodoPagoTarjeta:       "48",
+	MetodoPagoTransferencia: "47",
+}
+
+// ErrEmisorSinNIT is returned when the electronic invoice is requested without the issuer tax ID configured
+var ErrEmisorSinNIT = errors.New("el NIT del emisor no está configurado")
+
+// EsTipoDocumento reports whether tipo is one of the accepted identification document types
+func EsTipoDocumento(tipo string) bool {
+	_, ok := tiposDocumentoDIAN[tipo]
+	return ok
+}
+
+// EmisorFactura identifies the salon as the issuer of electronic invoices
+type EmisorFactura struct {
+	NIT                string // Without check digit
+	RazonSocial        string
+	Direccion          string
+	Ciudad             string
+	CodigoCiudad       string // DANE municipality code
+	Departamento       string
+	CodigoDepartamento string // DANE department code
+	Telefono           string
+	Correo             string
+	Ambiente           string // 1 production, 2 testing
+}
+
+// FacturaElectronica is an issued invoice rendered as a UBL 2.1 document
+type FacturaElectronica struct {
+	Numero string
+	CUFE   string
+	XML    []byte
+}
+
+// GenerarFacturaElectronica renders an issued invoice as a UBL 2.1 document with its CUFE. The CUFE is
+// the SHA-384 of the invoice number, issuance date and time, amounts, taxes, issuer and client IDs, the
+// technical key of the numbering resolution and the environment, so the same invoice always gets the same code.
+// Signing and sending the document to the tax authority are not done here.
+func (s *DatabaseService) GenerarFacturaElectronica(facID uint, emisor EmisorFactura) (*FacturaElectronica, error) {
+	factura, err := s.BuscarFacturaPorID(facID)
+	if err != nil {
+		return nil, err
+	}
+	if factura.FacID == 0 {
+		return nil, ErrFacturaNoEncontrada
+	}
+	if factura.FacNumero == nil || factura.FacFechaEmision == nil {
+		return nil, ErrFacturaNoEmitida
+	}
+	if emisor.NIT == "" {
+		return nil, ErrEmisorSinNIT
+	}
+
+	cliente, err := s.BuscarClientePorID(factura.CliID)
+	if err != nil {
+		return nil, err
+	}
+	lineas, err := s.ListarLineasFactura(facID)
+	if err != nil {
+		return nil, err
+	}
+	productos, err := s.ListarProductosFactura(facID)
+	if err != nil {
+		return nil, err
+	}
+	impuestos, err := s.ResumenImpuestosFactura(facID)
+	if err != nil {
+		return nil, err
+	}
+	pagos, err := s.ListarPagosFactura(facID)
+	if err != nil {
+		return nil, err
+	}
+	var resolucion *models.ResolucionFacturacion
+	if factura.ResID != nil {
+		if resolucion, err = s.BuscarResolucionFacturacion(*factura.ResID); err != nil {
+			return nil, err
+		}
+	}
+
+	return armarFacturaElectronica(datosFacturaElectronica{
+		factura:    factura,
+		cliente:    cliente,
+		lineas:     lineas,
+		productos:  productos,
+		impuestos:  impuestos,
+		pagos:      pagos,
+		resolucion: resolucion,
+	}, emisor)
+}
+
+// datosFacturaElectronica is everything read from the database to render an issued invoice
+type datosFacturaElectronica struct {
+	factura    *models.FacturaServicio
+	cliente    *models.Client
+	lineas     []models.LineaFactura
+	productos  []models.LineaProductoFactura
+	impuestos  []models.ImpuestoFactura
+	pagos      []models.ClientPayment
+	resolucion *models.ResolucionFacturacion // Nil for invoices issued without a resolution
+}
+
+// armarFacturaElectronica builds the UBL document and the CUFE of an issued invoice
+func armarFacturaElectronica(datos datosFacturaElectronica, emisor EmisorFactura) (*FacturaElectronica, error) {
+	factura, cliente, resolucion := datos.factura, datos.cliente, datos.resolucion
+	lineas, productos, impuestos := datos.lineas, datos.productos, datos.impuestos
+
+	// The document and the CUFE carry the moment the invoice was issued, not the draft date
+	emision := factura.FacFechaEmision.In(time.Local)
+
+	adquirente := adquirenteFactura(cliente)
+	claveTecnica := ""
+	if resolucion != nil && resolucion.ResClaveTecnica != nil {
+		claveTecnica = *resolucion.ResClaveTecnica
+	}
+	cufe := calcularCUFE(*factura.FacNumero, emision, factura.FacSubtotal, factura.FacImpuesto, factura.FacTotal,
+		emisor.NIT, adquirente.documento, claveTecnica, emisor.Ambiente)
+
+	documento := ublInvoice{
+		Xmlns:                ublNamespaceInvoice,
+		XmlnsCac:             ublNamespaceCAC,
+		XmlnsCbc:             ublNamespaceCBC,
+		UBLVersionID:         "UBL 2.1",
+		CustomizationID:      tipoOperacionEstandar,
+		ProfileID:            "DIAN 2.1: Factura Electrónica de Venta",
+		ProfileExecutionID:   emisor.Ambiente,
+		ID:                   *factura.FacNumero,
+		UUID:                 ublUUID{SchemeID: emisor.Ambiente, SchemeName: "CUFE-SHA384", Value: cufe},
+		IssueDate:            emision.Format("2006-01-02"),
+		IssueTime:            emision.Format("15:04:05-07:00"),
+		InvoiceTypeCode:      tipoFacturaVenta,
+		DocumentCurrencyCode: monedaFacturaElectronica,
+		LineCountNumeric:     len(lineas) + len(productos),
+		AccountingSupplierParty: ublAccountParty{
+			AdditionalAccountID: personaJuridica,
+			Party:               partyEmisor(emisor, factura.FacPrefijo),
+		},
+		AccountingCustomerParty: ublAccountParty{
+			AdditionalAccountID: adquirente.tipoPersona(),
+			Party:               partyAdquirente(adquirente, cliente),
+		},
+		PaymentMeans:       mediosPagoFactura(factura, datos.pagos, emision),
+		TaxTotal:           totalImpuestos(factura.FacImpuesto, impuestos),
+		LegalMonetaryTotal: totalesFactura(factura, impuestos),
+	}
+	if resolucion != nil {
+		documento.Notes = append(documento.Notes, fmt.Sprintf(
+			"Resolución de facturación %s del %s al %s, numeración %s%d a %s%d",
+			resolucion.ResNumero, resolucion.ResFechaDesde.Format("2006-01-02"), resolucion.ResFechaHasta.Format("2006-01-02"),
+			resolucion.ResPrefijo, resolucion.ResRangoDesde, resolucion.ResPrefijo, resolucion.ResRangoHasta))
+	}
+	if factura.FacEstado == EstadoFacturaAnulada {
+		documento.Notes = append(documento.Notes, "Factura anulada con nota crédito")
+	}
+
+	for _, linea := range lineas {
+		documento.InvoiceLines = append(documento.InvoiceLines, lineaFacturaUBL(len(documento.InvoiceLines)+1,
+			fmt.Sprintf("SER-%d", linea.SerID), linea.SerNombre, linea.DfsCantidad,
+			linea.TotalLinea, linea.DfsDescuento, linea.IvaPorcentaje, linea.IvaLinea))
+	}
+	for _, producto := range productos {
+		documento.InvoiceLines = append(documento.InvoiceLines, lineaFacturaUBL(len(documento.InvoiceLines)+1,
+			fmt.Sprintf("PROD-%d", producto.ProdID), producto.ProdNombre, producto.DfpCantidad,
+			producto.TotalLinea, producto.DfpDescuento, producto.IvaPorcentaje, producto.IvaLinea))
+	}
+
+	contenido, err := xml.MarshalIndent(documento, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return &FacturaElectronica{
+		Numero: *factura.FacNumero,
+		CUFE:   cufe,
+		XML:    append([]byte(xml.Header), contenido...),
+	}, nil
+}
+
+// calcularCUFE returns the hex SHA-384 of the invoice fields in the order of the DIAN technical
+// annex; the INC and ICA taxes are always zero for the salon
+func calcularCUFE(numero string, emision time.Time, subtotal, iva, total float64,
+	nitEmisor, documentoAdquirente, claveTecnica, ambiente string) string {
+	var cadena strings.Builder
+	cadena.WriteString(numero)
+	cadena.WriteString(emision.Format("2006-01-02"))
+	cadena.WriteString(emision.Format("15:04:05-07:00"))
+	cadena.WriteString(montoUBL(subtotal))
+	cadena.WriteString(tributoIVA + montoUBL(iva))
+	cadena.WriteString(tributoINC + montoUBL(0))
+	cadena.WriteString(tributoICA + montoUBL(0))
+	cadena.WriteString(montoUBL(total))
+	cadena.WriteString(nitEmisor)
+	cadena.WriteString(documentoAdquirente)
+	cadena.WriteString(claveTecnica)
+	cadena.WriteString(ambiente)
+
+	suma := sha512.Sum384([]byte(cadena.String()))
+	return hex.EncodeToString(suma[:])
+}
+
+// DigitoVerificacion computes the DIAN check digit of a tax ID
+func DigitoVerificacion(nit string) string {
+	pesos := []int{3, 7, 13, 17, 19, 23, 29, 37, 41, 43, 47, 53, 59, 67, 71}
+	suma := 0
+	for i, j := len(nit)-1, 0; i >= 0 && j < len(pesos); i, j = i-1, j+1 {
+		digito, err := strconv.Atoi(string(nit[i]))
+		if err != nil {
+			return ""
+		}
+		suma += digito * pesos[j]
+	}
+	residuo := suma % 11
+	if residuo > 1 {
+		return strconv.Itoa(11 - residuo)
+	}
+	return strconv.Itoa(residuo)
+}
+
+// montoUBL formats an amount with two decimals, as UBL amounts and the CUFE expect
+func montoUBL(valor float64) string {
+	return strconv.FormatFloat(redondear(valor), 'f', 2, 64)
+}
+
+func montoCOP(valor float64) ublAmount {
+	return ublAmount{CurrencyID: monedaFacturaElectronica, Value: montoUBL(valor)}
+}
+
+// adquirente is the client as identified on the electronic invoice
+type adquirente struct {
+	tipoDocumento string
+	documento     string
+	nombre        string
+}
+
+func (a adquirente) tipoPersona() string {
+	if a.tipoDocumento == "NIT" {
+		return personaJuridica
+	}
+	return personaNatural
+}
+
+// adquirenteFactura identifies the client by their document, or as the final consumer when they have none
+func adquirenteFactura(cliente *models.Client) adquirente {
+	nombre := strings.TrimSpace(cliente.CliNombre + " " + cliente.CliApellido)
+	if cliente.CliDocumento == nil || cliente.CliTipoDocumento == nil || !EsTipoDocumento(*cliente.CliTipoDocumento) {
+		return adquirente{tipoDocumento: tipoDocumentoConsumidorFinal, documento: documentoConsumidorFinal, nombre: nombreConsumidorFinal}
+	}
+	if nombre == "" {
+		nombre = nombreConsumidorFinal
+	}
+	return adquirente{tipoDocumento: *cliente.CliTipoDocumento, documento: *cliente.CliDocumento, nombre: nombre}
+}
+
+func identificacionUBL(tipoDocumento, documento string) ublCompanyID {
+	id := ublCompanyID{
+		SchemeAgencyID: agenciaDIAN,
+		SchemeName:     tiposDocumentoDIAN[tipoDocumento],
+		Value:          documento,
+	}
+	if tipoDocumento == "NIT" {
+		id.SchemeID = DigitoVerificacion(documento)
+	}
+	return id
+}
+
+func partyEmisor(emisor EmisorFactura, prefijo *string) ublParty {
+	id := identificacionUBL("NIT", emisor.NIT)
+	direccion := &ublAddress{
+		ID:                   emisor.CodigoCiudad,
+		CityName:             emisor.Ciudad,
+		CountrySubentity:     emisor.Departamento,
+		CountrySubentityCode: emisor.CodigoDepartamento,
+		Country:              ublCountry{IdentificationCode: "CO", Name: "Colombia"},
+	}
+	if emisor.Direccion != "" {
+		direccion.AddressLine = &ublAddressLine{Line: emisor.Direccion}
+	}
+	party := ublParty{
+		PartyName:        &ublPartyName{Name: emisor.RazonSocial},
+		PhysicalLocation: &ublLocation{Address: direccion},
+		PartyTaxScheme: ublPartyTaxScheme{
+			RegistrationName: emisor.RazonSocial,
+			CompanyID:        id,
+			TaxLevelCode:     responsabilidadNoAplica,
+			TaxScheme:        ublTaxScheme{ID: tributoIVA, Name: "IVA"},
+		},
+		PartyLegalEntity: ublPartyLegalEntity{RegistrationName: emisor.RazonSocial, CompanyID: id},
+	}
+	if prefijo != nil && *prefijo != "" {
+		party.PartyLegalEntity.CorporateRegistrationScheme = &ublRegistrationScheme{ID: *prefijo}
+	}
+	if emisor.Telefono != "" || emisor.Correo != "" {
+		party.Contact = &ublContact{Telephone: emisor.Telefono, ElectronicMail: emisor.Correo}
+	}
+	return party
+}
+
+func partyAdquirente(a adquirente, cliente *models.Client) ublParty {
+	id := identificacionUBL(a.tipoDocumento, a.documento)
+	esquema := ublTaxScheme{ID: "ZZ", Name: "No aplica"}
+	if a.tipoDocumento == "NIT" {
+		esquema = ublTaxScheme{ID: tributoIVA, Name: "IVA"}
+	}
+	party := ublParty{
+		PartyIdentification: &ublPartyIdentification{ID: id},
+		PartyTaxScheme: ublPartyTaxScheme{
+			RegistrationName: a.nombre,
+			CompanyID:        id,
+			TaxLevelCode:     responsabilidadNoAplica,
+			TaxScheme:        esquema,
+		},
+		PartyLegalEntity: ublPartyLegalEntity{RegistrationName: a.nombre, CompanyID: id},
+	}
+	if a.documento != documentoConsumidorFinal && (cliente.CliTelefono != "" || cliente.CliCorreo != "") {
+		party.Contact = &ublContact{Telephone: cliente.CliTelefono, ElectronicMail: cliente.CliCorreo}
+	}
+	return party
+}
+
+// mediosPagoFactura lists one payment means per method the client used; a paid invoice is cash
+// sale (contado), otherwise it is a credit sale due on the issue date
+func mediosPagoFactura(factura *models.FacturaServicio, pagos []models.ClientPayment, emision time.Time) []ublPaymentMeans {
+	forma := formaPagoCredito
+	vencimiento := emision.Format("2006-01-02")
+	if factura.FacEstadoPago == EstadoPagoPagada {
+		forma = formaPagoContado
+		vencimiento = ""
+	}
+
+	var medios []ublPaymentMeans
+	vistos := map[string]bool{}
+	for _, pago := range pagos {
+		codigo, ok := mediosPagoDIAN[pago.PagCliMetodo]
+		if !ok {
+			codigo = medioPagoOtro
+		}
+		if vistos[codigo] {
+			continue
+		}
+		vistos[codigo] = true
+		medios = append(medios, ublPaymentMeans{ID: forma, PaymentMeansCode: codigo, PaymentDueDate: vencimiento})
+	}
+	if len(medios) == 0 {
+		medios = append(medios, ublPaymentMeans{ID: forma, PaymentMeansCode: medioPagoOtro, PaymentDueDate: vencimiento})
+	}
+	return medios
+}
+
+func totalImpuestos(total float64, impuestos []models.ImpuestoFactura) ublTaxTotal {
+	tax := ublTaxTotal{TaxAmount: montoCOP(total)}
+	for _, impuesto := range impuestos {
+		tax.TaxSubtotals = append(tax.TaxSubtotals, subtotalIVA(impuesto.Base, impuesto.Impuesto, impuesto.IvaPorcentaje))
+	}
+	return tax
+}
+
+func subtotalIVA(base, impuesto, porcentaje float64) ublTaxSubtotal {
+	return ublTaxSubtotal{
+		TaxableAmount: montoCOP(base),
+		TaxAmount:     montoCOP(impuesto),
+		TaxCategory: ublTaxCategory{
+			Percent:   montoUBL(porcentaje),
+			TaxScheme: ublTaxScheme{ID: tributoIVA, Name: "IVA"},
+		},
+	}
+}
+
+// totalesFactura returns the monetary totals; the taxable amount only counts lines with a VAT rate
+func totalesFactura(factura *models.FacturaServicio, impuestos []models.ImpuestoFactura) ublMonetaryTotal {
+	gravado := 0.0
+	for _, impuesto := range impuestos {
+		if impuesto.IvaPorcentaje > 0 {
+			gravado += impuesto.Base
+		}
+	}
+	return ublMonetaryTotal{
+		LineExtensionAmount: montoCOP(factura.FacSubtotal),
+		TaxExclusiveAmount:  montoCOP(gravado),
+		TaxInclusiveAmount:  montoCOP(factura.FacTotal),
+		PayableAmount:       montoCOP(factura.FacTotal),
+	}
+}
+
+// lineaFacturaUBL converts an invoice line. Prices include VAT, so the line amount is the total
+// minus its VAT and the discount is shown without VAT as an allowance on the gross amount.
+func lineaFacturaUBL(numero int, codigo, descripcion string, cantidad int, total, descuento, porcentaje, iva float64) ublInvoiceLine {
+	base := redondear(total - iva)
+	descuentoBase := redondear(descuento * 100 / (100 + porcentaje))
+	bruto := base + descuentoBase
+
+	linea := ublInvoiceLine{
+		ID:                  strconv.Itoa(numero),
+		InvoicedQuantity:    ublQuantity{UnitCode: unidadMedidaUnidad, Value: strconv.Itoa(cantidad)},
+		LineExtensionAmount: montoCOP(base),
+		TaxTotal: ublTaxTotal{
+			TaxAmount:    montoCOP(iva),
+			TaxSubtotals: []ublTaxSubtotal{subtotalIVA(base, iva, porcentaje)},
+		},
+		Item: ublItem{
+			Description:               descripcion,
+			SellersItemIdentification: ublItemIdentification{ID: codigo},
+		},
+		Price: ublPrice{
+			PriceAmount:  montoCOP(bruto / float64(cantidad)),
+			BaseQuantity: ublQuantity{UnitCode: unidadMedidaUnidad, Value: "1"},
+		},
+	}
+	if descuentoBase > 0 {
+		linea.AllowanceCharge = &ublAllowanceCharge{
+			ID:                    "1",
+			ChargeIndicator:       false,
+			AllowanceChargeReason: "Descuento",
+			Amount:                montoCOP(descuentoBase),
+			BaseAmount:            montoCOP(bruto),
+		}
+	}
+	return linea
+}
+
+// UBL 2.1 Invoice elements, declared in the order the schema requires
+
+type ublInvoice struct {
+	XMLName  xml.Name `xml:"Invoice"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	XmlnsCac string   `xml:"xmlns:cac,attr"`
+	XmlnsCbc string   `xml:"xmlns:cbc,attr"`
+
+	UBLVersionID            string            `xml:"cbc:UBLVersionID"`
+	CustomizationID         string            `xml:"cbc:CustomizationID"`
+	ProfileID               string            `xml:"cbc:ProfileID"`
+	ProfileExecutionID      string            `xml:"cbc:ProfileExecutionID"`
+	ID                      string            `xml:"cbc:ID"`
+	UUID                    ublUUID           `xml:"cbc:UUID"`
+	IssueDate               string            `xml:"cbc:IssueDate"`
+	IssueTime               string            `xml:"cbc:IssueTime"`
+	InvoiceTypeCode         string            `xml:"cbc:InvoiceTypeCode"`
+	Notes                   []string          `xml:"cbc:Note"`
+	DocumentCurrencyCode    string            `xml:"cbc:DocumentCurrencyCode"`
+	LineCountNumeric        int               `xml:"cbc:LineCountNumeric"`
+	AccountingSupplierParty ublAccountParty   `xml:"cac:AccountingSupplierParty"`
+	AccountingCustomerParty ublAccountParty   `xml:"cac:AccountingCustomerParty"`
+	PaymentMeans            []ublPaymentMeans `xml:"cac:PaymentMeans"`
+	TaxTotal                ublTaxTotal       `xml:"cac:TaxTotal"`
+	LegalMonetaryTotal      ublMonetaryTotal  `xml:"cac:LegalMonetaryTotal"`
+	InvoiceLines            []ublInvoiceLine  `xml:"cac:InvoiceLine"`
+}
+
+type ublUUID struct {
+	SchemeID   string `xml:"schemeID,attr"`
+	SchemeName string `xml:"schemeName,attr"`
+	Value      string `xml:",chardata"`
+}
+
+type ublAmount struct {
+	CurrencyID string `xml:"currencyID,attr"`
+	Value      string `xml:",chardata"`
+}
+
+type ublQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ublCompanyID struct {
+	SchemeAgencyID string `xml:"schemeAgencyID,attr,omitempty"`
+	SchemeID       string `xml:"schemeID,attr,omitempty"` // Check digit of a NIT
+	SchemeName     string `xml:"schemeName,attr,omitempty"`
+	Value          string `xml:",chardata"`
+}
+
+type ublAccountParty struct {
+	AdditionalAccountID string   `xml:"cbc:AdditionalAccountID"`
+	Party               ublParty `xml:"cac:Party"`
+}
+
+type ublParty struct {
+	PartyIdentification *ublPartyIdentification `xml:"cac:PartyIdentification,omitempty"`
+	PartyName           *ublPartyName           `xml:"cac:PartyName,omitempty"`
+	PhysicalLocation    *ublLocation            `xml:"cac:PhysicalLocation,omitempty"`
+	PartyTaxScheme      ublPartyTaxScheme       `xml:"cac:PartyTaxScheme"`
+	PartyLegalEntity    ublPartyLegalEntity     `xml:"cac:PartyLegalEntity"`
+	Contact             *ublContact             `xml:"cac:Contact,omitempty"`
+}
+
+type ublPartyIdentification struct {
+	ID ublCompanyID `xml:"cbc:ID"`
+}
+
+type ublPartyName struct {
+	Name string `xml:"cbc:Name"`
+}
+
+type ublLocation struct {
+	Address *ublAddress `xml:"cac:Address"`
+}
+
+type ublAddress struct {
+	ID                   string          `xml:"cbc:ID,omitempty"`
+	CityName             string          `xml:"cbc:CityName,omitempty"`
+	CountrySubentity     string          `xml:"cbc:CountrySubentity,omitempty"`
+	CountrySubentityCode string          `xml:"cbc:CountrySubentityCode,omitempty"`
+	AddressLine          *ublAddressLine `xml:"cac:AddressLine,omitempty"`
+	Country              ublCountry      `xml:"cac:Country"`
+}
+
+type ublAddressLine struct {
+	Line string `xml:"cbc:Line"`
+}
+
+type ublCountry struct {
+	IdentificationCode string `xml:"cbc:IdentificationCode"`
+	Name               string `xml:"cbc:Name"`
+}
+
+type ublPartyTaxScheme struct {
+	RegistrationName string       `xml:"cbc:RegistrationName"`
+	CompanyID        ublCompanyID `xml:"cbc:CompanyID"`
+	TaxLevelCode     string       `xml:"cbc:TaxLevelCode"`
+	TaxScheme        ublTaxScheme `xml:"cac:TaxScheme"`
+}
+
+type ublTaxScheme struct {
+	ID   string `xml:"cbc:ID"`
+	Name string `xml:"cbc:Name"`
+}
+
+type ublPartyLegalEntity struct {
+	RegistrationName            string                 `xml:"cbc:RegistrationName"`
+	CompanyID                   ublCompanyID           `xml:"cbc:CompanyID"`
+	CorporateRegistrationScheme *ublRegistrationScheme `xml:"cac:CorporateRegistrationScheme,omitempty"`
+}
+
+type ublRegistrationScheme struct {
+	ID string `xml:"cbc:ID"` // Invoice prefix
+}
+
+type ublContact struct {
+	Telephone      string `xml:"cbc:Telephone,omitempty"`
+	ElectronicMail string `xml:"cbc:ElectronicMail,omitempty"`
+}
+
+type ublPaymentMeans struct {
+	ID               string `xml:"cbc:ID"`
+	PaymentMeansCode string `xml:"cbc:PaymentMeansCode"`
+	PaymentDueDate   string `xml:"cbc:PaymentDueDate,omitempty"`
+}
+
+type ublTaxTotal struct {
+	TaxAmount    ublAmount        `xml:"cbc:TaxAmount"`
+	TaxSubtotals []ublTaxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+type ublTaxSubtotal struct {
+	TaxableAmount ublAmount      `xml:"cbc:TaxableAmount"`
+	TaxAmount     ublAmount      `xml:"cbc:TaxAmount"`
+	TaxCategory   ublTaxCategory `xml:"cac:TaxCategory"`
+}
+
+type ublTaxCategory struct {
+	Percent   string       `xml:"cbc:Percent"`
+	TaxScheme ublTaxScheme `xml:"cac:TaxScheme"`
+}
+
+type ublMonetaryTotal struct {
+	LineExtensionAmount ublAmount `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount  ublAmount `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount  ublAmount `xml:"cbc:TaxInclusiveAmount"`
+	PayableAmount       ublAmount `xml:"cbc:PayableAmount"`
+}
+
+type ublInvoiceLine struct {
+	ID                  string              `xml:"cbc:ID"`
+	InvoicedQuantity    ublQuantity         `xml:"cbc:InvoicedQuantity"`
+	LineExtensionAmount ublAmount           `xml:"cbc:LineExtensionAmount"`
+	AllowanceCharge     *ublAllowanceCharge `xml:"cac:AllowanceCharge,omitempty"`
+	TaxTotal            ublTaxTotal         `xml:"cac:TaxTotal"`
+	Item                ublItem             `xml:"cac:Item"`
+	Price               ublPrice            `xml:"cac:Price"`
+}
+
+type ublAllowanceCharge struct {
+	ID                    string    `xml:"cbc:ID"`
+	ChargeIndicator       bool      `xml:"cbc:ChargeIndicator"`
+	AllowanceChargeReason string    `xml:"cbc:AllowanceChargeReason,omitempty"`
+	Amount                ublAmount `xml:"cbc:Amount"`
+	BaseAmount            ublAmount `xml:"cbc:BaseAmount"`
+}
+
+type ublItem struct {
+	Description               string                `xml:"cbc:Description"`
+	SellersItemIdentification ublItemIdentification `xml:"cac:SellersItemIdentification"`
+}
+
+type ublItemIdentification struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type ublPrice struct {
+	PriceAmount  ublAmount   `xml:"cbc:PriceAmount"`
+	BaseQuantity ublQuantity `xml:"cbc:BaseQuantity"`
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"salon/models"
+)
+
+// esquemaFacturaPrueba is a hand-written subset of the UBL 2.1 Invoice schema covering the elements
+// the exporter emits, not the official OASIS schema; see testdata/ubl21-subset/README.md
+var esquemaFacturaPrueba = filepath.Join("testdata", "ubl21-subset", "maindoc", "UBL-Invoice-2.1.xsd")
+
+func TestCalcularCUFE(t *testing.T) {
+	// Example of the DIAN technical annex for the electronic sales invoice (CUFE-SHA384)
+	emision := time.Date(2019, 1, 16, 10, 53, 10, 0, time.FixedZone("COT", -5*60*60))
+	cufe := calcularCUFE("323200000129", emision, 1500000, 285000, 1785000,
+		"700085371", "800199436", "693ff6f2a553c3646a063436fd4dd9ded0311471", "1")
+
+	esperado := "8bb918b19ba22a694f1da11c643b5e9de39adf60311cf179179e9b33381030bcd4c3c3f156c506ed5908f9276f5bd9b4"
+	if cufe != esperado {
+		t.Fatalf("calcularCUFE() = %s, want %s", cufe, esperado)
+	}
+}
+
+func TestArmarFacturaElectronicaCumpleEsquemaDePrueba(t *testing.T) {
+	datos, emisor := facturaElectronicaDePrueba()
+
+	resultado, err := armarFacturaElectronica(datos, emisor)
+	if err != nil {
+		t.Fatalf("armarFacturaElectronica() error = %v", err)
+	}
+
+	if err := validarContraEsquema(t, resultado.XML); err != nil {
+		t.Fatalf("the invoice does not match the test schema:\n%v\n%s", err, resultado.XML)
+	}
+
+	emision := datos.factura.FacFechaEmision.In(time.Local)
+	esperado := calcularCUFE(*datos.factura.FacNumero, emision, datos.factura.FacSubtotal, datos.factura.FacImpuesto,
+		datos.factura.FacTotal, emisor.NIT, *datos.cliente.CliDocumento, *datos.resolucion.ResClaveTecnica, emisor.Ambiente)
+	if resultado.CUFE != esperado {
+		t.Errorf("CUFE = %s, want %s", resultado.CUFE, esperado)
+	}
+
+	var documento struct {
+		ID        string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID"`
+		UUID      string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 UUID"`
+		IssueDate string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 IssueDate"`
+		IssueTime string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 IssueTime"`
+		Lineas    []struct {
+			ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID"`
+		} `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoiceLine"`
+	}
+	if err := xml.Unmarshal(resultado.XML, &documento); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v", err)
+	}
+	if documento.ID != "SETP990000001" || resultado.Numero != documento.ID {
+		t.Errorf("ID = %q, Numero = %q, want SETP990000001", documento.ID, resultado.Numero)
+	}
+	if documento.UUID != resultado.CUFE {
+		t.Errorf("UUID = %s, want the CUFE %s", documento.UUID, resultado.CUFE)
+	}
+	// The issuance moment is used, not the date and time typed in the draft
+	if documento.IssueDate != emision.Format("2006-01-02") || documento.IssueTime != emision.Format("15:04:05-07:00") {
+		t.Errorf("IssueDate/IssueTime = %s %s, want %s", documento.IssueDate, documento.IssueTime, emision.Format(time.RFC3339))
+	}
+	if len(documento.Lineas) != 2 {
+		t.Errorf("InvoiceLine count = %d, want 2", len(documento.Lineas))
+	}
+}
+
+func TestEsquemaDePruebaRechazaDocumentoIncompleto(t *testing.T) {
+	datos, emisor := facturaElectronicaDePrueba()
+	resultado, err := armarFacturaElectronica(datos, emisor)
+	if err != nil {
+		t.Fatalf("armarFacturaElectronica() error = %v", err)
+	}
+
+	// IssueDate is mandatory, so the schema has to reject the document without it
+	sinFecha := regexp.MustCompile(`\s*<cbc:IssueDate>[^<]*</cbc:IssueDate>`).ReplaceAll(resultado.XML, nil)
+	if bytes.Equal(sinFecha, resultado.XML) {
+		t.Fatal("the generated document has no cbc:IssueDate")
+	}
+	if err := validarContraEsquema(t, sinFecha); err == nil {
+		t.Fatal("a document without cbc:IssueDate matched the test schema")
+	}
+}
+
+// validarContraEsquema validates an XML document against the test schema with xmllint. Without
+// xmllint the test is skipped, unless EINVOICE_REQUIRE_XMLLINT is set so CI cannot skip it silently.
+func validarContraEsquema(t *testing.T, documento []byte) error {
+	t.Helper()
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		if os.Getenv("EINVOICE_REQUIRE_XMLLINT") != "" {
+			t.Fatalf("xmllint is required by EINVOICE_REQUIRE_XMLLINT: %v", err)
+		}
+		t.Skip("xmllint is not installed, the schema validation needs it")
+	}
+
+	archivo := filepath.Join(t.TempDir(), "factura.xml")
+	if err := os.WriteFile(archivo, documento, 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	salida, err := exec.Command(xmllint, "--noout", "--nonet", "--schema", esquemaFacturaPrueba, archivo).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, salida)
+	}
+	return nil
+}
+
+// facturaElectronicaDePrueba is an issued invoice with a discounted service, two units of a product
+// and a cash payment, billed to a company. Prices include 19% VAT.
+func facturaElectronicaDePrueba() (datosFacturaElectronica, EmisorFactura) {
+	texto := func(valor string) *string { return &valor }
+	entero := func(valor uint) *uint { return &valor }
+	consecutivo := 1
+	emitida := time.Date(2026, 10, 17, 10, 30, 0, 0, time.Local)
+
+	factura := &models.FacturaServicio{
+		FacID:           1,
+		FacTotal:        68800,
+		FacFecha:        time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local), // Draft date, earlier than the issue
+		FacHora:         "09:00:00",
+		CliID:           7,
+		FacPagado:       68800,
+		FacEstadoPago:   EstadoPagoPagada,
+		FacEstado:       EstadoFacturaEmitida,
+		FacFechaEmision: &emitida,
+		FacSubtotal:     57815.13,
+		FacImpuesto:     10984.87,
+		ResID:           entero(3),
+		FacPrefijo:      texto("SETP"),
+		FacConsecutivo:  &consecutivo,
+		FacNumero:       texto("SETP990000001"),
+	}
+
+	datos := datosFacturaElectronica{
+		factura: factura,
+		cliente: &models.Client{
+			CliID:            7,
+			CliNombre:        "Distribuciones",
+			CliApellido:      "Belleza S.A.S.",
+			CliTelefono:      "6015550101",
+			CliCorreo:        "compras@belleza.example",
+			CliTipoDocumento: texto("NIT"),
+			CliDocumento:     texto("900373115"),
+		},
+		lineas: []models.LineaFactura{{
+			DfsID:             1,
+			FacID:             1,
+			SerID:             2,
+			SerNombre:         "Corte y peinado",
+			DfsCantidad:       1,
+			DfsPrecioUnitario: 50000,
+			DfsDescuento:      5000,
+			TotalLinea:        45000,
+			IvaPorcentaje:     19,
+			IvaLinea:          7184.87,
+		}},
+		productos: []models.LineaProductoFactura{{
+			FacID:             1,
+			ProdID:            4,
+			ProdNombre:        "Shampoo reparador 400 ml",
+			DfpCantidad:       2,
+			DfpPrecioUnitario: 11900,
+			TotalLinea:        23800,
+			IvaPorcentaje:     19,
+			IvaLinea:          3800,
+		}},
+		impuestos: []models.ImpuestoFactura{{IvaPorcentaje: 19, Base: 57815.13, Impuesto: 10984.87, Total: 68800}},
+		pagos: []models.ClientPayment{{
+			PagCliID:       1,
+			FacID:          1,
+			PagCliFecha:    emitida,
+			PagCliMetodo:   MetodoPagoEfectivo,
+			PagCliRecibido: 70000,
+			PagCliMonto:    68800,
+			PagCliCambio:   1200,
+		}},
+		resolucion: &models.ResolucionFacturacion{
+			ResID:           3,
+			ResNumero:       "18760000001",
+			ResPrefijo:      "SETP",
+			ResRangoDesde:   990000000,
+			ResRangoHasta:   995000000,
+			ResFechaDesde:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local),
+			ResFechaHasta:   time.Date(2027, 12, 31, 0, 0, 0, 0, time.Local),
+			ResActiva:       true,
+			ResClaveTecnica: texto("fc8eac422eba16e22ffd8c6f94b3f40a6e38162c"),
+		},
+	}
+
+	emisor := EmisorFactura{
+		NIT:                "901234567",
+		RazonSocial:        "Beba Coiffure S.A.S.",
+		Direccion:          "Calle 10 # 20-30",
+		Ciudad:             "Bogotá, D.C.",
+		CodigoCiudad:       "11001",
+		Departamento:       "Bogotá",
+		CodigoDepartamento: "11",
+		Telefono:           "6015550100",
+		Correo:             "facturacion@beba.example",
+		Ambiente:           "2",
+	}
+	return datos, emisor
+}
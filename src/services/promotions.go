@@ -0,0 +1,479 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"salon/models"
+
+	"gorm.io/gorm"
+)
+
+// Promotion discount types (PROMOCION.pro_tipo_descuento)
+const (
+	TipoDescuentoPorcentaje = "Porcentaje"
+	TipoDescuentoValorFijo  = "Valor Fijo"
+)
+
+// Reasons a promotion does not apply to an invoice. limite_usos and limite_cliente are also
+// returned by sp_insertar_detalle_factura_promocion when a limit is reached concurrently.
+const (
+	MotivoPromocionNoEncontrada       = "no_encontrada"
+	MotivoPromocionNoVigente          = "no_vigente"
+	MotivoPromocionLimiteUsos         = "limite_usos"
+	MotivoPromocionLimiteCliente      = "limite_cliente"
+	MotivoPromocionServiciosFaltantes = "servicios_faltantes"
+	MotivoPromocionMontoMinimo        = "monto_minimo"
+)
+
+// ErrServicioNoEncontrado is returned when a draft invoice references an unknown service
+var ErrServicioNoEncontrado = errors.New("servicio no encontrado")
+
+// ErrEmpleadoNoEncontrado is returned when an invoice line references an unknown employee
+var ErrEmpleadoNoEncontrado = errors.New("empleado no encontrado")
+
+// ServicioFacturaParams is a service line of an invoice. The same service may appear on several
+// lines; Cantidad is the number of times it was performed and EmpID who performed it (optional).
+type ServicioFacturaParams struct {
+	SerID    uint
+	Cantidad int
+	EmpID    *uint
+}
+
+// ServiciosUnitarios turns a list of service IDs into one line per ID with quantity 1
+func ServiciosUnitarios(serIDs []uint) []ServicioFacturaParams {
+	servicios := make([]ServicioFacturaParams, 0, len(serIDs))
+	for _, serID := range serIDs {
+		servicios = append(servicios, ServicioFacturaParams{SerID: serID, Cantidad: 1})
+	}
+	return servicios
+}
+
+// PromocionNoAplicableError is returned when a coupon does not apply to the invoice, or when a
+// promotion reached one of its usage limits while the invoice was being created
+type PromocionNoAplicableError struct {
+	ProID  uint
+	Motivo string
+}
+
+func (e *PromocionNoAplicableError) Error() string {
+	return fmt.Sprintf("la promoción %d no aplica: %s", e.ProID, e.Motivo)
+}
+
+// LineaEvaluada is a draft invoice line with the discount chosen by the rules engine
+type LineaEvaluada struct {
+	SerID             uint    `json:"ser_id"`
+	SerNombre         string  `json:"ser_nombre"`
+	DfsCantidad       int     `json:"dfs_cantidad"`
+	DfsPrecioUnitario float64 `json:"dfs_precio_unitario"`
+	DfsDescuento      float64 `json:"dfs_descuento"`
+	ProID             *uint   `json:"pro_id"`
+	EmpID             *uint   `json:"emp_id"`
+
+	registrada bool // Already saved on the invoice; its discount is not evaluated again
+}
+
+// importe is the line amount before discounts; promotion discounts are taken from it
+func (l LineaEvaluada) importe() float64 {
+	return l.DfsPrecioUnitario * float64(l.DfsCantidad)
+}
+
+// ResultadoCupon explains whether a coupon code applies to a draft invoice
+type ResultadoCupon struct {
+	Promocion *models.PromocionConReglas `json:"promotion"`
+	Aplicable bool                       `json:"valid"`
+	Motivo    string                     `json:"reason,omitempty"`
+	Descuento float64                    `json:"discount"`
+}
+
+// EvaluacionPromociones is the outcome of applying the promotion rules to a draft invoice
+type EvaluacionPromociones struct {
+	Subtotal  float64         `json:"subtotal"`
+	Descuento float64         `json:"discount"`
+	Total     float64         `json:"total"`
+	Lineas    []LineaEvaluada `json:"lines"`
+	Cupon     *ResultadoCupon `json:"coupon,omitempty"`
+}
+
+// EvaluarPromociones applies the promotion rules to a draft invoice without saving anything.
+// The coupon, when given, is applied first; automatic promotions then take the remaining lines,
+// largest discount first, and a line never receives more than one promotion.
+func (s *DatabaseService) EvaluarPromociones(cliID uint, fecha string, serIDs []uint, codigo string) (*EvaluacionPromociones, error) {
+	return evaluarPromociones(s.DB, cliID, fecha, ServiciosUnitarios(serIDs), codigo)
+}
+
+// CrearFacturaServicios creates an invoice for the given services and retail products applying the
+// promotion rules to the service lines; the invoice, its lines, the promotion usage counters and the
+// stock movements of the products sold are saved in a single transaction. The invoice is issued
+// unless borrador is set, in which case it stays editable until EmitirFactura.
+func (s *DatabaseService) CrearFacturaServicios(cliID uint, fecha, hora string, servicios []ServicioFacturaParams, productos []ProductoFacturaParams, codigo, usuario string, borrador bool) (*models.FacturaServicio, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+	fail := func(err error) (*models.FacturaServicio, error) {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := verificarStockProductos(tx, productos); err != nil {
+		return fail(err)
+	}
+
+	evaluacion, err := evaluarPromociones(tx, cliID, fecha, servicios, codigo)
+	if err != nil {
+		return fail(err)
+	}
+	if cupon := evaluacion.Cupon; cupon != nil && !cupon.Aplicable {
+		var proID uint
+		if cupon.Promocion != nil {
+			proID = cupon.Promocion.ProID
+		}
+		return fail(&PromocionNoAplicableError{ProID: proID, Motivo: cupon.Motivo})
+	}
+
+	var factura models.FacturaServicio
+	if err := tx.Raw("CALL sp_insertar_factura_cliente(?, ?, ?)", fecha, hora, cliID).Scan(&factura).Error; err != nil {
+		return fail(err)
+	}
+
+	if err := insertarLineasFactura(tx, factura.FacID, evaluacion); err != nil {
+		return fail(err)
+	}
+	for _, producto := range productos {
+		err := tx.Exec("CALL sp_insertar_detalle_factura_producto(?, ?, ?, ?, ?)",
+			factura.FacID, producto.ProdID, producto.Cantidad, producto.Descuento, usuario).Error
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if !borrador {
+		if err := emitirFactura(tx, &factura); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.BuscarFacturaPorID(factura.FacID)
+}
+
+// insertarLineasFactura saves the evaluated lines and recalculates the invoice total. The stored
+// procedure locks each promotion and checks its limits again, so a limit reached by a concurrent
+// invoice is reported as PromocionNoAplicableError.
+func insertarLineasFactura(tx *gorm.DB, facID uint, evaluacion *EvaluacionPromociones) error {
+	for _, linea := range evaluacion.Lineas {
+		var resultado struct {
+			Aplicada bool    `gorm:"column:aplicada"`
+			Motivo   *string `gorm:"column:motivo"`
+		}
+		err := tx.Raw("CALL sp_insertar_detalle_factura_promocion(?, ?, ?, ?, ?, ?)",
+			facID, linea.SerID, linea.DfsCantidad, linea.EmpID, linea.ProID, linea.DfsDescuento).Scan(&resultado).Error
+		if err != nil {
+			return err
+		}
+		if !resultado.Aplicada {
+			noAplicable := &PromocionNoAplicableError{ProID: *linea.ProID}
+			if resultado.Motivo != nil {
+				noAplicable.Motivo = *resultado.Motivo
+			}
+			return noAplicable
+		}
+	}
+
+	return tx.Exec("CALL sp_recalcular_total_factura(?)", facID).Error
+}
+
+func evaluarPromociones(db *gorm.DB, cliID uint, fecha string, servicios []ServicioFacturaParams, codigo string) (*EvaluacionPromociones, error) {
+	lineas, err := evaluarServicios(db, servicios)
+	if err != nil {
+		return nil, err
+	}
+	evaluacion := &EvaluacionPromociones{Lineas: lineas}
+	for _, linea := range lineas {
+		evaluacion.Subtotal += linea.importe()
+	}
+
+	if codigo != "" {
+		var promociones []models.PromocionConReglas
+		if err := db.Raw("CALL sp_buscar_promocion_por_codigo(?, ?)", codigo, cliID).Scan(&promociones).Error; err != nil {
+			return nil, err
+		}
+
+		cupon := &ResultadoCupon{Motivo: MotivoPromocionNoEncontrada}
+		if len(promociones) > 0 {
+			cupon.Promocion = &promociones[0]
+			cupon.Motivo = evaluarReglasPromocion(cupon.Promocion, fecha, evaluacion)
+		}
+		if cupon.Motivo == "" {
+			descuentos := calcularDescuentosPromocion(&cupon.Promocion.Promotion, evaluacion.Lineas)
+			cupon.Aplicable = true
+			cupon.Descuento = aplicarDescuentos(evaluacion, cupon.Promocion.ProID, descuentos)
+		}
+		evaluacion.Cupon = cupon
+	}
+
+	var vigentes []models.PromocionConReglas
+	if err := db.Raw("CALL sp_listar_promociones_vigentes(?, ?)", fecha, cliID).Scan(&vigentes).Error; err != nil {
+		return nil, err
+	}
+	aplicarPromocionesAutomaticas(vigentes, fecha, evaluacion)
+
+	evaluacion.Total = redondear(evaluacion.Subtotal - evaluacion.Descuento)
+	return evaluacion, nil
+}
+
+// evaluarServicios looks up the price of each service line and checks its employee
+func evaluarServicios(db *gorm.DB, servicios []ServicioFacturaParams) ([]LineaEvaluada, error) {
+	lineas := make([]LineaEvaluada, 0, len(servicios))
+	empleados := make(map[uint]bool)
+	for _, linea := range servicios {
+		var servicio models.Service
+		if err := db.Raw("CALL sp_buscar_servicio_por_id(?)", linea.SerID).Scan(&servicio).Error; err != nil {
+			return nil, err
+		}
+		if servicio.SerID == 0 {
+			return nil, fmt.Errorf("%w: %d", ErrServicioNoEncontrado, linea.SerID)
+		}
+		if linea.EmpID != nil && !empleados[*linea.EmpID] {
+			var empleado models.Employee
+			if err := db.Raw("CALL BuscarEmpleadoPorID(?)", *linea.EmpID).Scan(&empleado).Error; err != nil {
+				return nil, err
+			}
+			if empleado.EmpID == 0 {
+				return nil, fmt.Errorf("%w: %d", ErrEmpleadoNoEncontrado, *linea.EmpID)
+			}
+			empleados[*linea.EmpID] = true
+		}
+
+		cantidad := linea.Cantidad
+		if cantidad <= 0 {
+			cantidad = 1
+		}
+		lineas = append(lineas, LineaEvaluada{
+			SerID:             servicio.SerID,
+			SerNombre:         servicio.SerNombre,
+			DfsCantidad:       cantidad,
+			DfsPrecioUnitario: servicio.SerPrecioUnitario,
+			EmpID:             linea.EmpID,
+		})
+	}
+	return lineas, nil
+}
+
+// aplicarPromocionesAutomaticas gives the free lines the automatic promotions in force, largest
+// discount first. Each promotion is applied once per invoice: a repeated service only gets it on
+// its first free line, and a promotion already on the invoice is not applied again.
+func aplicarPromocionesAutomaticas(vigentes []models.PromocionConReglas, fecha string, evaluacion *EvaluacionPromociones) {
+	enFactura := make(map[uint]bool)
+	for _, linea := range evaluacion.Lineas {
+		if linea.ProID != nil {
+			enFactura[*linea.ProID] = true
+		}
+	}
+
+	type candidata struct {
+		proID      uint
+		descuentos map[int]float64
+		total      float64
+	}
+	candidatas := make([]candidata, 0, len(vigentes))
+	for i := range vigentes {
+		if enFactura[vigentes[i].ProID] || evaluarReglasPromocion(&vigentes[i], fecha, evaluacion) != "" {
+			continue
+		}
+		descuentos := calcularDescuentosPromocion(&vigentes[i].Promotion, evaluacion.Lineas)
+		var total float64
+		for _, descuento := range descuentos {
+			total += descuento
+		}
+		if total > 0 {
+			candidatas = append(candidatas, candidata{proID: vigentes[i].ProID, descuentos: descuentos, total: total})
+		}
+	}
+	sort.SliceStable(candidatas, func(i, j int) bool { return candidatas[i].total > candidatas[j].total })
+
+	for _, c := range candidatas {
+		libres := true
+		for i := range c.descuentos {
+			if evaluacion.Lineas[i].ProID != nil {
+				libres = false
+				break
+			}
+		}
+		if libres {
+			aplicarDescuentos(evaluacion, c.proID, c.descuentos)
+		}
+	}
+}
+
+// agregarServicioFactura adds a service line to a draft invoice with the same promotion rules as a
+// new invoice; the lines already on the invoice keep their discounts. If a concurrent invoice used
+// up the chosen promotion, the line is saved without it.
+func agregarServicioFactura(tx *gorm.DB, facID uint, servicio ServicioFacturaParams) error {
+	var factura models.FacturaServicio
+	if err := tx.Raw("CALL sp_buscar_factura_por_id(?)", facID).Scan(&factura).Error; err != nil {
+		return err
+	}
+	var registradas []models.LineaFactura
+	if err := tx.Raw("CALL sp_listar_lineas_factura(?)", facID).Scan(&registradas).Error; err != nil {
+		return err
+	}
+	nuevas, err := evaluarServicios(tx, []ServicioFacturaParams{servicio})
+	if err != nil {
+		return err
+	}
+
+	evaluacion := &EvaluacionPromociones{Lineas: make([]LineaEvaluada, 0, len(registradas)+1)}
+	for _, linea := range registradas {
+		evaluacion.Lineas = append(evaluacion.Lineas, LineaEvaluada{
+			SerID:             linea.SerID,
+			SerNombre:         linea.SerNombre,
+			DfsCantidad:       linea.DfsCantidad,
+			DfsPrecioUnitario: linea.DfsPrecioUnitario,
+			DfsDescuento:      linea.DfsDescuento,
+			ProID:             linea.ProID,
+			EmpID:             linea.EmpID,
+			registrada:        true,
+		})
+	}
+	evaluacion.Lineas = append(evaluacion.Lineas, nuevas...)
+	for _, linea := range evaluacion.Lineas {
+		evaluacion.Subtotal += linea.importe()
+	}
+
+	fecha := factura.FacFecha.Format("2006-01-02")
+	var vigentes []models.PromocionConReglas
+	if err := tx.Raw("CALL sp_listar_promociones_vigentes(?, ?)", fecha, factura.CliID).Scan(&vigentes).Error; err != nil {
+		return err
+	}
+	aplicarPromocionesAutomaticas(vigentes, fecha, evaluacion)
+
+	nueva := evaluacion.Lineas[len(evaluacion.Lineas)-1]
+	var resultado struct {
+		Aplicada bool `gorm:"column:aplicada"`
+	}
+	err = tx.Raw("CALL sp_insertar_detalle_factura_promocion(?, ?, ?, ?, ?, ?)",
+		facID, nueva.SerID, nueva.DfsCantidad, nueva.EmpID, nueva.ProID, nueva.DfsDescuento).Scan(&resultado).Error
+	if err != nil {
+		return err
+	}
+	if !resultado.Aplicada {
+		err := tx.Raw("CALL sp_insertar_detalle_factura_promocion(?, ?, ?, ?, NULL, 0)",
+			facID, nueva.SerID, nueva.DfsCantidad, nueva.EmpID).Scan(&resultado).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Exec("CALL sp_recalcular_total_factura(?)", facID).Error
+}
+
+// evaluarReglasPromocion returns the reason the promotion does not apply to the draft invoice,
+// or an empty string when it does. Bundles need every one of their services on a line that has
+// not been discounted by another promotion yet.
+func evaluarReglasPromocion(promocion *models.PromocionConReglas, fecha string, evaluacion *EvaluacionPromociones) string {
+	if fecha < promocion.ProFechaInicio.Format("2006-01-02") || fecha > promocion.ProFechaFin.Format("2006-01-02") {
+		return MotivoPromocionNoVigente
+	}
+	if promocion.ProMaxUsos != nil && promocion.ProUsos >= *promocion.ProMaxUsos {
+		return MotivoPromocionLimiteUsos
+	}
+	if promocion.ProMaxUsosCliente != nil && promocion.UsosCliente >= *promocion.ProMaxUsosCliente {
+		return MotivoPromocionLimiteCliente
+	}
+	if lineasPromocion(&promocion.Promotion, evaluacion.Lineas) == nil {
+		return MotivoPromocionServiciosFaltantes
+	}
+	if promocion.ProMontoMinimo != nil && evaluacion.Subtotal < *promocion.ProMontoMinimo {
+		return MotivoPromocionMontoMinimo
+	}
+	return ""
+}
+
+// lineasPromocion returns the indexes of the free lines the promotion applies to, main service first,
+// or nil when one of its services is missing
+func lineasPromocion(promocion *models.Promotion, lineas []LineaEvaluada) []int {
+	requeridos := []uint{promocion.SerID}
+	if promocion.ServiciosPaquete != nil {
+		for _, valor := range strings.Split(*promocion.ServiciosPaquete, ",") {
+			serID, err := strconv.ParseUint(strings.TrimSpace(valor), 10, 32)
+			if err == nil {
+				requeridos = append(requeridos, uint(serID))
+			}
+		}
+	}
+
+	usadas := make(map[int]bool)
+	indices := make([]int, 0, len(requeridos))
+	for _, serID := range requeridos {
+		encontrada := -1
+		for i, linea := range lineas {
+			if linea.SerID == serID && linea.ProID == nil && !linea.registrada && !usadas[i] {
+				encontrada = i
+				break
+			}
+		}
+		if encontrada < 0 {
+			return nil
+		}
+		usadas[encontrada] = true
+		indices = append(indices, encontrada)
+	}
+	return indices
+}
+
+// calcularDescuentosPromocion returns the discount per line index. Percentages apply to every line
+// of the promotion; a fixed amount is taken from the main service first and any remainder from
+// the other bundle lines, never exceeding a line's amount (price times quantity).
+func calcularDescuentosPromocion(promocion *models.Promotion, lineas []LineaEvaluada) map[int]float64 {
+	descuentos := make(map[int]float64)
+	indices := lineasPromocion(promocion, lineas)
+
+	if promocion.ProTipoDescuento == TipoDescuentoValorFijo {
+		restante := 0.0
+		if promocion.ProDescuentoValor != nil {
+			restante = *promocion.ProDescuentoValor
+		}
+		for _, i := range indices {
+			descuento := math.Min(restante, lineas[i].importe())
+			descuentos[i] = redondear(descuento)
+			restante -= descuento
+		}
+		return descuentos
+	}
+
+	for _, i := range indices {
+		descuentos[i] = redondear(lineas[i].importe() * promocion.ProDescuentoPorcentaje / 100)
+	}
+	return descuentos
+}
+
+// aplicarDescuentos assigns the promotion to its lines and returns the total discount
+func aplicarDescuentos(evaluacion *EvaluacionPromociones, proID uint, descuentos map[int]float64) float64 {
+	var total float64
+	for i, descuento := range descuentos {
+		id := proID
+		evaluacion.Lineas[i].ProID = &id
+		evaluacion.Lineas[i].DfsDescuento = descuento
+		total += descuento
+	}
+	evaluacion.Descuento = redondear(evaluacion.Descuento + total)
+	return redondear(total)
+}
+
+func redondear(valor float64) float64 {
+	return math.Round(valor*100) / 100
+}
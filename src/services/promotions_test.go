@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"salon/models"
+)
+
+func TestAplicarPromocionesAutomaticasServicioRepetido(t *testing.T) {
+	proID := func(id uint) *uint { return &id }
+	promocion := func(id uint, porcentaje float64) models.PromocionConReglas {
+		return models.PromocionConReglas{Promotion: models.Promotion{
+			ProID:                  id,
+			SerID:                  5,
+			ProFechaInicio:         time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local),
+			ProFechaFin:            time.Date(2026, 12, 31, 0, 0, 0, 0, time.Local),
+			ProTipoDescuento:       TipoDescuentoPorcentaje,
+			ProDescuentoPorcentaje: porcentaje,
+		}}
+	}
+	corte := LineaEvaluada{SerID: 5, DfsCantidad: 1, DfsPrecioUnitario: 50000}
+
+	tests := []struct {
+		name       string
+		vigentes   []models.PromocionConReglas
+		lineas     []LineaEvaluada
+		promocion  []*uint
+		descuentos []float64
+	}{
+		{
+			name:       "new invoice discounts only the first line of a repeated service",
+			vigentes:   []models.PromocionConReglas{promocion(1, 20)},
+			lineas:     []LineaEvaluada{corte, corte},
+			promocion:  []*uint{proID(1), nil},
+			descuentos: []float64{10000, 0},
+		},
+		{
+			name:       "the largest promotion wins the first line",
+			vigentes:   []models.PromocionConReglas{promocion(1, 10), promocion(2, 20)},
+			lineas:     []LineaEvaluada{corte, corte},
+			promocion:  []*uint{proID(2), nil},
+			descuentos: []float64{10000, 0},
+		},
+		{
+			name:     "line added after one that already has the promotion",
+			vigentes: []models.PromocionConReglas{promocion(1, 20)},
+			lineas: []LineaEvaluada{
+				{SerID: 5, DfsCantidad: 1, DfsPrecioUnitario: 50000, DfsDescuento: 10000, ProID: proID(1), registrada: true},
+				corte,
+			},
+			promocion:  []*uint{proID(1), nil},
+			descuentos: []float64{10000, 0},
+		},
+		{
+			name:     "line added after one saved without promotion",
+			vigentes: []models.PromocionConReglas{promocion(1, 20)},
+			lineas: []LineaEvaluada{
+				{SerID: 5, DfsCantidad: 1, DfsPrecioUnitario: 50000, registrada: true},
+				corte,
+			},
+			promocion:  []*uint{nil, proID(1)},
+			descuentos: []float64{0, 10000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluacion := &EvaluacionPromociones{Lineas: append([]LineaEvaluada(nil), tt.lineas...)}
+			for _, linea := range evaluacion.Lineas {
+				evaluacion.Subtotal += linea.importe()
+			}
+
+			aplicarPromocionesAutomaticas(tt.vigentes, "2026-10-17", evaluacion)
+
+			for i, linea := range evaluacion.Lineas {
+				switch {
+				case tt.promocion[i] == nil && linea.ProID != nil:
+					t.Errorf("line %d pro_id = %d, want none", i, *linea.ProID)
+				case tt.promocion[i] != nil && (linea.ProID == nil || *linea.ProID != *tt.promocion[i]):
+					t.Errorf("line %d pro_id = %v, want %d", i, linea.ProID, *tt.promocion[i])
+				}
+				if linea.DfsDescuento != tt.descuentos[i] {
+					t.Errorf("line %d discount = %v, want %v", i, linea.DfsDescuento, tt.descuentos[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"salon/models"
+)
+
+// Groupings of the profit and loss report
+const (
+	AgrupacionMes    = "month"
+	AgrupacionSemana = "week"
+)
+
+// Lines returned by sp_reporte_estado_resultados (concepto)
+const (
+	ConceptoVentas       = "Ventas"
+	ConceptoNotasCredito = "Notas crédito"
+	ConceptoCostoVentas  = "Costo ventas"
+	ConceptoMerma        = "Merma"
+	ConceptoNomina       = "Nómina"
+	ConceptoGasto        = "Gasto"
+	ConceptoCompras      = "Compras"
+)
+
+// ErrAgrupacionInvalida is returned when the report is grouped by something other than month or week
+var ErrAgrupacionInvalida = errors.New("agrupación no válida")
+
+// ErrRangoFechasInvalido is returned when a report ends before it starts
+var ErrRangoFechasInvalido = errors.New("la fecha final es anterior a la inicial")
+
+// EstadoResultados builds the profit and loss report between two dates, one row per month or week,
+// and compares its total with the previous period of the same length. When the range covers whole
+// months the previous period is the same number of months before it.
+func (s *DatabaseService) EstadoResultados(desde, hasta time.Time, agrupacion string) (*models.EstadoResultados, error) {
+	if agrupacion != AgrupacionMes && agrupacion != AgrupacionSemana {
+		return nil, ErrAgrupacionInvalida
+	}
+	if hasta.Before(desde) {
+		return nil, ErrRangoFechasInvalido
+	}
+
+	conceptos, err := s.ReporteEstadoResultados(desde, hasta, agrupacion)
+	if err != nil {
+		return nil, err
+	}
+
+	anteriorDesde, anteriorHasta := periodoAnterior(desde, hasta)
+	conceptosAnteriores, err := s.ReporteEstadoResultados(anteriorDesde, anteriorHasta, agrupacion)
+	if err != nil {
+		return nil, err
+	}
+
+	periodos := resultadosPorPeriodo(conceptos, desde, hasta, agrupacion)
+	total := sumarResultados(periodos, desde, hasta)
+	anterior := sumarResultados(resultadosPorPeriodo(conceptosAnteriores, anteriorDesde, anteriorHasta, agrupacion),
+		anteriorDesde, anteriorHasta)
+
+	return &models.EstadoResultados{
+		Agrupacion:      agrupacion,
+		Periodos:        periodos,
+		Total:           total,
+		PeriodoAnterior: anterior,
+		Variacion: map[string]models.CambioResultado{
+			"ingresos":      cambioResultado(anterior.Ingresos, total.Ingresos),
+			"costo_ventas":  cambioResultado(anterior.CostoVentas, total.CostoVentas),
+			"nomina":        cambioResultado(anterior.Nomina, total.Nomina),
+			"total_gastos":  cambioResultado(anterior.TotalGastos, total.TotalGastos),
+			"utilidad_neta": cambioResultado(anterior.UtilidadNeta, total.UtilidadNeta),
+		},
+	}, nil
+}
+
+// ReporteEstadoResultados returns the profit and loss amounts grouped by period, line and expense type
+func (s *DatabaseService) ReporteEstadoResultados(desde, hasta time.Time, agrupacion string) ([]models.ConceptoResultado, error) {
+	var conceptos []models.ConceptoResultado
+	err := s.DB.Raw("CALL sp_reporte_estado_resultados(?, ?, ?)",
+		desde.Format("2006-01-02"), hasta.Format("2006-01-02"), agrupacion).Scan(&conceptos).Error
+	return conceptos, err
+}
+
+// periodoAnterior returns the period of the same length right before a range
+func periodoAnterior(desde, hasta time.Time) (time.Time, time.Time) {
+	if desde.Day() == 1 && hasta.AddDate(0, 0, 1).Day() == 1 {
+		meses := (hasta.Year()-desde.Year())*12 + int(hasta.Month()-desde.Month()) + 1
+		return desde.AddDate(0, -meses, 0), desde.AddDate(0, 0, -1)
+	}
+	dias := int(hasta.Sub(desde).Hours()/24) + 1
+	return desde.AddDate(0, 0, -dias), desde.AddDate(0, 0, -1)
+}
+
+// inicioPeriodo returns the first day of the month, or the Monday of the week, of a date
+func inicioPeriodo(fecha time.Time, agrupacion string) time.Time {
+	if agrupacion == AgrupacionSemana {
+		return fecha.AddDate(0, 0, -((int(fecha.Weekday()) + 6) % 7))
+	}
+	return fecha.AddDate(0, 0, 1-fecha.Day())
+}
+
+// resultadosPorPeriodo lays the report lines out on every month or week of the range, including
+// the ones without movements; the first and last periods are cut to the range
+func resultadosPorPeriodo(conceptos []models.ConceptoResultado, desde, hasta time.Time, agrupacion string) []models.ResultadoPeriodo {
+	var periodos []models.ResultadoPeriodo
+	indices := make(map[string]int)
+	for inicio := inicioPeriodo(desde, agrupacion); !inicio.After(hasta); {
+		siguiente := inicio.AddDate(0, 1, 0)
+		etiqueta := inicio.Format("2006-01")
+		if agrupacion == AgrupacionSemana {
+			siguiente = inicio.AddDate(0, 0, 7)
+			anio, semana := inicio.ISOWeek()
+			etiqueta = fmt.Sprintf("%d-W%02d", anio, semana)
+		}
+
+		periodoDesde, periodoHasta := inicio, siguiente.AddDate(0, 0, -1)
+		if periodoDesde.Before(desde) {
+			periodoDesde = desde
+		}
+		if periodoHasta.After(hasta) {
+			periodoHasta = hasta
+		}
+		indices[inicio.Format("2006-01-02")] = len(periodos)
+		periodos = append(periodos, models.ResultadoPeriodo{
+			Periodo: etiqueta,
+			Desde:   periodoDesde.Format("2006-01-02"),
+			Hasta:   periodoHasta.Format("2006-01-02"),
+			Gastos:  make(map[string]float64),
+		})
+		inicio = siguiente
+	}
+
+	for _, concepto := range conceptos {
+		i, ok := indices[concepto.Periodo.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		acumularConcepto(&periodos[i], concepto)
+	}
+	for i := range periodos {
+		calcularUtilidad(&periodos[i])
+	}
+	return periodos
+}
+
+func acumularConcepto(periodo *models.ResultadoPeriodo, concepto models.ConceptoResultado) {
+	switch concepto.Concepto {
+	case ConceptoVentas:
+		periodo.Ventas += concepto.Monto
+	case ConceptoNotasCredito:
+		periodo.NotasCredito += concepto.Monto
+	case ConceptoCostoVentas:
+		periodo.CostoVentas += concepto.Monto
+	case ConceptoMerma:
+		periodo.Merma += concepto.Monto
+	case ConceptoNomina:
+		periodo.Nomina += concepto.Monto
+	case ConceptoGasto:
+		tipo := "Sin tipo"
+		if concepto.Detalle != nil && *concepto.Detalle != "" {
+			tipo = *concepto.Detalle
+		}
+		periodo.Gastos[tipo] += concepto.Monto
+	case ConceptoCompras:
+		periodo.Compras += concepto.Monto
+	}
+}
+
+// calcularUtilidad derives revenue, gross and net profit of a period from its lines
+func calcularUtilidad(periodo *models.ResultadoPeriodo) {
+	periodo.TotalGastos = 0
+	for tipo, monto := range periodo.Gastos {
+		periodo.Gastos[tipo] = redondear(monto)
+		periodo.TotalGastos += monto
+	}
+	periodo.Ventas = redondear(periodo.Ventas)
+	periodo.NotasCredito = redondear(periodo.NotasCredito)
+	periodo.CostoVentas = redondear(periodo.CostoVentas)
+	periodo.Merma = redondear(periodo.Merma)
+	periodo.Nomina = redondear(periodo.Nomina)
+	periodo.Compras = redondear(periodo.Compras)
+	periodo.TotalGastos = redondear(periodo.TotalGastos)
+
+	periodo.Ingresos = redondear(periodo.Ventas - periodo.NotasCredito)
+	periodo.UtilidadBruta = redondear(periodo.Ingresos - periodo.CostoVentas - periodo.Merma)
+	periodo.UtilidadNeta = redondear(periodo.UtilidadBruta - periodo.Nomina - periodo.TotalGastos)
+}
+
+// sumarResultados adds up the periods of a range
+func sumarResultados(periodos []models.ResultadoPeriodo, desde, hasta time.Time) models.ResultadoPeriodo {
+	total := models.ResultadoPeriodo{
+		Periodo: desde.Format("2006-01-02") + "/" + hasta.Format("2006-01-02"),
+		Desde:   desde.Format("2006-01-02"),
+		Hasta:   hasta.Format("2006-01-02"),
+		Gastos:  make(map[string]float64),
+	}
+	for _, periodo := range periodos {
+		total.Ventas += periodo.Ventas
+		total.NotasCredito += periodo.NotasCredito
+		total.CostoVentas += periodo.CostoVentas
+		total.Merma += periodo.Merma
+		total.Nomina += periodo.Nomina
+		total.Compras += periodo.Compras
+		for tipo, monto := range periodo.Gastos {
+			total.Gastos[tipo] += monto
+		}
+	}
+	calcularUtilidad(&total)
+	return total
+}
+
+func cambioResultado(anterior, actual float64) models.CambioResultado {
+	cambio := models.CambioResultado{
+		Anterior:   anterior,
+		Actual:     actual,
+		Diferencia: redondear(actual - anterior),
+	}
+	if anterior != 0 {
+		porcentaje := redondear((actual - anterior) / math.Abs(anterior) * 100)
+		cambio.Porcentaje = &porcentaje
+	}
+	return cambio
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"salon/models"
+)
+
+// fecha parses a date the way the report handlers do
+func fecha(t *testing.T, valor string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse("2006-01-02", valor)
+	if err != nil {
+		t.Fatalf("time.Parse(%q) error = %v", valor, err)
+	}
+	return parsed
+}
+
+func TestPeriodoAnterior(t *testing.T) {
+	tests := []struct {
+		name         string
+		desde, hasta string
+		wantDesde    string
+		wantHasta    string
+	}{
+		{"whole month", "2025-06-01", "2025-06-30", "2025-05-01", "2025-05-31"},
+		{"whole month after a shorter one", "2025-03-01", "2025-03-31", "2025-02-01", "2025-02-28"},
+		{"whole month after a leap February", "2024-03-01", "2024-03-31", "2024-02-01", "2024-02-29"},
+		{"whole quarter", "2025-04-01", "2025-06-30", "2025-01-01", "2025-03-31"},
+		{"whole months across the year", "2025-01-01", "2025-02-28", "2024-11-01", "2024-12-31"},
+		{"week", "2025-06-09", "2025-06-15", "2025-06-02", "2025-06-08"},
+		{"partial month counts days", "2025-06-10", "2025-06-30", "2025-05-20", "2025-06-09"},
+		{"single day", "2025-06-15", "2025-06-15", "2025-06-14", "2025-06-14"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desde, hasta := periodoAnterior(fecha(t, tt.desde), fecha(t, tt.hasta))
+			if got := desde.Format("2006-01-02"); got != tt.wantDesde {
+				t.Errorf("desde = %s, want %s", got, tt.wantDesde)
+			}
+			if got := hasta.Format("2006-01-02"); got != tt.wantHasta {
+				t.Errorf("hasta = %s, want %s", got, tt.wantHasta)
+			}
+		})
+	}
+}
+
+func TestInicioPeriodo(t *testing.T) {
+	tests := []struct {
+		fecha      string
+		agrupacion string
+		want       string
+	}{
+		{"2025-06-15", AgrupacionMes, "2025-06-01"},
+		{"2025-06-01", AgrupacionMes, "2025-06-01"},
+		{"2025-06-15", AgrupacionSemana, "2025-06-09"}, // Sunday belongs to the week started on Monday
+		{"2025-06-09", AgrupacionSemana, "2025-06-09"},
+		{"2025-01-01", AgrupacionSemana, "2024-12-30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.agrupacion+" "+tt.fecha, func(t *testing.T) {
+			if got := inicioPeriodo(fecha(t, tt.fecha), tt.agrupacion).Format("2006-01-02"); got != tt.want {
+				t.Errorf("inicioPeriodo() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultadosPorPeriodo(t *testing.T) {
+	type periodo struct{ etiqueta, desde, hasta string }
+	tests := []struct {
+		name         string
+		desde, hasta string
+		agrupacion   string
+		want         []periodo
+	}{
+		{
+			name:       "months cut to the range",
+			desde:      "2025-05-15",
+			hasta:      "2025-07-10",
+			agrupacion: AgrupacionMes,
+			want: []periodo{
+				{"2025-05", "2025-05-15", "2025-05-31"},
+				{"2025-06", "2025-06-01", "2025-06-30"},
+				{"2025-07", "2025-07-01", "2025-07-10"},
+			},
+		},
+		{
+			name:       "ISO weeks across the year",
+			desde:      "2024-12-28",
+			hasta:      "2025-01-07",
+			agrupacion: AgrupacionSemana,
+			want: []periodo{
+				{"2024-W52", "2024-12-28", "2024-12-29"},
+				{"2025-W01", "2024-12-30", "2025-01-05"},
+				{"2025-W02", "2025-01-06", "2025-01-07"},
+			},
+		},
+		{
+			name:       "single whole month",
+			desde:      "2025-02-01",
+			hasta:      "2025-02-28",
+			agrupacion: AgrupacionMes,
+			want:       []periodo{{"2025-02", "2025-02-01", "2025-02-28"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			periodos := resultadosPorPeriodo(nil, fecha(t, tt.desde), fecha(t, tt.hasta), tt.agrupacion)
+			if len(periodos) != len(tt.want) {
+				t.Fatalf("got %d periods, want %d: %+v", len(periodos), len(tt.want), periodos)
+			}
+			for i, want := range tt.want {
+				got := periodos[i]
+				if got.Periodo != want.etiqueta || got.Desde != want.desde || got.Hasta != want.hasta {
+					t.Errorf("period %d = %s %s..%s, want %s %s..%s",
+						i, got.Periodo, got.Desde, got.Hasta, want.etiqueta, want.desde, want.hasta)
+				}
+			}
+		})
+	}
+}
+
+func TestResultadosPorPeriodoAcumulaConceptos(t *testing.T) {
+	arriendo := "Arriendo"
+	conceptos := []models.ConceptoResultado{
+		{Periodo: fecha(t, "2025-06-01"), Concepto: ConceptoVentas, Monto: 1000},
+		{Periodo: fecha(t, "2025-06-01"), Concepto: ConceptoNotasCredito, Monto: 100},
+		{Periodo: fecha(t, "2025-06-01"), Concepto: ConceptoCostoVentas, Monto: 300},
+		{Periodo: fecha(t, "2025-06-01"), Concepto: ConceptoMerma, Monto: 20},
+		{Periodo: fecha(t, "2025-06-01"), Concepto: ConceptoNomina, Monto: 200},
+		{Periodo: fecha(t, "2025-06-01"), Concepto: ConceptoGasto, Detalle: &arriendo, Monto: 150},
+		{Periodo: fecha(t, "2025-06-01"), Concepto: ConceptoGasto, Monto: 30},
+		{Periodo: fecha(t, "2025-06-01"), Concepto: ConceptoCompras, Monto: 500},
+		{Periodo: fecha(t, "2025-08-01"), Concepto: ConceptoVentas, Monto: 999}, // Outside the range
+	}
+
+	periodos := resultadosPorPeriodo(conceptos, fecha(t, "2025-06-01"), fecha(t, "2025-07-31"), AgrupacionMes)
+	if len(periodos) != 2 {
+		t.Fatalf("got %d periods, want 2", len(periodos))
+	}
+
+	junio := periodos[0]
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"ingresos", junio.Ingresos, 900},
+		{"utilidad_bruta", junio.UtilidadBruta, 580},
+		{"total_gastos", junio.TotalGastos, 180},
+		{"utilidad_neta", junio.UtilidadNeta, 200},
+		{"compras", junio.Compras, 500},
+		{"gastos Arriendo", junio.Gastos["Arriendo"], 150},
+		{"gastos Sin tipo", junio.Gastos["Sin tipo"], 30},
+		{"julio ventas", periodos[1].Ventas, 0},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"salon/models"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -35,6 +40,15 @@ func (s *DatabaseService) logOperation(operation, details string) {
 	log.Printf("[DB_OPERATION] Connection: %s | Operation: %s | Details: %s", s.ConnectionType, operation, details)
 }
 
+// errSenalUsuario is the MySQL error number of SIGNAL SQLSTATE '45000'
+const errSenalUsuario = 1644
+
+// esSenalSP reports whether err is the SIGNAL raised by a stored procedure with the given message
+func esSenalSP(err error, mensaje string) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errSenalUsuario && mysqlErr.Message == mensaje
+}
+
 // ============= EMPLOYEE PROCEDURES =============
 
 func (s *DatabaseService) GetEmpleados() ([]models.Employee, error) {
@@ -72,6 +86,74 @@ func (s *DatabaseService) DeleteEmpleado(id uint) error {
 	return s.DB.Exec("CALL sp_delete_empleado(?)", id).Error
 }
 
+// ============= EMPLOYEE SCHEDULE PROCEDURES =============
+
+func (s *DatabaseService) ListarHorariosEmpleado(empID uint) ([]models.HorarioEmpleado, error) {
+	var horarios []models.HorarioEmpleado
+	err := s.DB.Raw("CALL sp_listar_horarios_empleado(?)", empID).Scan(&horarios).Error
+	return horarios, err
+}
+
+func (s *DatabaseService) BuscarHorarioPorID(horID uint) (*models.HorarioEmpleado, error) {
+	var horario models.HorarioEmpleado
+	err := s.DB.Raw("CALL sp_buscar_horario_por_id(?)", horID).Scan(&horario).Error
+	if err != nil {
+		return nil, err
+	}
+	if horario.HorID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &horario, nil
+}
+
+func (s *DatabaseService) InsertarHorario(diaSemana, horaEntrada, horaSalida string, empID uint) error {
+	return s.DB.Exec("CALL sp_insertar_horario(?, ?, ?, ?)",
+		diaSemana, horaEntrada, horaSalida, empID).Error
+}
+
+func (s *DatabaseService) ActualizarHorario(horID uint, diaSemana, horaEntrada, horaSalida string) error {
+	return s.DB.Exec("CALL sp_actualizar_horario(?, ?, ?, ?)",
+		horID, diaSemana, horaEntrada, horaSalida).Error
+}
+
+func (s *DatabaseService) EliminarHorario(horID uint) error {
+	return s.DB.Exec("CALL sp_eliminar_horario(?)", horID).Error
+}
+
+func (s *DatabaseService) ListarExcepcionesEmpleado(empID uint) ([]models.HorarioExcepcion, error) {
+	var excepciones []models.HorarioExcepcion
+	err := s.DB.Raw("CALL sp_listar_excepciones_empleado(?)", empID).Scan(&excepciones).Error
+	return excepciones, err
+}
+
+func (s *DatabaseService) BuscarExcepcionPorID(hexID uint) (*models.HorarioExcepcion, error) {
+	var excepcion models.HorarioExcepcion
+	err := s.DB.Raw("CALL sp_buscar_excepcion_por_id(?)", hexID).Scan(&excepcion).Error
+	if err != nil {
+		return nil, err
+	}
+	if excepcion.HexID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &excepcion, nil
+}
+
+// InsertarExcepcion stores a day off, holiday or special-hours entry. horaEntrada and
+// horaSalida are nil for full-day exceptions.
+func (s *DatabaseService) InsertarExcepcion(fecha, tipo string, horaEntrada, horaSalida *string, motivo string, empID uint) error {
+	return s.DB.Exec("CALL sp_insertar_excepcion(?, ?, ?, ?, ?, ?)",
+		fecha, tipo, horaEntrada, horaSalida, motivo, empID).Error
+}
+
+func (s *DatabaseService) ActualizarExcepcion(hexID uint, fecha, tipo string, horaEntrada, horaSalida *string, motivo string) error {
+	return s.DB.Exec("CALL sp_actualizar_excepcion(?, ?, ?, ?, ?, ?)",
+		hexID, fecha, tipo, horaEntrada, horaSalida, motivo).Error
+}
+
+func (s *DatabaseService) EliminarExcepcion(hexID uint) error {
+	return s.DB.Exec("CALL sp_eliminar_excepcion(?)", hexID).Error
+}
+
 // ============= CLIENT PROCEDURES =============
 
 func (s *DatabaseService) GetClientes() ([]models.Client, error) {
@@ -107,6 +189,11 @@ func (s *DatabaseService) DeleteCliente(id uint) error {
 	return s.DB.Exec("CALL sp_delete_cliente(?)", id).Error
 }
 
+// ActualizarDocumentoCliente records the identification document printed on the client's electronic invoices
+func (s *DatabaseService) ActualizarDocumentoCliente(cliID uint, tipoDocumento, documento string) error {
+	return s.DB.Exec("CALL sp_actualizar_documento_cliente(?, ?, ?)", cliID, tipoDocumento, documento).Error
+}
+
 // ============= PRODUCT PROCEDURES =============
 
 func (s *DatabaseService) GetProductos() ([]models.Product, error) {
@@ -115,22 +202,56 @@ func (s *DatabaseService) GetProductos() ([]models.Product, error) {
 	return products, err
 }
 
-func (s *DatabaseService) InsertProducto(prod models.Product) error {
-	return s.DB.Exec("CALL sp_insert_producto(?, ?, ?, ?)",
+// InsertProducto creates a product; its initial quantity enters the stock ledger as an adjustment
+// at ProdCostoPromedio, which becomes the starting average cost
+func (s *DatabaseService) InsertProducto(prod models.Product, usuario string) error {
+	return s.execEnTransaccion("CALL sp_insert_producto(?, ?, ?, ?, ?, ?)",
 		prod.ProdNombre, prod.ProdDescripcion,
-		prod.ProdCantidadDisponible, prod.ProdPrecioUnitario).Error
+		prod.ProdCantidadDisponible, prod.ProdPrecioUnitario, prod.ProdCostoPromedio, usuario)
 }
 
-func (s *DatabaseService) UpdateProducto(prod models.Product) error {
-	return s.DB.Exec("CALL sp_update_producto(?, ?, ?, ?, ?)",
+// UpdateProducto updates a product; a different quantity is recorded as an adjustment movement
+func (s *DatabaseService) UpdateProducto(prod models.Product, usuario, motivo string) error {
+	return s.execEnTransaccion("CALL sp_update_producto(?, ?, ?, ?, ?, ?, ?)",
 		prod.ProdID, prod.ProdNombre, prod.ProdDescripcion,
-		prod.ProdCantidadDisponible, prod.ProdPrecioUnitario).Error
+		prod.ProdCantidadDisponible, prod.ProdPrecioUnitario, usuario, textoOpcional(motivo))
+}
+
+func (s *DatabaseService) BuscarProductoPorID(prodID uint) (*models.Product, error) {
+	var producto models.Product
+	err := s.DB.Raw("CALL sp_buscar_producto_por_id(?)", prodID).Scan(&producto).Error
+	if err != nil {
+		return nil, err
+	}
+	return &producto, nil
 }
 
 func (s *DatabaseService) DeleteProducto(id uint) error {
 	return s.DB.Exec("CALL sp_delete_producto(?)", id).Error
 }
 
+// ============= SERVICE PRODUCT (RECIPE) PROCEDURES =============
+
+func (s *DatabaseService) ListarProductosServicio(serID uint) ([]models.ProductoServicio, error) {
+	var productos []models.ProductoServicio
+	err := s.DB.Raw("CALL sp_listar_productos_servicio(?)", serID).Scan(&productos).Error
+	return productos, err
+}
+
+func (s *DatabaseService) InsertarProductoUsado(pu models.ProductoUsado) error {
+	return s.DB.Exec("CALL sp_insertar_producto_usado(?, ?, ?, ?)",
+		pu.SerID, pu.ProdID, pu.PruCantidadUsada, pu.PruBotellasUsadas).Error
+}
+
+func (s *DatabaseService) ActualizarProductoUsado(pu models.ProductoUsado) error {
+	return s.DB.Exec("CALL sp_actualizar_producto_usado(?, ?, ?, ?)",
+		pu.SerID, pu.ProdID, pu.PruCantidadUsada, pu.PruBotellasUsadas).Error
+}
+
+func (s *DatabaseService) EliminarProductoUsado(serID, prodID uint) error {
+	return s.DB.Exec("CALL sp_eliminar_producto_usado(?, ?)", serID, prodID).Error
+}
+
 // ============= VIEW PROCEDURES FOR DASHBOARD =============
 
 func (s *DatabaseService) GetEmpleadosActivos() (*models.EmpleadosActivos, error) {
@@ -169,6 +290,13 @@ func (s *DatabaseService) GetProductosBajos() (*models.ProductosBajos, error) {
 	return &result, nil
 }
 
+// GetProductosReorden lists the products below their minimum stock with the suggested order quantity
+func (s *DatabaseService) GetProductosReorden() ([]models.ProductoReorden, error) {
+	var productos []models.ProductoReorden
+	err := s.DB.Raw("CALL sp_get_productos_reorden()").Scan(&productos).Error
+	return productos, err
+}
+
 func (s *DatabaseService) GetTotalProductos() (*models.TotalProductos, error) {
 	var result models.TotalProductos
 	err := s.DB.Raw("CALL sp_get_total_productos()").Scan(&result).Error
@@ -400,9 +528,29 @@ func (s *DatabaseService) InsertarUsuarioSistema(username, email, password strin
 
 // ============= INVENTORY PROCEDURES =============
 
-func (s *DatabaseService) CrearInventario(fecha string, prodID uint, cantidad int, observaciones string) error {
-	return s.DB.Exec("CALL CrearInventario(?, ?, ?, ?)",
-		fecha, prodID, cantidad, observaciones).Error
+// Stock movement types of the inventory ledger
+const (
+	MovimientoCompra          = "Compra"
+	MovimientoConsumoServicio = "Consumo Servicio"
+	MovimientoVenta           = "Venta"
+	MovimientoAjuste          = "Ajuste"
+	MovimientoMerma           = "Merma"
+	MovimientoDevolucion      = "Devolución"
+)
+
+// textoOpcional maps an empty string to NULL for optional procedure parameters
+func textoOpcional(texto string) *string {
+	if texto == "" {
+		return nil
+	}
+	return &texto
+}
+
+// CrearInventario adds an inventory entry; a counted quantity different from the current stock
+// is recorded as an adjustment movement
+func (s *DatabaseService) CrearInventario(fecha string, prodID uint, cantidad int, observaciones, usuario, motivo string) error {
+	return s.execEnTransaccion("CALL CrearInventario(?, ?, ?, ?, ?, ?)",
+		fecha, prodID, cantidad, observaciones, usuario, textoOpcional(motivo))
 }
 
 func (s *DatabaseService) ObtenerInventarioCompleto() ([]models.InventoryComplete, error) {
@@ -411,15 +559,155 @@ func (s *DatabaseService) ObtenerInventarioCompleto() ([]models.InventoryComplet
 	return inventories, err
 }
 
-func (s *DatabaseService) ActualizarInventario(invID uint, fecha string, cantidad int, observaciones string) error {
-	return s.DB.Exec("CALL ActualizarInventario(?, ?, ?, ?)",
-		invID, fecha, cantidad, observaciones).Error
+// ActualizarInventario no longer overwrites the quantity: the difference with the current stock
+// is recorded as an adjustment movement
+func (s *DatabaseService) ActualizarInventario(invID uint, fecha string, cantidad int, observaciones, usuario, motivo string) error {
+	return s.execEnTransaccion("CALL ActualizarInventario(?, ?, ?, ?, ?, ?)",
+		invID, fecha, cantidad, observaciones, usuario, textoOpcional(motivo))
+}
+
+// ActualizarReordenProducto sets the minimum stock, usual order quantity and preferred supplier of a product
+func (s *DatabaseService) ActualizarReordenProducto(prodID uint, stockMinimo, cantidadReorden int, provID *uint) error {
+	return s.DB.Exec("CALL sp_actualizar_reorden_producto(?, ?, ?, ?)",
+		prodID, stockMinimo, cantidadReorden, provID).Error
+}
+
+// ActualizarIvaProducto sets the VAT rate included in a product's price; lines already invoiced keep
+// the rate they were billed with
+func (s *DatabaseService) ActualizarIvaProducto(prodID uint, ivaPorcentaje float64) error {
+	return s.DB.Exec("CALL sp_actualizar_iva_producto(?, ?)", prodID, ivaPorcentaje).Error
+}
+
+// RegistrarMovimientoInventario appends a movement to the stock ledger; cantidad is positive for
+// stock coming in and negative for stock going out. An incoming movement with costoUnitario updates
+// the weighted average cost; otherwise the movement is valued at the current average.
+func (s *DatabaseService) RegistrarMovimientoInventario(prodID uint, tipo string, cantidad int, usuario, motivo, referencia string, costoUnitario *float64) error {
+	return s.execEnTransaccion("CALL sp_registrar_movimiento_inventario(?, ?, ?, ?, ?, ?, ?)",
+		prodID, tipo, cantidad, usuario, textoOpcional(motivo), textoOpcional(referencia), costoUnitario)
+}
+
+// ReporteValorizacionInventario values the stock of every product at its weighted average cost
+func (s *DatabaseService) ReporteValorizacionInventario() ([]models.ValorizacionProducto, error) {
+	var productos []models.ValorizacionProducto
+	err := s.DB.Raw("CALL sp_reporte_valorizacion_inventario()").Scan(&productos).Error
+	return productos, err
+}
+
+// ReporteCostoSalidas returns the cost of service consumption, sales (net of invoice returns) and
+// waste per product in a date range; empty dates leave the range open
+func (s *DatabaseService) ReporteCostoSalidas(desde, hasta string) ([]models.CostoSalida, error) {
+	var salidas []models.CostoSalida
+	err := s.DB.Raw("CALL sp_reporte_costo_salidas(?, ?)", textoOpcional(desde), textoOpcional(hasta)).Scan(&salidas).Error
+	return salidas, err
+}
+
+// ListarMovimientosProducto returns the ledger of a product; empty dates leave the range open
+func (s *DatabaseService) ListarMovimientosProducto(prodID uint, desde, hasta string) ([]models.MovimientoInventario, error) {
+	var movimientos []models.MovimientoInventario
+	err := s.DB.Raw("CALL sp_listar_movimientos_producto(?, ?, ?)",
+		prodID, textoOpcional(desde), textoOpcional(hasta)).Scan(&movimientos).Error
+	return movimientos, err
 }
 
 func (s *DatabaseService) EliminarInventario(invID uint) error {
 	return s.DB.Exec("CALL EliminarInventario(?)", invID).Error
 }
 
+// ============= STOCK COUNT PROCEDURES =============
+
+// Stock count session states
+const (
+	EstadoConteoAbierto   = "Abierto"
+	EstadoConteoAprobado  = "Aprobado"
+	EstadoConteoCancelado = "Cancelado"
+)
+
+// ConteoParams is the quantity of a product counted by one user
+type ConteoParams struct {
+	ProdID   uint
+	Cantidad int
+}
+
+// AbrirConteoInventario opens a count session and freezes the current stock and cost of every product
+func (s *DatabaseService) AbrirConteoInventario(usuario, observaciones string) (*models.ConteoInventario, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var conteo models.ConteoInventario
+	err := tx.Raw("CALL sp_abrir_conteo_inventario(?, ?)", usuario, textoOpcional(observaciones)).Scan(&conteo).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return &conteo, nil
+}
+
+func (s *DatabaseService) ListarConteosInventario() ([]models.ConteoInventario, error) {
+	var conteos []models.ConteoInventario
+	err := s.DB.Raw("CALL sp_listar_conteos_inventario()").Scan(&conteos).Error
+	return conteos, err
+}
+
+func (s *DatabaseService) BuscarConteoInventario(conID uint) (*models.ConteoInventario, error) {
+	var conteo models.ConteoInventario
+	err := s.DB.Raw("CALL sp_buscar_conteo_inventario(?)", conID).Scan(&conteo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &conteo, nil
+}
+
+// RegistrarConteo saves the quantities counted by a user along with the system stock at that moment;
+// counting a product again replaces that user's previous quantity. All lines are saved in a single transaction.
+func (s *DatabaseService) RegistrarConteo(conID uint, usuario string, conteos []ConteoParams) error {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, conteo := range conteos {
+		err := tx.Exec("CALL sp_registrar_conteo_producto(?, ?, ?, ?)",
+			conID, conteo.ProdID, conteo.Cantidad, usuario).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+func (s *DatabaseService) ListarDiferenciasConteo(conID uint) ([]models.DiferenciaConteo, error) {
+	var diferencias []models.DiferenciaConteo
+	err := s.DB.Raw("CALL sp_listar_diferencias_conteo(?)", conID).Scan(&diferencias).Error
+	return diferencias, err
+}
+
+// AprobarConteoInventario posts every variance of the count as an adjustment movement and closes the session
+func (s *DatabaseService) AprobarConteoInventario(conID uint, usuario string) error {
+	return s.execEnTransaccion("CALL sp_aprobar_conteo_inventario(?, ?)", conID, usuario)
+}
+
+func (s *DatabaseService) CancelarConteoInventario(conID uint, usuario string) error {
+	return s.DB.Exec("CALL sp_cancelar_conteo_inventario(?, ?)", conID, usuario).Error
+}
+
 // ============= ALTERNATIVE PRODUCT PROCEDURES =============
 
 func (s *DatabaseService) CrearProducto(nombre, descripcion string, cantidad int, precio float64) error {
@@ -502,12 +790,38 @@ type PromocionParams struct {
 	Descuento   float64
 	SerID       uint
 	Usos        int
+
+	TipoDescuento    string   // Porcentaje or Valor Fijo
+	DescuentoValor   *float64 // Amount off for Valor Fijo promotions
+	MaxUsos          *int     // Nil means unlimited
+	MaxUsosCliente   *int     // Nil means unlimited
+	MontoMinimo      *float64 // Minimum invoice subtotal
+	Codigo           *string  // Coupon code
+	ServiciosPaquete []uint   // Extra services that must be on the invoice (bundle)
+}
+
+// serviciosPaqueteJSON encodes the bundle services for the stored procedures, nil when there is no bundle
+func (p PromocionParams) serviciosPaqueteJSON() (interface{}, error) {
+	if len(p.ServiciosPaquete) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(p.ServiciosPaquete)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
 }
 
 func (s *DatabaseService) CrearPromocion(params PromocionParams) error {
-	return s.DB.Exec("CALL sp_crear_promocion(?, ?, ?, ?, ?, ?, ?)",
+	paquete, err := params.serviciosPaqueteJSON()
+	if err != nil {
+		return err
+	}
+	return s.DB.Exec("CALL sp_crear_promocion(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
 		params.Nombre, params.Descripcion, params.FechaInicio, params.FechaFin,
-		params.Descuento, params.SerID, params.Usos).Error
+		params.Descuento, params.SerID, params.Usos,
+		params.TipoDescuento, params.DescuentoValor, params.MaxUsos, params.MaxUsosCliente,
+		params.MontoMinimo, params.Codigo, paquete).Error
 }
 
 func (s *DatabaseService) ListarPromociones() ([]models.PromotionWithService, error) {
@@ -526,9 +840,15 @@ func (s *DatabaseService) ObtenerPromocionPorID(proID uint) (*models.Promotion,
 }
 
 func (s *DatabaseService) ActualizarPromocion(proID uint, params PromocionParams) error {
-	return s.DB.Exec("CALL sp_actualizar_promocion(?, ?, ?, ?, ?, ?, ?, ?)",
+	paquete, err := params.serviciosPaqueteJSON()
+	if err != nil {
+		return err
+	}
+	return s.DB.Exec("CALL sp_actualizar_promocion(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
 		proID, params.Nombre, params.Descripcion, params.FechaInicio, params.FechaFin,
-		params.Descuento, params.SerID, params.Usos).Error
+		params.Descuento, params.SerID, params.Usos,
+		params.TipoDescuento, params.DescuentoValor, params.MaxUsos, params.MaxUsosCliente,
+		params.MontoMinimo, params.Codigo, paquete).Error
 }
 
 func (s *DatabaseService) EliminarPromocion(proID uint) error {
@@ -556,9 +876,58 @@ func (s *DatabaseService) VerHistorialEmpleado(empID uint) ([]models.EmployeeHis
 	return historial, err
 }
 
+// CitaConflictoError is returned when an appointment overlaps another booking
+// of the same employee or client
+type CitaConflictoError struct {
+	CitID         uint
+	MismoEmpleado bool
+}
+
+func (e *CitaConflictoError) Error() string {
+	if e.MismoEmpleado {
+		return fmt.Sprintf("el empleado ya tiene la cita %d en ese horario", e.CitID)
+	}
+	return fmt.Sprintf("el cliente ya tiene la cita %d en ese horario", e.CitID)
+}
+
+// guardarCitaSinConflictos runs the overlap check and the write in one transaction.
+// sp_buscar_conflicto_cita locks the employee and client rows, so concurrent bookings
+// for either of them wait until this transaction commits
+func (s *DatabaseService) guardarCitaSinConflictos(citID uint, fecha, hora string, empID, serID, cliID uint, guardar func(tx *gorm.DB) error) error {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var conflicto models.CitaAgenda
+	if err := tx.Raw("CALL sp_buscar_conflicto_cita(?, ?, ?, ?, ?, ?)",
+		citID, fecha, hora, empID, serID, cliID).Scan(&conflicto).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if conflicto.CitID != 0 {
+		tx.Rollback()
+		return &CitaConflictoError{CitID: conflicto.CitID, MismoEmpleado: conflicto.EmpID == empID}
+	}
+
+	if err := guardar(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
 func (s *DatabaseService) InsertarCita(fecha string, hora string, empID, serID, cliID uint) error {
-	return s.DB.Exec("CALL sp_insertar_cita(?, ?, ?, ?, ?)",
-		fecha, hora, empID, serID, cliID).Error
+	return s.guardarCitaSinConflictos(0, fecha, hora, empID, serID, cliID, func(tx *gorm.DB) error {
+		return tx.Exec("CALL sp_insertar_cita(?, ?, ?, ?, ?)",
+			fecha, hora, empID, serID, cliID).Error
+	})
 }
 
 func (s *DatabaseService) ListarCitas() ([]models.Cita, error) {
@@ -577,20 +946,87 @@ func (s *DatabaseService) BuscarCitaPorID(citID uint) (*models.Cita, error) {
 }
 
 func (s *DatabaseService) ActualizarCita(citID uint, fecha string, hora string, empID, serID, cliID uint) error {
-	return s.DB.Exec("CALL sp_actualizar_cita(?, ?, ?, ?, ?, ?)",
-		citID, fecha, hora, empID, serID, cliID).Error
+	return s.guardarCitaSinConflictos(citID, fecha, hora, empID, serID, cliID, func(tx *gorm.DB) error {
+		return tx.Exec("CALL sp_actualizar_cita(?, ?, ?, ?, ?, ?)",
+			citID, fecha, hora, empID, serID, cliID).Error
+	})
 }
 
 func (s *DatabaseService) EliminarCita(citID uint) error {
 	return s.DB.Exec("CALL sp_eliminar_cita(?)", citID).Error
 }
 
+// ErrTransicionCitaNoPermitida is returned when sp_cambiar_estado_cita rejects the transition,
+// e.g. because the appointment changed status after it was read
+var ErrTransicionCitaNoPermitida = errors.New("transición de estado de cita no permitida")
+
+// ErrStockInsuficienteReceta is returned when completing or invoicing an appointment and a product of
+// the service's recipe does not have the units the recipe uses; nothing is deducted
+var ErrStockInsuficienteReceta = errors.New("stock insuficiente para la receta del servicio")
+
+// errConsumoReceta maps the SIGNAL of sp_descontar_productos_cita for a recipe without enough stock
+func errConsumoReceta(err error) error {
+	if esSenalSP(err, "Stock insuficiente para la receta del servicio") {
+		return ErrStockInsuficienteReceta
+	}
+	return err
+}
+
+// CambiarEstadoCita moves an appointment to a new status; sp_cambiar_estado_cita
+// rejects transitions that are not allowed from the current status
+func (s *DatabaseService) CambiarEstadoCita(citID uint, estado, observaciones, usuario string) error {
+	err := s.DB.Exec("CALL sp_cambiar_estado_cita(?, ?, ?, ?)", citID, estado, observaciones, usuario).Error
+	if esSenalSP(err, "Transición de estado de cita no permitida") {
+		return ErrTransicionCitaNoPermitida
+	}
+	return errConsumoReceta(err)
+}
+
+// CancelarCitaCliente cancels an appointment owned by cliID; tardia records that the
+// cancellation happened inside the minimum notice period
+func (s *DatabaseService) CancelarCitaCliente(citID, cliID uint, tardia bool, motivo string) error {
+	return s.DB.Exec("CALL sp_cancelar_cita_cliente(?, ?, ?, ?)", citID, cliID, tardia, motivo).Error
+}
+
+func (s *DatabaseService) ListarCancelacionesTardias() ([]models.Cita, error) {
+	var citas []models.Cita
+	err := s.DB.Raw("CALL sp_listar_cancelaciones_tardias()").Scan(&citas).Error
+	return citas, err
+}
+
+func (s *DatabaseService) ListarHistorialEstadoCita(citID uint) ([]models.HistorialEstadoCita, error) {
+	var historial []models.HistorialEstadoCita
+	err := s.DB.Raw("CALL sp_listar_historial_estado_cita(?)", citID).Scan(&historial).Error
+	return historial, err
+}
+
 func (s *DatabaseService) VerCitasCliente(cliID uint) ([]models.CitaConDetalles, error) {
 	var citas []models.CitaConDetalles
 	err := s.DB.Raw("CALL sp_ver_citas_cliente(?)", cliID).Scan(&citas).Error
 	return citas, err
 }
 
+// ============= AVAILABILITY PROCEDURES =============
+// empID is optional in these lookups: nil returns the rows for every employee
+
+func (s *DatabaseService) ListarHorariosPorDia(diaSemana string, empID *uint) ([]models.HorarioEmpleado, error) {
+	var horarios []models.HorarioEmpleado
+	err := s.DB.Raw("CALL sp_listar_horarios_por_dia(?, ?)", diaSemana, empID).Scan(&horarios).Error
+	return horarios, err
+}
+
+func (s *DatabaseService) ListarExcepcionesPorFecha(fecha string, empID *uint) ([]models.HorarioExcepcion, error) {
+	var excepciones []models.HorarioExcepcion
+	err := s.DB.Raw("CALL sp_listar_excepciones_por_fecha(?, ?)", fecha, empID).Scan(&excepciones).Error
+	return excepciones, err
+}
+
+func (s *DatabaseService) ListarCitasPorFecha(fecha string, empID *uint) ([]models.CitaAgenda, error) {
+	var citas []models.CitaAgenda
+	err := s.DB.Raw("CALL sp_listar_citas_por_fecha(?, ?)", fecha, empID).Scan(&citas).Error
+	return citas, err
+}
+
 // ============= SERVICE PROCEDURES =============
 
 func (s *DatabaseService) InsertarServicio(nombre, descripcion, categoria string, precioUnitario float64, duracionEstimada int) error {
@@ -622,6 +1058,12 @@ func (s *DatabaseService) EliminarServicio(serID uint) error {
 	return s.DB.Exec("CALL sp_eliminar_servicio(?)", serID).Error
 }
 
+// ActualizarIvaServicio sets the VAT rate included in a service's price; lines already invoiced keep
+// the rate they were billed with
+func (s *DatabaseService) ActualizarIvaServicio(serID uint, ivaPorcentaje float64) error {
+	return s.DB.Exec("CALL sp_actualizar_iva_servicio(?, ?)", serID, ivaPorcentaje).Error
+}
+
 // ============= INVOICE PROCEDURES =============
 
 func (s *DatabaseService) InsertarFactura(total float64, fecha string, hora string, cliID uint) error {
@@ -629,31 +1071,8 @@ func (s *DatabaseService) InsertarFactura(total float64, fecha string, hora stri
 		total, fecha, hora, cliID).Error
 }
 
-func (s *DatabaseService) InsertarFacturaSinTotal(fecha string, hora string, cliID uint) error {
-	return s.DB.Exec("CALL sp_insertar_factura_sin_total(?, ?, ?)",
-		fecha, hora, cliID).Error
-}
-
-func (s *DatabaseService) ObtenerUltimaFactura() (*models.FacturaServicio, error) {
-	var factura models.FacturaServicio
-	err := s.DB.Raw("CALL sp_obtener_ultima_factura()").Scan(&factura).Error
-	if err != nil {
-		return nil, err
-	}
-	return &factura, nil
-}
-
 func (s *DatabaseService) RecalcularTotalFactura(facID uint) error {
-	return s.DB.Exec(`
-		UPDATE FACTURA_SERVICIO f
-		SET fac_total = (
-			SELECT COALESCE(SUM(s.ser_precio_unitario), 0)
-			FROM DETALLE_FACTURA_SERVICIO dfs
-			JOIN SERVICIO s ON dfs.ser_id = s.ser_id
-			WHERE dfs.fac_id = ?
-		)
-		WHERE fac_id = ?
-	`, facID, facID).Error
+	return s.DB.Exec("CALL sp_recalcular_total_factura(?)", facID).Error
 }
 
 func (s *DatabaseService) ListarFacturas() ([]models.FacturaServicio, error) {
@@ -671,89 +1090,1114 @@ func (s *DatabaseService) BuscarFacturaPorID(facID uint) (*models.FacturaServici
 	return &factura, nil
 }
 
-func (s *DatabaseService) ActualizarFactura(facID uint, total float64, fecha string, hora string, cliID uint) error {
-	return s.DB.Exec("CALL sp_actualizar_factura(?, ?, ?, ?, ?)",
-		facID, total, fecha, hora, cliID).Error
+// ActualizarFactura updates the header of a draft invoice; its totals are recalculated from the lines
+func (s *DatabaseService) ActualizarFactura(facID uint, fecha string, hora string, cliID uint) error {
+	return s.execEnTransaccion("CALL sp_actualizar_factura(?, ?, ?, ?)",
+		facID, fecha, hora, cliID)
 }
 
-func (s *DatabaseService) EliminarFactura(facID uint) error {
-	return s.DB.Exec("CALL sp_eliminar_factura(?)", facID).Error
+// EliminarFactura deletes a draft invoice; the products sold in it go back to stock as returns.
+// Issued invoices cannot be deleted, they are voided or corrected with credit notes.
+func (s *DatabaseService) EliminarFactura(facID uint, usuario string) error {
+	return s.execEnTransaccion("CALL sp_eliminar_factura(?, ?)", facID, usuario)
 }
 
-// ============= INVOICE DETAIL PROCEDURES =============
+// Invoice states (FACTURA_SERVICIO.fac_estado). Only drafts can be edited or deleted.
+const (
+	EstadoFacturaBorrador = "Borrador"
+	EstadoFacturaEmitida  = "Emitida"
+	EstadoFacturaAnulada  = "Anulada"
+)
 
-func (s *DatabaseService) InsertarDetalleFactura(facID, serID uint) error {
-	return s.DB.Exec("CALL sp_insertar_detalle_factura(?, ?)",
-		facID, serID).Error
-}
+// ErrFacturaNoBorrador is returned when a change is attempted on an invoice that was already issued
+var ErrFacturaNoBorrador = errors.New("la factura ya fue emitida")
 
-func (s *DatabaseService) ListarDetallesFactura() ([]models.DetalleFacturaServicio, error) {
-	var detalles []models.DetalleFacturaServicio
-	err := s.DB.Raw("CALL sp_listar_detalles_factura()").Scan(&detalles).Error
-	return detalles, err
-}
+// ErrFacturaSinLineas is returned when issuing an invoice without services or products
+var ErrFacturaSinLineas = errors.New("la factura no tiene servicios ni productos")
 
-func (s *DatabaseService) BuscarDetallePorFactura(facID uint) ([]models.DetalleFacturaServicio, error) {
-	var detalles []models.DetalleFacturaServicio
-	err := s.DB.Raw("CALL sp_buscar_detalle_por_factura(?)", facID).Scan(&detalles).Error
-	return detalles, err
-}
+// ErrSinResolucionFacturacion is returned when issuing an invoice with no active numbering resolution
+var ErrSinResolucionFacturacion = errors.New("no hay una resolución de facturación activa")
 
-func (s *DatabaseService) ActualizarDetalleFactura(facID, serID uint) error {
-	return s.DB.Exec("CALL sp_actualizar_detalle_factura(?, ?)",
-		facID, serID).Error
-}
+// ErrRangoFacturacionAgotado is returned when the active resolution has no consecutive numbers left
+var ErrRangoFacturacionAgotado = errors.New("se agotó el rango de numeración de la resolución")
 
-func (s *DatabaseService) EliminarDetalleFactura(facID uint) error {
-	return s.DB.Exec("CALL sp_eliminar_detalle_factura(?)", facID).Error
+// ResolucionNoVigenteError is returned when the issue date (today) is outside the validity dates of the
+// active numbering resolution
+type ResolucionNoVigenteError struct {
+	ResNumero string
+	Desde     time.Time
+	Hasta     time.Time
+	Fecha     time.Time
 }
 
-func (s *DatabaseService) ListarFacturasCliente(cliID uint) ([]models.InvoiceDetailResponse, error) {
-	var facturas []models.InvoiceDetailResponse
-	err := s.DB.Raw("CALL sp_listar_facturas_cliente(?)", cliID).Scan(&facturas).Error
-	return facturas, err
+func (e *ResolucionNoVigenteError) Error() string {
+	return fmt.Sprintf("la fecha %s está fuera de la vigencia de la resolución %s (%s a %s)",
+		e.Fecha.Format("2006-01-02"), e.ResNumero, e.Desde.Format("2006-01-02"), e.Hasta.Format("2006-01-02"))
 }
 
-// ============= PURCHASE PROCEDURES =============
-
-func (s *DatabaseService) InsertarCompra(fecha string, total float64, metodoPago string, provID, gasID uint) error {
-	return s.DB.Exec("CALL sp_insertar_compra(?, ?, ?, ?, ?)",
-		fecha, total, metodoPago, provID, gasID).Error
+// emitirFactura assigns the next consecutive of the active resolution and issues the invoice. The
+// resolution stays locked until the caller's transaction ends, so numbers are never repeated and a
+// rolled back invoice does not leave a gap.
+func emitirFactura(tx *gorm.DB, factura *models.FacturaServicio) error {
+	var resolucion models.ResolucionFacturacion
+	if err := tx.Raw("CALL sp_bloquear_resolucion_activa()").Scan(&resolucion).Error; err != nil {
+		return err
+	}
+	if resolucion.ResID == 0 {
+		return ErrSinResolucionFacturacion
+	}
+	// The invoice is issued today, whatever date was typed in the draft
+	now := time.Now()
+	hoy := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if hoy.Before(resolucion.ResFechaDesde) || hoy.After(resolucion.ResFechaHasta) {
+		return &ResolucionNoVigenteError{
+			ResNumero: resolucion.ResNumero,
+			Desde:     resolucion.ResFechaDesde,
+			Hasta:     resolucion.ResFechaHasta,
+			Fecha:     hoy,
+		}
+	}
+	if resolucion.ConsecutivosDisponibles <= 0 {
+		return ErrRangoFacturacionAgotado
+	}
+	return tx.Exec("CALL sp_emitir_factura(?)", factura.FacID).Error
 }
 
-func (s *DatabaseService) BuscarCompraPorID(comID uint) (*models.PurchaseWithDetails, error) {
-	var compra models.PurchaseWithDetails
-	err := s.DB.Raw("CALL sp_buscar_compra_por_id(?)", comID).Scan(&compra).Error
-	if err != nil {
+// EmitirFactura issues a draft invoice; from then on it can only be voided or corrected with credit notes
+func (s *DatabaseService) EmitirFactura(facID uint) (*models.FacturaServicio, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+	fail := func(err error) (*models.FacturaServicio, error) {
+		tx.Rollback()
 		return nil, err
 	}
-	return &compra, nil
-}
 
-func (s *DatabaseService) ObtenerUltimaCompra() (*models.Purchase, error) {
-	var compra models.Purchase
-	err := s.DB.Raw("CALL sp_obtener_ultima_compra()").Scan(&compra).Error
-	if err != nil {
+	var factura models.FacturaServicio
+	if err := tx.Raw("CALL sp_bloquear_factura(?)", facID).Scan(&factura).Error; err != nil {
+		return fail(err)
+	}
+	if factura.FacID == 0 {
+		return fail(ErrFacturaNoEncontrada)
+	}
+	if factura.FacEstado != EstadoFacturaBorrador {
+		return fail(ErrFacturaNoBorrador)
+	}
+
+	var lineas []models.LineaFactura
+	if err := tx.Raw("CALL sp_listar_lineas_factura(?)", facID).Scan(&lineas).Error; err != nil {
+		return fail(err)
+	}
+	var productos []models.LineaProductoFactura
+	if err := tx.Raw("CALL sp_listar_productos_factura(?)", facID).Scan(&productos).Error; err != nil {
+		return fail(err)
+	}
+	if len(lineas) == 0 && len(productos) == 0 {
+		return fail(ErrFacturaSinLineas)
+	}
+
+	if err := emitirFactura(tx, &factura); err != nil {
+		return fail(err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		return nil, err
 	}
-	return &compra, nil
+	return s.BuscarFacturaPorID(facID)
 }
 
-func (s *DatabaseService) ActualizarCompra(comID uint, fecha string, total float64, metodoPago string, provID, gasID uint) error {
-	return s.DB.Exec("CALL sp_actualizar_compra(?, ?, ?, ?, ?, ?)",
-		comID, fecha, total, metodoPago, provID, gasID).Error
-}
+// ============= APPOINTMENT CHECKOUT PROCEDURES =============
 
-func (s *DatabaseService) EliminarCompra(comID uint) error {
-	return s.DB.Exec("CALL sp_eliminar_compra(?)", comID).Error
+// Appointment statuses stored in CITA.cit_estado. Only completed appointments can be invoiced.
+const (
+	EstadoCitaProgramada = "Programada"
+	EstadoCitaConfirmada = "Confirmada"
+	EstadoCitaEnCurso    = "En Curso"
+	EstadoCitaCompletada = "Completada"
+	EstadoCitaCancelada  = "Cancelada"
+	EstadoCitaNoAsistio  = "No Asistio"
+)
+
+// Reasons an appointment cannot be added to an invoice
+const (
+	MotivoCitaNoEncontrada = "no_encontrada"
+	MotivoCitaNoCompletada = "no_completada"
+	MotivoCitaOtroCliente  = "otro_cliente"
+	MotivoCitaYaFacturada  = "ya_facturada"
+)
+
+// CitaNoFacturableError is returned by FacturarCitas when one of the appointments cannot be billed
+type CitaNoFacturableError struct {
+	CitID  uint
+	FacID  uint // Invoice that already billed the appointment, for MotivoCitaYaFacturada
+	Motivo string
+}
+
+func (e *CitaNoFacturableError) Error() string {
+	return fmt.Sprintf("la cita %d no se puede facturar: %s", e.CitID, e.Motivo)
+}
+
+// FacturarCitas creates and issues a single invoice for completed appointments of the same client
+// and links the appointments to it; promotions are applied by the rules engine. The appointments stay
+// locked until the transaction ends, so two checkouts cannot bill the same visit.
+func (s *DatabaseService) FacturarCitas(citIDs []uint, fecha, hora, usuario string) (*models.FacturaServicio, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+	fail := func(err error) (*models.FacturaServicio, error) {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var cliID uint
+	citas := make([]models.Cita, 0, len(citIDs))
+	for _, citID := range citIDs {
+		var cita models.Cita
+		if err := tx.Raw("CALL sp_bloquear_cita(?)", citID).Scan(&cita).Error; err != nil {
+			return fail(err)
+		}
+
+		switch {
+		case cita.CitID == 0:
+			return fail(&CitaNoFacturableError{CitID: citID, Motivo: MotivoCitaNoEncontrada})
+		case cita.FacID != nil:
+			return fail(&CitaNoFacturableError{CitID: citID, FacID: *cita.FacID, Motivo: MotivoCitaYaFacturada})
+		case cita.CitEstado != EstadoCitaCompletada:
+			return fail(&CitaNoFacturableError{CitID: citID, Motivo: MotivoCitaNoCompletada})
+		case cliID != 0 && cita.CliID != cliID:
+			return fail(&CitaNoFacturableError{CitID: citID, Motivo: MotivoCitaOtroCliente})
+		}
+
+		cliID = cita.CliID
+		citas = append(citas, cita)
+	}
+
+	// One line per appointment, performed by the appointment's employee; the same service may repeat
+	servicios := make([]ServicioFacturaParams, 0, len(citas))
+	for _, cita := range citas {
+		empID := cita.EmpID
+		servicios = append(servicios, ServicioFacturaParams{SerID: cita.SerID, Cantidad: 1, EmpID: &empID})
+	}
+	evaluacion, err := evaluarPromociones(tx, cliID, fecha, servicios, "")
+	if err != nil {
+		return fail(err)
+	}
+
+	var factura models.FacturaServicio
+	if err := tx.Raw("CALL sp_insertar_factura_cliente(?, ?, ?)", fecha, hora, cliID).Scan(&factura).Error; err != nil {
+		return fail(err)
+	}
+
+	if err := insertarLineasFactura(tx, factura.FacID, evaluacion); err != nil {
+		return fail(err)
+	}
+	for _, cita := range citas {
+		if err := tx.Exec("CALL sp_vincular_cita_factura(?, ?)", cita.CitID, factura.FacID).Error; err != nil {
+			return fail(err)
+		}
+		// Appointments completed while their service had no recipe deduct it when invoiced
+		if err := tx.Exec("CALL sp_descontar_productos_cita(?, ?)", cita.CitID, usuario).Error; err != nil {
+			return fail(errConsumoReceta(err))
+		}
+	}
+
+	if err := emitirFactura(tx, &factura); err != nil {
+		return fail(err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.BuscarFacturaPorID(factura.FacID)
+}
+
+func (s *DatabaseService) ListarLineasFactura(facID uint) ([]models.LineaFactura, error) {
+	var lineas []models.LineaFactura
+	err := s.DB.Raw("CALL sp_listar_lineas_factura(?)", facID).Scan(&lineas).Error
+	return lineas, err
+}
+
+func (s *DatabaseService) ListarCitasFactura(facID uint) ([]models.Cita, error) {
+	var citas []models.Cita
+	err := s.DB.Raw("CALL sp_listar_citas_factura(?)", facID).Scan(&citas).Error
+	return citas, err
 }
 
-func (s *DatabaseService) ListarCompras() ([]models.PurchaseWithProducts, error) {
+// ============= INVOICE DETAIL PROCEDURES =============
+
+// execEnTransaccion runs a procedure call in its own transaction so the invoice line,
+// the promotion usage counter and the invoice total change together
+func (s *DatabaseService) execEnTransaccion(sql string, values ...interface{}) error {
+	return s.enTransaccion(func(tx *gorm.DB) error {
+		return tx.Exec(sql, values...).Error
+	})
+}
+
+// enTransaccion runs guardar in its own transaction, rolling back when it fails
+func (s *DatabaseService) enTransaccion(guardar func(tx *gorm.DB) error) error {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := guardar(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// InsertarDetalleFactura adds a new service line, even if the service is already on the invoice.
+// The promotion rules of a new invoice decide its discount (see agregarServicioFactura), so a
+// promotion already on the invoice is not applied to a repeated service again.
+func (s *DatabaseService) InsertarDetalleFactura(facID uint, servicio ServicioFacturaParams) error {
+	return s.enTransaccion(func(tx *gorm.DB) error {
+		return agregarServicioFactura(tx, facID, servicio)
+	})
+}
+
+// EliminarLineaFactura removes one service line; a promotion on the line is withdrawn from the
+// whole invoice and its use given back
+func (s *DatabaseService) EliminarLineaFactura(facID, dfsID uint) error {
+	return s.execEnTransaccion("CALL sp_eliminar_linea_factura(?, ?)", facID, dfsID)
+}
+
+func (s *DatabaseService) ListarDetallesFactura() ([]models.DetalleFacturaServicio, error) {
+	var detalles []models.DetalleFacturaServicio
+	err := s.DB.Raw("CALL sp_listar_detalles_factura()").Scan(&detalles).Error
+	return detalles, err
+}
+
+func (s *DatabaseService) BuscarDetallePorFactura(facID uint) ([]models.DetalleFacturaServicio, error) {
+	var detalles []models.DetalleFacturaServicio
+	err := s.DB.Raw("CALL sp_buscar_detalle_por_factura(?)", facID).Scan(&detalles).Error
+	return detalles, err
+}
+
+// ActualizarDetalleFactura replaces the service lines of a draft invoice with a single line of serID
+func (s *DatabaseService) ActualizarDetalleFactura(facID, serID uint) error {
+	return s.enTransaccion(func(tx *gorm.DB) error {
+		if err := tx.Exec("CALL sp_eliminar_detalle_factura(?)", facID).Error; err != nil {
+			return err
+		}
+		return agregarServicioFactura(tx, facID, ServicioFacturaParams{SerID: serID, Cantidad: 1})
+	})
+}
+
+func (s *DatabaseService) EliminarDetalleFactura(facID uint) error {
+	return s.execEnTransaccion("CALL sp_eliminar_detalle_factura(?)", facID)
+}
+
+func (s *DatabaseService) ListarFacturasCliente(cliID uint) ([]models.InvoiceDetailResponse, error) {
+	var facturas []models.InvoiceDetailResponse
+	err := s.DB.Raw("CALL sp_listar_facturas_cliente(?)", cliID).Scan(&facturas).Error
+	return facturas, err
+}
+
+// ============= INVOICE PRODUCT PROCEDURES =============
+
+// ErrProductoNoEncontrado is returned when an invoice references an unknown product
+var ErrProductoNoEncontrado = errors.New("producto no encontrado")
+
+// StockInsuficienteError is returned when an invoice sells more units of a product than are in stock
+type StockInsuficienteError struct {
+	ProdID     uint
+	Disponible int
+	Solicitado int
+}
+
+func (e *StockInsuficienteError) Error() string {
+	return fmt.Sprintf("stock insuficiente del producto %d: disponible %d, solicitado %d", e.ProdID, e.Disponible, e.Solicitado)
+}
+
+// ProductoFacturaParams is a retail product sold on an invoice; Descuento applies to the whole line
+type ProductoFacturaParams struct {
+	ProdID    uint
+	Cantidad  int
+	Descuento float64
+}
+
+// verificarStockProductos checks that every product exists and has enough stock for the units sold,
+// adding up repeated products. sp_registrar_movimiento_inventario checks the stock again under lock.
+func verificarStockProductos(db *gorm.DB, productos []ProductoFacturaParams) error {
+	solicitados := make(map[uint]int, len(productos))
+	for _, producto := range productos {
+		solicitados[producto.ProdID] += producto.Cantidad
+	}
+
+	for _, producto := range productos {
+		cantidad, pendiente := solicitados[producto.ProdID]
+		if !pendiente {
+			continue
+		}
+		delete(solicitados, producto.ProdID)
+
+		var actual models.Product
+		if err := db.Raw("CALL sp_buscar_producto_por_id(?)", producto.ProdID).Scan(&actual).Error; err != nil {
+			return err
+		}
+		if actual.ProdID == 0 {
+			return fmt.Errorf("%w: %d", ErrProductoNoEncontrado, producto.ProdID)
+		}
+		if actual.ProdCantidadDisponible < cantidad {
+			return &StockInsuficienteError{ProdID: producto.ProdID, Disponible: actual.ProdCantidadDisponible, Solicitado: cantidad}
+		}
+	}
+	return nil
+}
+
+// InsertarProductoFactura sells a product on an existing invoice: the units leave the stock as a
+// sale, the line is priced at the current product price and the invoice total is recalculated
+func (s *DatabaseService) InsertarProductoFactura(facID uint, producto ProductoFacturaParams, usuario string) error {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := verificarStockProductos(tx, []ProductoFacturaParams{producto}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err := tx.Exec("CALL sp_insertar_detalle_factura_producto(?, ?, ?, ?, ?)",
+		facID, producto.ProdID, producto.Cantidad, producto.Descuento, usuario).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// EliminarProductoFactura removes a product line from an invoice and returns its units to stock
+func (s *DatabaseService) EliminarProductoFactura(facID, prodID uint, usuario string) error {
+	return s.execEnTransaccion("CALL sp_eliminar_detalle_factura_producto(?, ?, ?)",
+		facID, prodID, usuario)
+}
+
+func (s *DatabaseService) ListarProductosFactura(facID uint) ([]models.LineaProductoFactura, error) {
+	var lineas []models.LineaProductoFactura
+	err := s.DB.Raw("CALL sp_listar_productos_factura(?)", facID).Scan(&lineas).Error
+	return lineas, err
+}
+
+// ============= CLIENT PAYMENT PROCEDURES =============
+
+// Client payment methods (PAGO_CLIENTE.pag_cli_metodo)
+const (
+	MetodoPagoEfectivo         = "Efectivo"
+	MetodoPagoTarjeta          = "Tarjeta"
+	MetodoPagoTransferencia    = "Transferencia"
+	MetodoPagoBilleteraDigital = "Billetera Digital"
+)
+
+// Invoice payment states (FACTURA_SERVICIO.fac_estado_pago)
+const (
+	EstadoPagoPendiente = "Pendiente"
+	EstadoPagoParcial   = "Parcial"
+	EstadoPagoPagada    = "Pagada"
+)
+
+// EsMetodoPago reports whether metodo is one of the accepted client payment methods
+func EsMetodoPago(metodo string) bool {
+	switch metodo {
+	case MetodoPagoEfectivo, MetodoPagoTarjeta, MetodoPagoTransferencia, MetodoPagoBilleteraDigital:
+		return true
+	}
+	return false
+}
+
+// ErrFacturaNoEncontrada is returned when a payment references an unknown invoice
+var ErrFacturaNoEncontrada = errors.New("factura no encontrada")
+
+// ErrFacturaPagada is returned when a payment is registered on an invoice with no balance left
+var ErrFacturaPagada = errors.New("la factura ya está pagada")
+
+// ErrFacturaNoEmitida is returned when a payment or a credit note targets a draft or voided invoice
+var ErrFacturaNoEmitida = errors.New("la factura no está emitida")
+
+// SaldoFactura returns what is still owed on an invoice: the total minus the credit notes minus
+// the payments that were not refunded
+func SaldoFactura(factura *models.FacturaServicio) float64 {
+	return redondear(factura.FacTotal - factura.FacAcreditado - factura.FacPagado + factura.FacReembolsado)
+}
+
+// PagoExcedeSaldoError is returned when a non-cash tender is larger than the invoice balance it
+// would be applied to; only cash can exceed the balance and return change
+type PagoExcedeSaldoError struct {
+	Metodo string
+	Monto  float64
+	Saldo  float64
+}
+
+func (e *PagoExcedeSaldoError) Error() string {
+	return fmt.Sprintf("el pago con %s por %.2f supera el saldo de %.2f", e.Metodo, e.Monto, e.Saldo)
+}
+
+// PagoClienteParams is one tender of a client payment; Monto is the amount handed over
+type PagoClienteParams struct {
+	Metodo     string
+	Monto      float64
+	Entidad    string
+	Referencia string
+}
+
+// RegistrarPagosFactura applies one or more tenders to an invoice's balance in a single transaction
+// and returns the saved payments. Cash tenders are applied last, so the change is calculated on
+// whatever the card, transfer and wallet tenders leave unpaid.
+func (s *DatabaseService) RegistrarPagosFactura(facID uint, pagos []PagoClienteParams, usuario string) ([]models.ClientPayment, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+	fail := func(err error) ([]models.ClientPayment, error) {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var factura models.FacturaServicio
+	if err := tx.Raw("CALL sp_bloquear_factura(?)", facID).Scan(&factura).Error; err != nil {
+		return fail(err)
+	}
+	if factura.FacID == 0 {
+		return fail(ErrFacturaNoEncontrada)
+	}
+	if factura.FacEstado != EstadoFacturaEmitida {
+		return fail(ErrFacturaNoEmitida)
+	}
+
+	ordenados := make([]PagoClienteParams, 0, len(pagos))
+	for _, pago := range pagos {
+		if pago.Metodo != MetodoPagoEfectivo {
+			ordenados = append(ordenados, pago)
+		}
+	}
+	for _, pago := range pagos {
+		if pago.Metodo == MetodoPagoEfectivo {
+			ordenados = append(ordenados, pago)
+		}
+	}
+
+	saldo := SaldoFactura(&factura)
+	for _, pago := range ordenados {
+		if saldo <= 0 {
+			return fail(ErrFacturaPagada)
+		}
+		if pago.Metodo != MetodoPagoEfectivo && pago.Monto > saldo {
+			return fail(&PagoExcedeSaldoError{Metodo: pago.Metodo, Monto: pago.Monto, Saldo: saldo})
+		}
+		saldo = redondear(saldo - math.Min(pago.Monto, saldo))
+	}
+
+	registrados := make([]models.ClientPayment, 0, len(ordenados))
+	for _, pago := range ordenados {
+		var registrado models.ClientPayment
+		err := tx.Raw("CALL sp_registrar_pago_cliente(?, ?, ?, ?, ?, ?)",
+			facID, pago.Metodo, pago.Monto, textoOpcional(pago.Entidad), textoOpcional(pago.Referencia), usuario).Scan(&registrado).Error
+		if err != nil {
+			return fail(err)
+		}
+		registrados = append(registrados, registrado)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return registrados, nil
+}
+
+func (s *DatabaseService) ListarPagosFactura(facID uint) ([]models.ClientPayment, error) {
+	var pagos []models.ClientPayment
+	err := s.DB.Raw("CALL sp_listar_pagos_factura(?)", facID).Scan(&pagos).Error
+	return pagos, err
+}
+
+// ReporteSaldosPendientes lists the invoices with an outstanding balance, oldest first; a nil
+// cliID includes every client
+func (s *DatabaseService) ReporteSaldosPendientes(cliID *uint) ([]models.SaldoPendiente, error) {
+	var saldos []models.SaldoPendiente
+	err := s.DB.Raw("CALL sp_reporte_saldos_pendientes(?)", cliID).Scan(&saldos).Error
+	return saldos, err
+}
+
+// ============= CREDIT NOTE PROCEDURES =============
+
+// Credit note types (NOTA_CREDITO.nc_tipo)
+const (
+	TipoNotaCreditoAnulacion  = "Anulación"
+	TipoNotaCreditoDevolucion = "Devolución"
+)
+
+// Reasons an invoice cannot be voided, as returned by sp_anular_factura
+const (
+	MotivoAnulacionNoEmitida       = "no_emitida"
+	MotivoAnulacionFueraDePlazo    = "fuera_de_plazo"
+	MotivoAnulacionConNotasCredito = "con_notas_credito"
+)
+
+// FacturaNoAnulableError is returned by AnularFactura when the invoice is not issued, was not
+// issued today or already has credit notes
+type FacturaNoAnulableError struct {
+	FacID  uint
+	Motivo string
+}
+
+func (e *FacturaNoAnulableError) Error() string {
+	return fmt.Sprintf("la factura %d no se puede anular: %s", e.FacID, e.Motivo)
+}
+
+// AnularFactura voids an invoice issued today: a credit note reverses every line, the products go
+// back to stock, what was paid is refunded, the promotion uses are given back and the billed
+// appointments can be invoiced again. The invoice itself is kept, marked as Anulada.
+func (s *DatabaseService) AnularFactura(facID uint, motivo, usuario string) (*models.NotaCredito, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+	fail := func(err error) (*models.NotaCredito, error) {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var factura models.FacturaServicio
+	if err := tx.Raw("CALL sp_bloquear_factura(?)", facID).Scan(&factura).Error; err != nil {
+		return fail(err)
+	}
+	if factura.FacID == 0 {
+		return fail(ErrFacturaNoEncontrada)
+	}
+
+	var resultado struct {
+		NcID    *uint   `gorm:"column:nc_id"`
+		Rechazo *string `gorm:"column:rechazo"`
+	}
+	err := tx.Raw("CALL sp_anular_factura(?, ?, ?)", facID, motivo, usuario).Scan(&resultado).Error
+	if err != nil {
+		return fail(err)
+	}
+	if resultado.Rechazo != nil {
+		return fail(&FacturaNoAnulableError{FacID: facID, Motivo: *resultado.Rechazo})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return s.BuscarNotaCredito(*resultado.NcID)
+}
+
+// ErrLineaFacturaNoEncontrada is returned when a credit note references a line that is not on the invoice
+var ErrLineaFacturaNoEncontrada = errors.New("línea de factura no encontrada")
+
+// ErrFacturaAcreditada is returned when a full credit note is requested for an invoice with nothing
+// left to credit
+var ErrFacturaAcreditada = errors.New("la factura ya fue acreditada por completo")
+
+// CantidadAcreditadaExcedeError is returned when a credit note credits more units of a line than
+// were invoiced and not yet credited
+type CantidadAcreditadaExcedeError struct {
+	DfsID      uint
+	ProdID     uint
+	Disponible int
+	Solicitado int
+}
+
+func (e *CantidadAcreditadaExcedeError) Error() string {
+	if e.ProdID != 0 {
+		return fmt.Sprintf("el producto %d solo tiene %d unidades por devolver, se pidieron %d", e.ProdID, e.Disponible, e.Solicitado)
+	}
+	return fmt.Sprintf("la línea %d solo tiene %d unidades por acreditar, se pidieron %d", e.DfsID, e.Disponible, e.Solicitado)
+}
+
+// LineaNotaCreditoParams is a line credited by a credit note: a service line (DfsID) or a product
+// sold on the invoice (ProdID)
+type LineaNotaCreditoParams struct {
+	DfsID    uint
+	ProdID   uint
+	Cantidad int
+}
+
+// CrearNotaCredito registers a refund on an issued invoice. Each line is credited in proportion to
+// its net total and returned products go back to stock; a nil lineas credits everything not yet
+// credited. If the client already paid more than what is left to collect, the difference is refunded
+// with metodoReembolso (cash when empty).
+func (s *DatabaseService) CrearNotaCredito(facID uint, motivo, metodoReembolso string, lineas []LineaNotaCreditoParams, usuario string) (*models.NotaCredito, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+	fail := func(err error) (*models.NotaCredito, error) {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var factura models.FacturaServicio
+	if err := tx.Raw("CALL sp_bloquear_factura(?)", facID).Scan(&factura).Error; err != nil {
+		return fail(err)
+	}
+	if factura.FacID == 0 {
+		return fail(ErrFacturaNoEncontrada)
+	}
+	if factura.FacEstado != EstadoFacturaEmitida {
+		return fail(ErrFacturaNoEmitida)
+	}
+
+	lineas, err := lineasAcreditables(tx, facID, lineas)
+	if err != nil {
+		return fail(err)
+	}
+
+	var nota models.NotaCredito
+	err = tx.Raw("CALL sp_insertar_nota_credito(?, ?, ?, ?, ?)",
+		facID, TipoNotaCreditoDevolucion, motivo, textoOpcional(metodoReembolso), usuario).Scan(&nota).Error
+	if err != nil {
+		return fail(err)
+	}
+
+	for _, linea := range lineas {
+		if linea.ProdID != 0 {
+			err = tx.Exec("CALL sp_acreditar_producto_factura(?, ?, ?, ?)", nota.NcID, linea.ProdID, linea.Cantidad, usuario).Error
+		} else {
+			err = tx.Exec("CALL sp_acreditar_linea_servicio(?, ?, ?)", nota.NcID, linea.DfsID, linea.Cantidad).Error
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := tx.Exec("CALL sp_aplicar_nota_credito(?)", nota.NcID).Error; err != nil {
+		return fail(err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return s.BuscarNotaCredito(nota.NcID)
+}
+
+// lineasAcreditables checks the requested lines against the invoice lines and what earlier credit
+// notes already credited, adding up repeated lines. A nil lineas returns every unit still creditable.
+func lineasAcreditables(db *gorm.DB, facID uint, lineas []LineaNotaCreditoParams) ([]LineaNotaCreditoParams, error) {
+	var servicios []models.LineaFactura
+	if err := db.Raw("CALL sp_listar_lineas_factura(?)", facID).Scan(&servicios).Error; err != nil {
+		return nil, err
+	}
+	var productos []models.LineaProductoFactura
+	if err := db.Raw("CALL sp_listar_productos_factura(?)", facID).Scan(&productos).Error; err != nil {
+		return nil, err
+	}
+
+	disponiblesServicio := make(map[uint]int, len(servicios))
+	for _, servicio := range servicios {
+		disponiblesServicio[servicio.DfsID] = servicio.DfsCantidad - servicio.CantidadAcreditada
+	}
+	disponiblesProducto := make(map[uint]int, len(productos))
+	for _, producto := range productos {
+		disponiblesProducto[producto.ProdID] = producto.DfpCantidad - producto.CantidadAcreditada
+	}
+
+	if lineas == nil {
+		for _, servicio := range servicios {
+			if cantidad := disponiblesServicio[servicio.DfsID]; cantidad > 0 {
+				lineas = append(lineas, LineaNotaCreditoParams{DfsID: servicio.DfsID, Cantidad: cantidad})
+			}
+		}
+		for _, producto := range productos {
+			if cantidad := disponiblesProducto[producto.ProdID]; cantidad > 0 {
+				lineas = append(lineas, LineaNotaCreditoParams{ProdID: producto.ProdID, Cantidad: cantidad})
+			}
+		}
+		if len(lineas) == 0 {
+			return nil, ErrFacturaAcreditada
+		}
+		return lineas, nil
+	}
+
+	solicitadosServicio := make(map[uint]int)
+	solicitadosProducto := make(map[uint]int)
+	for _, linea := range lineas {
+		if linea.ProdID != 0 {
+			disponible, existe := disponiblesProducto[linea.ProdID]
+			if !existe {
+				return nil, fmt.Errorf("%w: producto %d", ErrLineaFacturaNoEncontrada, linea.ProdID)
+			}
+			solicitadosProducto[linea.ProdID] += linea.Cantidad
+			if solicitadosProducto[linea.ProdID] > disponible {
+				return nil, &CantidadAcreditadaExcedeError{ProdID: linea.ProdID, Disponible: disponible, Solicitado: solicitadosProducto[linea.ProdID]}
+			}
+			continue
+		}
+
+		disponible, existe := disponiblesServicio[linea.DfsID]
+		if !existe {
+			return nil, fmt.Errorf("%w: %d", ErrLineaFacturaNoEncontrada, linea.DfsID)
+		}
+		solicitadosServicio[linea.DfsID] += linea.Cantidad
+		if solicitadosServicio[linea.DfsID] > disponible {
+			return nil, &CantidadAcreditadaExcedeError{DfsID: linea.DfsID, Disponible: disponible, Solicitado: solicitadosServicio[linea.DfsID]}
+		}
+	}
+	return lineas, nil
+}
+
+// BuscarNotaCredito returns a credit note with its lines; NcID is 0 when it does not exist
+func (s *DatabaseService) BuscarNotaCredito(ncID uint) (*models.NotaCredito, error) {
+	var nota models.NotaCredito
+	if err := s.DB.Raw("CALL sp_buscar_nota_credito(?)", ncID).Scan(&nota).Error; err != nil {
+		return nil, err
+	}
+	if nota.NcID == 0 {
+		return &nota, nil
+	}
+
+	var lineas []models.LineaNotaCredito
+	if err := s.DB.Raw("CALL sp_listar_lineas_notas_credito_factura(?)", nota.FacID).Scan(&lineas).Error; err != nil {
+		return nil, err
+	}
+	for _, linea := range lineas {
+		if linea.NcID == ncID {
+			nota.Lineas = append(nota.Lineas, linea)
+		}
+	}
+	return &nota, nil
+}
+
+// ListarNotasCreditoFactura returns the credit notes of an invoice, oldest first, with their lines
+func (s *DatabaseService) ListarNotasCreditoFactura(facID uint) ([]models.NotaCredito, error) {
+	var notas []models.NotaCredito
+	if err := s.DB.Raw("CALL sp_listar_notas_credito_factura(?)", facID).Scan(&notas).Error; err != nil {
+		return nil, err
+	}
+
+	var lineas []models.LineaNotaCredito
+	if err := s.DB.Raw("CALL sp_listar_lineas_notas_credito_factura(?)", facID).Scan(&lineas).Error; err != nil {
+		return nil, err
+	}
+	for i := range notas {
+		for _, linea := range lineas {
+			if linea.NcID == notas[i].NcID {
+				notas[i].Lineas = append(notas[i].Lineas, linea)
+			}
+		}
+	}
+	return notas, nil
+}
+
+// ============= INVOICE NUMBERING AND TAX PROCEDURES =============
+
+// ResolucionFacturacionParams is a numbering resolution to register; fechas use the 2006-01-02 layout
+type ResolucionFacturacionParams struct {
+	Numero       string
+	Prefijo      string
+	RangoDesde   int
+	RangoHasta   int
+	FechaDesde   string
+	FechaHasta   string
+	Activa       bool
+	ClaveTecnica string // Technical key issued with the resolution, used in the CUFE
+}
+
+// CrearResolucionFacturacion registers a numbering resolution. An active resolution replaces the one
+// that was numbering invoices until now.
+func (s *DatabaseService) CrearResolucionFacturacion(params ResolucionFacturacionParams) (*models.ResolucionFacturacion, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var resolucion models.ResolucionFacturacion
+	err := tx.Raw("CALL sp_insertar_resolucion_facturacion(?, ?, ?, ?, ?, ?, ?, ?)",
+		params.Numero, params.Prefijo, params.RangoDesde, params.RangoHasta,
+		params.FechaDesde, params.FechaHasta, params.Activa, textoOpcional(params.ClaveTecnica)).Scan(&resolucion).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return s.BuscarResolucionFacturacion(resolucion.ResID)
+}
+
+func (s *DatabaseService) ListarResolucionesFacturacion() ([]models.ResolucionFacturacion, error) {
+	var resoluciones []models.ResolucionFacturacion
+	err := s.DB.Raw("CALL sp_listar_resoluciones_facturacion()").Scan(&resoluciones).Error
+	return resoluciones, err
+}
+
+func (s *DatabaseService) BuscarResolucionFacturacion(resID uint) (*models.ResolucionFacturacion, error) {
+	var resolucion models.ResolucionFacturacion
+	err := s.DB.Raw("CALL sp_buscar_resolucion_facturacion(?)", resID).Scan(&resolucion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &resolucion, nil
+}
+
+// ActivarResolucionFacturacion makes a resolution the one that numbers new invoices and deactivates the rest
+func (s *DatabaseService) ActivarResolucionFacturacion(resID uint) error {
+	return s.DB.Exec("CALL sp_activar_resolucion_facturacion(?)", resID).Error
+}
+
+// ResumenImpuestosFactura returns the VAT of an invoice grouped by rate
+func (s *DatabaseService) ResumenImpuestosFactura(facID uint) ([]models.ImpuestoFactura, error) {
+	var impuestos []models.ImpuestoFactura
+	err := s.DB.Raw("CALL sp_resumen_impuestos_factura(?)", facID).Scan(&impuestos).Error
+	return impuestos, err
+}
+
+// ============= PURCHASE PROCEDURES =============
+
+// Purchase order states
+const (
+	EstadoCompraBorrador             = "Borrador"
+	EstadoCompraAprobada             = "Aprobada"
+	EstadoCompraEnviada              = "Enviada"
+	EstadoCompraParcialmenteRecibida = "Parcialmente Recibida"
+	EstadoCompraRecibida             = "Recibida"
+	EstadoCompraCerrada              = "Cerrada"
+	EstadoCompraCancelada            = "Cancelada"
+)
+
+// transicionesCompra mirrors the transitions accepted by sp_cambiar_estado_compra; the receipt
+// states are only reached through RecibirCompra
+var transicionesCompra = map[string][]string{
+	EstadoCompraBorrador:             {EstadoCompraAprobada, EstadoCompraCancelada},
+	EstadoCompraAprobada:             {EstadoCompraEnviada, EstadoCompraCancelada},
+	EstadoCompraEnviada:              {EstadoCompraParcialmenteRecibida, EstadoCompraRecibida, EstadoCompraCancelada},
+	EstadoCompraParcialmenteRecibida: {EstadoCompraRecibida, EstadoCompraCerrada},
+	EstadoCompraRecibida:             {EstadoCompraCerrada},
+}
+
+// EsEstadoCompra reports whether estado is a known purchase order state
+func EsEstadoCompra(estado string) bool {
+	switch estado {
+	case EstadoCompraBorrador, EstadoCompraAprobada, EstadoCompraEnviada, EstadoCompraParcialmenteRecibida,
+		EstadoCompraRecibida, EstadoCompraCerrada, EstadoCompraCancelada:
+		return true
+	}
+	return false
+}
+
+// PuedeCambiarEstadoCompra reports whether a purchase order can move from actual to nuevo
+func PuedeCambiarEstadoCompra(actual, nuevo string) bool {
+	for _, estado := range transicionesCompra[actual] {
+		if estado == nuevo {
+			return true
+		}
+	}
+	return false
+}
+
+// PuedeRecibirCompra reports whether goods can be received for a purchase in the given state
+func PuedeRecibirCompra(estado string) bool {
+	return estado == EstadoCompraEnviada || estado == EstadoCompraParcialmenteRecibida
+}
+
+// DetalleCompraParams is one line of a new purchase
+type DetalleCompraParams struct {
+	ProdID         uint
+	Cantidad       int
+	PrecioUnitario float64
+}
+
+// CrearCompra creates a purchase with all its lines in a single transaction; the purchase ID comes
+// from the insert itself and the stored total is the sum of the lines
+func (s *DatabaseService) CrearCompra(fecha, metodoPago string, provID, gasID uint, detalles []DetalleCompraParams) (*models.Purchase, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	compra, err := crearCompra(tx, fecha, metodoPago, provID, gasID, detalles)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return compra, nil
+}
+
+// CrearComprasReorden turns reorder suggestions into draft purchases, one per preferred supplier,
+// at the estimated cost. Products without a supplier or suggested quantity are skipped. All
+// purchases are created in a single transaction.
+func (s *DatabaseService) CrearComprasReorden(fecha, metodoPago string, gasID uint, productos []models.ProductoReorden) ([]models.Purchase, error) {
+	proveedores := make([]uint, 0)
+	detallesPorProveedor := make(map[uint][]DetalleCompraParams)
+	for _, producto := range productos {
+		if producto.ProvID == nil || producto.CantidadSugerida <= 0 {
+			continue
+		}
+		provID := *producto.ProvID
+		if _, ok := detallesPorProveedor[provID]; !ok {
+			proveedores = append(proveedores, provID)
+		}
+		detallesPorProveedor[provID] = append(detallesPorProveedor[provID], DetalleCompraParams{
+			ProdID:         producto.ProdID,
+			Cantidad:       producto.CantidadSugerida,
+			PrecioUnitario: producto.CostoEstimado,
+		})
+	}
+
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	compras := make([]models.Purchase, 0, len(proveedores))
+	for _, provID := range proveedores {
+		compra, err := crearCompra(tx, fecha, metodoPago, provID, gasID, detallesPorProveedor[provID])
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		compras = append(compras, *compra)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return compras, nil
+}
+
+// crearCompra inserts a draft purchase and its lines inside tx and returns it with the computed total
+func crearCompra(tx *gorm.DB, fecha, metodoPago string, provID, gasID uint, detalles []DetalleCompraParams) (*models.Purchase, error) {
+	var compra models.Purchase
+	if err := tx.Raw("CALL sp_insertar_compra(?, ?, ?, ?)", fecha, metodoPago, provID, gasID).Scan(&compra).Error; err != nil {
+		return nil, err
+	}
+
+	for _, detalle := range detalles {
+		err := tx.Exec("CALL sp_insertar_detalle_compra(?, ?, ?, ?)",
+			compra.ComID, detalle.ProdID, detalle.Cantidad, detalle.PrecioUnitario).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Exec("CALL sp_recalcular_total_compra(?)", compra.ComID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Raw("CALL sp_buscar_compra_por_id(?)", compra.ComID).Scan(&compra).Error; err != nil {
+		return nil, err
+	}
+	return &compra, nil
+}
+
+func (s *DatabaseService) BuscarCompraPorID(comID uint) (*models.PurchaseWithDetails, error) {
+	var compra models.PurchaseWithDetails
+	err := s.DB.Raw("CALL sp_buscar_compra_por_id(?)", comID).Scan(&compra).Error
+	if err != nil {
+		return nil, err
+	}
+	return &compra, nil
+}
+
+// ActualizarCompra updates the header of a purchase; the total is recalculated from its lines
+func (s *DatabaseService) ActualizarCompra(comID uint, fecha string, metodoPago string, provID, gasID uint) error {
+	return s.DB.Exec("CALL sp_actualizar_compra(?, ?, ?, ?, ?)",
+		comID, fecha, metodoPago, provID, gasID).Error
+}
+
+// ErrCompraNoEliminable is returned when deleting a purchase that is neither a draft nor cancelled
+var ErrCompraNoEliminable = errors.New("solo se pueden eliminar compras en borrador o canceladas")
+
+// EliminarCompra deletes a draft or cancelled purchase with its lines
+func (s *DatabaseService) EliminarCompra(comID uint) error {
+	err := s.DB.Exec("CALL sp_eliminar_compra(?)", comID).Error
+	if esSenalSP(err, "Solo se pueden eliminar compras en borrador o canceladas") {
+		return ErrCompraNoEliminable
+	}
+	return err
+}
+
+// ListarCompras lists purchases, optionally filtered by state and supplier (empty or 0 means all)
+func (s *DatabaseService) ListarCompras(estado string, provID uint) ([]models.PurchaseWithProducts, error) {
+	var estadoParam, provParam interface{}
+	if estado != "" {
+		estadoParam = estado
+	}
+	if provID != 0 {
+		provParam = provID
+	}
+
 	var compras []models.PurchaseWithProducts
-	err := s.DB.Raw("CALL sp_listar_compras()").Scan(&compras).Error
+	err := s.DB.Raw("CALL sp_listar_compras(?, ?)", estadoParam, provParam).Scan(&compras).Error
 	return compras, err
 }
 
+// CambiarEstadoCompra moves a purchase order to a new state and records the change in its history
+func (s *DatabaseService) CambiarEstadoCompra(comID uint, estado, usuario, observaciones string) error {
+	var obs *string
+	if observaciones != "" {
+		obs = &observaciones
+	}
+	return s.execEnTransaccion("CALL sp_cambiar_estado_compra(?, ?, ?, ?)", comID, estado, usuario, obs)
+}
+
+func (s *DatabaseService) ListarHistorialEstadoCompra(comID uint) ([]models.HistorialEstadoCompra, error) {
+	var historial []models.HistorialEstadoCompra
+	err := s.DB.Raw("CALL sp_listar_historial_estado_compra(?)", comID).Scan(&historial).Error
+	return historial, err
+}
+
 // ============= PURCHASE DETAIL PROCEDURES =============
 
 func (s *DatabaseService) InsertarDetalleCompra(comID, prodID uint, cantidad int, precioUnitario float64) error {
@@ -771,6 +2215,62 @@ func (s *DatabaseService) EliminarDetalleCompra(comID, prodID uint) error {
 		comID, prodID).Error
 }
 
+// RecepcionParams is the quantity of a purchase line received in one delivery
+type RecepcionParams struct {
+	ProdID        uint
+	Cantidad      int
+	CostoUnitario float64
+	Observaciones string
+}
+
+// RecibirCompra records a (full or partial) delivery of a purchase: every received line increments
+// the product stock and stores its unit cost. All lines are saved in a single transaction, the
+// purchase moves to Parcialmente Recibida or Recibida and the updated purchase lines are returned.
+func (s *DatabaseService) RecibirCompra(comID uint, usuario string, recepciones []RecepcionParams) ([]models.DetalleCompra, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+	fail := func(err error) ([]models.DetalleCompra, error) {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, recepcion := range recepciones {
+		err := tx.Exec("CALL sp_recibir_detalle_compra(?, ?, ?, ?, ?, ?)",
+			comID, recepcion.ProdID, recepcion.Cantidad, recepcion.CostoUnitario,
+			textoOpcional(recepcion.Observaciones), usuario).Error
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := tx.Exec("CALL sp_actualizar_estado_recepcion_compra(?, ?)", comID, usuario).Error; err != nil {
+		return fail(err)
+	}
+
+	var detalles []models.DetalleCompra
+	if err := tx.Raw("CALL sp_listar_detalles_compra(?)", comID).Scan(&detalles).Error; err != nil {
+		return fail(err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return detalles, nil
+}
+
+func (s *DatabaseService) ListarRecepcionesCompra(comID uint) ([]models.RecepcionCompra, error) {
+	var recepciones []models.RecepcionCompra
+	err := s.DB.Raw("CALL sp_listar_recepciones_compra(?)", comID).Scan(&recepciones).Error
+	return recepciones, err
+}
+
 func (s *DatabaseService) ListarDetallesCompra(comID uint) ([]models.DetalleCompra, error) {
 	var detalles []models.DetalleCompra
 	err := s.DB.Raw("CALL sp_listar_detalles_compra(?)", comID).Scan(&detalles).Error
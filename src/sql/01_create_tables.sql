@@ -54,7 +54,9 @@ CREATE TABLE IF NOT EXISTS salondb.`CLIENTE` (
   `cli_nombre` VARCHAR(50) NULL DEFAULT NULL COMMENT 'Nombre del cliente',
   `cli_apellido` VARCHAR(50) NULL DEFAULT NULL COMMENT 'Apellido del cliente',
   `cli_telefono` VARCHAR(20) NULL DEFAULT NULL COMMENT 'Número de teléfono del cliente',
-  `cli_correo` VARCHAR(100) NOT NULL COMMENT 'Dirección de correo del cliente'
+  `cli_correo` VARCHAR(100) NOT NULL COMMENT 'Dirección de correo del cliente',
+  `cli_tipo_documento` VARCHAR(5) NULL DEFAULT NULL COMMENT 'Tipo de documento de identidad del cliente (CC, CE, NIT, PP, TI)',
+  `cli_documento` VARCHAR(20) NULL DEFAULT NULL COMMENT 'Número de documento con el que se identifica al cliente en la factura electrónica'
 );
 
 
@@ -87,6 +89,22 @@ CREATE TABLE IF NOT EXISTS salondb.`HORARIO_EMPLEADO` (
 );
 
 
+-- -----------------------------------------------------
+-- Table salondb.`HORARIO_EXCEPCION`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`HORARIO_EXCEPCION` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`HORARIO_EXCEPCION` (
+  `hex_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único de la excepción al horario del empleado',
+  `hex_fecha` DATE NOT NULL COMMENT 'Fecha a la que aplica la excepción',
+  `hex_tipo` VARCHAR(20) NOT NULL COMMENT 'Tipo de excepción (Descanso, Festivo, Vacaciones, Horario Especial)',
+  `hex_hora_entrada` TIME NULL DEFAULT NULL COMMENT 'Hora de entrada cuando la excepción es un horario especial',
+  `hex_hora_salida` TIME NULL DEFAULT NULL COMMENT 'Hora de salida cuando la excepción es un horario especial',
+  `hex_motivo` TEXT NULL DEFAULT NULL COMMENT 'Motivo u observaciones de la excepción',
+  `emp_id` INT NOT NULL COMMENT 'Código que sirve como identificador único del empleado'
+);
+
+
 -- -----------------------------------------------------
 -- Table salondb.`SERVICIO`
 -- -----------------------------------------------------
@@ -98,7 +116,8 @@ CREATE TABLE IF NOT EXISTS salondb.`SERVICIO` (
   `ser_descripcion` TEXT NULL COMMENT 'Descripción del servicio',
   `ser_categoria` VARCHAR(50) NOT NULL COMMENT 'Categoría del servicio (ej: Corte, Peinado, Coloración, Tratamiento)',
   `ser_precio_unitario` DECIMAL(10,2) NOT NULL COMMENT 'Precio unitario del servicio',
-  `ser_duracion_estimada` INT NULL COMMENT 'Duración estimada del servicio en minutos'
+  `ser_duracion_estimada` INT NULL COMMENT 'Duración estimada del servicio en minutos',
+  `ser_iva_porcentaje` DECIMAL(5,2) NOT NULL DEFAULT 19 COMMENT 'Tarifa de IVA del servicio (el precio ya incluye el impuesto)'
 );
 
 
@@ -111,8 +130,13 @@ CREATE TABLE IF NOT EXISTS salondb.`PRODUCTO` (
   `prod_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Código que sirve como identificador único del producto',
   `prod_nombre` VARCHAR(100) NOT NULL COMMENT 'Nombre del producto',
   `prod_descripcion` TEXT NULL COMMENT 'Descripción del producto',
-  `prod_cantidad_disponible` TINYINT(3) NOT NULL COMMENT 'Cantidad disponible del producto',
-  `prod_precio_unitario` DECIMAL(10,2) NOT NULL COMMENT 'Precio unitario del producto'
+  `prod_cantidad_disponible` INT NOT NULL COMMENT 'Cantidad disponible del producto',
+  `prod_precio_unitario` DECIMAL(10,2) NOT NULL COMMENT 'Precio unitario del producto',
+  `prod_costo_promedio` DECIMAL(12,4) NOT NULL DEFAULT 0 COMMENT 'Costo promedio ponderado por unidad, recalculado en cada entrada con costo',
+  `prod_stock_minimo` INT NOT NULL DEFAULT 5 COMMENT 'Stock mínimo: por debajo de esta cantidad el producto se debe volver a pedir',
+  `prod_cantidad_reorden` INT NOT NULL DEFAULT 0 COMMENT 'Cantidad que se pide normalmente al reabastecer el producto',
+  `prov_id` INT NULL DEFAULT NULL COMMENT 'Proveedor preferido para reabastecer el producto',
+  `prod_iva_porcentaje` DECIMAL(5,2) NOT NULL DEFAULT 19 COMMENT 'Tarifa de IVA del producto (el precio ya incluye el impuesto)'
 );
 
 
@@ -125,11 +149,30 @@ CREATE TABLE IF NOT EXISTS salondb.`PRODUCTO_USADO` (
   `ser_id` INT NOT NULL COMMENT 'Códgo que sirve como identificador único del servicio realizado en el que se utilizó el producto',
   `prod_id` INT NOT NULL COMMENT 'Código que sirve como identificador único del producto utilizado',
   `pru_cantidad_usada` SMALLINT UNSIGNED NOT NULL COMMENT 'Cantidad utilizada del producto durante el servicio',
-  `pru_botellas_usadas` SMALLINT UNSIGNED NOT NULL,
+  `pru_botellas_usadas` SMALLINT UNSIGNED NOT NULL COMMENT 'Unidades del producto que se descuentan del stock cada vez que se realiza el servicio',
   PRIMARY KEY (`ser_id`, `prod_id`)
   );
 
 
+-- -----------------------------------------------------
+-- Table salondb.`RESOLUCION_FACTURACION`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`RESOLUCION_FACTURACION` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`RESOLUCION_FACTURACION` (
+  `res_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único de la resolución de facturación',
+  `res_numero` VARCHAR(50) NOT NULL COMMENT 'Número de la resolución que autoriza el rango de numeración',
+  `res_prefijo` VARCHAR(10) NOT NULL DEFAULT '' COMMENT 'Prefijo autorizado que antecede al consecutivo de las facturas',
+  `res_rango_desde` INT NOT NULL COMMENT 'Primer consecutivo autorizado',
+  `res_rango_hasta` INT NOT NULL COMMENT 'Último consecutivo autorizado',
+  `res_consecutivo_actual` INT NULL DEFAULT NULL COMMENT 'Último consecutivo asignado (NULL si aún no se ha emitido ninguna factura)',
+  `res_fecha_desde` DATE NOT NULL COMMENT 'Fecha desde la que se puede facturar con la resolución',
+  `res_fecha_hasta` DATE NOT NULL COMMENT 'Fecha hasta la que se puede facturar con la resolución',
+  `res_clave_tecnica` VARCHAR(100) NULL DEFAULT NULL COMMENT 'Clave técnica del rango, usada para calcular el CUFE de la factura electrónica',
+  `res_activa` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Indica si es la resolución con la que se numeran las facturas nuevas (solo una a la vez)'
+  );
+
+
 -- -----------------------------------------------------
 -- Table salondb.`FACTURA_SERVICIO`
 -- -----------------------------------------------------
@@ -138,9 +181,21 @@ DROP TABLE IF EXISTS salondb.`FACTURA_SERVICIO` ;
 CREATE TABLE IF NOT EXISTS salondb.`FACTURA_SERVICIO` (
   `fac_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Código que sirve como identificador único de la factura con los servicios realizados a un cliente',
   `fac_total` DECIMAL(10,2) NOT NULL COMMENT 'Total gastado por el cliente durante su estancia en el salón de belleza',
+  `fac_subtotal` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT 'Total de la factura antes de IVA',
+  `fac_impuesto` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT 'IVA incluido en el total de la factura',
   `fac_fecha` DATE NOT NULL COMMENT 'Fecha en la que se imprimió la factura',
   `fac_hora` TIME NOT NULL COMMENT 'Hora en la que se imprimió la factura',
-  `cli_id` INT NOT NULL COMMENT 'Identificador del cliente para la impresión de la factura'
+  `cli_id` INT NOT NULL COMMENT 'Identificador del cliente para la impresión de la factura',
+  `fac_pagado` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT 'Suma de los pagos del cliente aplicados a la factura',
+  `fac_estado_pago` VARCHAR(20) NOT NULL DEFAULT 'Pendiente' COMMENT 'Estado de pago de la factura (Pendiente, Parcial, Pagada)',
+  `fac_estado` VARCHAR(20) NOT NULL DEFAULT 'Borrador' COMMENT 'Estado de la factura (Borrador, Emitida, Anulada); una factura emitida ya no se modifica',
+  `fac_fecha_emision` DATETIME NULL DEFAULT NULL COMMENT 'Fecha y hora en la que se emitió la factura',
+  `fac_acreditado` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT 'Suma de las notas crédito aplicadas a la factura',
+  `fac_reembolsado` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT 'Valor devuelto al cliente por las notas crédito',
+  `res_id` INT NULL DEFAULT NULL COMMENT 'Resolución con la que se numeró la factura al emitirla',
+  `fac_prefijo` VARCHAR(10) NULL DEFAULT NULL COMMENT 'Prefijo de la resolución al momento de emitir la factura',
+  `fac_consecutivo` INT NULL DEFAULT NULL COMMENT 'Consecutivo legal asignado al emitir la factura (NULL mientras es borrador)',
+  `fac_numero` VARCHAR(30) NULL DEFAULT NULL COMMENT 'Número legal de la factura: prefijo seguido del consecutivo'
   );
 
 
@@ -150,9 +205,83 @@ CREATE TABLE IF NOT EXISTS salondb.`FACTURA_SERVICIO` (
 DROP TABLE IF EXISTS salondb.`DETALLE_FACTURA_SERVICIO` ;
 
 CREATE TABLE IF NOT EXISTS salondb.`DETALLE_FACTURA_SERVICIO` (
+  `dfs_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único de la línea de factura (un servicio puede repetirse en la misma factura)',
   `fac_id` INT NOT NULL COMMENT 'Identificador único de la factura',
   `ser_id` INT NOT NULL COMMENT 'Identificador único del servicio',
-  PRIMARY KEY (`fac_id`, `ser_id`)
+  `dfs_cantidad` INT NOT NULL DEFAULT 1 COMMENT 'Veces que se realizó el servicio en la línea',
+  `dfs_precio_unitario` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT 'Precio del servicio al momento de facturarlo',
+  `dfs_descuento` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT 'Valor descontado a la línea por la promoción aplicada',
+  `pro_id` INT NULL DEFAULT NULL COMMENT 'Identificador de la promoción aplicada a la línea (NULL si no tuvo descuento)',
+  `emp_id` INT NULL DEFAULT NULL COMMENT 'Empleado que realizó el servicio (NULL si no se registró)',
+  `dfs_iva_porcentaje` DECIMAL(5,2) NOT NULL DEFAULT 0 COMMENT 'Tarifa de IVA del servicio al momento de facturarlo'
+  );
+
+
+-- -----------------------------------------------------
+-- Table salondb.`PAGO_CLIENTE`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`PAGO_CLIENTE` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`PAGO_CLIENTE` (
+  `pag_cli_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único del pago del cliente',
+  `fac_id` INT NOT NULL COMMENT 'Factura a la que se aplica el pago',
+  `pag_cli_fecha` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Fecha y hora del pago',
+  `pag_cli_metodo` VARCHAR(20) NOT NULL COMMENT 'Medio de pago (Efectivo, Tarjeta, Transferencia, Billetera Digital)',
+  `pag_cli_entidad` VARCHAR(50) NULL DEFAULT NULL COMMENT 'Franquicia, banco o billetera digital (Visa, Bancolombia, Nequi, Daviplata...)',
+  `pag_cli_referencia` VARCHAR(100) NULL DEFAULT NULL COMMENT 'Número de comprobante, voucher o transacción',
+  `pag_cli_recibido` DECIMAL(10,2) NOT NULL COMMENT 'Valor entregado por el cliente con este medio de pago',
+  `pag_cli_monto` DECIMAL(10,2) NOT NULL COMMENT 'Valor aplicado al saldo de la factura',
+  `pag_cli_cambio` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT 'Cambio devuelto al cliente (solo en efectivo)',
+  `pag_cli_usuario` VARCHAR(100) NULL DEFAULT NULL COMMENT 'Correo del usuario que registró el pago'
+  );
+
+
+-- -----------------------------------------------------
+-- Table salondb.`NOTA_CREDITO`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`NOTA_CREDITO` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`NOTA_CREDITO` (
+  `nc_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único de la nota crédito',
+  `fac_id` INT NOT NULL COMMENT 'Factura emitida que corrige la nota crédito',
+  `nc_fecha` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Fecha y hora de la nota crédito',
+  `nc_tipo` VARCHAR(20) NOT NULL COMMENT 'Tipo de nota crédito (Anulación de la factura completa o Devolución parcial o total)',
+  `nc_motivo` VARCHAR(255) NOT NULL COMMENT 'Motivo de la anulación o de la devolución',
+  `nc_total` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT 'Valor acreditado a la factura (se resta de los ingresos)',
+  `nc_reembolso` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT 'Valor devuelto al cliente porque ya lo había pagado',
+  `nc_metodo_reembolso` VARCHAR(20) NULL DEFAULT NULL COMMENT 'Medio con el que se devolvió el dinero al cliente',
+  `nc_usuario` VARCHAR(100) NULL DEFAULT NULL COMMENT 'Correo del usuario que registró la nota crédito'
+  );
+
+
+-- -----------------------------------------------------
+-- Table salondb.`DETALLE_NOTA_CREDITO`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`DETALLE_NOTA_CREDITO` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`DETALLE_NOTA_CREDITO` (
+  `dnc_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único de la línea de la nota crédito',
+  `nc_id` INT NOT NULL COMMENT 'Nota crédito a la que pertenece la línea',
+  `dfs_id` INT NULL DEFAULT NULL COMMENT 'Línea de servicio acreditada (NULL si la línea es de un producto)',
+  `prod_id` INT NULL DEFAULT NULL COMMENT 'Producto devuelto (NULL si la línea es de un servicio)',
+  `dnc_cantidad` INT NOT NULL COMMENT 'Unidades acreditadas de la línea de la factura',
+  `dnc_valor` DECIMAL(10,2) NOT NULL COMMENT 'Valor acreditado, proporcional al total neto de la línea de la factura'
+  );
+
+
+-- -----------------------------------------------------
+-- Table salondb.`DETALLE_FACTURA_PRODUCTO`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`DETALLE_FACTURA_PRODUCTO` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`DETALLE_FACTURA_PRODUCTO` (
+  `fac_id` INT NOT NULL COMMENT 'Identificador único de la factura',
+  `prod_id` INT NOT NULL COMMENT 'Identificador único del producto vendido en mostrador',
+  `dfp_cantidad` INT NOT NULL COMMENT 'Unidades vendidas del producto',
+  `dfp_precio_unitario` DECIMAL(10,2) NOT NULL COMMENT 'Precio de venta por unidad al momento de facturarlo',
+  `dfp_descuento` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT 'Valor descontado al total de la línea',
+  `dfp_iva_porcentaje` DECIMAL(5,2) NOT NULL DEFAULT 0 COMMENT 'Tarifa de IVA del producto al momento de facturarlo',
+  PRIMARY KEY (`fac_id`, `prod_id`)
   );
 
 
@@ -210,7 +339,10 @@ CREATE TABLE IF NOT EXISTS salondb.`COMPRA_PRODUCTO` (
   `cop_total_compra` DECIMAL(10,2) NOT NULL COMMENT 'Monto total de la compra realizada',
   `cop_metodo_pago` VARCHAR(50) NOT NULL COMMENT 'Método de pago utilizado para realizar la compra',
   `prov_id` INT NOT NULL COMMENT 'Identificador único del proveedor',
-  `gas_id` INT NOT NULL COMMENT 'Identificador único del gasto'
+  `gas_id` INT NOT NULL COMMENT 'Identificador único del gasto',
+  `cop_estado` VARCHAR(25) NOT NULL DEFAULT 'Borrador' COMMENT 'Estado de la orden de compra (Borrador, Aprobada, Enviada, Parcialmente Recibida, Recibida, Cerrada, Cancelada)',
+  `cop_aprobada_por` VARCHAR(100) NULL DEFAULT NULL COMMENT 'Correo del usuario que aprobó la orden de compra',
+  `cop_fecha_aprobacion` DATETIME NULL DEFAULT NULL COMMENT 'Fecha y hora de aprobación de la orden de compra'
   );
 
 
@@ -224,10 +356,43 @@ CREATE TABLE IF NOT EXISTS salondb.`DETALLE_COMPRA` (
   `prod_id` INT NOT NULL COMMENT 'Identificador único de producto comprado',
   `dec_cantidad` INT NOT NULL COMMENT 'Cantidad de producto comprado',
   `dec_precio_unitario` DECIMAL(10,2) NOT NULL COMMENT 'Precio unitario del producto',
+  `dec_cantidad_recibida` INT NOT NULL DEFAULT 0 COMMENT 'Cantidad del producto recibida hasta el momento',
   PRIMARY KEY (`com_id`, `prod_id`)
   );
 
 
+-- -----------------------------------------------------
+-- Table salondb.`HISTORIAL_ESTADO_COMPRA`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`HISTORIAL_ESTADO_COMPRA` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`HISTORIAL_ESTADO_COMPRA` (
+  `hco_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único del cambio de estado de la compra',
+  `com_id` INT NOT NULL COMMENT 'Identificador único de la compra',
+  `hco_estado_anterior` VARCHAR(25) NULL DEFAULT NULL COMMENT 'Estado de la compra antes del cambio',
+  `hco_estado_nuevo` VARCHAR(25) NOT NULL COMMENT 'Estado de la compra después del cambio',
+  `hco_fecha` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Fecha y hora del cambio de estado',
+  `hco_usuario` VARCHAR(100) NULL DEFAULT NULL COMMENT 'Correo del usuario que realizó el cambio',
+  `hco_observaciones` TEXT NULL DEFAULT NULL COMMENT 'Observaciones sobre el cambio de estado'
+  );
+
+
+-- -----------------------------------------------------
+-- Table salondb.`RECEPCION_COMPRA`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`RECEPCION_COMPRA` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`RECEPCION_COMPRA` (
+  `rec_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único de la recepción',
+  `com_id` INT NOT NULL COMMENT 'Identificador único de la compra recibida',
+  `prod_id` INT NOT NULL COMMENT 'Identificador único del producto recibido',
+  `rec_fecha` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Fecha y hora de la recepción',
+  `rec_cantidad` INT NOT NULL COMMENT 'Cantidad recibida en esta entrega',
+  `rec_costo_unitario` DECIMAL(10,2) NOT NULL COMMENT 'Costo unitario con el que se recibió el producto',
+  `rec_observaciones` TEXT NULL DEFAULT NULL COMMENT 'Observaciones de la recepción'
+  );
+
+
 -- -----------------------------------------------------
 -- Table salondb.`INVENTARIO`
 -- -----------------------------------------------------
@@ -242,6 +407,73 @@ CREATE TABLE IF NOT EXISTS salondb.`INVENTARIO` (
   );
 
 
+-- -----------------------------------------------------
+-- Table salondb.`MOVIMIENTO_INVENTARIO`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`MOVIMIENTO_INVENTARIO` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`MOVIMIENTO_INVENTARIO` (
+  `mov_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único del movimiento de inventario',
+  `prod_id` INT NOT NULL COMMENT 'Identificador único del producto',
+  `mov_fecha` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Fecha y hora del movimiento',
+  `mov_tipo` VARCHAR(20) NOT NULL COMMENT 'Tipo de movimiento (Compra, Consumo Servicio, Venta, Ajuste, Merma, Devolución)',
+  `mov_cantidad` INT NOT NULL COMMENT 'Unidades que entran (positivo) o salen (negativo) del stock',
+  `mov_saldo` INT NOT NULL COMMENT 'Stock del producto después del movimiento',
+  `mov_costo_unitario` DECIMAL(12,4) NOT NULL DEFAULT 0 COMMENT 'Costo por unidad del movimiento (costo de compra en entradas, costo promedio en salidas)',
+  `mov_costo_promedio` DECIMAL(12,4) NOT NULL DEFAULT 0 COMMENT 'Costo promedio ponderado del producto después del movimiento',
+  `mov_usuario` VARCHAR(100) NULL DEFAULT NULL COMMENT 'Correo del usuario que registró el movimiento',
+  `mov_motivo` TEXT NULL DEFAULT NULL COMMENT 'Motivo del movimiento',
+  `mov_referencia` VARCHAR(50) NULL DEFAULT NULL COMMENT 'Documento que originó el movimiento (COMPRA:id, CITA:id, FACTURA:id)'
+  );
+
+
+-- -----------------------------------------------------
+-- Table salondb.`CONTEO_INVENTARIO`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`CONTEO_INVENTARIO` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`CONTEO_INVENTARIO` (
+  `con_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único de la sesión de conteo físico',
+  `con_fecha_apertura` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Fecha y hora en que se abrió el conteo',
+  `con_estado` VARCHAR(20) NOT NULL DEFAULT 'Abierto' COMMENT 'Estado del conteo (Abierto, Aprobado, Cancelado)',
+  `con_abierto_por` VARCHAR(100) NULL DEFAULT NULL COMMENT 'Correo del usuario que abrió el conteo',
+  `con_cerrado_por` VARCHAR(100) NULL DEFAULT NULL COMMENT 'Correo del usuario que aprobó o canceló el conteo',
+  `con_fecha_cierre` DATETIME NULL DEFAULT NULL COMMENT 'Fecha y hora de aprobación o cancelación del conteo',
+  `con_observaciones` TEXT NULL DEFAULT NULL COMMENT 'Observaciones del conteo'
+  );
+
+
+-- -----------------------------------------------------
+-- Table salondb.`DETALLE_CONTEO`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`DETALLE_CONTEO` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`DETALLE_CONTEO` (
+  `con_id` INT NOT NULL COMMENT 'Identificador único de la sesión de conteo físico',
+  `prod_id` INT NOT NULL COMMENT 'Identificador único del producto',
+  `dco_stock_sistema` INT NOT NULL COMMENT 'Stock del sistema al abrir el conteo',
+  `dco_costo_unitario` DECIMAL(10,2) NOT NULL COMMENT 'Costo unitario con el que se valoran las diferencias',
+  PRIMARY KEY (`con_id`, `prod_id`)
+  );
+
+
+-- -----------------------------------------------------
+-- Table salondb.`REGISTRO_CONTEO`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`REGISTRO_CONTEO` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`REGISTRO_CONTEO` (
+  `rco_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único del registro de conteo',
+  `con_id` INT NOT NULL COMMENT 'Identificador único de la sesión de conteo físico',
+  `prod_id` INT NOT NULL COMMENT 'Identificador único del producto',
+  `rco_cantidad` INT NOT NULL COMMENT 'Cantidad contada por el usuario',
+  `rco_stock_sistema` INT NOT NULL COMMENT 'Stock del sistema cuando se registró la cantidad contada',
+  `rco_usuario` VARCHAR(100) NOT NULL COMMENT 'Correo del usuario que contó el producto',
+  `rco_fecha` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Fecha y hora del registro',
+  UNIQUE KEY `uq_registro_conteo` (`con_id`, `prod_id`, `rco_usuario`)
+  );
+
+
 -- -----------------------------------------------------
 -- Table salondb.`PROMOCION`
 -- -----------------------------------------------------
@@ -255,7 +487,25 @@ CREATE TABLE IF NOT EXISTS salondb.`PROMOCION` (
   `pro_fecha_fin` DATE NOT NULL COMMENT 'Fecha de finalización de la promoción',
   `pro_descuento_porcentaje` DECIMAL(5,2) NULL DEFAULT NULL COMMENT 'Porcentaje de descuento que un servicio posee gracias a la promoción',
   `ser_id` INT NOT NULL COMMENT 'Identificador único del servicio al cual va dirigido la promoción',
-  `pro_usos` INT(2) NULL
+  `pro_usos` INT(2) NULL COMMENT 'Cantidad de facturas en las que se ha aplicado la promoción',
+  `pro_tipo_descuento` VARCHAR(20) NOT NULL DEFAULT 'Porcentaje' COMMENT 'Tipo de descuento (Porcentaje o Valor Fijo)',
+  `pro_descuento_valor` DECIMAL(10,2) NULL DEFAULT NULL COMMENT 'Valor a descontar cuando el descuento es de valor fijo',
+  `pro_max_usos` INT NULL DEFAULT NULL COMMENT 'Cantidad máxima de usos de la promoción (NULL sin límite)',
+  `pro_max_usos_cliente` INT NULL DEFAULT NULL COMMENT 'Cantidad máxima de usos de la promoción por cliente (NULL sin límite)',
+  `pro_monto_minimo` DECIMAL(10,2) NULL DEFAULT NULL COMMENT 'Subtotal mínimo de la factura para aplicar la promoción',
+  `pro_codigo` VARCHAR(30) NULL DEFAULT NULL UNIQUE COMMENT 'Código de cupón; las promociones con código solo se aplican al redimirlo'
+  );
+
+
+-- -----------------------------------------------------
+-- Table salondb.`PROMOCION_SERVICIO`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`PROMOCION_SERVICIO` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`PROMOCION_SERVICIO` (
+  `pro_id` INT NOT NULL COMMENT 'Identificador único de la promoción',
+  `ser_id` INT NOT NULL COMMENT 'Servicio adicional que debe estar en la factura para aplicar la promoción (paquete)',
+  PRIMARY KEY (`pro_id`, `ser_id`)
   );
 
 
@@ -270,7 +520,11 @@ CREATE TABLE IF NOT EXISTS salondb.`CITA` (
   `cit_hora` TIME NOT NULL COMMENT 'Hora de la cita programada',
   `emp_id` INT NOT NULL COMMENT 'Identificador único del empleado que fue agendado para la cita',
   `ser_id` INT NOT NULL COMMENT 'Identificador único del servicio que se realizará en la cita',
-  `cli_id` INT NOT NULL COMMENT 'Identificador único del cliente que agendó la cita'
+  `cli_id` INT NOT NULL COMMENT 'Identificador único del cliente que agendó la cita',
+  `cit_estado` VARCHAR(20) NOT NULL DEFAULT 'Programada' COMMENT 'Estado de la cita (Programada, Confirmada, En Curso, Completada, Cancelada, No Asistio)',
+  `cit_cancelacion_tardia` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Indica si el cliente canceló la cita dentro del plazo mínimo de aviso',
+  `fac_id` INT NULL DEFAULT NULL COMMENT 'Identificador de la factura con la que se cobró la cita (NULL si aún no se ha facturado)',
+  `cit_consumo_registrado` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Indica si ya se descontaron del stock los productos usados en la cita'
   );
 
 
@@ -285,6 +539,21 @@ CREATE TABLE IF NOT EXISTS salondb.`HISTORIAL_CITA` (
   `cit_id` INT NOT NULL COMMENT 'Identificador único de alguna de las citas tomadas por el cliente'
   );
 
+
+-- -----------------------------------------------------
+-- Table salondb.`HISTORIAL_ESTADO_CITA`
+-- -----------------------------------------------------
+DROP TABLE IF EXISTS salondb.`HISTORIAL_ESTADO_CITA` ;
+
+CREATE TABLE IF NOT EXISTS salondb.`HISTORIAL_ESTADO_CITA` (
+  `hec_id` INT PRIMARY KEY NOT NULL AUTO_INCREMENT COMMENT 'Identificador único del cambio de estado de la cita',
+  `hec_estado_anterior` VARCHAR(20) NULL DEFAULT NULL COMMENT 'Estado de la cita antes del cambio (NULL cuando la cita se crea)',
+  `hec_estado_nuevo` VARCHAR(20) NOT NULL COMMENT 'Estado de la cita después del cambio',
+  `hec_fecha` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Fecha y hora en que se registró el cambio de estado',
+  `hec_observaciones` TEXT NULL DEFAULT NULL COMMENT 'Observaciones sobre el cambio de estado',
+  `cit_id` INT NOT NULL COMMENT 'Identificador único de la cita'
+  );
+
 -- Table creation completed successfully
 -- Log completion (using INSERT IGNORE to prevent duplicates)
 INSERT IGNORE INTO salondb.db_initialization_log (script_name, status) 
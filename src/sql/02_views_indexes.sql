@@ -8,27 +8,70 @@ CREATE VIEW vw_empleados_activos AS
 CREATE VIEW vw_citas_hoy AS
   SELECT COUNT(*) AS total_hoy
   FROM CITA
-  WHERE cit_fecha = CURDATE();
+  WHERE cit_fecha = CURDATE()
+    AND cit_estado NOT IN ('Cancelada', 'No Asistio');
 
+-- Ingresos del mes: facturas emitidas (también las anuladas) menos las notas crédito del mes,
+-- que restan en el mes en que se registran aunque la factura sea de un mes anterior
 CREATE VIEW vw_ingresos_mensuales AS
-  SELECT COALESCE(SUM(fac_total),0) AS ingresos_mes
-  FROM FACTURA_SERVICIO
-  WHERE MONTH(fac_fecha) = MONTH(CURDATE())
-    AND YEAR(fac_fecha) = YEAR(CURDATE());
+  SELECT COALESCE((
+      SELECT SUM(fac_total)
+      FROM FACTURA_SERVICIO
+      WHERE fac_estado <> 'Borrador'
+        AND MONTH(fac_fecha) = MONTH(CURDATE())
+        AND YEAR(fac_fecha) = YEAR(CURDATE())
+    ), 0) - COALESCE((
+      SELECT SUM(nc_total)
+      FROM NOTA_CREDITO
+      WHERE MONTH(nc_fecha) = MONTH(CURDATE())
+        AND YEAR(nc_fecha) = YEAR(CURDATE())
+    ), 0) AS ingresos_mes;
 
 CREATE VIEW vw_productos_bajos AS
   SELECT COUNT(*) AS productos_bajos
-  FROM INVENTARIO
-  WHERE inv_cantidad_actual < 5;
+  FROM PRODUCTO
+  WHERE prod_cantidad_disponible < prod_stock_minimo;
+
+-- Productos por debajo del stock mínimo con la cantidad sugerida para pedir:
+-- lo que falta para el mínimo descontando lo pendiente en compras abiertas, al menos la cantidad de reorden
+CREATE VIEW vw_productos_reorden AS
+  SELECT r.*,
+    IF(r.prod_stock_minimo - r.prod_cantidad_disponible - r.en_pedido > 0,
+       GREATEST(r.prod_cantidad_reorden, r.prod_stock_minimo - r.prod_cantidad_disponible - r.en_pedido),
+       0) AS cantidad_sugerida
+  FROM (
+    SELECT p.prod_id, p.prod_nombre, p.prod_cantidad_disponible, p.prod_stock_minimo, p.prod_cantidad_reorden,
+      p.prov_id, prov.prov_nombre,
+      COALESCE(pend.en_pedido, 0) AS en_pedido,
+      COALESCE((
+        SELECT dc.dec_precio_unitario
+        FROM DETALLE_COMPRA dc
+        JOIN COMPRA_PRODUCTO cp ON cp.com_id = dc.com_id
+        WHERE dc.prod_id = p.prod_id AND cp.cop_estado <> 'Cancelada'
+        ORDER BY cp.cop_fecha_compra DESC, cp.com_id DESC
+        LIMIT 1
+      ), NULLIF(p.prod_costo_promedio, 0), p.prod_precio_unitario) AS costo_estimado
+    FROM PRODUCTO p
+    LEFT JOIN PROVEEDOR prov ON prov.prov_id = p.prov_id
+    LEFT JOIN (
+      SELECT dc.prod_id, SUM(GREATEST(dc.dec_cantidad - dc.dec_cantidad_recibida, 0)) AS en_pedido
+      FROM DETALLE_COMPRA dc
+      JOIN COMPRA_PRODUCTO cp ON cp.com_id = dc.com_id
+      WHERE cp.cop_estado IN ('Borrador', 'Aprobada', 'Enviada', 'Parcialmente Recibida')
+      GROUP BY dc.prod_id
+    ) pend ON pend.prod_id = p.prod_id
+    WHERE p.prod_cantidad_disponible < p.prod_stock_minimo
+  ) r;
 
 CREATE VIEW vw_total_productos AS
   SELECT COUNT(*) AS total_productos
   FROM PRODUCTO;
 
+-- Valor del inventario al costo promedio ponderado (valor_venta: a precio de venta)
 CREATE VIEW vw_valor_inventario AS
-  SELECT COALESCE(SUM(prod.prod_precio_unitario * inv.inv_cantidad_actual),0) AS valor_total
-  FROM INVENTARIO inv
-  JOIN PRODUCTO prod ON inv.prod_id = prod.prod_id;
+  SELECT COALESCE(ROUND(SUM(prod_costo_promedio * prod_cantidad_disponible), 2),0) AS valor_total,
+    COALESCE(SUM(prod_precio_unitario * prod_cantidad_disponible),0) AS valor_venta
+  FROM PRODUCTO;
 
 CREATE VIEW vw_servicios_totales AS
   SELECT COUNT(*) AS total_servicios
@@ -50,8 +93,16 @@ CREATE VIEW vw_total_proveedores AS
 
 -- Creación de índices para optimizar las vistas
 CREATE INDEX idx_cita_fecha ON CITA (cit_fecha);
+CREATE INDEX idx_cita_factura ON CITA (fac_id);
 CREATE INDEX idx_factura_fecha ON FACTURA_SERVICIO (fac_fecha);
+CREATE INDEX idx_detalle_factura_factura ON DETALLE_FACTURA_SERVICIO (fac_id);
+CREATE UNIQUE INDEX idx_factura_numero ON FACTURA_SERVICIO (fac_numero);
+CREATE INDEX idx_nota_credito_factura ON NOTA_CREDITO (fac_id);
+CREATE INDEX idx_nota_credito_fecha ON NOTA_CREDITO (nc_fecha);
+CREATE INDEX idx_detalle_nota_credito_nota ON DETALLE_NOTA_CREDITO (nc_id);
 CREATE INDEX idx_gasto_fecha ON GASTO_MENSUAL (gas_fecha);
+CREATE INDEX idx_pago_fecha ON PAGO (pag_fecha);
+CREATE INDEX idx_compra_fecha ON COMPRA_PRODUCTO (cop_fecha_compra);
 CREATE INDEX idx_inv_cantidad ON INVENTARIO (inv_cantidad_actual);
 CREATE INDEX idx_prod_precio ON PRODUCTO (prod_precio_unitario);
 
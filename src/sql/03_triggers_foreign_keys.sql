@@ -7,9 +7,11 @@ BEFORE DELETE ON EMPLEADO
 FOR EACH ROW
 BEGIN
   DELETE FROM HORARIO_EMPLEADO WHERE emp_id = OLD.emp_id;
+  DELETE FROM HORARIO_EXCEPCION WHERE emp_id = OLD.emp_id;
   DELETE FROM USUARIO_SISTEMA WHERE emp_id = OLD.emp_id;
   DELETE FROM CITA WHERE emp_id = OLD.emp_id;
   DELETE FROM PAGO WHERE emp_id = OLD.emp_id;
+  UPDATE DETALLE_FACTURA_SERVICIO SET emp_id = NULL WHERE emp_id = OLD.emp_id;
 END;
 //
 
@@ -19,9 +21,11 @@ AFTER UPDATE ON EMPLEADO
 FOR EACH ROW
 BEGIN
   UPDATE HORARIO_EMPLEADO SET emp_id = NEW.emp_id WHERE emp_id = OLD.emp_id;
+  UPDATE HORARIO_EXCEPCION SET emp_id = NEW.emp_id WHERE emp_id = OLD.emp_id;
   UPDATE USUARIO_SISTEMA SET emp_id = NEW.emp_id WHERE emp_id = OLD.emp_id;
   UPDATE CITA SET emp_id = NEW.emp_id WHERE emp_id = OLD.emp_id;
   UPDATE PAGO SET emp_id = NEW.emp_id WHERE emp_id = OLD.emp_id;
+  UPDATE DETALLE_FACTURA_SERVICIO SET emp_id = NEW.emp_id WHERE emp_id = OLD.emp_id;
 END;
 //
 
@@ -53,9 +57,12 @@ BEFORE DELETE ON SERVICIO
 FOR EACH ROW
 BEGIN
   DELETE FROM CITA WHERE ser_id = OLD.ser_id;
+  DELETE FROM DETALLE_NOTA_CREDITO
+  WHERE dfs_id IN (SELECT dfs_id FROM DETALLE_FACTURA_SERVICIO WHERE ser_id = OLD.ser_id);
   DELETE FROM DETALLE_FACTURA_SERVICIO WHERE ser_id = OLD.ser_id;
   DELETE FROM PRODUCTO_USADO WHERE ser_id = OLD.ser_id;
   DELETE FROM PROMOCION WHERE ser_id = OLD.ser_id;
+  DELETE FROM PROMOCION_SERVICIO WHERE ser_id = OLD.ser_id;
 END;
 //
 
@@ -68,6 +75,7 @@ BEGIN
   UPDATE DETALLE_FACTURA_SERVICIO SET ser_id = NEW.ser_id WHERE ser_id = OLD.ser_id;
   UPDATE PRODUCTO_USADO SET ser_id = NEW.ser_id WHERE ser_id = OLD.ser_id;
   UPDATE PROMOCION SET ser_id = NEW.ser_id WHERE ser_id = OLD.ser_id;
+  UPDATE PROMOCION_SERVICIO SET ser_id = NEW.ser_id WHERE ser_id = OLD.ser_id;
 END;
 //
 
@@ -78,7 +86,15 @@ FOR EACH ROW
 BEGIN
   DELETE FROM PRODUCTO_USADO WHERE prod_id = OLD.prod_id;
   DELETE FROM DETALLE_COMPRA WHERE prod_id = OLD.prod_id;
+  DELETE FROM RECEPCION_COMPRA WHERE prod_id = OLD.prod_id;
   DELETE FROM INVENTARIO WHERE prod_id = OLD.prod_id;
+  DELETE FROM DETALLE_CONTEO WHERE prod_id = OLD.prod_id;
+  DELETE FROM REGISTRO_CONTEO WHERE prod_id = OLD.prod_id;
+  DELETE FROM DETALLE_FACTURA_PRODUCTO WHERE prod_id = OLD.prod_id;
+  DELETE FROM DETALLE_NOTA_CREDITO WHERE prod_id = OLD.prod_id;
+  SET @cascada_producto = OLD.prod_id;
+  DELETE FROM MOVIMIENTO_INVENTARIO WHERE prod_id = OLD.prod_id;
+  SET @cascada_producto = NULL;
 END;
 //
 
@@ -89,7 +105,56 @@ FOR EACH ROW
 BEGIN
   UPDATE PRODUCTO_USADO SET prod_id = NEW.prod_id WHERE prod_id = OLD.prod_id;
   UPDATE DETALLE_COMPRA SET prod_id = NEW.prod_id WHERE prod_id = OLD.prod_id;
+  UPDATE RECEPCION_COMPRA SET prod_id = NEW.prod_id WHERE prod_id = OLD.prod_id;
   UPDATE INVENTARIO SET prod_id = NEW.prod_id WHERE prod_id = OLD.prod_id;
+  UPDATE DETALLE_CONTEO SET prod_id = NEW.prod_id WHERE prod_id = OLD.prod_id;
+  UPDATE REGISTRO_CONTEO SET prod_id = NEW.prod_id WHERE prod_id = OLD.prod_id;
+  UPDATE DETALLE_FACTURA_PRODUCTO SET prod_id = NEW.prod_id WHERE prod_id = OLD.prod_id;
+  UPDATE DETALLE_NOTA_CREDITO SET prod_id = NEW.prod_id WHERE prod_id = OLD.prod_id;
+  SET @cascada_producto = OLD.prod_id;
+  UPDATE MOVIMIENTO_INVENTARIO SET prod_id = NEW.prod_id WHERE prod_id = OLD.prod_id;
+  SET @cascada_producto = NULL;
+END;
+//
+
+-- Trigger DELETE con cascada manual para CONTEO_INVENTARIO
+CREATE TRIGGER trg_delete_conteo_inventario
+BEFORE DELETE ON CONTEO_INVENTARIO
+FOR EACH ROW
+BEGIN
+  DELETE FROM REGISTRO_CONTEO WHERE con_id = OLD.con_id;
+  DELETE FROM DETALLE_CONTEO WHERE con_id = OLD.con_id;
+END;
+//
+
+-- Trigger DELETE para PROVEEDOR: los productos quedan sin proveedor preferido
+CREATE TRIGGER trg_delete_proveedor
+BEFORE DELETE ON PROVEEDOR
+FOR EACH ROW
+BEGIN
+  UPDATE PRODUCTO SET prov_id = NULL WHERE prov_id = OLD.prov_id;
+END;
+//
+
+-- Los movimientos de inventario solo se agregan: únicamente la cascada desde PRODUCTO
+-- puede modificarlos o borrarlos
+CREATE TRIGGER trg_update_movimiento_inventario
+BEFORE UPDATE ON MOVIMIENTO_INVENTARIO
+FOR EACH ROW
+BEGIN
+  IF @cascada_producto IS NULL OR @cascada_producto <> OLD.prod_id THEN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Los movimientos de inventario no se pueden modificar';
+  END IF;
+END;
+//
+
+CREATE TRIGGER trg_delete_movimiento_inventario
+BEFORE DELETE ON MOVIMIENTO_INVENTARIO
+FOR EACH ROW
+BEGIN
+  IF @cascada_producto IS NULL OR @cascada_producto <> OLD.prod_id THEN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Los movimientos de inventario no se pueden eliminar';
+  END IF;
 END;
 //
 
@@ -98,7 +163,17 @@ CREATE TRIGGER trg_delete_factura
 BEFORE DELETE ON FACTURA_SERVICIO
 FOR EACH ROW
 BEGIN
+  -- Devolver los usos de las promociones aplicadas en las líneas de la factura
+  -- (una factura anulada ya los devolvió)
+  IF OLD.fac_estado <> 'Anulada' THEN
+    CALL sp_liberar_promociones_factura(OLD.fac_id);
+  END IF;
+  DELETE FROM NOTA_CREDITO WHERE fac_id = OLD.fac_id;
   DELETE FROM DETALLE_FACTURA_SERVICIO WHERE fac_id = OLD.fac_id;
+  DELETE FROM DETALLE_FACTURA_PRODUCTO WHERE fac_id = OLD.fac_id;
+  DELETE FROM PAGO_CLIENTE WHERE fac_id = OLD.fac_id;
+  -- Las citas cobradas con la factura vuelven a quedar pendientes de facturar
+  UPDATE CITA SET fac_id = NULL WHERE fac_id = OLD.fac_id;
 END;
 //
 
@@ -111,16 +186,40 @@ BEGIN
     UPDATE DETALLE_FACTURA_SERVICIO 
     SET fac_id = NEW.fac_id 
     WHERE fac_id = OLD.fac_id;
+    UPDATE DETALLE_FACTURA_PRODUCTO SET fac_id = NEW.fac_id WHERE fac_id = OLD.fac_id;
+    UPDATE PAGO_CLIENTE SET fac_id = NEW.fac_id WHERE fac_id = OLD.fac_id;
+    UPDATE NOTA_CREDITO SET fac_id = NEW.fac_id WHERE fac_id = OLD.fac_id;
+    UPDATE CITA SET fac_id = NEW.fac_id WHERE fac_id = OLD.fac_id;
   END IF;
 END;
 //
 
+-- Trigger DELETE con cascada manual para NOTA_CREDITO
+CREATE TRIGGER trg_delete_nota_credito
+BEFORE DELETE ON NOTA_CREDITO
+FOR EACH ROW
+BEGIN
+  DELETE FROM DETALLE_NOTA_CREDITO WHERE nc_id = OLD.nc_id;
+END;
+//
+
+-- Trigger UPDATE con cascada manual para NOTA_CREDITO
+CREATE TRIGGER trg_update_nota_credito
+AFTER UPDATE ON NOTA_CREDITO
+FOR EACH ROW
+BEGIN
+  UPDATE DETALLE_NOTA_CREDITO SET nc_id = NEW.nc_id WHERE nc_id = OLD.nc_id;
+END;
+//
+
 -- Trigger DELETE con cascada manual para COMPRA_PRODUCTO
 CREATE TRIGGER trg_delete_compra
 BEFORE DELETE ON COMPRA_PRODUCTO
 FOR EACH ROW
 BEGIN
   DELETE FROM DETALLE_COMPRA WHERE com_id = OLD.com_id;
+  DELETE FROM RECEPCION_COMPRA WHERE com_id = OLD.com_id;
+  DELETE FROM HISTORIAL_ESTADO_COMPRA WHERE com_id = OLD.com_id;
 END;
 //
 
@@ -130,6 +229,8 @@ AFTER UPDATE ON COMPRA_PRODUCTO
 FOR EACH ROW
 BEGIN
   UPDATE DETALLE_COMPRA SET com_id = NEW.com_id WHERE com_id = OLD.com_id;
+  UPDATE RECEPCION_COMPRA SET com_id = NEW.com_id WHERE com_id = OLD.com_id;
+  UPDATE HISTORIAL_ESTADO_COMPRA SET com_id = NEW.com_id WHERE com_id = OLD.com_id;
 END;
 //
 
@@ -139,6 +240,7 @@ BEFORE DELETE ON CITA
 FOR EACH ROW
 BEGIN
   DELETE FROM HISTORIAL_CITA WHERE cit_id = OLD.cit_id;
+  DELETE FROM HISTORIAL_ESTADO_CITA WHERE cit_id = OLD.cit_id;
 END;
 //
 
@@ -148,6 +250,18 @@ AFTER UPDATE ON CITA
 FOR EACH ROW
 BEGIN
   UPDATE HISTORIAL_CITA SET cit_id = NEW.cit_id WHERE cit_id = OLD.cit_id;
+  UPDATE HISTORIAL_ESTADO_CITA SET cit_id = NEW.cit_id WHERE cit_id = OLD.cit_id;
+END;
+//
+
+-- Trigger DELETE con cascada manual para PROMOCION
+CREATE TRIGGER trg_delete_promocion
+BEFORE DELETE ON PROMOCION
+FOR EACH ROW
+BEGIN
+  DELETE FROM PROMOCION_SERVICIO WHERE pro_id = OLD.pro_id;
+  -- Las líneas facturadas conservan el valor descontado
+  UPDATE DETALLE_FACTURA_SERVICIO SET pro_id = NULL WHERE pro_id = OLD.pro_id;
 END;
 //
 
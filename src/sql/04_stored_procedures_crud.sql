@@ -145,18 +145,23 @@ END;
 
 -- PRODUCTO
 
--- Insertar Producto
+-- Insertar Producto (el stock inicial entra como movimiento de ajuste a su costo unitario)
 CREATE PROCEDURE sp_insert_producto(
-  IN p_nombre VARCHAR(100), IN p_descripcion TEXT, IN p_cantidad TINYINT, IN p_precio DECIMAL(10,2)
+  IN p_nombre VARCHAR(100), IN p_descripcion TEXT, IN p_cantidad INT, IN p_precio DECIMAL(10,2),
+  IN p_costo DECIMAL(12,4), IN p_usuario VARCHAR(100)
 )
 BEGIN
   INSERT INTO PRODUCTO(prod_nombre, prod_descripcion, prod_cantidad_disponible, prod_precio_unitario)
-  VALUES (p_nombre, p_descripcion, p_cantidad, p_precio);
+  VALUES (p_nombre, p_descripcion, 0, p_precio);
 
   SET @last_id = LAST_INSERT_ID();
 
   INSERT INTO INVENTARIO(inv_fecha_actualizacion, prod_id, inv_cantidad_actual, inv_observaciones)
-  VALUES (CURDATE(), @last_id, p_cantidad, NULL);
+  VALUES (CURDATE(), @last_id, 0, NULL);
+
+  IF p_cantidad > 0 THEN
+    CALL sp_registrar_movimiento_inventario(@last_id, 'Ajuste', p_cantidad, p_usuario, 'Stock inicial', NULL, p_costo);
+  END IF;
 END;
 //
 
@@ -168,17 +173,23 @@ BEGIN
 END;
 //
 
--- Actualizar Producto
+-- Actualizar Producto (un cambio de cantidad se registra como movimiento de ajuste)
 CREATE PROCEDURE sp_update_producto(
-  IN p_id INT, IN p_nombre VARCHAR(100), IN p_descripcion TEXT, IN p_cantidad TINYINT, IN p_precio DECIMAL(10,2)
+  IN p_id INT, IN p_nombre VARCHAR(100), IN p_descripcion TEXT, IN p_cantidad INT, IN p_precio DECIMAL(10,2),
+  IN p_usuario VARCHAR(100), IN p_motivo TEXT
 )
 BEGIN
-  UPDATE INVENTARIO
-  SET inv_cantidad_actual = p_cantidad WHERE p_id=prod_id;
+  DECLARE v_actual INT;
+
+  SELECT prod_cantidad_disponible INTO v_actual FROM PRODUCTO WHERE prod_id = p_id FOR UPDATE;
+
+  IF v_actual IS NOT NULL AND p_cantidad <> v_actual THEN
+    CALL sp_registrar_movimiento_inventario(p_id, 'Ajuste', p_cantidad - v_actual, p_usuario,
+      COALESCE(p_motivo, 'Actualización del producto'), NULL, NULL);
+  END IF;
 
   UPDATE PRODUCTO
-  SET prod_nombre = p_nombre, prod_descripcion = p_descripcion, prod_cantidad_disponible = p_cantidad,
-      prod_precio_unitario = p_precio
+  SET prod_nombre = p_nombre, prod_descripcion = p_descripcion, prod_precio_unitario = p_precio
   WHERE prod_id = p_id;
 END;
 //
@@ -338,9 +349,15 @@ CREATE PROCEDURE CrearInventario(
   IN p_fecha DATE,
   IN p_prod_id INT,
   IN p_cantidad INT,
-  IN p_observaciones TEXT
+  IN p_observaciones TEXT,
+  IN p_usuario VARCHAR(100),
+  IN p_motivo TEXT
 )
 BEGIN
+  DECLARE v_actual INT;
+
+  SELECT prod_cantidad_disponible INTO v_actual FROM PRODUCTO WHERE prod_id = p_prod_id FOR UPDATE;
+
   INSERT INTO INVENTARIO (
     inv_fecha_actualizacion,
     prod_id,
@@ -350,9 +367,15 @@ BEGIN
   VALUES (
     p_fecha,
     p_prod_id,
-    p_cantidad,
+    COALESCE(v_actual, 0),
     p_observaciones
   );
+
+  -- La cantidad contada se registra como ajuste sobre el stock actual
+  IF p_cantidad <> COALESCE(v_actual, 0) THEN
+    CALL sp_registrar_movimiento_inventario(p_prod_id, 'Ajuste', p_cantidad - COALESCE(v_actual, 0), p_usuario,
+      COALESCE(p_motivo, p_observaciones, 'Registro de inventario'), NULL, NULL);
+  END IF;
 END;
 //
 
@@ -380,13 +403,29 @@ CREATE PROCEDURE ActualizarInventario(
   IN p_inv_id INT,
   IN p_fecha DATE,
   IN p_cantidad INT,
-  IN p_observaciones TEXT
+  IN p_observaciones TEXT,
+  IN p_usuario VARCHAR(100),
+  IN p_motivo TEXT
 )
 BEGIN
+  DECLARE v_prod_id INT;
+  DECLARE v_actual INT;
+
+  SELECT i.prod_id, p.prod_cantidad_disponible INTO v_prod_id, v_actual
+  FROM INVENTARIO i
+  JOIN PRODUCTO p ON p.prod_id = i.prod_id
+  WHERE i.inv_id = p_inv_id
+  FOR UPDATE;
+
+  -- La cantidad ya no se sobrescribe: la diferencia se registra como ajuste
+  IF v_prod_id IS NOT NULL AND p_cantidad <> v_actual THEN
+    CALL sp_registrar_movimiento_inventario(v_prod_id, 'Ajuste', p_cantidad - v_actual, p_usuario,
+      COALESCE(p_motivo, p_observaciones, 'Ajuste de inventario'), NULL, NULL);
+  END IF;
+
   UPDATE INVENTARIO
   SET
     inv_fecha_actualizacion = p_fecha,
-    inv_cantidad_actual = p_cantidad,
     inv_observaciones = p_observaciones
   WHERE inv_id = p_inv_id;
 END;
@@ -622,9 +661,25 @@ CREATE PROCEDURE sp_crear_promocion (
     IN p_fecha_fin DATE,
     IN p_descuento DECIMAL(5,2),
     IN p_ser_id INT,
-    IN p_usos INT
+    IN p_usos INT,
+    IN p_tipo_descuento VARCHAR(20),
+    IN p_descuento_valor DECIMAL(10,2),
+    IN p_max_usos INT,
+    IN p_max_usos_cliente INT,
+    IN p_monto_minimo DECIMAL(10,2),
+    IN p_codigo VARCHAR(30),
+    IN p_servicios_paquete JSON
 )
 BEGIN
+    DECLARE v_pro_id INT;
+    DECLARE EXIT HANDLER FOR SQLEXCEPTION
+    BEGIN
+        ROLLBACK;
+        RESIGNAL;
+    END;
+
+    START TRANSACTION;
+
     INSERT INTO PROMOCION (
         pro_nombre,
         pro_descripcion,
@@ -632,7 +687,13 @@ BEGIN
         pro_fecha_fin,
         pro_descuento_porcentaje,
         ser_id,
-        pro_usos
+        pro_usos,
+        pro_tipo_descuento,
+        pro_descuento_valor,
+        pro_max_usos,
+        pro_max_usos_cliente,
+        pro_monto_minimo,
+        pro_codigo
     )
     VALUES (
         p_nombre,
@@ -641,8 +702,26 @@ BEGIN
         p_fecha_fin,
         p_descuento,
         p_ser_id,
-        p_usos
+        p_usos,
+        COALESCE(p_tipo_descuento, 'Porcentaje'),
+        p_descuento_valor,
+        p_max_usos,
+        p_max_usos_cliente,
+        p_monto_minimo,
+        p_codigo
     );
+
+    SET v_pro_id = LAST_INSERT_ID();
+
+    -- Servicios adicionales del paquete, recibidos como arreglo JSON de ser_id
+    IF p_servicios_paquete IS NOT NULL THEN
+        INSERT IGNORE INTO PROMOCION_SERVICIO (pro_id, ser_id)
+        SELECT v_pro_id, j.ser_id
+        FROM JSON_TABLE(p_servicios_paquete, '$[*]' COLUMNS (ser_id INT PATH '$')) j
+        WHERE j.ser_id <> p_ser_id;
+    END IF;
+
+    COMMIT;
 END $$
 
 DELIMITER ;
@@ -652,8 +731,10 @@ DELIMITER $$
 
 CREATE PROCEDURE sp_listar_promociones()
 BEGIN
-    SELECT pro_id, pro_nombre, pro_descripcion, pro_fecha_inicio, pro_fecha_fin, pro_descuento_porcentaje, pro_usos, ser_nombre
-    FROM PROMOCION NATURAL JOIN SERVICIO ORDER BY pro_fecha_inicio DESC, pro_fecha_fin DESC;
+    SELECT pro_id, pro_nombre, pro_descripcion, pro_fecha_inicio, pro_fecha_fin, pro_descuento_porcentaje, pro_usos, ser_nombre,
+           pro_tipo_descuento, pro_descuento_valor, pro_max_usos, pro_max_usos_cliente, pro_monto_minimo, pro_codigo,
+           (SELECT GROUP_CONCAT(ps.ser_id ORDER BY ps.ser_id) FROM PROMOCION_SERVICIO ps WHERE ps.pro_id = p.pro_id) AS servicios_paquete
+    FROM PROMOCION p NATURAL JOIN SERVICIO ORDER BY pro_fecha_inicio DESC, pro_fecha_fin DESC;
 END $$
 
 DELIMITER ;
@@ -665,7 +746,10 @@ CREATE PROCEDURE sp_obtener_promocion_por_id (
     IN p_pro_id INT
 )
 BEGIN
-    SELECT * FROM PROMOCION WHERE pro_id = p_pro_id;
+    SELECT p.*,
+           (SELECT GROUP_CONCAT(ps.ser_id ORDER BY ps.ser_id) FROM PROMOCION_SERVICIO ps WHERE ps.pro_id = p.pro_id) AS servicios_paquete
+    FROM PROMOCION p
+    WHERE p.pro_id = p_pro_id;
 END $$
 
 DELIMITER ;
@@ -681,9 +765,24 @@ CREATE PROCEDURE sp_actualizar_promocion (
     IN p_fecha_fin DATE,
     IN p_descuento DECIMAL(5,2),
     IN p_ser_id INT,
-    IN p_usos INT
+    IN p_usos INT,
+    IN p_tipo_descuento VARCHAR(20),
+    IN p_descuento_valor DECIMAL(10,2),
+    IN p_max_usos INT,
+    IN p_max_usos_cliente INT,
+    IN p_monto_minimo DECIMAL(10,2),
+    IN p_codigo VARCHAR(30),
+    IN p_servicios_paquete JSON
 )
 BEGIN
+    DECLARE EXIT HANDLER FOR SQLEXCEPTION
+    BEGIN
+        ROLLBACK;
+        RESIGNAL;
+    END;
+
+    START TRANSACTION;
+
     UPDATE PROMOCION
     SET
         pro_nombre = p_nombre,
@@ -692,8 +791,24 @@ BEGIN
         pro_fecha_fin = p_fecha_fin,
         pro_descuento_porcentaje = p_descuento,
         ser_id = p_ser_id,
-        pro_usos = p_usos
+        pro_usos = p_usos,
+        pro_tipo_descuento = COALESCE(p_tipo_descuento, 'Porcentaje'),
+        pro_descuento_valor = p_descuento_valor,
+        pro_max_usos = p_max_usos,
+        pro_max_usos_cliente = p_max_usos_cliente,
+        pro_monto_minimo = p_monto_minimo,
+        pro_codigo = p_codigo
     WHERE pro_id = p_pro_id;
+
+    DELETE FROM PROMOCION_SERVICIO WHERE pro_id = p_pro_id;
+    IF p_servicios_paquete IS NOT NULL THEN
+        INSERT IGNORE INTO PROMOCION_SERVICIO (pro_id, ser_id)
+        SELECT p_pro_id, j.ser_id
+        FROM JSON_TABLE(p_servicios_paquete, '$[*]' COLUMNS (ser_id INT PATH '$')) j
+        WHERE j.ser_id <> p_ser_id;
+    END IF;
+
+    COMMIT;
 END $$
 
 DELIMITER ;
@@ -766,7 +881,7 @@ DELIMITER $$
 
 CREATE PROCEDURE sp_ver_citas_empleado(IN p_emp_id INT)
 BEGIN
-  SELECT cit_id, cit_fecha, cit_hora, ser_id, cli_id
+  SELECT cit_id, cit_fecha, cit_hora, emp_id, ser_id, cli_id, cit_estado
   FROM CITA
   WHERE emp_id = p_emp_id
   ORDER BY cit_fecha DESC, cit_hora DESC;
@@ -800,6 +915,9 @@ CREATE PROCEDURE sp_insertar_cita (
 BEGIN
     INSERT INTO CITA (cit_fecha, cit_hora, emp_id, ser_id, cli_id)
     VALUES (p_fecha, p_hora, p_emp_id, p_ser_id, p_cli_id);
+
+    INSERT INTO HISTORIAL_ESTADO_CITA (hec_estado_anterior, hec_estado_nuevo, cit_id)
+    VALUES (NULL, 'Programada', LAST_INSERT_ID());
 END$$
 DELIMITER ;
 
@@ -856,7 +974,7 @@ DELIMITER ;
 DELIMITER $$
 CREATE PROCEDURE sp_ver_citas_cliente(IN p_cli_id INT)
 BEGIN
-    SELECT c.cit_id, c.cit_fecha, c.cit_hora, c.emp_id, c.ser_id, c.cli_id,
+    SELECT c.cit_id, c.cit_fecha, c.cit_hora, c.emp_id, c.ser_id, c.cli_id, c.cit_estado,
            e.emp_nombre, e.emp_apellido, e.emp_puesto,
            s.ser_nombre, s.ser_descripcion, s.ser_precio_unitario
     FROM CITA c
@@ -936,19 +1054,6 @@ BEGIN
 END$$
 DELIMITER ;
 
--- Insertar una nueva factura (sin total, será calculado por trigger)
-DELIMITER $$
-CREATE PROCEDURE sp_insertar_factura_sin_total (
-    IN p_fecha DATE,
-    IN p_hora TIME,
-    IN p_cli_id INT
-)
-BEGIN
-    INSERT INTO FACTURA_SERVICIO (fac_total, fac_fecha, fac_hora, cli_id)
-    VALUES (0, p_fecha, p_hora, p_cli_id);
-END$$
-DELIMITER ;
-
 -- Insertar una nueva factura (mantener compatibilidad)
 DELIMITER $$
 CREATE PROCEDURE sp_insertar_factura (
@@ -981,63 +1086,210 @@ BEGIN
 END$$
 DELIMITER ;
 
--- Obtener el ID de la última factura insertada
-DELIMITER $$
-CREATE PROCEDURE sp_obtener_ultima_factura()
-BEGIN
-    SELECT * FROM FACTURA_SERVICIO ORDER BY fac_id DESC LIMIT 1;
-END$$
-DELIMITER ;
-
--- Actualizar factura existente
+-- Actualizar factura existente (solo borradores)
 DELIMITER $$
 CREATE PROCEDURE sp_actualizar_factura (
     IN p_fac_id INT,
-    IN p_total DECIMAL(10,2),
     IN p_fecha DATE,
     IN p_hora TIME,
     IN p_cli_id INT
 )
 BEGIN
+    CALL sp_validar_factura_borrador(p_fac_id);
+
     UPDATE FACTURA_SERVICIO
-    SET fac_total = p_total,
-        fac_fecha = p_fecha,
+    SET fac_fecha = p_fecha,
         fac_hora = p_hora,
         cli_id = p_cli_id
     WHERE fac_id = p_fac_id;
+
+    -- Los totales siempre salen de las líneas
+    CALL sp_recalcular_total_factura(p_fac_id);
 END$$
 DELIMITER ;
 
--- Eliminar una factura
+-- Eliminar una factura en borrador; los productos vendidos en ella vuelven al stock.
+-- Las facturas emitidas se corrigen con notas crédito o se anulan, nunca se eliminan
 DELIMITER $$
 CREATE PROCEDURE sp_eliminar_factura (
-    IN p_fac_id INT
+    IN p_fac_id INT,
+    IN p_usuario VARCHAR(100)
 )
 BEGIN
+    CALL sp_validar_factura_borrador(p_fac_id);
+    CALL sp_devolver_productos_factura(p_fac_id, p_usuario);
     DELETE FROM FACTURA_SERVICIO WHERE fac_id = p_fac_id;
 END$$
 DELIMITER ;
 
--- Insertar detalle de factura con recálculo automático del total
+-- Recalcular el total de una factura a partir de sus líneas de servicios y de productos
+-- (precio por cantidad menos descuento). Los precios incluyen IVA: el impuesto de cada línea es
+-- total * tarifa / (100 + tarifa), redondeado por línea, y el subtotal es el total sin impuesto
 DELIMITER $$
-CREATE PROCEDURE sp_insertar_detalle_factura (
-    IN p_fac_id INT,
-    IN p_ser_id INT
+CREATE PROCEDURE sp_recalcular_total_factura (
+    IN p_fac_id INT
 )
 BEGIN
-    -- Insert the detail
-    INSERT INTO DETALLE_FACTURA_SERVICIO (fac_id, ser_id)
-    VALUES (p_fac_id, p_ser_id);
-    
-    -- Recalculate and update the invoice total
-    UPDATE FACTURA_SERVICIO 
+    UPDATE FACTURA_SERVICIO
     SET fac_total = (
-        SELECT COALESCE(SUM(s.ser_precio_unitario), 0)
+        SELECT COALESCE(SUM(dfs.dfs_cantidad * dfs.dfs_precio_unitario - dfs.dfs_descuento), 0)
         FROM DETALLE_FACTURA_SERVICIO dfs
-        JOIN SERVICIO s ON dfs.ser_id = s.ser_id
         WHERE dfs.fac_id = p_fac_id
-    )
+    ) + (
+        SELECT COALESCE(SUM(dfp.dfp_cantidad * dfp.dfp_precio_unitario - dfp.dfp_descuento), 0)
+        FROM DETALLE_FACTURA_PRODUCTO dfp
+        WHERE dfp.fac_id = p_fac_id
+    ),
+    fac_impuesto = (
+        SELECT COALESCE(SUM(ROUND((dfs.dfs_cantidad * dfs.dfs_precio_unitario - dfs.dfs_descuento)
+            * dfs.dfs_iva_porcentaje / (100 + dfs.dfs_iva_porcentaje), 2)), 0)
+        FROM DETALLE_FACTURA_SERVICIO dfs
+        WHERE dfs.fac_id = p_fac_id
+    ) + (
+        SELECT COALESCE(SUM(ROUND((dfp.dfp_cantidad * dfp.dfp_precio_unitario - dfp.dfp_descuento)
+            * dfp.dfp_iva_porcentaje / (100 + dfp.dfp_iva_porcentaje), 2)), 0)
+        FROM DETALLE_FACTURA_PRODUCTO dfp
+        WHERE dfp.fac_id = p_fac_id
+    ),
+    fac_subtotal = fac_total - fac_impuesto
     WHERE fac_id = p_fac_id;
+
+    -- Un cambio en el total puede cambiar el estado de pago
+    CALL sp_actualizar_estado_pago_factura(p_fac_id);
+END$$
+DELIMITER ;
+
+-- Devolver los usos de las promociones aplicadas en una factura
+DELIMITER $$
+CREATE PROCEDURE sp_liberar_promociones_factura (
+    IN p_fac_id INT
+)
+BEGIN
+    -- pro_usos cuenta facturas: cada promoción presente en la factura devuelve un uso
+    UPDATE PROMOCION p
+    JOIN (
+        SELECT DISTINCT pro_id
+        FROM DETALLE_FACTURA_SERVICIO
+        WHERE fac_id = p_fac_id AND pro_id IS NOT NULL
+    ) d ON p.pro_id = d.pro_id
+    SET p.pro_usos = GREATEST(COALESCE(p.pro_usos, 0) - 1, 0);
+END$$
+DELIMITER ;
+
+-- Registrar una línea de factura con la promoción indicada. Bloquea la promoción y vuelve a
+-- validar sus límites de usos; si ya no aplica deja el motivo en p_motivo y no inserta la línea.
+-- pro_usos cuenta facturas, por lo que solo se incrementa con la primera línea de la factura.
+-- Cada llamada crea una línea nueva, así que un servicio puede repetirse en la misma factura.
+DELIMITER $$
+CREATE PROCEDURE sp_registrar_linea_factura (
+    IN p_fac_id INT,
+    IN p_ser_id INT,
+    IN p_cantidad INT,
+    IN p_emp_id INT,
+    IN p_pro_id INT,
+    IN p_descuento DECIMAL(10,2),
+    OUT p_motivo VARCHAR(30)
+)
+proc: BEGIN
+    DECLARE v_precio DECIMAL(10,2);
+    DECLARE v_iva DECIMAL(5,2);
+    DECLARE v_cli_id INT;
+    DECLARE v_usos INT;
+    DECLARE v_max_usos INT;
+    DECLARE v_max_usos_cliente INT;
+    DECLARE v_usos_cliente INT;
+    DECLARE v_lineas_promocion INT;
+
+    SET p_motivo = NULL;
+
+    CALL sp_validar_factura_borrador(p_fac_id);
+
+    IF p_cantidad IS NULL OR p_cantidad <= 0 THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La cantidad del servicio debe ser mayor que cero';
+    END IF;
+
+    SELECT ser_precio_unitario, ser_iva_porcentaje INTO v_precio, v_iva FROM SERVICIO WHERE ser_id = p_ser_id;
+
+    IF v_precio IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'El servicio no existe';
+    END IF;
+
+    IF p_pro_id IS NOT NULL THEN
+        SELECT COALESCE(pro_usos, 0), pro_max_usos, pro_max_usos_cliente
+        INTO v_usos, v_max_usos, v_max_usos_cliente
+        FROM PROMOCION
+        WHERE pro_id = p_pro_id
+        FOR UPDATE;
+
+        SELECT COUNT(*) INTO v_lineas_promocion
+        FROM DETALLE_FACTURA_SERVICIO
+        WHERE fac_id = p_fac_id AND pro_id = p_pro_id;
+
+        IF v_lineas_promocion = 0 THEN
+            IF v_max_usos IS NOT NULL AND v_usos >= v_max_usos THEN
+                SET p_motivo = 'limite_usos';
+                LEAVE proc;
+            END IF;
+
+            SELECT cli_id INTO v_cli_id FROM FACTURA_SERVICIO WHERE fac_id = p_fac_id;
+            SELECT COUNT(DISTINCT dfs.fac_id) INTO v_usos_cliente
+            FROM DETALLE_FACTURA_SERVICIO dfs
+            JOIN FACTURA_SERVICIO f ON dfs.fac_id = f.fac_id
+            WHERE dfs.pro_id = p_pro_id AND f.cli_id = v_cli_id
+              AND f.fac_estado <> 'Anulada';
+
+            IF v_max_usos_cliente IS NOT NULL AND v_usos_cliente >= v_max_usos_cliente THEN
+                SET p_motivo = 'limite_cliente';
+                LEAVE proc;
+            END IF;
+
+            UPDATE PROMOCION SET pro_usos = v_usos + 1 WHERE pro_id = p_pro_id;
+        END IF;
+    END IF;
+
+    -- El precio y la tarifa de IVA se copian del servicio: cambios posteriores de SERVICIO no alteran la factura
+    INSERT INTO DETALLE_FACTURA_SERVICIO (fac_id, ser_id, dfs_cantidad, dfs_precio_unitario, dfs_descuento, pro_id, emp_id,
+        dfs_iva_porcentaje)
+    VALUES (p_fac_id, p_ser_id, p_cantidad, v_precio, LEAST(COALESCE(p_descuento, 0), v_precio * p_cantidad),
+        p_pro_id, p_emp_id, v_iva);
+END$$
+DELIMITER ;
+
+-- Insertar una línea de factura con una promoción ya evaluada por la aplicación (cupones,
+-- paquetes, montos mínimos). Devuelve si la promoción se pudo aplicar y el motivo si no.
+DELIMITER $$
+CREATE PROCEDURE sp_insertar_detalle_factura_promocion (
+    IN p_fac_id INT,
+    IN p_ser_id INT,
+    IN p_cantidad INT,
+    IN p_emp_id INT,
+    IN p_pro_id INT,
+    IN p_descuento DECIMAL(10,2)
+)
+BEGIN
+    DECLARE v_motivo VARCHAR(30);
+
+    CALL sp_registrar_linea_factura(p_fac_id, p_ser_id, p_cantidad, p_emp_id, p_pro_id, p_descuento, v_motivo);
+
+    SELECT v_motivo IS NULL AS aplicada, v_motivo AS motivo;
+END$$
+DELIMITER ;
+
+-- Insertar una línea de factura sin promoción y recalcular el total (la usan los datos de prueba).
+-- Las promociones de las líneas que se agregan desde la API las elige la aplicación con las mismas
+-- reglas de una factura nueva y se guardan con sp_insertar_detalle_factura_promocion.
+DELIMITER $$
+CREATE PROCEDURE sp_insertar_detalle_factura (
+    IN p_fac_id INT,
+    IN p_ser_id INT,
+    IN p_cantidad INT,
+    IN p_emp_id INT
+)
+BEGIN
+    DECLARE v_motivo VARCHAR(30);
+
+    CALL sp_registrar_linea_factura(p_fac_id, p_ser_id, p_cantidad, p_emp_id, NULL, 0, v_motivo);
+    CALL sp_recalcular_total_factura(p_fac_id);
 END$$
 DELIMITER ;
 
@@ -1059,48 +1311,56 @@ BEGIN
 END$$
 DELIMITER ;
 
--- Actualizar detalle de factura con recálculo automático del total
+-- Eliminar detalle de factura con recálculo automático del total
 DELIMITER $$
-CREATE PROCEDURE sp_actualizar_detalle_factura (
-    IN p_fac_id INT,
-    IN p_ser_id INT
+CREATE PROCEDURE sp_eliminar_detalle_factura (
+    IN p_fac_id INT
 )
 BEGIN
-    -- Update the detail
-    UPDATE DETALLE_FACTURA_SERVICIO
-    SET ser_id = p_ser_id
-    WHERE fac_id = p_fac_id;
-    
+    CALL sp_validar_factura_borrador(p_fac_id);
+
+    -- Give back the promotion uses of the removed lines
+    CALL sp_liberar_promociones_factura(p_fac_id);
+
+    -- Delete the detail
+    DELETE FROM DETALLE_FACTURA_SERVICIO WHERE fac_id = p_fac_id;
+
     -- Recalculate and update the invoice total
-    UPDATE FACTURA_SERVICIO 
-    SET fac_total = (
-        SELECT COALESCE(SUM(s.ser_precio_unitario), 0)
-        FROM DETALLE_FACTURA_SERVICIO dfs
-        JOIN SERVICIO s ON dfs.ser_id = s.ser_id
-        WHERE dfs.fac_id = p_fac_id
-    )
-    WHERE fac_id = p_fac_id;
+    CALL sp_recalcular_total_factura(p_fac_id);
 END$$
 DELIMITER ;
 
--- Eliminar detalle de factura con recálculo automático del total
+-- Eliminar una línea de servicio de una factura. Si la línea tenía promoción, la promoción se
+-- retira también de las demás líneas de la factura (un paquete incompleto deja de aplicar) y se
+-- devuelve su uso; luego se recalcula el total
 DELIMITER $$
-CREATE PROCEDURE sp_eliminar_detalle_factura (
-    IN p_fac_id INT
+CREATE PROCEDURE sp_eliminar_linea_factura (
+    IN p_fac_id INT,
+    IN p_dfs_id INT
 )
 BEGIN
-    -- Delete the detail
-    DELETE FROM DETALLE_FACTURA_SERVICIO WHERE fac_id = p_fac_id;
-    
-    -- Recalculate and update the invoice total
-    UPDATE FACTURA_SERVICIO 
-    SET fac_total = (
-        SELECT COALESCE(SUM(s.ser_precio_unitario), 0)
-        FROM DETALLE_FACTURA_SERVICIO dfs
-        JOIN SERVICIO s ON dfs.ser_id = s.ser_id
-        WHERE dfs.fac_id = p_fac_id
-    )
-    WHERE fac_id = p_fac_id;
+    DECLARE v_existe INT;
+    DECLARE v_pro_id INT;
+
+    CALL sp_validar_factura_borrador(p_fac_id);
+
+    SELECT dfs_id, pro_id INTO v_existe, v_pro_id
+    FROM DETALLE_FACTURA_SERVICIO
+    WHERE dfs_id = p_dfs_id AND fac_id = p_fac_id
+    FOR UPDATE;
+
+    IF v_existe IS NOT NULL THEN
+        IF v_pro_id IS NOT NULL THEN
+            UPDATE PROMOCION SET pro_usos = GREATEST(COALESCE(pro_usos, 0) - 1, 0) WHERE pro_id = v_pro_id;
+            UPDATE DETALLE_FACTURA_SERVICIO
+            SET pro_id = NULL, dfs_descuento = 0
+            WHERE fac_id = p_fac_id AND pro_id = v_pro_id;
+        END IF;
+
+        DELETE FROM DETALLE_FACTURA_SERVICIO WHERE dfs_id = p_dfs_id;
+
+        CALL sp_recalcular_total_factura(p_fac_id);
+    END IF;
 END$$
 DELIMITER ;
 
@@ -1116,14 +1376,31 @@ BEGIN
         fs.fac_fecha,
         fs.fac_hora,
         fs.cli_id,
+        fs.fac_pagado,
+        fs.fac_estado_pago,
+        fs.fac_estado,
+        fs.fac_acreditado,
+        fs.fac_reembolsado,
+        fs.fac_numero,
+        fs.fac_subtotal,
+        fs.fac_impuesto,
         CONCAT(c.cli_nombre, ' ', c.cli_apellido) AS cli_nombre,
-        COALESCE(GROUP_CONCAT(s.ser_nombre SEPARATOR ', '), '') AS servicios
+        COALESCE(GROUP_CONCAT(s.ser_nombre SEPARATOR ', '), '') AS servicios,
+        COALESCE((
+            SELECT GROUP_CONCAT(CONCAT(dfp.dfp_cantidad, ' x ', p.prod_nombre) SEPARATOR ', ')
+            FROM DETALLE_FACTURA_PRODUCTO dfp
+            JOIN PRODUCTO p ON dfp.prod_id = p.prod_id
+            WHERE dfp.fac_id = fs.fac_id
+        ), '') AS productos
     FROM FACTURA_SERVICIO fs
     INNER JOIN CLIENTE c ON fs.cli_id = c.cli_id
     LEFT JOIN DETALLE_FACTURA_SERVICIO dfs ON fs.fac_id = dfs.fac_id
     LEFT JOIN SERVICIO s ON dfs.ser_id = s.ser_id
     WHERE fs.cli_id = p_cli_id
-    GROUP BY fs.fac_id, fs.fac_total, fs.fac_fecha, fs.fac_hora, fs.cli_id, c.cli_nombre, c.cli_apellido
+      AND fs.fac_estado <> 'Borrador'
+    GROUP BY fs.fac_id, fs.fac_total, fs.fac_fecha, fs.fac_hora, fs.cli_id, fs.fac_pagado, fs.fac_estado_pago,
+        fs.fac_estado, fs.fac_acreditado, fs.fac_reembolsado, fs.fac_numero, fs.fac_subtotal, fs.fac_impuesto,
+        c.cli_nombre, c.cli_apellido
     ORDER BY fs.fac_fecha DESC, fs.fac_hora DESC;
 END$$
 DELIMITER ;
@@ -1204,16 +1481,39 @@ DELIMITER ;
 DELIMITER $$
 CREATE PROCEDURE sp_insertar_compra (
     IN p_fecha DATE,
-    IN p_total DECIMAL(10,2),
     IN p_metodo_pago VARCHAR(50),
     IN p_prov_id INT,
     IN p_gas_id INT
 )
 BEGIN
+    -- El total se calcula a partir de las líneas con sp_recalcular_total_compra
     INSERT INTO COMPRA_PRODUCTO (
         cop_fecha_compra, cop_total_compra, cop_metodo_pago, prov_id, gas_id
     )
-    VALUES (p_fecha, p_total, p_metodo_pago, p_prov_id, p_gas_id);
+    VALUES (p_fecha, 0, p_metodo_pago, p_prov_id, p_gas_id);
+
+    SET @com_id = LAST_INSERT_ID();
+
+    INSERT INTO HISTORIAL_ESTADO_COMPRA (com_id, hco_estado_anterior, hco_estado_nuevo)
+    VALUES (@com_id, NULL, 'Borrador');
+
+    SELECT * FROM COMPRA_PRODUCTO WHERE com_id = @com_id;
+END$$
+DELIMITER ;
+
+-- Recalcular el total de una compra a partir de sus líneas
+DELIMITER $$
+CREATE PROCEDURE sp_recalcular_total_compra (
+    IN p_com_id INT
+)
+BEGIN
+    UPDATE COMPRA_PRODUCTO
+    SET cop_total_compra = (
+        SELECT COALESCE(SUM(dec_cantidad * dec_precio_unitario), 0)
+        FROM DETALLE_COMPRA
+        WHERE com_id = p_com_id
+    )
+    WHERE com_id = p_com_id;
 END$$
 DELIMITER ;
 
@@ -1232,21 +1532,11 @@ BEGIN
 END$$
 DELIMITER ;
 
--- Obtener última compra insertada
-DELIMITER $$
-CREATE PROCEDURE sp_obtener_ultima_compra()
-BEGIN
-    SELECT * FROM COMPRA_PRODUCTO 
-    WHERE com_id = LAST_INSERT_ID();
-END$$
-DELIMITER ;
-
--- Actualizar compra
+-- Actualizar compra (el total siempre sale de las líneas)
 DELIMITER $$
 CREATE PROCEDURE sp_actualizar_compra (
     IN p_com_id INT,
     IN p_fecha DATE,
-    IN p_total DECIMAL(10,2),
     IN p_metodo_pago VARCHAR(50),
     IN p_prov_id INT,
     IN p_gas_id INT
@@ -1254,11 +1544,12 @@ CREATE PROCEDURE sp_actualizar_compra (
 BEGIN
     UPDATE COMPRA_PRODUCTO
     SET cop_fecha_compra = p_fecha,
-        cop_total_compra = p_total,
         cop_metodo_pago = p_metodo_pago,
         prov_id = p_prov_id,
         gas_id = p_gas_id
     WHERE com_id = p_com_id;
+
+    CALL sp_recalcular_total_compra(p_com_id);
 END$$
 DELIMITER ;
 
@@ -1268,6 +1559,18 @@ CREATE PROCEDURE sp_eliminar_compra (
     IN p_com_id INT
 )
 BEGIN
+    DECLARE v_estado VARCHAR(25);
+
+    -- Solo borradores o canceladas: las demás ya pudieron mover stock con sus recepciones
+    SELECT cop_estado INTO v_estado
+    FROM COMPRA_PRODUCTO
+    WHERE com_id = p_com_id
+    FOR UPDATE;
+
+    IF v_estado IS NOT NULL AND v_estado NOT IN ('Borrador', 'Cancelada') THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Solo se pueden eliminar compras en borrador o canceladas';
+    END IF;
+
     -- First delete details
     DELETE FROM DETALLE_COMPRA WHERE com_id = p_com_id;
     -- Then delete the purchase
@@ -1277,19 +1580,27 @@ DELIMITER ;
 
 -- SELECT a COMPRA con PRODUCTOS
 DELIMITER $$
-CREATE PROCEDURE sp_listar_compras()
+CREATE PROCEDURE sp_listar_compras(
+    IN p_estado VARCHAR(25),
+    IN p_prov_id INT
+)
 BEGIN
+    -- Filtros opcionales: NULL devuelve todas las compras
     SELECT
         cp.com_id,
         cp.cop_fecha_compra,
         p.prov_nombre AS proveedor,
         GROUP_CONCAT(pr.prod_nombre SEPARATOR ', ') AS productos,
         cp.cop_total_compra,
-        cp.cop_metodo_pago
+        cp.cop_metodo_pago,
+        cp.cop_estado,
+        cp.prov_id
     FROM COMPRA_PRODUCTO cp
     INNER JOIN PROVEEDOR p ON cp.prov_id = p.prov_id
     LEFT JOIN DETALLE_COMPRA dc ON cp.com_id = dc.com_id
     LEFT JOIN PRODUCTO pr ON dc.prod_id = pr.prod_id
+    WHERE (p_estado IS NULL OR cp.cop_estado = p_estado)
+      AND (p_prov_id IS NULL OR cp.prov_id = p_prov_id)
     GROUP BY cp.com_id
     ORDER BY cp.cop_fecha_compra DESC;
 END$$
@@ -1387,6 +1698,1933 @@ BEGIN
 END$$
 DELIMITER ;
 
+-- HORARIO_EMPLEADO
+
+-- Listar horario semanal de un empleado
+DELIMITER $$
+CREATE PROCEDURE sp_listar_horarios_empleado (
+    IN p_emp_id INT
+)
+BEGIN
+    SELECT *
+    FROM HORARIO_EMPLEADO
+    WHERE emp_id = p_emp_id
+    ORDER BY FIELD(hor_dia_semana, 'Lunes', 'Martes', 'Miercoles', 'Jueves', 'Viernes', 'Sabado', 'Domingo'),
+             hor_hora_entrada;
+END$$
+DELIMITER ;
+
+-- Buscar horario por ID
+DELIMITER $$
+CREATE PROCEDURE sp_buscar_horario_por_id (
+    IN p_hor_id INT
+)
+BEGIN
+    SELECT * FROM HORARIO_EMPLEADO WHERE hor_id = p_hor_id;
+END$$
+DELIMITER ;
+
+-- Insertar horario
+DELIMITER $$
+CREATE PROCEDURE sp_insertar_horario (
+    IN p_dia_semana VARCHAR(20),
+    IN p_hora_entrada TIME,
+    IN p_hora_salida TIME,
+    IN p_emp_id INT
+)
+BEGIN
+    IF p_hora_entrada >= p_hora_salida THEN
+        SIGNAL SQLSTATE '45000'
+            SET MESSAGE_TEXT = 'La hora de entrada debe ser anterior a la hora de salida.';
+    END IF;
+
+    INSERT INTO HORARIO_EMPLEADO (hor_dia_semana, hor_hora_entrada, hor_hora_salida, emp_id)
+    VALUES (p_dia_semana, p_hora_entrada, p_hora_salida, p_emp_id);
+END$$
+DELIMITER ;
+
+-- Actualizar horario
+DELIMITER $$
+CREATE PROCEDURE sp_actualizar_horario (
+    IN p_hor_id INT,
+    IN p_dia_semana VARCHAR(20),
+    IN p_hora_entrada TIME,
+    IN p_hora_salida TIME
+)
+BEGIN
+    IF p_hora_entrada >= p_hora_salida THEN
+        SIGNAL SQLSTATE '45000'
+            SET MESSAGE_TEXT = 'La hora de entrada debe ser anterior a la hora de salida.';
+    END IF;
+
+    UPDATE HORARIO_EMPLEADO
+    SET hor_dia_semana = p_dia_semana,
+        hor_hora_entrada = p_hora_entrada,
+        hor_hora_salida = p_hora_salida
+    WHERE hor_id = p_hor_id;
+END$$
+DELIMITER ;
+
+-- Eliminar horario
+DELIMITER $$
+CREATE PROCEDURE sp_eliminar_horario (
+    IN p_hor_id INT
+)
+BEGIN
+    DELETE FROM HORARIO_EMPLEADO WHERE hor_id = p_hor_id;
+END$$
+DELIMITER ;
+
+-- HORARIO_EXCEPCION
+
+-- Listar excepciones de horario de un empleado
+DELIMITER $$
+CREATE PROCEDURE sp_listar_excepciones_empleado (
+    IN p_emp_id INT
+)
+BEGIN
+    SELECT *
+    FROM HORARIO_EXCEPCION
+    WHERE emp_id = p_emp_id
+    ORDER BY hex_fecha DESC;
+END$$
+DELIMITER ;
+
+-- Buscar excepción por ID
+DELIMITER $$
+CREATE PROCEDURE sp_buscar_excepcion_por_id (
+    IN p_hex_id INT
+)
+BEGIN
+    SELECT * FROM HORARIO_EXCEPCION WHERE hex_id = p_hex_id;
+END$$
+DELIMITER ;
+
+-- Insertar excepción de horario (días libres, festivos, horarios especiales)
+DELIMITER $$
+CREATE PROCEDURE sp_insertar_excepcion (
+    IN p_fecha DATE,
+    IN p_tipo VARCHAR(20),
+    IN p_hora_entrada TIME,
+    IN p_hora_salida TIME,
+    IN p_motivo TEXT,
+    IN p_emp_id INT
+)
+BEGIN
+    INSERT INTO HORARIO_EXCEPCION (hex_fecha, hex_tipo, hex_hora_entrada, hex_hora_salida, hex_motivo, emp_id)
+    VALUES (p_fecha, p_tipo, p_hora_entrada, p_hora_salida, p_motivo, p_emp_id);
+END$$
+DELIMITER ;
+
+-- Actualizar excepción de horario
+DELIMITER $$
+CREATE PROCEDURE sp_actualizar_excepcion (
+    IN p_hex_id INT,
+    IN p_fecha DATE,
+    IN p_tipo VARCHAR(20),
+    IN p_hora_entrada TIME,
+    IN p_hora_salida TIME,
+    IN p_motivo TEXT
+)
+BEGIN
+    UPDATE HORARIO_EXCEPCION
+    SET hex_fecha = p_fecha,
+        hex_tipo = p_tipo,
+        hex_hora_entrada = p_hora_entrada,
+        hex_hora_salida = p_hora_salida,
+        hex_motivo = p_motivo
+    WHERE hex_id = p_hex_id;
+END$$
+DELIMITER ;
+
+-- Eliminar excepción de horario
+DELIMITER $$
+CREATE PROCEDURE sp_eliminar_excepcion (
+    IN p_hex_id INT
+)
+BEGIN
+    DELETE FROM HORARIO_EXCEPCION WHERE hex_id = p_hex_id;
+END$$
+DELIMITER ;
+
+-- DISPONIBILIDAD DE CITAS
+
+-- Horarios de todos los empleados (o de uno) para un día de la semana
+DELIMITER $$
+CREATE PROCEDURE sp_listar_horarios_por_dia (
+    IN p_dia_semana VARCHAR(20),
+    IN p_emp_id INT
+)
+BEGIN
+    SELECT *
+    FROM HORARIO_EMPLEADO
+    WHERE hor_dia_semana = p_dia_semana
+      AND (p_emp_id IS NULL OR emp_id = p_emp_id)
+    ORDER BY emp_id, hor_hora_entrada;
+END$$
+DELIMITER ;
+
+-- Excepciones de horario de todos los empleados (o de uno) para una fecha
+DELIMITER $$
+CREATE PROCEDURE sp_listar_excepciones_por_fecha (
+    IN p_fecha DATE,
+    IN p_emp_id INT
+)
+BEGIN
+    SELECT *
+    FROM HORARIO_EXCEPCION
+    WHERE hex_fecha = p_fecha
+      AND (p_emp_id IS NULL OR emp_id = p_emp_id)
+    ORDER BY emp_id, hex_hora_entrada;
+END$$
+DELIMITER ;
+
+-- Citas de una fecha con la duración estimada de su servicio. Como serviceDuration en Go, un servicio
+-- sin duración o con duración cero ocupa 60 minutos (lo mismo en sp_buscar_conflicto_cita)
+DELIMITER $$
+CREATE PROCEDURE sp_listar_citas_por_fecha (
+    IN p_fecha DATE,
+    IN p_emp_id INT
+)
+BEGIN
+    SELECT c.cit_id, c.cit_fecha, c.cit_hora, c.emp_id, c.ser_id, c.cli_id,
+           IF(s.ser_duracion_estimada > 0, s.ser_duracion_estimada, 60) AS ser_duracion_estimada
+    FROM CITA c
+    JOIN SERVICIO s ON c.ser_id = s.ser_id
+    WHERE c.cit_fecha = p_fecha
+      AND c.cit_estado NOT IN ('Cancelada', 'No Asistio')
+      AND (p_emp_id IS NULL OR c.emp_id = p_emp_id)
+    ORDER BY c.emp_id, c.cit_hora;
+END$$
+DELIMITER ;
+
+-- Buscar una cita que se cruce con el horario solicitado para el empleado o el cliente.
+-- Debe llamarse dentro de la misma transacción que inserta/actualiza la cita: el bloqueo de
+-- las filas de EMPLEADO y CLIENTE serializa las reservas concurrentes.
+DELIMITER $$
+CREATE PROCEDURE sp_buscar_conflicto_cita (
+    IN p_cit_id INT,
+    IN p_fecha DATE,
+    IN p_hora TIME,
+    IN p_emp_id INT,
+    IN p_ser_id INT,
+    IN p_cli_id INT
+)
+BEGIN
+    DECLARE v_bloqueo INT;
+    DECLARE v_duracion INT;
+
+    SELECT emp_id INTO v_bloqueo FROM EMPLEADO WHERE emp_id = p_emp_id FOR UPDATE;
+    SELECT cli_id INTO v_bloqueo FROM CLIENTE WHERE cli_id = p_cli_id FOR UPDATE;
+
+    SELECT IF(ser_duracion_estimada > 0, ser_duracion_estimada, 60) INTO v_duracion
+    FROM SERVICIO
+    WHERE ser_id = p_ser_id;
+
+    SELECT c.cit_id, c.cit_fecha, c.cit_hora, c.emp_id, c.ser_id, c.cli_id,
+           IF(s.ser_duracion_estimada > 0, s.ser_duracion_estimada, 60) AS ser_duracion_estimada
+    FROM CITA c
+    JOIN SERVICIO s ON c.ser_id = s.ser_id
+    WHERE c.cit_fecha = p_fecha
+      AND c.cit_id <> COALESCE(p_cit_id, 0)
+      AND c.cit_estado NOT IN ('Cancelada', 'No Asistio')
+      AND (c.emp_id = p_emp_id OR c.cli_id = p_cli_id)
+      AND c.cit_hora < ADDTIME(p_hora, SEC_TO_TIME(COALESCE(v_duracion, 60) * 60))
+      AND p_hora < ADDTIME(c.cit_hora, SEC_TO_TIME(IF(s.ser_duracion_estimada > 0, s.ser_duracion_estimada, 60) * 60))
+    ORDER BY c.emp_id = p_emp_id DESC, c.cit_hora
+    LIMIT 1;
+END$$
+DELIMITER ;
+
+-- Cambiar el estado de una cita validando la transición y registrando la fecha del cambio
+DELIMITER $$
+CREATE PROCEDURE sp_cambiar_estado_cita (
+    IN p_cit_id INT,
+    IN p_estado VARCHAR(20),
+    IN p_observaciones TEXT,
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_estado_actual VARCHAR(20);
+    DECLARE EXIT HANDLER FOR SQLEXCEPTION
+    BEGIN
+        ROLLBACK;
+        RESIGNAL;
+    END;
+
+    START TRANSACTION;
+
+    SELECT cit_estado INTO v_estado_actual
+    FROM CITA
+    WHERE cit_id = p_cit_id
+    FOR UPDATE;
+
+    IF v_estado_actual IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La cita no existe';
+    END IF;
+
+    IF NOT (
+        (v_estado_actual = 'Programada' AND p_estado IN ('Confirmada', 'En Curso', 'Cancelada', 'No Asistio')) OR
+        (v_estado_actual = 'Confirmada' AND p_estado IN ('En Curso', 'Cancelada', 'No Asistio')) OR
+        (v_estado_actual = 'En Curso' AND p_estado = 'Completada')
+    ) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Transición de estado de cita no permitida';
+    END IF;
+
+    UPDATE CITA SET cit_estado = p_estado WHERE cit_id = p_cit_id;
+
+    INSERT INTO HISTORIAL_ESTADO_CITA (hec_estado_anterior, hec_estado_nuevo, hec_observaciones, cit_id)
+    VALUES (v_estado_actual, p_estado, p_observaciones, p_cit_id);
+
+    IF p_estado = 'Completada' THEN
+        CALL sp_descontar_productos_cita(p_cit_id, p_usuario);
+    END IF;
+
+    COMMIT;
+END$$
+DELIMITER ;
+
+-- Historial de cambios de estado de una cita
+DELIMITER $$
+CREATE PROCEDURE sp_listar_historial_estado_cita (
+    IN p_cit_id INT
+)
+BEGIN
+    SELECT * FROM HISTORIAL_ESTADO_CITA WHERE cit_id = p_cit_id ORDER BY hec_fecha, hec_id;
+END$$
+DELIMITER ;
+
+-- Cancelación de una cita por parte del cliente que la agendó.
+-- p_tardia marca las cancelaciones hechas dentro del plazo mínimo de aviso.
+DELIMITER $$
+CREATE PROCEDURE sp_cancelar_cita_cliente (
+    IN p_cit_id INT,
+    IN p_cli_id INT,
+    IN p_tardia TINYINT(1),
+    IN p_motivo TEXT
+)
+BEGIN
+    DECLARE v_estado_actual VARCHAR(20);
+    DECLARE v_cli_id INT;
+    DECLARE EXIT HANDLER FOR SQLEXCEPTION
+    BEGIN
+        ROLLBACK;
+        RESIGNAL;
+    END;
+
+    START TRANSACTION;
+
+    SELECT cit_estado, cli_id INTO v_estado_actual, v_cli_id
+    FROM CITA
+    WHERE cit_id = p_cit_id
+    FOR UPDATE;
+
+    IF v_cli_id IS NULL OR v_cli_id <> p_cli_id THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La cita no pertenece al cliente';
+    END IF;
+
+    IF v_estado_actual NOT IN ('Programada', 'Confirmada') THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Transición de estado de cita no permitida';
+    END IF;
+
+    UPDATE CITA
+    SET cit_estado = 'Cancelada',
+        cit_cancelacion_tardia = p_tardia
+    WHERE cit_id = p_cit_id;
+
+    INSERT INTO HISTORIAL_ESTADO_CITA (hec_estado_anterior, hec_estado_nuevo, hec_observaciones, cit_id)
+    VALUES (v_estado_actual, 'Cancelada', p_motivo, p_cit_id);
+
+    COMMIT;
+END$$
+DELIMITER ;
+
+-- Citas canceladas por el cliente dentro del plazo mínimo de aviso
+DELIMITER $$
+CREATE PROCEDURE sp_listar_cancelaciones_tardias()
+BEGIN
+    SELECT * FROM CITA
+    WHERE cit_estado = 'Cancelada' AND cit_cancelacion_tardia = 1
+    ORDER BY cit_fecha DESC, cit_hora DESC;
+END$$
+DELIMITER ;
+
+-- Leer una cita bloqueándola hasta el final de la transacción (usado al facturar)
+DELIMITER $$
+CREATE PROCEDURE sp_bloquear_cita (
+    IN p_cit_id INT
+)
+BEGIN
+    SELECT * FROM CITA WHERE cit_id = p_cit_id FOR UPDATE;
+END$$
+DELIMITER ;
+
+-- Crear una factura vacía y devolverla con su ID
+DELIMITER $$
+CREATE PROCEDURE sp_insertar_factura_cliente (
+    IN p_fecha DATE,
+    IN p_hora TIME,
+    IN p_cli_id INT
+)
+BEGIN
+    INSERT INTO FACTURA_SERVICIO (fac_total, fac_fecha, fac_hora, cli_id)
+    VALUES (0, p_fecha, p_hora, p_cli_id);
+
+    SELECT * FROM FACTURA_SERVICIO WHERE fac_id = LAST_INSERT_ID();
+END$$
+DELIMITER ;
+
+-- Asociar una cita a la factura con la que se cobró
+DELIMITER $$
+CREATE PROCEDURE sp_vincular_cita_factura (
+    IN p_cit_id INT,
+    IN p_fac_id INT
+)
+BEGIN
+    UPDATE CITA SET fac_id = p_fac_id WHERE cit_id = p_cit_id AND fac_id IS NULL;
+END$$
+DELIMITER ;
+
+-- Citas cobradas con una factura
+DELIMITER $$
+CREATE PROCEDURE sp_listar_citas_factura (
+    IN p_fac_id INT
+)
+BEGIN
+    SELECT * FROM CITA WHERE fac_id = p_fac_id ORDER BY cit_fecha, cit_hora;
+END$$
+DELIMITER ;
+
+-- Líneas de una factura con el descuento aplicado, la promoción que lo originó, el empleado
+-- que realizó el servicio, el IVA incluido y las unidades ya acreditadas con notas crédito
+DELIMITER $$
+CREATE PROCEDURE sp_listar_lineas_factura (
+    IN p_fac_id INT
+)
+BEGIN
+    SELECT dfs.dfs_id, dfs.fac_id, dfs.ser_id, s.ser_nombre, dfs.dfs_cantidad, dfs.dfs_precio_unitario,
+           dfs.dfs_descuento, dfs.pro_id, p.pro_nombre, dfs.emp_id,
+           CONCAT(e.emp_nombre, ' ', e.emp_apellido) AS emp_nombre,
+           dfs.dfs_cantidad * dfs.dfs_precio_unitario - dfs.dfs_descuento AS total_linea,
+           dfs.dfs_iva_porcentaje AS iva_porcentaje,
+           ROUND((dfs.dfs_cantidad * dfs.dfs_precio_unitario - dfs.dfs_descuento)
+               * dfs.dfs_iva_porcentaje / (100 + dfs.dfs_iva_porcentaje), 2) AS iva_linea,
+           (SELECT COALESCE(SUM(dnc.dnc_cantidad), 0)
+            FROM DETALLE_NOTA_CREDITO dnc
+            WHERE dnc.dfs_id = dfs.dfs_id) AS cantidad_acreditada
+    FROM DETALLE_FACTURA_SERVICIO dfs
+    JOIN SERVICIO s ON dfs.ser_id = s.ser_id
+    LEFT JOIN PROMOCION p ON dfs.pro_id = p.pro_id
+    LEFT JOIN EMPLEADO e ON dfs.emp_id = e.emp_id
+    WHERE dfs.fac_id = p_fac_id
+    ORDER BY dfs.dfs_id;
+END$$
+DELIMITER ;
+
+-- Promociones automáticas (sin cupón) vigentes en una fecha, con los usos del cliente y los
+-- servicios adicionales del paquete
+DELIMITER $$
+CREATE PROCEDURE sp_listar_promociones_vigentes (
+    IN p_fecha DATE,
+    IN p_cli_id INT
+)
+BEGIN
+    SELECT p.*,
+           (SELECT COUNT(DISTINCT dfs.fac_id)
+            FROM DETALLE_FACTURA_SERVICIO dfs
+            JOIN FACTURA_SERVICIO f ON dfs.fac_id = f.fac_id
+            WHERE dfs.pro_id = p.pro_id AND f.cli_id = p_cli_id
+              AND f.fac_estado <> 'Anulada') AS usos_cliente,
+           (SELECT GROUP_CONCAT(ps.ser_id ORDER BY ps.ser_id)
+            FROM PROMOCION_SERVICIO ps
+            WHERE ps.pro_id = p.pro_id) AS servicios_paquete
+    FROM PROMOCION p
+    WHERE p_fecha BETWEEN p.pro_fecha_inicio AND p.pro_fecha_fin
+      AND p.pro_codigo IS NULL
+    ORDER BY p.pro_id;
+END$$
+DELIMITER ;
+
+-- Promoción de un código de cupón (sin filtrar por fecha, para poder explicar por qué no aplica)
+DELIMITER $$
+CREATE PROCEDURE sp_buscar_promocion_por_codigo (
+    IN p_codigo VARCHAR(30),
+    IN p_cli_id INT
+)
+BEGIN
+    SELECT p.*,
+           (SELECT COUNT(DISTINCT dfs.fac_id)
+            FROM DETALLE_FACTURA_SERVICIO dfs
+            JOIN FACTURA_SERVICIO f ON dfs.fac_id = f.fac_id
+            WHERE dfs.pro_id = p.pro_id AND f.cli_id = p_cli_id
+              AND f.fac_estado <> 'Anulada') AS usos_cliente,
+           (SELECT GROUP_CONCAT(ps.ser_id ORDER BY ps.ser_id)
+            FROM PROMOCION_SERVICIO ps
+            WHERE ps.pro_id = p.pro_id) AS servicios_paquete
+    FROM PROMOCION p
+    WHERE p.pro_codigo = p_codigo;
+END$$
+DELIMITER ;
+
+-- Productos que consume un servicio (receta)
+DELIMITER $$
+CREATE PROCEDURE sp_listar_productos_servicio (
+    IN p_ser_id INT
+)
+BEGIN
+    SELECT pu.ser_id, pu.prod_id, p.prod_nombre, pu.pru_cantidad_usada, pu.pru_botellas_usadas, p.prod_cantidad_disponible
+    FROM PRODUCTO_USADO pu
+    JOIN PRODUCTO p ON pu.prod_id = p.prod_id
+    WHERE pu.ser_id = p_ser_id
+    ORDER BY p.prod_nombre;
+END$$
+DELIMITER ;
+
+DELIMITER $$
+CREATE PROCEDURE sp_insertar_producto_usado (
+    IN p_ser_id INT,
+    IN p_prod_id INT,
+    IN p_cantidad_usada SMALLINT UNSIGNED,
+    IN p_botellas_usadas SMALLINT UNSIGNED
+)
+BEGIN
+    INSERT INTO PRODUCTO_USADO (ser_id, prod_id, pru_cantidad_usada, pru_botellas_usadas)
+    VALUES (p_ser_id, p_prod_id, p_cantidad_usada, p_botellas_usadas);
+END$$
+DELIMITER ;
+
+DELIMITER $$
+CREATE PROCEDURE sp_actualizar_producto_usado (
+    IN p_ser_id INT,
+    IN p_prod_id INT,
+    IN p_cantidad_usada SMALLINT UNSIGNED,
+    IN p_botellas_usadas SMALLINT UNSIGNED
+)
+BEGIN
+    UPDATE PRODUCTO_USADO
+    SET pru_cantidad_usada = p_cantidad_usada,
+        pru_botellas_usadas = p_botellas_usadas
+    WHERE ser_id = p_ser_id AND prod_id = p_prod_id;
+END$$
+DELIMITER ;
+
+DELIMITER $$
+CREATE PROCEDURE sp_eliminar_producto_usado (
+    IN p_ser_id INT,
+    IN p_prod_id INT
+)
+BEGIN
+    DELETE FROM PRODUCTO_USADO WHERE ser_id = p_ser_id AND prod_id = p_prod_id;
+END$$
+DELIMITER ;
+
+-- Descontar del stock los productos de la receta del servicio de una cita: cada producto baja las
+-- unidades completas de la receta (pru_botellas_usadas) y el movimiento anota la cantidad usada.
+-- Solo descuenta una vez por cita (cit_consumo_registrado). Si algún producto no alcanza, falla sin
+-- descontar nada, así que la cita no se completa ni se factura hasta que haya stock.
+-- Se llama al completar la cita y al facturarla, dentro de la transacción del llamador.
+DELIMITER $$
+CREATE PROCEDURE sp_descontar_productos_cita (
+    IN p_cit_id INT,
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_ser_id INT;
+    DECLARE v_registrado TINYINT(1);
+    DECLARE v_prod_id INT;
+    DECLARE v_unidades INT;
+    DECLARE v_cantidad_usada INT;
+    DECLARE v_productos INT DEFAULT 0;
+    DECLARE v_fin INT DEFAULT 0;
+    DECLARE cur_receta CURSOR FOR
+        SELECT prod_id, pru_botellas_usadas, pru_cantidad_usada
+        FROM PRODUCTO_USADO
+        WHERE ser_id = v_ser_id;
+    DECLARE CONTINUE HANDLER FOR NOT FOUND SET v_fin = 1;
+
+    SELECT ser_id, cit_consumo_registrado INTO v_ser_id, v_registrado
+    FROM CITA
+    WHERE cit_id = p_cit_id
+    FOR UPDATE;
+
+    IF v_ser_id IS NOT NULL AND v_registrado = 0 THEN
+        -- Bloquea los productos de la receta y valida todo antes de descontar
+        IF EXISTS (
+            SELECT 1
+            FROM PRODUCTO_USADO pu
+            JOIN PRODUCTO p ON p.prod_id = pu.prod_id
+            WHERE pu.ser_id = v_ser_id
+              AND p.prod_cantidad_disponible < pu.pru_botellas_usadas
+            FOR UPDATE
+        ) THEN
+            SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Stock insuficiente para la receta del servicio';
+        END IF;
+
+        OPEN cur_receta;
+        leer_receta: LOOP
+            FETCH cur_receta INTO v_prod_id, v_unidades, v_cantidad_usada;
+            IF v_fin = 1 THEN
+                LEAVE leer_receta;
+            END IF;
+            SET v_productos = v_productos + 1;
+            IF v_unidades > 0 THEN
+                CALL sp_registrar_movimiento_inventario(v_prod_id, 'Consumo Servicio', -v_unidades, p_usuario,
+                    CONCAT('Consumo de la receta del servicio (cantidad usada: ', v_cantidad_usada, ')'),
+                    CONCAT('CITA:', p_cit_id), NULL);
+            END IF;
+        END LOOP;
+        CLOSE cur_receta;
+
+        -- Si el servicio aún no tenía receta, el consumo se registra al facturar la cita
+        IF v_productos > 0 THEN
+            UPDATE CITA SET cit_consumo_registrado = 1 WHERE cit_id = p_cit_id;
+        END IF;
+    END IF;
+END$$
+DELIMITER ;
+
+-- Registrar la recepción (total o parcial) de una línea de compra: la cantidad recibida entra
+-- al stock como movimiento de compra y se guarda el costo unitario recibido.
+-- Se ejecuta dentro de la transacción del llamador.
+DELIMITER $$
+CREATE PROCEDURE sp_recibir_detalle_compra (
+    IN p_com_id INT,
+    IN p_prod_id INT,
+    IN p_cantidad INT,
+    IN p_costo_unitario DECIMAL(10,2),
+    IN p_observaciones TEXT,
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_existe INT DEFAULT 0;
+
+    SELECT COUNT(*) INTO v_existe
+    FROM DETALLE_COMPRA
+    WHERE com_id = p_com_id AND prod_id = p_prod_id
+    FOR UPDATE;
+
+    IF v_existe = 0 THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'El producto no hace parte de la compra';
+    END IF;
+
+    INSERT INTO RECEPCION_COMPRA (com_id, prod_id, rec_cantidad, rec_costo_unitario, rec_observaciones)
+    VALUES (p_com_id, p_prod_id, p_cantidad, p_costo_unitario, p_observaciones);
+
+    UPDATE DETALLE_COMPRA
+    SET dec_cantidad_recibida = dec_cantidad_recibida + p_cantidad
+    WHERE com_id = p_com_id AND prod_id = p_prod_id;
+
+    CALL sp_registrar_movimiento_inventario(p_prod_id, 'Compra', p_cantidad, p_usuario,
+        COALESCE(p_observaciones, 'Recepción de compra'), CONCAT('COMPRA:', p_com_id), p_costo_unitario);
+END$$
+DELIMITER ;
+
+-- Recepciones registradas para una compra
+DELIMITER $$
+CREATE PROCEDURE sp_listar_recepciones_compra (
+    IN p_com_id INT
+)
+BEGIN
+    SELECT r.*, p.prod_nombre
+    FROM RECEPCION_COMPRA r
+    JOIN PRODUCTO p ON r.prod_id = p.prod_id
+    WHERE r.com_id = p_com_id
+    ORDER BY r.rec_fecha, r.rec_id;
+END$$
+DELIMITER ;
+
+-- Cambiar el estado de una orden de compra validando la transición.
+-- Los estados de recepción los asigna sp_actualizar_estado_recepcion_compra.
+-- Se ejecuta dentro de la transacción del llamador.
+DELIMITER $$
+CREATE PROCEDURE sp_cambiar_estado_compra (
+    IN p_com_id INT,
+    IN p_estado VARCHAR(25),
+    IN p_usuario VARCHAR(100),
+    IN p_observaciones TEXT
+)
+BEGIN
+    DECLARE v_estado_actual VARCHAR(25);
+
+    SELECT cop_estado INTO v_estado_actual
+    FROM COMPRA_PRODUCTO
+    WHERE com_id = p_com_id
+    FOR UPDATE;
+
+    IF v_estado_actual IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La compra no existe';
+    END IF;
+
+    IF NOT (
+        (v_estado_actual = 'Borrador' AND p_estado IN ('Aprobada', 'Cancelada')) OR
+        (v_estado_actual = 'Aprobada' AND p_estado IN ('Enviada', 'Cancelada')) OR
+        (v_estado_actual = 'Enviada' AND p_estado IN ('Parcialmente Recibida', 'Recibida', 'Cancelada')) OR
+        (v_estado_actual = 'Parcialmente Recibida' AND p_estado IN ('Recibida', 'Cerrada')) OR
+        (v_estado_actual = 'Recibida' AND p_estado = 'Cerrada')
+    ) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Transición de estado de compra no permitida';
+    END IF;
+
+    UPDATE COMPRA_PRODUCTO SET cop_estado = p_estado WHERE com_id = p_com_id;
+
+    IF p_estado = 'Aprobada' THEN
+        UPDATE COMPRA_PRODUCTO
+        SET cop_aprobada_por = p_usuario, cop_fecha_aprobacion = NOW()
+        WHERE com_id = p_com_id;
+    END IF;
+
+    INSERT INTO HISTORIAL_ESTADO_COMPRA (com_id, hco_estado_anterior, hco_estado_nuevo, hco_usuario, hco_observaciones)
+    VALUES (p_com_id, v_estado_actual, p_estado, p_usuario, p_observaciones);
+END$$
+DELIMITER ;
+
+-- Pasar una compra a Parcialmente Recibida o Recibida según lo recibido en sus líneas
+DELIMITER $$
+CREATE PROCEDURE sp_actualizar_estado_recepcion_compra (
+    IN p_com_id INT,
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_estado_actual VARCHAR(25);
+    DECLARE v_pendientes INT;
+    DECLARE v_estado VARCHAR(25);
+
+    SELECT cop_estado INTO v_estado_actual FROM COMPRA_PRODUCTO WHERE com_id = p_com_id FOR UPDATE;
+
+    SELECT COUNT(*) INTO v_pendientes
+    FROM DETALLE_COMPRA
+    WHERE com_id = p_com_id AND dec_cantidad_recibida < dec_cantidad;
+
+    SET v_estado = IF(v_pendientes = 0, 'Recibida', 'Parcialmente Recibida');
+
+    IF v_estado <> v_estado_actual THEN
+        CALL sp_cambiar_estado_compra(p_com_id, v_estado, p_usuario, NULL);
+    END IF;
+END$$
+DELIMITER ;
+
+-- Historial de estados de una compra
+DELIMITER $$
+CREATE PROCEDURE sp_listar_historial_estado_compra (
+    IN p_com_id INT
+)
+BEGIN
+    SELECT * FROM HISTORIAL_ESTADO_COMPRA WHERE com_id = p_com_id ORDER BY hco_fecha, hco_id;
+END$$
+DELIMITER ;
+
+-- Registrar un movimiento en el kardex de inventario. Es el único punto que cambia el stock:
+-- el saldo se calcula a partir de los movimientos anteriores y se copia a PRODUCTO e INVENTARIO.
+-- Una entrada con costo (p_costo_unitario) recalcula el costo promedio ponderado; las salidas y
+-- las entradas sin costo se valoran al costo promedio vigente.
+-- Se ejecuta dentro de la transacción del llamador.
+DELIMITER $$
+CREATE PROCEDURE sp_registrar_movimiento_inventario (
+    IN p_prod_id INT,
+    IN p_tipo VARCHAR(20),
+    IN p_cantidad INT,
+    IN p_usuario VARCHAR(100),
+    IN p_motivo TEXT,
+    IN p_referencia VARCHAR(50),
+    IN p_costo_unitario DECIMAL(12,4)
+)
+BEGIN
+    DECLARE v_existe INT;
+    DECLARE v_saldo INT;
+    DECLARE v_costo_promedio DECIMAL(12,4);
+    DECLARE v_costo_movimiento DECIMAL(12,4);
+
+    SELECT prod_id, prod_costo_promedio INTO v_existe, v_costo_promedio
+    FROM PRODUCTO
+    WHERE prod_id = p_prod_id
+    FOR UPDATE;
+
+    IF v_existe IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'El producto no existe';
+    END IF;
+
+    IF p_tipo NOT IN ('Compra', 'Consumo Servicio', 'Venta', 'Ajuste', 'Merma', 'Devolución') THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Tipo de movimiento de inventario no válido';
+    END IF;
+
+    -- Compras entran al stock; consumos, ventas y mermas salen. Ajustes y devoluciones van en ambos sentidos
+    IF p_cantidad = 0
+        OR (p_tipo = 'Compra' AND p_cantidad < 0)
+        OR (p_tipo IN ('Consumo Servicio', 'Venta', 'Merma') AND p_cantidad > 0) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Cantidad no válida para el tipo de movimiento';
+    END IF;
+
+    SELECT COALESCE(SUM(mov_cantidad), 0) + p_cantidad INTO v_saldo
+    FROM MOVIMIENTO_INVENTARIO
+    WHERE prod_id = p_prod_id;
+
+    IF v_saldo < 0 THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'El movimiento deja el stock en negativo';
+    END IF;
+
+    IF p_cantidad > 0 AND p_costo_unitario IS NOT NULL THEN
+        SET v_costo_movimiento = p_costo_unitario;
+        -- (stock anterior * costo promedio + unidades recibidas * costo) / stock nuevo
+        SET v_costo_promedio = ((v_saldo - p_cantidad) * v_costo_promedio + p_cantidad * p_costo_unitario) / v_saldo;
+    ELSE
+        SET v_costo_movimiento = v_costo_promedio;
+    END IF;
+
+    INSERT INTO MOVIMIENTO_INVENTARIO (prod_id, mov_tipo, mov_cantidad, mov_saldo, mov_costo_unitario,
+        mov_costo_promedio, mov_usuario, mov_motivo, mov_referencia)
+    VALUES (p_prod_id, p_tipo, p_cantidad, v_saldo, v_costo_movimiento,
+        v_costo_promedio, p_usuario, p_motivo, p_referencia);
+
+    UPDATE PRODUCTO
+    SET prod_cantidad_disponible = v_saldo, prod_costo_promedio = v_costo_promedio
+    WHERE prod_id = p_prod_id;
+
+    UPDATE INVENTARIO
+    SET inv_cantidad_actual = v_saldo, inv_fecha_actualizacion = CURDATE()
+    WHERE prod_id = p_prod_id;
+END$$
+DELIMITER ;
+
+-- Movimientos de un producto en un rango de fechas (NULL deja el extremo abierto)
+DELIMITER $$
+CREATE PROCEDURE sp_listar_movimientos_producto (
+    IN p_prod_id INT,
+    IN p_desde DATE,
+    IN p_hasta DATE
+)
+BEGIN
+    SELECT m.*, p.prod_nombre
+    FROM MOVIMIENTO_INVENTARIO m
+    JOIN PRODUCTO p ON p.prod_id = m.prod_id
+    WHERE m.prod_id = p_prod_id
+      AND (p_desde IS NULL OR m.mov_fecha >= p_desde)
+      AND (p_hasta IS NULL OR m.mov_fecha < DATE_ADD(p_hasta, INTERVAL 1 DAY))
+    ORDER BY m.mov_fecha, m.mov_id;
+END$$
+DELIMITER ;
+
+-- Buscar un producto por ID
+DELIMITER $$
+CREATE PROCEDURE sp_buscar_producto_por_id (
+    IN p_prod_id INT
+)
+BEGIN
+    SELECT * FROM PRODUCTO WHERE prod_id = p_prod_id;
+END$$
+DELIMITER ;
+
+-- Configurar el punto de reorden de un producto
+DELIMITER $$
+CREATE PROCEDURE sp_actualizar_reorden_producto (
+    IN p_prod_id INT,
+    IN p_stock_minimo INT,
+    IN p_cantidad_reorden INT,
+    IN p_prov_id INT
+)
+BEGIN
+    UPDATE PRODUCTO
+    SET prod_stock_minimo = p_stock_minimo,
+        prod_cantidad_reorden = p_cantidad_reorden,
+        prov_id = p_prov_id
+    WHERE prod_id = p_prod_id;
+END$$
+DELIMITER ;
+
+-- Abrir una sesión de conteo físico: guarda el stock y el costo de cada producto en ese momento.
+-- Se ejecuta dentro de la transacción del llamador.
+DELIMITER $$
+CREATE PROCEDURE sp_abrir_conteo_inventario (
+    IN p_usuario VARCHAR(100),
+    IN p_observaciones TEXT
+)
+BEGIN
+    IF EXISTS (SELECT 1 FROM CONTEO_INVENTARIO WHERE con_estado = 'Abierto') THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Ya hay un conteo abierto';
+    END IF;
+
+    INSERT INTO CONTEO_INVENTARIO (con_abierto_por, con_observaciones)
+    VALUES (p_usuario, p_observaciones);
+
+    SET @con_id = LAST_INSERT_ID();
+
+    INSERT INTO DETALLE_CONTEO (con_id, prod_id, dco_stock_sistema, dco_costo_unitario)
+    SELECT @con_id, prod_id, prod_cantidad_disponible, prod_costo_promedio
+    FROM PRODUCTO;
+
+    SELECT * FROM CONTEO_INVENTARIO WHERE con_id = @con_id;
+END$$
+DELIMITER ;
+
+-- Listar sesiones de conteo con el número de productos contados
+DELIMITER $$
+CREATE PROCEDURE sp_listar_conteos_inventario ()
+BEGIN
+    SELECT c.*,
+        (SELECT COUNT(DISTINCT r.prod_id) FROM REGISTRO_CONTEO r WHERE r.con_id = c.con_id) AS productos_contados
+    FROM CONTEO_INVENTARIO c
+    ORDER BY c.con_fecha_apertura DESC, c.con_id DESC;
+END$$
+DELIMITER ;
+
+-- Buscar una sesión de conteo por ID
+DELIMITER $$
+CREATE PROCEDURE sp_buscar_conteo_inventario (
+    IN p_con_id INT
+)
+BEGIN
+    SELECT c.*,
+        (SELECT COUNT(DISTINCT r.prod_id) FROM REGISTRO_CONTEO r WHERE r.con_id = c.con_id) AS productos_contados
+    FROM CONTEO_INVENTARIO c
+    WHERE c.con_id = p_con_id;
+END$$
+DELIMITER ;
+
+-- Registrar la cantidad contada de un producto. Cada usuario tiene un registro por producto
+-- (volver a contar lo reemplaza); lo contado por varios usuarios se suma. El registro guarda el
+-- stock del sistema en ese momento, porque el inventario se sigue moviendo mientras el conteo
+-- está abierto. Se ejecuta dentro de la transacción del llamador.
+DELIMITER $$
+CREATE PROCEDURE sp_registrar_conteo_producto (
+    IN p_con_id INT,
+    IN p_prod_id INT,
+    IN p_cantidad INT,
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_stock INT;
+
+    IF NOT EXISTS (SELECT 1 FROM CONTEO_INVENTARIO WHERE con_id = p_con_id AND con_estado = 'Abierto') THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'El conteo no está abierto';
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM DETALLE_CONTEO WHERE con_id = p_con_id AND prod_id = p_prod_id) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'El producto no hace parte del conteo';
+    END IF;
+
+    IF p_cantidad IS NULL OR p_cantidad < 0 THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La cantidad contada no puede ser negativa';
+    END IF;
+
+    -- Bloquea el producto para que ningún movimiento se cuele entre la lectura del stock y el registro
+    SELECT prod_cantidad_disponible INTO v_stock FROM PRODUCTO WHERE prod_id = p_prod_id FOR UPDATE;
+
+    INSERT INTO REGISTRO_CONTEO (con_id, prod_id, rco_cantidad, rco_stock_sistema, rco_usuario)
+    VALUES (p_con_id, p_prod_id, p_cantidad, v_stock, p_usuario)
+    ON DUPLICATE KEY UPDATE rco_cantidad = VALUES(rco_cantidad), rco_stock_sistema = VALUES(rco_stock_sistema),
+        rco_fecha = CURRENT_TIMESTAMP;
+END$$
+DELIMITER ;
+
+-- Diferencias de un conteo, valoradas al costo unitario congelado al abrir. Lo contado se compara con
+-- el stock del sistema del último registro del producto (stock_al_contar), no con el de la apertura:
+-- las ventas y consumos entre la apertura y el conteo ya salieron del estante y del sistema.
+-- Los productos sin contar tienen cantidad_contada NULL y no se ajustan.
+DELIMITER $$
+CREATE PROCEDURE sp_listar_diferencias_conteo (
+    IN p_con_id INT
+)
+BEGIN
+    SELECT d.con_id, d.prod_id, p.prod_nombre, d.dco_stock_sistema, d.dco_costo_unitario,
+        r.cantidad_contada, r.contado_por, r.stock_al_contar,
+        r.cantidad_contada - r.stock_al_contar AS diferencia,
+        ROUND((r.cantidad_contada - r.stock_al_contar) * d.dco_costo_unitario, 2) AS valor_diferencia
+    FROM DETALLE_CONTEO d
+    JOIN PRODUCTO p ON p.prod_id = d.prod_id
+    LEFT JOIN (
+        SELECT rc.prod_id, SUM(rc.rco_cantidad) AS cantidad_contada,
+            GROUP_CONCAT(DISTINCT rc.rco_usuario ORDER BY rc.rco_usuario SEPARATOR ', ') AS contado_por,
+            (SELECT u.rco_stock_sistema FROM REGISTRO_CONTEO u
+             WHERE u.con_id = rc.con_id AND u.prod_id = rc.prod_id
+             ORDER BY u.rco_fecha DESC, u.rco_id DESC LIMIT 1) AS stock_al_contar
+        FROM REGISTRO_CONTEO rc
+        WHERE rc.con_id = p_con_id
+        GROUP BY rc.con_id, rc.prod_id
+    ) r ON r.prod_id = d.prod_id
+    WHERE d.con_id = p_con_id
+    ORDER BY p.prod_nombre;
+END$$
+DELIMITER ;
+
+-- Aprobar un conteo: la diferencia de cada producto contado contra el stock del sistema de su último
+-- registro se aplica como un movimiento de ajuste sobre el stock actual, así los movimientos
+-- posteriores al conteo se conservan. Se ejecuta dentro de la transacción del llamador.
+DELIMITER $$
+CREATE PROCEDURE sp_aprobar_conteo_inventario (
+    IN p_con_id INT,
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_estado VARCHAR(20);
+    DECLARE v_prod_id INT;
+    DECLARE v_diferencia INT;
+    DECLARE v_fin INT DEFAULT 0;
+    DECLARE cur_diferencias CURSOR FOR
+        SELECT c.prod_id, c.cantidad_contada - c.stock_al_contar
+        FROM (
+            SELECT r.prod_id, SUM(r.rco_cantidad) AS cantidad_contada,
+                (SELECT u.rco_stock_sistema FROM REGISTRO_CONTEO u
+                 WHERE u.con_id = r.con_id AND u.prod_id = r.prod_id
+                 ORDER BY u.rco_fecha DESC, u.rco_id DESC LIMIT 1) AS stock_al_contar
+            FROM REGISTRO_CONTEO r
+            WHERE r.con_id = p_con_id
+            GROUP BY r.con_id, r.prod_id
+        ) c
+        WHERE c.cantidad_contada <> c.stock_al_contar;
+    DECLARE CONTINUE HANDLER FOR NOT FOUND SET v_fin = 1;
+
+    SELECT con_estado INTO v_estado FROM CONTEO_INVENTARIO WHERE con_id = p_con_id FOR UPDATE;
+
+    IF v_estado IS NULL OR v_estado <> 'Abierto' THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'El conteo no está abierto';
+    END IF;
+
+    OPEN cur_diferencias;
+    leer_diferencias: LOOP
+        FETCH cur_diferencias INTO v_prod_id, v_diferencia;
+        IF v_fin = 1 THEN
+            LEAVE leer_diferencias;
+        END IF;
+        CALL sp_registrar_movimiento_inventario(v_prod_id, 'Ajuste', v_diferencia, p_usuario,
+            CONCAT('Conteo físico #', p_con_id), CONCAT('CONTEO:', p_con_id), NULL);
+    END LOOP;
+    CLOSE cur_diferencias;
+
+    UPDATE CONTEO_INVENTARIO
+    SET con_estado = 'Aprobado', con_cerrado_por = p_usuario, con_fecha_cierre = NOW()
+    WHERE con_id = p_con_id;
+END$$
+DELIMITER ;
+
+-- Cancelar un conteo abierto sin tocar el stock
+DELIMITER $$
+CREATE PROCEDURE sp_cancelar_conteo_inventario (
+    IN p_con_id INT,
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    UPDATE CONTEO_INVENTARIO
+    SET con_estado = 'Cancelado', con_cerrado_por = p_usuario, con_fecha_cierre = NOW()
+    WHERE con_id = p_con_id AND con_estado = 'Abierto';
+END$$
+DELIMITER ;
+
+-- Valorización del inventario al costo promedio ponderado, con el valor a precio de venta como referencia
+DELIMITER $$
+CREATE PROCEDURE sp_reporte_valorizacion_inventario ()
+BEGIN
+    SELECT prod_id, prod_nombre, prod_cantidad_disponible, prod_costo_promedio,
+        ROUND(prod_cantidad_disponible * prod_costo_promedio, 2) AS valor_costo,
+        prod_precio_unitario,
+        ROUND(prod_cantidad_disponible * prod_precio_unitario, 2) AS valor_venta
+    FROM PRODUCTO
+    ORDER BY valor_costo DESC, prod_nombre;
+END$$
+DELIMITER ;
+
+-- Costo de lo que salió del inventario en un rango de fechas (NULL deja el extremo abierto),
+-- por producto y tipo de salida, valorado al costo promedio de cada movimiento.
+-- Las devoluciones de facturas y notas crédito se restan de las ventas.
+DELIMITER $$
+CREATE PROCEDURE sp_reporte_costo_salidas (
+    IN p_desde DATE,
+    IN p_hasta DATE
+)
+BEGIN
+    SELECT m.prod_id, p.prod_nombre,
+        IF(m.mov_tipo = 'Devolución', 'Venta', m.mov_tipo) AS mov_tipo,
+        -SUM(m.mov_cantidad) AS unidades,
+        ROUND(-SUM(m.mov_cantidad * m.mov_costo_unitario), 2) AS costo
+    FROM MOVIMIENTO_INVENTARIO m
+    JOIN PRODUCTO p ON p.prod_id = m.prod_id
+    WHERE (m.mov_tipo IN ('Consumo Servicio', 'Venta', 'Merma')
+           OR (m.mov_tipo = 'Devolución'
+               AND (m.mov_referencia LIKE 'FACTURA:%' OR m.mov_referencia LIKE 'NOTA_CREDITO:%')))
+      AND (p_desde IS NULL OR m.mov_fecha >= p_desde)
+      AND (p_hasta IS NULL OR m.mov_fecha < DATE_ADD(p_hasta, INTERVAL 1 DAY))
+    GROUP BY m.prod_id, p.prod_nombre, IF(m.mov_tipo = 'Devolución', 'Venta', m.mov_tipo)
+    ORDER BY costo DESC, p.prod_nombre;
+END$$
+DELIMITER ;
+
+-- Vender un producto en una factura: descuenta el stock con un movimiento de venta, registra la
+-- línea al precio actual del producto y recalcula el total. Si el producto ya está en la factura
+-- se suman las unidades y el descuento a la línea existente. No abre transacción propia.
+DELIMITER $$
+CREATE PROCEDURE sp_insertar_detalle_factura_producto (
+    IN p_fac_id INT,
+    IN p_prod_id INT,
+    IN p_cantidad INT,
+    IN p_descuento DECIMAL(10,2),
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_precio DECIMAL(10,2);
+    DECLARE v_iva DECIMAL(5,2);
+
+    CALL sp_validar_factura_borrador(p_fac_id);
+
+    IF p_cantidad IS NULL OR p_cantidad <= 0 THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La cantidad vendida debe ser mayor que cero';
+    END IF;
+
+    SELECT prod_precio_unitario, prod_iva_porcentaje INTO v_precio, v_iva FROM PRODUCTO WHERE prod_id = p_prod_id;
+
+    -- El movimiento bloquea el producto y rechaza la venta si deja el stock en negativo
+    CALL sp_registrar_movimiento_inventario(p_prod_id, 'Venta', -p_cantidad, p_usuario,
+        CONCAT('Venta en factura #', p_fac_id), CONCAT('FACTURA:', p_fac_id), NULL);
+
+    INSERT INTO DETALLE_FACTURA_PRODUCTO (fac_id, prod_id, dfp_cantidad, dfp_precio_unitario, dfp_descuento,
+        dfp_iva_porcentaje)
+    VALUES (p_fac_id, p_prod_id, p_cantidad, v_precio,
+        LEAST(GREATEST(COALESCE(p_descuento, 0), 0), p_cantidad * v_precio), v_iva)
+    ON DUPLICATE KEY UPDATE
+        dfp_descuento = LEAST(dfp_descuento + GREATEST(COALESCE(p_descuento, 0), 0),
+            (dfp_cantidad + p_cantidad) * dfp_precio_unitario),
+        dfp_cantidad = dfp_cantidad + p_cantidad;
+
+    CALL sp_recalcular_total_factura(p_fac_id);
+END$$
+DELIMITER ;
+
+-- Quitar un producto de una factura devolviendo sus unidades al stock y recalculando el total
+DELIMITER $$
+CREATE PROCEDURE sp_eliminar_detalle_factura_producto (
+    IN p_fac_id INT,
+    IN p_prod_id INT,
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_cantidad INT;
+
+    CALL sp_validar_factura_borrador(p_fac_id);
+
+    SELECT dfp_cantidad INTO v_cantidad
+    FROM DETALLE_FACTURA_PRODUCTO
+    WHERE fac_id = p_fac_id AND prod_id = p_prod_id
+    FOR UPDATE;
+
+    IF v_cantidad IS NOT NULL THEN
+        CALL sp_registrar_movimiento_inventario(p_prod_id, 'Devolución', v_cantidad, p_usuario,
+            CONCAT('Producto retirado de la factura #', p_fac_id), CONCAT('FACTURA:', p_fac_id), NULL);
+
+        DELETE FROM DETALLE_FACTURA_PRODUCTO WHERE fac_id = p_fac_id AND prod_id = p_prod_id;
+
+        CALL sp_recalcular_total_factura(p_fac_id);
+    END IF;
+END$$
+DELIMITER ;
+
+-- Devolver al stock todos los productos vendidos en una factura (usado al eliminarla)
+DELIMITER $$
+CREATE PROCEDURE sp_devolver_productos_factura (
+    IN p_fac_id INT,
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_prod_id INT;
+    DECLARE v_cantidad INT;
+    DECLARE v_fin INT DEFAULT 0;
+    DECLARE cur_productos CURSOR FOR
+        SELECT prod_id, dfp_cantidad
+        FROM DETALLE_FACTURA_PRODUCTO
+        WHERE fac_id = p_fac_id;
+    DECLARE CONTINUE HANDLER FOR NOT FOUND SET v_fin = 1;
+
+    OPEN cur_productos;
+    leer_productos: LOOP
+        FETCH cur_productos INTO v_prod_id, v_cantidad;
+        IF v_fin = 1 THEN
+            LEAVE leer_productos;
+        END IF;
+        CALL sp_registrar_movimiento_inventario(v_prod_id, 'Devolución', v_cantidad, p_usuario,
+            CONCAT('Factura #', p_fac_id, ' eliminada'), CONCAT('FACTURA:', p_fac_id), NULL);
+    END LOOP;
+    CLOSE cur_productos;
+END$$
+DELIMITER ;
+
+-- Líneas de productos de una factura con el nombre del producto, el total de la línea, el IVA
+-- incluido y las unidades ya devueltas con notas crédito
+DELIMITER $$
+CREATE PROCEDURE sp_listar_productos_factura (
+    IN p_fac_id INT
+)
+BEGIN
+    SELECT dfp.fac_id, dfp.prod_id, p.prod_nombre, dfp.dfp_cantidad, dfp.dfp_precio_unitario,
+           dfp.dfp_descuento,
+           dfp.dfp_cantidad * dfp.dfp_precio_unitario - dfp.dfp_descuento AS total_linea,
+           dfp.dfp_iva_porcentaje AS iva_porcentaje,
+           ROUND((dfp.dfp_cantidad * dfp.dfp_precio_unitario - dfp.dfp_descuento)
+               * dfp.dfp_iva_porcentaje / (100 + dfp.dfp_iva_porcentaje), 2) AS iva_linea,
+           (SELECT COALESCE(SUM(dnc.dnc_cantidad), 0)
+            FROM DETALLE_NOTA_CREDITO dnc
+            JOIN NOTA_CREDITO nc ON dnc.nc_id = nc.nc_id
+            WHERE nc.fac_id = dfp.fac_id AND dnc.prod_id = dfp.prod_id) AS cantidad_acreditada
+    FROM DETALLE_FACTURA_PRODUCTO dfp
+    JOIN PRODUCTO p ON dfp.prod_id = p.prod_id
+    WHERE dfp.fac_id = p_fac_id
+    ORDER BY p.prod_nombre;
+END$$
+DELIMITER ;
+
+-- Recalcular lo pagado, lo acreditado y el estado de pago de una factura a partir de sus pagos y
+-- de sus notas crédito. El saldo es el total menos lo acreditado menos lo pagado neto de reembolsos
+DELIMITER $$
+CREATE PROCEDURE sp_actualizar_estado_pago_factura (
+    IN p_fac_id INT
+)
+BEGIN
+    UPDATE FACTURA_SERVICIO
+    SET fac_pagado = (
+            SELECT COALESCE(SUM(pag_cli_monto), 0)
+            FROM PAGO_CLIENTE
+            WHERE fac_id = p_fac_id
+        ),
+        fac_acreditado = (
+            SELECT COALESCE(SUM(nc_total), 0)
+            FROM NOTA_CREDITO
+            WHERE fac_id = p_fac_id
+        ),
+        fac_reembolsado = (
+            SELECT COALESCE(SUM(nc_reembolso), 0)
+            FROM NOTA_CREDITO
+            WHERE fac_id = p_fac_id
+        ),
+        fac_estado_pago = CASE
+            WHEN fac_pagado - fac_reembolsado >= fac_total - fac_acreditado THEN 'Pagada'
+            WHEN fac_pagado - fac_reembolsado > 0 THEN 'Parcial'
+            ELSE 'Pendiente'
+        END
+    WHERE fac_id = p_fac_id;
+END$$
+DELIMITER ;
+
+-- Leer una factura bloqueándola hasta el final de la transacción (usado al registrar pagos)
+DELIMITER $$
+CREATE PROCEDURE sp_bloquear_factura (
+    IN p_fac_id INT
+)
+BEGIN
+    SELECT * FROM FACTURA_SERVICIO WHERE fac_id = p_fac_id FOR UPDATE;
+END$$
+DELIMITER ;
+
+-- Registrar un pago del cliente sobre el saldo de una factura y devolverlo. En efectivo, lo que
+-- supere el saldo se devuelve como cambio; los demás medios no pueden superar el saldo.
+-- No abre transacción propia: un pago con varios medios se registra en la transacción del llamador.
+DELIMITER $$
+CREATE PROCEDURE sp_registrar_pago_cliente (
+    IN p_fac_id INT,
+    IN p_metodo VARCHAR(20),
+    IN p_recibido DECIMAL(10,2),
+    IN p_entidad VARCHAR(50),
+    IN p_referencia VARCHAR(100),
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_estado VARCHAR(20);
+    DECLARE v_saldo DECIMAL(10,2);
+    DECLARE v_monto DECIMAL(10,2);
+    DECLARE v_cambio DECIMAL(10,2) DEFAULT 0;
+    DECLARE v_pag_cli_id INT;
+
+    SELECT fac_estado, fac_total - fac_acreditado - fac_pagado + fac_reembolsado INTO v_estado, v_saldo
+    FROM FACTURA_SERVICIO
+    WHERE fac_id = p_fac_id
+    FOR UPDATE;
+
+    IF v_estado IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La factura no existe';
+    END IF;
+
+    IF v_estado <> 'Emitida' THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Solo se registran pagos sobre facturas emitidas';
+    END IF;
+
+    IF p_metodo NOT IN ('Efectivo', 'Tarjeta', 'Transferencia', 'Billetera Digital') THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Medio de pago no válido';
+    END IF;
+
+    IF p_recibido IS NULL OR p_recibido <= 0 THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'El valor del pago debe ser mayor que cero';
+    END IF;
+
+    IF v_saldo <= 0 THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La factura ya está pagada';
+    END IF;
+
+    SET v_monto = p_recibido;
+    IF p_recibido > v_saldo THEN
+        IF p_metodo <> 'Efectivo' THEN
+            SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'El pago supera el saldo de la factura';
+        END IF;
+        SET v_monto = v_saldo;
+        SET v_cambio = p_recibido - v_saldo;
+    END IF;
+
+    INSERT INTO PAGO_CLIENTE (fac_id, pag_cli_metodo, pag_cli_entidad, pag_cli_referencia,
+        pag_cli_recibido, pag_cli_monto, pag_cli_cambio, pag_cli_usuario)
+    VALUES (p_fac_id, p_metodo, p_entidad, p_referencia, p_recibido, v_monto, v_cambio, p_usuario);
+    SET v_pag_cli_id = LAST_INSERT_ID();
+
+    CALL sp_actualizar_estado_pago_factura(p_fac_id);
+
+    SELECT * FROM PAGO_CLIENTE WHERE pag_cli_id = v_pag_cli_id;
+END$$
+DELIMITER ;
+
+-- Pagos registrados sobre una factura
+DELIMITER $$
+CREATE PROCEDURE sp_listar_pagos_factura (
+    IN p_fac_id INT
+)
+BEGIN
+    SELECT * FROM PAGO_CLIENTE WHERE fac_id = p_fac_id ORDER BY pag_cli_fecha, pag_cli_id;
+END$$
+DELIMITER ;
+
+-- Facturas emitidas con saldo pendiente (opcionalmente de un cliente), de la más antigua a la
+-- más reciente; las notas crédito reducen el saldo
+DELIMITER $$
+CREATE PROCEDURE sp_reporte_saldos_pendientes (
+    IN p_cli_id INT
+)
+BEGIN
+    SELECT f.fac_id, f.fac_fecha, f.cli_id,
+        CONCAT(c.cli_nombre, ' ', c.cli_apellido) AS cli_nombre,
+        f.fac_total, f.fac_acreditado, f.fac_pagado, f.fac_reembolsado,
+        f.fac_total - f.fac_acreditado - f.fac_pagado + f.fac_reembolsado AS saldo,
+        f.fac_estado_pago,
+        DATEDIFF(CURDATE(), f.fac_fecha) AS dias_pendiente
+    FROM FACTURA_SERVICIO f
+    JOIN CLIENTE c ON c.cli_id = f.cli_id
+    WHERE f.fac_estado = 'Emitida'
+      AND f.fac_total - f.fac_acreditado > f.fac_pagado - f.fac_reembolsado
+      AND (p_cli_id IS NULL OR f.cli_id = p_cli_id)
+    ORDER BY f.fac_fecha, f.fac_id;
+END$$
+DELIMITER ;
+
+-- Rechazar cambios sobre una factura que ya no es borrador: una factura emitida solo se corrige
+-- con notas crédito
+DELIMITER $$
+CREATE PROCEDURE sp_validar_factura_borrador (
+    IN p_fac_id INT
+)
+BEGIN
+    DECLARE v_estado VARCHAR(20);
+
+    SELECT fac_estado INTO v_estado FROM FACTURA_SERVICIO WHERE fac_id = p_fac_id;
+
+    IF v_estado IS NOT NULL AND v_estado <> 'Borrador' THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La factura ya fue emitida y no se puede modificar';
+    END IF;
+END$$
+DELIMITER ;
+
+-- Emitir una factura en borrador con el siguiente consecutivo de la resolución activa; desde ese
+-- momento la factura no se modifica ni se elimina. La resolución se bloquea hasta el fin de la
+-- transacción, así que dos emisiones no toman el mismo número y un rollback no deja huecos
+DELIMITER $$
+CREATE PROCEDURE sp_emitir_factura (
+    IN p_fac_id INT
+)
+BEGIN
+    DECLARE v_estado VARCHAR(20);
+    DECLARE v_lineas INT;
+    DECLARE v_res_id INT;
+    DECLARE v_prefijo VARCHAR(10);
+    DECLARE v_desde INT;
+    DECLARE v_hasta INT;
+    DECLARE v_actual INT;
+    DECLARE v_fecha_desde DATE;
+    DECLARE v_fecha_hasta DATE;
+    DECLARE v_consecutivo INT;
+
+    SELECT fac_estado INTO v_estado FROM FACTURA_SERVICIO WHERE fac_id = p_fac_id FOR UPDATE;
+
+    IF v_estado IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La factura no existe';
+    END IF;
+
+    IF v_estado <> 'Borrador' THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La factura ya fue emitida';
+    END IF;
+
+    SELECT (SELECT COUNT(*) FROM DETALLE_FACTURA_SERVICIO WHERE fac_id = p_fac_id)
+         + (SELECT COUNT(*) FROM DETALLE_FACTURA_PRODUCTO WHERE fac_id = p_fac_id)
+    INTO v_lineas;
+
+    IF v_lineas = 0 THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La factura no tiene servicios ni productos';
+    END IF;
+
+    -- Subtotal, IVA y total se fijan a partir de las líneas antes de numerar la factura
+    CALL sp_recalcular_total_factura(p_fac_id);
+
+    SELECT res_id, res_prefijo, res_rango_desde, res_rango_hasta, res_consecutivo_actual,
+           res_fecha_desde, res_fecha_hasta
+    INTO v_res_id, v_prefijo, v_desde, v_hasta, v_actual, v_fecha_desde, v_fecha_hasta
+    FROM RESOLUCION_FACTURACION
+    WHERE res_activa = 1
+    LIMIT 1
+    FOR UPDATE;
+
+    IF v_res_id IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'No hay una resolución de facturación activa';
+    END IF;
+
+    -- La vigencia se valida con la fecha de emisión (hoy), no con la fecha digitada en el borrador
+    IF CURDATE() < v_fecha_desde OR CURDATE() > v_fecha_hasta THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La fecha de emisión está fuera de la vigencia de la resolución';
+    END IF;
+
+    SET v_consecutivo = COALESCE(v_actual + 1, v_desde);
+
+    IF v_consecutivo > v_hasta THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Se agotó el rango de numeración de la resolución';
+    END IF;
+
+    UPDATE RESOLUCION_FACTURACION SET res_consecutivo_actual = v_consecutivo WHERE res_id = v_res_id;
+
+    UPDATE FACTURA_SERVICIO
+    SET fac_estado = 'Emitida', fac_fecha_emision = NOW(),
+        res_id = v_res_id, fac_prefijo = v_prefijo, fac_consecutivo = v_consecutivo,
+        fac_numero = CONCAT(v_prefijo, v_consecutivo)
+    WHERE fac_id = p_fac_id;
+END$$
+DELIMITER ;
+
+-- Crear una nota crédito vacía sobre una factura emitida y devolverla con su ID.
+-- No abre transacción propia: las líneas se agregan en la transacción del llamador.
+DELIMITER $$
+CREATE PROCEDURE sp_insertar_nota_credito (
+    IN p_fac_id INT,
+    IN p_tipo VARCHAR(20),
+    IN p_motivo VARCHAR(255),
+    IN p_metodo_reembolso VARCHAR(20),
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_estado VARCHAR(20);
+
+    SELECT fac_estado INTO v_estado FROM FACTURA_SERVICIO WHERE fac_id = p_fac_id FOR UPDATE;
+
+    IF v_estado IS NULL OR v_estado <> 'Emitida' THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Solo se emiten notas crédito sobre facturas emitidas';
+    END IF;
+
+    INSERT INTO NOTA_CREDITO (fac_id, nc_tipo, nc_motivo, nc_metodo_reembolso, nc_usuario)
+    VALUES (p_fac_id, p_tipo, p_motivo, p_metodo_reembolso, p_usuario);
+
+    SELECT * FROM NOTA_CREDITO WHERE nc_id = LAST_INSERT_ID();
+END$$
+DELIMITER ;
+
+-- Acreditar unidades de una línea de servicio de la factura de la nota crédito. El valor es
+-- proporcional al total neto de la línea; la última unidad se lleva el resto del redondeo para que
+-- una línea acreditada por completo sume exactamente su total
+DELIMITER $$
+CREATE PROCEDURE sp_acreditar_linea_servicio (
+    IN p_nc_id INT,
+    IN p_dfs_id INT,
+    IN p_cantidad INT
+)
+BEGIN
+    DECLARE v_cantidad INT;
+    DECLARE v_total DECIMAL(10,2);
+    DECLARE v_acreditada INT;
+    DECLARE v_valor_acreditado DECIMAL(10,2);
+    DECLARE v_valor DECIMAL(10,2);
+
+    SELECT dfs.dfs_cantidad, dfs.dfs_cantidad * dfs.dfs_precio_unitario - dfs.dfs_descuento
+    INTO v_cantidad, v_total
+    FROM DETALLE_FACTURA_SERVICIO dfs
+    JOIN NOTA_CREDITO nc ON nc.fac_id = dfs.fac_id
+    WHERE dfs.dfs_id = p_dfs_id AND nc.nc_id = p_nc_id;
+
+    IF v_cantidad IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La línea de servicio no pertenece a la factura';
+    END IF;
+
+    SELECT COALESCE(SUM(dnc_cantidad), 0), COALESCE(SUM(dnc_valor), 0)
+    INTO v_acreditada, v_valor_acreditado
+    FROM DETALLE_NOTA_CREDITO
+    WHERE dfs_id = p_dfs_id;
+
+    IF p_cantidad IS NULL OR p_cantidad <= 0 OR v_acreditada + p_cantidad > v_cantidad THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La cantidad acreditada supera la cantidad facturada';
+    END IF;
+
+    IF v_acreditada + p_cantidad = v_cantidad THEN
+        SET v_valor = v_total - v_valor_acreditado;
+    ELSE
+        SET v_valor = ROUND(v_total * p_cantidad / v_cantidad, 2);
+    END IF;
+
+    INSERT INTO DETALLE_NOTA_CREDITO (nc_id, dfs_id, prod_id, dnc_cantidad, dnc_valor)
+    VALUES (p_nc_id, p_dfs_id, NULL, p_cantidad, v_valor);
+END$$
+DELIMITER ;
+
+-- Devolver unidades de un producto vendido en la factura de la nota crédito: las unidades vuelven
+-- al stock con un movimiento de devolución y se acreditan en proporción al total neto de la línea
+DELIMITER $$
+CREATE PROCEDURE sp_acreditar_producto_factura (
+    IN p_nc_id INT,
+    IN p_prod_id INT,
+    IN p_cantidad INT,
+    IN p_usuario VARCHAR(100)
+)
+BEGIN
+    DECLARE v_fac_id INT;
+    DECLARE v_cantidad INT;
+    DECLARE v_total DECIMAL(10,2);
+    DECLARE v_acreditada INT;
+    DECLARE v_valor_acreditado DECIMAL(10,2);
+    DECLARE v_valor DECIMAL(10,2);
+
+    SELECT dfp.fac_id, dfp.dfp_cantidad, dfp.dfp_cantidad * dfp.dfp_precio_unitario - dfp.dfp_descuento
+    INTO v_fac_id, v_cantidad, v_total
+    FROM DETALLE_FACTURA_PRODUCTO dfp
+    JOIN NOTA_CREDITO nc ON nc.fac_id = dfp.fac_id
+    WHERE dfp.prod_id = p_prod_id AND nc.nc_id = p_nc_id;
+
+    IF v_cantidad IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'El producto no se vendió en la factura';
+    END IF;
+
+    SELECT COALESCE(SUM(dnc.dnc_cantidad), 0), COALESCE(SUM(dnc.dnc_valor), 0)
+    INTO v_acreditada, v_valor_acreditado
+    FROM DETALLE_NOTA_CREDITO dnc
+    JOIN NOTA_CREDITO nc ON dnc.nc_id = nc.nc_id
+    WHERE nc.fac_id = v_fac_id AND dnc.prod_id = p_prod_id;
+
+    IF p_cantidad IS NULL OR p_cantidad <= 0 OR v_acreditada + p_cantidad > v_cantidad THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La cantidad devuelta supera la cantidad vendida';
+    END IF;
+
+    IF v_acreditada + p_cantidad = v_cantidad THEN
+        SET v_valor = v_total - v_valor_acreditado;
+    ELSE
+        SET v_valor = ROUND(v_total * p_cantidad / v_cantidad, 2);
+    END IF;
+
+    CALL sp_registrar_movimiento_inventario(p_prod_id, 'Devolución', p_cantidad, p_usuario,
+        CONCAT('Nota crédito #', p_nc_id, ' de la factura #', v_fac_id), CONCAT('NOTA_CREDITO:', p_nc_id), NULL);
+
+    INSERT INTO DETALLE_NOTA_CREDITO (nc_id, dfs_id, prod_id, dnc_cantidad, dnc_valor)
+    VALUES (p_nc_id, NULL, p_prod_id, p_cantidad, v_valor);
+END$$
+DELIMITER ;
+
+-- Totalizar una nota crédito y aplicarla a su factura. Si el cliente ya pagó más de lo que queda
+-- por cobrar después del crédito, la diferencia se le reembolsa (por defecto en efectivo)
+DELIMITER $$
+CREATE PROCEDURE sp_aplicar_nota_credito (
+    IN p_nc_id INT
+)
+BEGIN
+    DECLARE v_fac_id INT;
+    DECLARE v_lineas INT;
+    DECLARE v_total DECIMAL(10,2);
+    DECLARE v_neto DECIMAL(10,2);
+    DECLARE v_neto_pagado DECIMAL(10,2);
+    DECLARE v_reembolso DECIMAL(10,2);
+
+    SELECT fac_id INTO v_fac_id FROM NOTA_CREDITO WHERE nc_id = p_nc_id;
+
+    SELECT COUNT(*), COALESCE(SUM(dnc_valor), 0) INTO v_lineas, v_total
+    FROM DETALLE_NOTA_CREDITO
+    WHERE nc_id = p_nc_id;
+
+    IF v_lineas = 0 THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La nota crédito no tiene líneas';
+    END IF;
+
+    SELECT fac_total - fac_acreditado - v_total, fac_pagado - fac_reembolsado
+    INTO v_neto, v_neto_pagado
+    FROM FACTURA_SERVICIO
+    WHERE fac_id = v_fac_id
+    FOR UPDATE;
+
+    SET v_reembolso = GREATEST(v_neto_pagado - v_neto, 0);
+
+    UPDATE NOTA_CREDITO
+    SET nc_total = v_total,
+        nc_reembolso = v_reembolso,
+        nc_metodo_reembolso = CASE WHEN v_reembolso > 0 THEN COALESCE(nc_metodo_reembolso, 'Efectivo') END
+    WHERE nc_id = p_nc_id;
+
+    CALL sp_actualizar_estado_pago_factura(v_fac_id);
+END$$
+DELIMITER ;
+
+-- Anular una factura emitida el mismo día y sin notas crédito previas: se emite una nota crédito
+-- de anulación por todas sus líneas, los productos vuelven al stock, se reembolsa lo pagado, se
+-- devuelven los usos de las promociones y las citas quedan libres para facturarse de nuevo.
+-- Devuelve la nota crédito, o el motivo por el que la factura no se puede anular.
+-- No abre transacción propia.
+DELIMITER $$
+CREATE PROCEDURE sp_anular_factura (
+    IN p_fac_id INT,
+    IN p_motivo VARCHAR(255),
+    IN p_usuario VARCHAR(100)
+)
+proc: BEGIN
+    DECLARE v_estado VARCHAR(20);
+    DECLARE v_fecha DATE;
+    DECLARE v_notas INT;
+    DECLARE v_rechazo VARCHAR(30) DEFAULT NULL;
+    DECLARE v_nc_id INT;
+    DECLARE v_dfs_id INT;
+    DECLARE v_prod_id INT;
+    DECLARE v_cantidad INT;
+    DECLARE v_fin INT DEFAULT 0;
+    DECLARE cur_servicios CURSOR FOR
+        SELECT dfs_id, dfs_cantidad
+        FROM DETALLE_FACTURA_SERVICIO
+        WHERE fac_id = p_fac_id;
+    DECLARE cur_productos CURSOR FOR
+        SELECT prod_id, dfp_cantidad
+        FROM DETALLE_FACTURA_PRODUCTO
+        WHERE fac_id = p_fac_id;
+    DECLARE CONTINUE HANDLER FOR NOT FOUND SET v_fin = 1;
+
+    -- El plazo se cuenta desde la emisión, no desde la fecha que se digitó en el borrador
+    SELECT fac_estado, DATE(fac_fecha_emision) INTO v_estado, v_fecha
+    FROM FACTURA_SERVICIO
+    WHERE fac_id = p_fac_id
+    FOR UPDATE;
+
+    IF v_estado IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La factura no existe';
+    END IF;
+
+    SELECT COUNT(*) INTO v_notas FROM NOTA_CREDITO WHERE fac_id = p_fac_id;
+
+    IF v_estado <> 'Emitida' THEN
+        SET v_rechazo = 'no_emitida';
+    ELSEIF v_fecha IS NULL OR v_fecha <> CURDATE() THEN
+        SET v_rechazo = 'fuera_de_plazo';
+    ELSEIF v_notas > 0 THEN
+        SET v_rechazo = 'con_notas_credito';
+    END IF;
+
+    IF v_rechazo IS NOT NULL THEN
+        SELECT NULL AS nc_id, v_rechazo AS rechazo;
+        LEAVE proc;
+    END IF;
+
+    INSERT INTO NOTA_CREDITO (fac_id, nc_tipo, nc_motivo, nc_usuario)
+    VALUES (p_fac_id, 'Anulación', p_motivo, p_usuario);
+    SET v_nc_id = LAST_INSERT_ID();
+
+    SET v_fin = 0;
+    OPEN cur_servicios;
+    leer_servicios: LOOP
+        FETCH cur_servicios INTO v_dfs_id, v_cantidad;
+        IF v_fin = 1 THEN
+            LEAVE leer_servicios;
+        END IF;
+        CALL sp_acreditar_linea_servicio(v_nc_id, v_dfs_id, v_cantidad);
+    END LOOP;
+    CLOSE cur_servicios;
+
+    SET v_fin = 0;
+    OPEN cur_productos;
+    leer_productos: LOOP
+        FETCH cur_productos INTO v_prod_id, v_cantidad;
+        IF v_fin = 1 THEN
+            LEAVE leer_productos;
+        END IF;
+        CALL sp_acreditar_producto_factura(v_nc_id, v_prod_id, v_cantidad, p_usuario);
+    END LOOP;
+    CLOSE cur_productos;
+
+    CALL sp_aplicar_nota_credito(v_nc_id);
+
+    UPDATE FACTURA_SERVICIO SET fac_estado = 'Anulada' WHERE fac_id = p_fac_id;
+    CALL sp_liberar_promociones_factura(p_fac_id);
+    UPDATE CITA SET fac_id = NULL WHERE fac_id = p_fac_id;
+
+    SELECT v_nc_id AS nc_id, NULL AS rechazo;
+END$$
+DELIMITER ;
+
+-- Buscar una nota crédito por ID
+DELIMITER $$
+CREATE PROCEDURE sp_buscar_nota_credito (
+    IN p_nc_id INT
+)
+BEGIN
+    SELECT * FROM NOTA_CREDITO WHERE nc_id = p_nc_id;
+END$$
+DELIMITER ;
+
+-- Notas crédito de una factura
+DELIMITER $$
+CREATE PROCEDURE sp_listar_notas_credito_factura (
+    IN p_fac_id INT
+)
+BEGIN
+    SELECT * FROM NOTA_CREDITO WHERE fac_id = p_fac_id ORDER BY nc_fecha, nc_id;
+END$$
+DELIMITER ;
+
+-- Líneas de las notas crédito de una factura con el servicio o el producto acreditado
+DELIMITER $$
+CREATE PROCEDURE sp_listar_lineas_notas_credito_factura (
+    IN p_fac_id INT
+)
+BEGIN
+    SELECT dnc.dnc_id, dnc.nc_id, dnc.dfs_id, dnc.prod_id,
+           COALESCE(s.ser_nombre, p.prod_nombre) AS descripcion,
+           dnc.dnc_cantidad, dnc.dnc_valor
+    FROM DETALLE_NOTA_CREDITO dnc
+    JOIN NOTA_CREDITO nc ON dnc.nc_id = nc.nc_id
+    LEFT JOIN DETALLE_FACTURA_SERVICIO dfs ON dnc.dfs_id = dfs.dfs_id
+    LEFT JOIN SERVICIO s ON dfs.ser_id = s.ser_id
+    LEFT JOIN PRODUCTO p ON dnc.prod_id = p.prod_id
+    WHERE nc.fac_id = p_fac_id
+    ORDER BY dnc.nc_id, dnc.dnc_id;
+END$$
+DELIMITER ;
+
+-- Registrar una resolución de facturación y devolverla con su ID. Si se crea activa, las
+-- demás resoluciones se desactivan. Se ejecuta dentro de la transacción del llamador.
+DELIMITER $$
+CREATE PROCEDURE sp_insertar_resolucion_facturacion (
+    IN p_numero VARCHAR(50),
+    IN p_prefijo VARCHAR(10),
+    IN p_rango_desde INT,
+    IN p_rango_hasta INT,
+    IN p_fecha_desde DATE,
+    IN p_fecha_hasta DATE,
+    IN p_activa TINYINT(1),
+    IN p_clave_tecnica VARCHAR(100)
+)
+BEGIN
+    DECLARE v_res_id INT;
+
+    IF p_rango_desde <= 0 OR p_rango_hasta < p_rango_desde THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'El rango de numeración no es válido';
+    END IF;
+
+    IF p_fecha_hasta < p_fecha_desde THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'La vigencia de la resolución no es válida';
+    END IF;
+
+    IF p_activa = 1 THEN
+        UPDATE RESOLUCION_FACTURACION SET res_activa = 0 WHERE res_activa = 1;
+    END IF;
+
+    INSERT INTO RESOLUCION_FACTURACION (res_numero, res_prefijo, res_rango_desde, res_rango_hasta,
+        res_fecha_desde, res_fecha_hasta, res_activa, res_clave_tecnica)
+    VALUES (p_numero, COALESCE(p_prefijo, ''), p_rango_desde, p_rango_hasta, p_fecha_desde, p_fecha_hasta,
+        COALESCE(p_activa, 0), p_clave_tecnica);
+
+    SET v_res_id = LAST_INSERT_ID();
+
+    SELECT * FROM RESOLUCION_FACTURACION WHERE res_id = v_res_id;
+END$$
+DELIMITER ;
+
+-- Resoluciones de facturación con los consecutivos que les quedan disponibles
+DELIMITER $$
+CREATE PROCEDURE sp_listar_resoluciones_facturacion()
+BEGIN
+    SELECT r.*,
+           r.res_rango_hasta - COALESCE(r.res_consecutivo_actual, r.res_rango_desde - 1) AS consecutivos_disponibles
+    FROM RESOLUCION_FACTURACION r
+    ORDER BY r.res_activa DESC, r.res_fecha_desde DESC, r.res_id DESC;
+END$$
+DELIMITER ;
+
+-- Buscar una resolución de facturación por ID
+DELIMITER $$
+CREATE PROCEDURE sp_buscar_resolucion_facturacion (
+    IN p_res_id INT
+)
+BEGIN
+    SELECT r.*,
+           r.res_rango_hasta - COALESCE(r.res_consecutivo_actual, r.res_rango_desde - 1) AS consecutivos_disponibles
+    FROM RESOLUCION_FACTURACION r
+    WHERE r.res_id = p_res_id;
+END$$
+DELIMITER ;
+
+-- Resolución activa bloqueada hasta el fin de la transacción del llamador (la usa la emisión de
+-- facturas para validar la vigencia y el rango antes de asignar el consecutivo)
+DELIMITER $$
+CREATE PROCEDURE sp_bloquear_resolucion_activa()
+BEGIN
+    SELECT r.*,
+           r.res_rango_hasta - COALESCE(r.res_consecutivo_actual, r.res_rango_desde - 1) AS consecutivos_disponibles
+    FROM RESOLUCION_FACTURACION r
+    WHERE r.res_activa = 1
+    LIMIT 1
+    FOR UPDATE;
+END$$
+DELIMITER ;
+
+-- Activar una resolución de facturación; las demás se desactivan para que solo una numere facturas
+DELIMITER $$
+CREATE PROCEDURE sp_activar_resolucion_facturacion (
+    IN p_res_id INT
+)
+BEGIN
+    UPDATE RESOLUCION_FACTURACION SET res_activa = (res_id = p_res_id);
+END$$
+DELIMITER ;
+
+-- Configurar la tarifa de IVA de un servicio (solo afecta las líneas facturadas desde ahora)
+DELIMITER $$
+CREATE PROCEDURE sp_actualizar_iva_servicio (
+    IN p_ser_id INT,
+    IN p_iva_porcentaje DECIMAL(5,2)
+)
+BEGIN
+    UPDATE SERVICIO SET ser_iva_porcentaje = p_iva_porcentaje WHERE ser_id = p_ser_id;
+END$$
+DELIMITER ;
+
+-- Configurar la tarifa de IVA de un producto (solo afecta las líneas facturadas desde ahora)
+DELIMITER $$
+CREATE PROCEDURE sp_actualizar_iva_producto (
+    IN p_prod_id INT,
+    IN p_iva_porcentaje DECIMAL(5,2)
+)
+BEGIN
+    UPDATE PRODUCTO SET prod_iva_porcentaje = p_iva_porcentaje WHERE prod_id = p_prod_id;
+END$$
+DELIMITER ;
+
+-- Discriminación del IVA de una factura por tarifa: base gravable, impuesto y total de las líneas
+-- de servicios y de productos con la misma tarifa
+DELIMITER $$
+CREATE PROCEDURE sp_resumen_impuestos_factura (
+    IN p_fac_id INT
+)
+BEGIN
+    SELECT l.iva_porcentaje,
+           SUM(l.total_linea) - SUM(l.iva_linea) AS base,
+           SUM(l.iva_linea) AS impuesto,
+           SUM(l.total_linea) AS total
+    FROM (
+        SELECT dfs.dfs_iva_porcentaje AS iva_porcentaje,
+               dfs.dfs_cantidad * dfs.dfs_precio_unitario - dfs.dfs_descuento AS total_linea,
+               ROUND((dfs.dfs_cantidad * dfs.dfs_precio_unitario - dfs.dfs_descuento)
+                   * dfs.dfs_iva_porcentaje / (100 + dfs.dfs_iva_porcentaje), 2) AS iva_linea
+        FROM DETALLE_FACTURA_SERVICIO dfs
+        WHERE dfs.fac_id = p_fac_id
+        UNION ALL
+        SELECT dfp.dfp_iva_porcentaje,
+               dfp.dfp_cantidad * dfp.dfp_precio_unitario - dfp.dfp_descuento,
+               ROUND((dfp.dfp_cantidad * dfp.dfp_precio_unitario - dfp.dfp_descuento)
+                   * dfp.dfp_iva_porcentaje / (100 + dfp.dfp_iva_porcentaje), 2)
+        FROM DETALLE_FACTURA_PRODUCTO dfp
+        WHERE dfp.fac_id = p_fac_id
+    ) l
+    GROUP BY l.iva_porcentaje
+    ORDER BY l.iva_porcentaje DESC;
+END$$
+DELIMITER ;
+
+-- Registrar el documento de identidad de un cliente (lo usa la factura electrónica)
+DELIMITER $$
+CREATE PROCEDURE sp_actualizar_documento_cliente (
+    IN p_cli_id INT,
+    IN p_tipo_documento VARCHAR(5),
+    IN p_documento VARCHAR(20)
+)
+BEGIN
+    UPDATE CLIENTE
+    SET cli_tipo_documento = p_tipo_documento,
+        cli_documento = p_documento
+    WHERE cli_id = p_cli_id;
+END$$
+DELIMITER ;
+
+-- Estado de resultados entre dos fechas por mes o por semana (de lunes a domingo): una fila por
+-- periodo, concepto y tipo de gasto. Las ventas cuentan las facturas emitidas y anuladas en la fecha
+-- de emisión, y las notas crédito se restan en la fecha en que se registraron; ambas van sin IVA,
+-- que no es ingreso del salón (cada línea acreditada usa la tarifa y el redondeo de su línea de la
+-- factura). El costo de ventas es el inventario consumido por servicios o vendido, valorado al costo
+-- promedio, menos lo devuelto por facturas y notas crédito. Los gastos a los que se imputan pagos
+-- de nómina o compras no se repiten como otros gastos, y las compras se informan aparte porque su
+-- costo entra al resultado cuando el inventario sale.
+DELIMITER $$
+CREATE PROCEDURE sp_reporte_estado_resultados (
+    IN p_desde DATE,
+    IN p_hasta DATE,
+    IN p_agrupacion VARCHAR(10)
+)
+BEGIN
+    IF p_agrupacion NOT IN ('month', 'week') THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Agrupación no válida, use month o week';
+    END IF;
+
+    SELECT
+        IF(p_agrupacion = 'week',
+           DATE_SUB(m.fecha, INTERVAL WEEKDAY(m.fecha) DAY),
+           DATE_SUB(m.fecha, INTERVAL DAYOFMONTH(m.fecha) - 1 DAY)) AS periodo,
+        m.concepto,
+        m.detalle,
+        ROUND(SUM(m.monto), 2) AS monto
+    FROM (
+        SELECT DATE(fac_fecha_emision) AS fecha, 'Ventas' AS concepto, NULL AS detalle, fac_subtotal AS monto
+        FROM FACTURA_SERVICIO
+        WHERE fac_estado <> 'Borrador'
+          AND fac_fecha_emision >= p_desde AND fac_fecha_emision < DATE_ADD(p_hasta, INTERVAL 1 DAY)
+
+        UNION ALL
+        SELECT DATE(nc.nc_fecha), 'Notas crédito', NULL,
+               dnc.dnc_valor - ROUND(dnc.dnc_valor * COALESCE(dfs.dfs_iva_porcentaje, dfp.dfp_iva_porcentaje, 0)
+                   / (100 + COALESCE(dfs.dfs_iva_porcentaje, dfp.dfp_iva_porcentaje, 0)), 2)
+        FROM NOTA_CREDITO nc
+        JOIN DETALLE_NOTA_CREDITO dnc ON dnc.nc_id = nc.nc_id
+        LEFT JOIN DETALLE_FACTURA_SERVICIO dfs ON dfs.dfs_id = dnc.dfs_id
+        LEFT JOIN DETALLE_FACTURA_PRODUCTO dfp ON dfp.fac_id = nc.fac_id AND dfp.prod_id = dnc.prod_id
+        WHERE nc.nc_fecha >= p_desde AND nc.nc_fecha < DATE_ADD(p_hasta, INTERVAL 1 DAY)
+
+        UNION ALL
+        SELECT DATE(mov_fecha), 'Costo ventas', NULL, -mov_cantidad * mov_costo_unitario
+        FROM MOVIMIENTO_INVENTARIO
+        WHERE (mov_tipo IN ('Consumo Servicio', 'Venta')
+               OR (mov_tipo = 'Devolución'
+                   AND (mov_referencia LIKE 'FACTURA:%' OR mov_referencia LIKE 'NOTA_CREDITO:%')))
+          AND mov_fecha >= p_desde AND mov_fecha < DATE_ADD(p_hasta, INTERVAL 1 DAY)
+
+        UNION ALL
+        SELECT DATE(mov_fecha), 'Merma', NULL, -mov_cantidad * mov_costo_unitario
+        FROM MOVIMIENTO_INVENTARIO
+        WHERE mov_tipo = 'Merma'
+          AND mov_fecha >= p_desde AND mov_fecha < DATE_ADD(p_hasta, INTERVAL 1 DAY)
+
+        UNION ALL
+        SELECT pag_fecha, 'Nómina', NULL, pag_monto
+        FROM PAGO
+        WHERE pag_fecha BETWEEN p_desde AND p_hasta
+
+        UNION ALL
+        SELECT g.gas_fecha, 'Gasto', g.gas_tipo, g.gas_monto
+        FROM GASTO_MENSUAL g
+        WHERE g.gas_fecha BETWEEN p_desde AND p_hasta
+          AND NOT EXISTS (SELECT 1 FROM PAGO p WHERE p.gas_id = g.gas_id)
+          AND NOT EXISTS (SELECT 1 FROM COMPRA_PRODUCTO c WHERE c.gas_id = g.gas_id)
+
+        UNION ALL
+        SELECT cop_fecha_compra, 'Compras', NULL, cop_total_compra
+        FROM COMPRA_PRODUCTO
+        WHERE cop_estado NOT IN ('Borrador', 'Cancelada')
+          AND cop_fecha_compra BETWEEN p_desde AND p_hasta
+    ) m
+    GROUP BY periodo, m.concepto, m.detalle
+    ORDER BY periodo, m.concepto, m.detalle;
+END$$
+DELIMITER ;
+
 -- Log CRUD stored procedures completion
 INSERT IGNORE INTO salondb.db_initialization_log (script_name, status)
 VALUES ('04_stored_procedures_crud.sql', 'SUCCESS');
@@ -26,6 +26,12 @@ BEGIN
 END;
 //
 
+CREATE PROCEDURE sp_get_productos_reorden()
+BEGIN
+  SELECT * FROM vw_productos_reorden ORDER BY prov_nombre, prod_nombre;
+END;
+//
+
 CREATE PROCEDURE sp_get_total_productos()
 BEGIN
   SELECT * FROM vw_total_productos;
@@ -35,6 +35,11 @@ CALL sp_insert_cliente('Nacho', 'Quevedo', '+57 4724823662', 'tabad@gmail.com',
 CALL sp_insert_cliente('Humberto', 'Ropero', '+57 4739577738', 'genovevablazquez@gmail.com', '123456');
 CALL sp_insert_cliente('Aranzazu', 'Naranjo', '+57 4733433200', 'dbeltran@gmail.com', '123456');
 
+-- Identification documents for the electronic invoice (clients without one are billed as final consumers)
+CALL sp_actualizar_documento_cliente(2, 'CC', '1020456789');
+CALL sp_actualizar_documento_cliente(4, 'CC', '52345678');
+CALL sp_actualizar_documento_cliente(12, 'NIT', '900123456');
+
 
 CALL CrearProveedor('Zamora Inc', '+57 4720062312', 'gilanselma@gmail.com', 'Paseo Carmen Reina 1, Murcia, 08621');
 CALL CrearProveedor('Zamora and Sons', '+57 4639845461', 'santiago79@gmail.com', 'C. de Rene Tamarit 1, Avila, 65918');
@@ -66,16 +71,32 @@ CALL sp_insertar_servicio('Hidratacion facial', 'Tratamiento de hidratacion para
 
 
 
-CALL sp_insert_producto('Tinte 1', 'Tinte rubio', 18, 39165.07);
-CALL sp_insert_producto('Tinte 2', 'Tinte castaño', 6, 28490.41);
-CALL sp_insert_producto('Pintauñas 1', 'Pintauñas rojo', 9, 49580.93);
-CALL sp_insert_producto('Pintauñas 2', 'Pintauñas blanco', 18, 18784.61);
-CALL sp_insert_producto('Shampoo 1', 'Shampoo para cabellos lisos', 11, 43714.08);
-CALL sp_insert_producto('Shampoo 2', 'Shampoo para cabellos rizados', 2, 19161.92);
-CALL sp_insert_producto('Crema de peinar 1', 'Crema de peinar para cabellos lisos', 2, 42201.83);
-CALL sp_insert_producto('Acondicionador 1', 'Acondicionador para cabellos ondulados', 13, 20709.64);
-CALL sp_insert_producto('Mascarilla 1', 'Mascarilla facial', 13, 20709.64);
+CALL sp_insert_producto('Tinte 1', 'Tinte rubio', 18, 39165.07, 21500, NULL);
+CALL sp_insert_producto('Tinte 2', 'Tinte castaño', 6, 28490.41, 15600, NULL);
+CALL sp_insert_producto('Pintauñas 1', 'Pintauñas rojo', 9, 49580.93, 27200, NULL);
+CALL sp_insert_producto('Pintauñas 2', 'Pintauñas blanco', 18, 18784.61, 10300, NULL);
+CALL sp_insert_producto('Shampoo 1', 'Shampoo para cabellos lisos', 11, 43714.08, 24000, NULL);
+CALL sp_insert_producto('Shampoo 2', 'Shampoo para cabellos rizados', 2, 19161.92, 10500, NULL);
+CALL sp_insert_producto('Crema de peinar 1', 'Crema de peinar para cabellos lisos', 2, 42201.83, 23200, NULL);
+CALL sp_insert_producto('Acondicionador 1', 'Acondicionador para cabellos ondulados', 13, 20709.64, 11400, NULL);
+CALL sp_insert_producto('Mascarilla 1', 'Mascarilla facial', 13, 20709.64, 11400, NULL);
+
+-- Puntos de reorden y proveedor preferido
+UPDATE PRODUCTO SET prod_stock_minimo = 8, prod_cantidad_reorden = 12, prov_id = 4 WHERE prod_id IN (1, 2);
+UPDATE PRODUCTO SET prod_stock_minimo = 6, prod_cantidad_reorden = 10, prov_id = 6 WHERE prod_id IN (3, 4);
+UPDATE PRODUCTO SET prod_stock_minimo = 5, prod_cantidad_reorden = 10, prov_id = 2 WHERE prod_id IN (5, 6, 7, 8);
 
+-- Productos que consume cada servicio (se descuentan al completar las citas)
+INSERT INTO PRODUCTO_USADO (ser_id, prod_id, pru_cantidad_usada, pru_botellas_usadas) VALUES
+(1, 9, 300, 3),
+(2, 2, 200, 2),
+(3, 2, 100, 3),
+(4, 9, 200, 2),
+(5, 5, 600, 1),
+(6, 1, 300, 1),
+(7, 3, 300, 3),
+(8, 8, 300, 1),
+(9, 2, 400, 2);
 
 
 INSERT INTO GASTO_MENSUAL (gas_descripcion, gas_fecha, gas_monto, gas_tipo) VALUES
@@ -145,6 +166,14 @@ CALL sp_insertar_cita('2025-05-18', '11:37:08', 1, 4, 15);
 CALL sp_insertar_cita('2025-06-01', '11:32:14', 13, 5, 4);
 CALL sp_insertar_cita('2025-06-07', '13:11:14', 3, 9, 8);
 
+CALL sp_cambiar_estado_cita(3, 'Confirmada', NULL, NULL);
+CALL sp_cambiar_estado_cita(3, 'En Curso', NULL, NULL);
+CALL sp_cambiar_estado_cita(3, 'Completada', NULL, NULL);
+CALL sp_cambiar_estado_cita(13, 'En Curso', NULL, NULL);
+CALL sp_cambiar_estado_cita(13, 'Completada', 'Cliente satisfecho con el resultado', NULL);
+CALL sp_cambiar_estado_cita(12, 'No Asistio', NULL, NULL);
+CALL sp_cambiar_estado_cita(10, 'Cancelada', 'Cancelada por el cliente', NULL);
+
 -- Temporarily disable the trigger that causes dynamic SQL issues
 DROP TRIGGER IF EXISTS trg_after_insert_usuario_sistema;
 
@@ -166,69 +195,101 @@ DROP TRIGGER IF EXISTS trg_after_insert_usuario_sistema;
 
 
 -- Insert invoice details using stored procedures that automatically calculate totals
-CALL sp_insertar_detalle_factura(1, 6);
-CALL sp_insertar_detalle_factura(1, 7);
-CALL sp_insertar_detalle_factura(2, 5);
-CALL sp_insertar_detalle_factura(2, 2);
-CALL sp_insertar_detalle_factura(3, 3);
-CALL sp_insertar_detalle_factura(3, 7);
-CALL sp_insertar_detalle_factura(4, 2);
-CALL sp_insertar_detalle_factura(4, 3);
-CALL sp_insertar_detalle_factura(5, 4);
-CALL sp_insertar_detalle_factura(5, 5);
-CALL sp_insertar_detalle_factura(6, 3);
-CALL sp_insertar_detalle_factura(6, 8);
-CALL sp_insertar_detalle_factura(7, 1);
-CALL sp_insertar_detalle_factura(7, 2);
-CALL sp_insertar_detalle_factura(8, 5);
-CALL sp_insertar_detalle_factura(9, 1);
-CALL sp_insertar_detalle_factura(10, 3);
-CALL sp_insertar_detalle_factura(11, 2);
-CALL sp_insertar_detalle_factura(12, 7);
-CALL sp_insertar_detalle_factura(13, 1);
-CALL sp_insertar_detalle_factura(14, 2);
-CALL sp_insertar_detalle_factura(15, 4);
+CALL sp_insertar_detalle_factura(1, 6, 1, NULL);
+CALL sp_insertar_detalle_factura(1, 7, 1, NULL);
+CALL sp_insertar_detalle_factura(2, 5, 1, NULL);
+CALL sp_insertar_detalle_factura(2, 2, 1, NULL);
+CALL sp_insertar_detalle_factura(3, 3, 1, NULL);
+CALL sp_insertar_detalle_factura(3, 7, 1, NULL);
+CALL sp_insertar_detalle_factura(4, 2, 1, NULL);
+CALL sp_insertar_detalle_factura(4, 3, 1, NULL);
+CALL sp_insertar_detalle_factura(5, 4, 1, NULL);
+CALL sp_insertar_detalle_factura(5, 5, 1, NULL);
+CALL sp_insertar_detalle_factura(6, 3, 1, NULL);
+CALL sp_insertar_detalle_factura(6, 8, 1, NULL);
+CALL sp_insertar_detalle_factura(7, 1, 1, NULL);
+CALL sp_insertar_detalle_factura(7, 2, 1, NULL);
+CALL sp_insertar_detalle_factura(8, 5, 1, NULL);
+CALL sp_insertar_detalle_factura(9, 1, 1, NULL);
+CALL sp_insertar_detalle_factura(10, 3, 1, NULL);
+CALL sp_insertar_detalle_factura(11, 2, 1, NULL);
+CALL sp_insertar_detalle_factura(12, 7, 1, NULL);
+CALL sp_insertar_detalle_factura(13, 1, 1, NULL);
+CALL sp_insertar_detalle_factura(14, 2, 1, NULL);
+CALL sp_insertar_detalle_factura(15, 4, 1, NULL);
+-- The same service twice on one bill (two clients served together)
+CALL sp_insertar_detalle_factura(8, 5, 2, NULL);
+
+-- Retail products sold at the counter (deduct stock and add to the invoice total)
+CALL sp_insertar_detalle_factura_producto(2, 5, 1, 0, NULL);
+CALL sp_insertar_detalle_factura_producto(5, 8, 2, 0, NULL);
+CALL sp_insertar_detalle_factura_producto(9, 9, 1, 2000, NULL);
+
+-- Invoice numbering resolution: issued invoices take consecutive numbers SALO1, SALO2, ...
+CALL sp_insertar_resolucion_facturacion('18764000000001', 'SALO', 1, 5000, '2025-06-01', '2027-05-31', 1,
+    'fc8eac422eba16e22ffd8c6f94b3f40a6e38162c');
+
+-- Issue the invoices: from here on they are only corrected with credit notes
+CALL sp_emitir_factura(1);
+CALL sp_emitir_factura(2);
+CALL sp_emitir_factura(3);
+CALL sp_emitir_factura(4);
+CALL sp_emitir_factura(5);
+CALL sp_emitir_factura(6);
+CALL sp_emitir_factura(7);
+CALL sp_emitir_factura(8);
+CALL sp_emitir_factura(9);
+CALL sp_emitir_factura(10);
+CALL sp_emitir_factura(11);
+CALL sp_emitir_factura(12);
+CALL sp_emitir_factura(13);
+CALL sp_emitir_factura(14);
+CALL sp_emitir_factura(15);
+
+-- Client payments: cash with change, split tender and partial payments
+CALL sp_registrar_pago_cliente(1, 'Efectivo', 50000, NULL, NULL, NULL);
+CALL sp_registrar_pago_cliente(2, 'Tarjeta', 60000, 'Visa', '004512', NULL);
+CALL sp_registrar_pago_cliente(2, 'Billetera Digital', 20000, 'Nequi', 'M8812034', NULL);
+CALL sp_registrar_pago_cliente(3, 'Transferencia', 46000, 'Bancolombia', 'TRF-20250616-118', NULL);
+CALL sp_registrar_pago_cliente(4, 'Efectivo', 20000, NULL, NULL, NULL);
+
+-- Credit note: one of the two units of product 8 sold on invoice 5 is returned unopened
+CALL sp_insertar_nota_credito(5, 'Devolución', 'Producto devuelto sin abrir', NULL, NULL);
+CALL sp_acreditar_producto_factura(1, 8, 1, NULL);
+CALL sp_aplicar_nota_credito(1);
 
-INSERT INTO PRODUCTO_USADO (ser_id, prod_id, pru_cantidad_usada, pru_botellas_usadas) VALUES
-(1, 9, 300, 3),
-(2, 2, 200, 2),
-(3, 2, 100, 3),
-(4, 9, 200, 2),
-(5, 5, 600, 1),
-(6, 1, 300, 1),
-(7, 3, 300, 3),
-(8, 8, 300, 1),
-(9, 2, 400, 2);
 
 
 -- Active promotions (July 2025 and future)
-CALL sp_crear_promocion('Descuento Coloración', 'Descuento especial en servicios de coloración para lucir un nuevo look.', '2025-07-01', '2025-09-01', 27.6, 1, 1);
-CALL sp_crear_promocion('Promo Manicura', 'Oferta especial en manicuras para mantener tus uñas perfectas.', '2025-07-15', '2025-09-15', 25.86, 2, 3);
-CALL sp_crear_promocion('Corte Especial', 'Promoción en cortes de cabello para renovar tu estilo.', '2025-07-10', '2025-08-10', 8.7, 3, 1);
-CALL sp_crear_promocion('Alisado Express', 'Descuento en servicios de alisado para cabello sedoso.', '2025-08-01', '2025-10-01', 12.71, 4, 1);
-CALL sp_crear_promocion('Pedicura Relajante', 'Oferta especial en pedicuras para el cuidado completo de tus pies.', '2025-07-05', '2025-08-05', 27.47, 5, 4);
-CALL sp_crear_promocion('Decoración Uñas', 'Promoción en decoración de uñas con diseños únicos.', '2025-08-15', '2025-10-15', 21.67, 6, 3);
-CALL sp_crear_promocion('Cejas Perfectas', 'Descuento en depilación de cejas para una mirada impecable.', '2025-09-01', '2025-11-01', 27.1, 7, 5);
-CALL sp_crear_promocion('Mascarilla Facial', 'Oferta en mascarillas faciales para una piel radiante.', '2025-07-18', '2025-08-18', 23.74, 8, 4);
-CALL sp_crear_promocion('Hidratación Facial', 'Promoción en tratamientos de hidratación facial.', '2025-08-20', '2025-10-20', 26.62, 9, 3);
-CALL sp_crear_promocion('Combo Coloración', 'Descuento especial en paquetes de coloración completa.', '2025-07-12', '2025-08-12', 27.04, 1, 3);
-CALL sp_crear_promocion('Uñas Glamour', 'Oferta en servicios completos de decoración de uñas.', '2025-09-15', '2025-11-15', 23.41, 6, 7);
-CALL sp_crear_promocion('Belleza Facial', 'Promoción en tratamientos faciales de depilación.', '2025-08-10', '2025-09-10', 25.06, 7, 4);
-CALL sp_crear_promocion('Promo Relajación', 'Descuento en servicios de relajación y cuidado facial.', '2025-07-08', '2025-08-08', 11.67, 8, 2);
-CALL sp_crear_promocion('Nuevo Look', 'Oferta especial para cambio de imagen completo.', '2025-10-01', '2025-12-01', 14.56, 1, 8);
-CALL sp_crear_promocion('Verano Radiante', 'Promoción de verano con descuentos en múltiples servicios.', '2025-07-01', '2025-08-31', 10.56, 9, 8);
+CALL sp_crear_promocion('Descuento Coloración', 'Descuento especial en servicios de coloración para lucir un nuevo look.', '2025-07-01', '2025-09-01', 27.6, 1, 1, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Promo Manicura', 'Oferta especial en manicuras para mantener tus uñas perfectas.', '2025-07-15', '2025-09-15', 25.86, 2, 3, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Corte Especial', 'Promoción en cortes de cabello para renovar tu estilo.', '2025-07-10', '2025-08-10', 8.7, 3, 1, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Alisado Express', 'Descuento en servicios de alisado para cabello sedoso.', '2025-08-01', '2025-10-01', 12.71, 4, 1, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Pedicura Relajante', 'Oferta especial en pedicuras para el cuidado completo de tus pies.', '2025-07-05', '2025-08-05', 27.47, 5, 4, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Decoración Uñas', 'Promoción en decoración de uñas con diseños únicos.', '2025-08-15', '2025-10-15', 21.67, 6, 3, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Cejas Perfectas', 'Descuento en depilación de cejas para una mirada impecable.', '2025-09-01', '2025-11-01', 27.1, 7, 5, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Mascarilla Facial', 'Oferta en mascarillas faciales para una piel radiante.', '2025-07-18', '2025-08-18', 23.74, 8, 4, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Hidratación Facial', 'Promoción en tratamientos de hidratación facial.', '2025-08-20', '2025-10-20', 26.62, 9, 3, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Combo Coloración', 'Descuento especial en paquetes de coloración completa.', '2025-07-12', '2025-08-12', 27.04, 1, 3, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Uñas Glamour', 'Oferta en servicios completos de decoración de uñas.', '2025-09-15', '2025-11-15', 23.41, 6, 7, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Belleza Facial', 'Promoción en tratamientos faciales de depilación.', '2025-08-10', '2025-09-10', 25.06, 7, 4, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Promo Relajación', 'Descuento en servicios de relajación y cuidado facial.', '2025-07-08', '2025-08-08', 11.67, 8, 2, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Bienvenida', 'Cupón de bienvenida con valor fijo en el primer corte.', '2025-07-01', '2025-12-31', NULL, 3, 0, 'Valor Fijo', 5000, 100, 1, NULL, 'BIENVENIDA', NULL);
+CALL sp_crear_promocion('Paquete Manos y Pies', 'Descuento al tomar manicura y pedicura en la misma factura.', '2025-07-01', '2025-12-31', 15, 2, 0, 'Porcentaje', NULL, NULL, 2, 40000, NULL, JSON_ARRAY(5));
+CALL sp_crear_promocion('Nuevo Look', 'Oferta especial para cambio de imagen completo.', '2025-10-01', '2025-12-01', 14.56, 1, 8, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Verano Radiante', 'Promoción de verano con descuentos en múltiples servicios.', '2025-07-01', '2025-08-31', 10.56, 9, 8, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
 
 -- Expired promotions (before July 20, 2025)
-CALL sp_crear_promocion('Primavera Bella', 'Promoción de primavera con descuentos especiales.', '2025-03-01', '2025-05-31', 20.0, 1, 2);
-CALL sp_crear_promocion('San Valentín', 'Descuento especial para el día del amor y la amistad.', '2025-02-10', '2025-02-20', 30.0, 2, 1);
-CALL sp_crear_promocion('Día de la Madre', 'Promoción especial para celebrar a las madres.', '2025-05-01', '2025-05-15', 25.0, 8, 3);
-CALL sp_crear_promocion('Año Nuevo Look', 'Cambio de imagen para empezar el año.', '2025-01-01', '2025-01-31', 15.0, 3, 2);
-CALL sp_crear_promocion('Vacaciones Invierno', 'Descuentos especiales para las vacaciones de invierno.', '2024-12-15', '2025-01-15', 18.0, 5, 4);
-CALL sp_crear_promocion('Regreso a Clases', 'Promoción para lucir genial en el regreso a clases.', '2025-01-20', '2025-02-28', 22.0, 7, 3);
-CALL sp_crear_promocion('Abril Radiante', 'Promoción de abril para lucir espectacular.', '2025-04-01', '2025-04-30', 17.5, 9, 5);
-CALL sp_crear_promocion('Mayo Flores', 'Como las flores de mayo, luce hermosa.', '2025-05-01', '2025-05-31', 19.0, 6, 2);
-CALL sp_crear_promocion('Junio Perfecto', 'Termina el primer semestre con un look perfecto.', '2025-06-01', '2025-06-30', 24.0, 4, 6);
-CALL sp_crear_promocion('Día del Padre', 'Promoción especial para consentir a papá.', '2025-06-15', '2025-06-20', 12.0, 3, 1);
+CALL sp_crear_promocion('Primavera Bella', 'Promoción de primavera con descuentos especiales.', '2025-03-01', '2025-05-31', 20.0, 1, 2, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('San Valentín', 'Descuento especial para el día del amor y la amistad.', '2025-02-10', '2025-02-20', 30.0, 2, 1, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Día de la Madre', 'Promoción especial para celebrar a las madres.', '2025-05-01', '2025-05-15', 25.0, 8, 3, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Año Nuevo Look', 'Cambio de imagen para empezar el año.', '2025-01-01', '2025-01-31', 15.0, 3, 2, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Vacaciones Invierno', 'Descuentos especiales para las vacaciones de invierno.', '2024-12-15', '2025-01-15', 18.0, 5, 4, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Regreso a Clases', 'Promoción para lucir genial en el regreso a clases.', '2025-01-20', '2025-02-28', 22.0, 7, 3, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Abril Radiante', 'Promoción de abril para lucir espectacular.', '2025-04-01', '2025-04-30', 17.5, 9, 5, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Mayo Flores', 'Como las flores de mayo, luce hermosa.', '2025-05-01', '2025-05-31', 19.0, 6, 2, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Junio Perfecto', 'Termina el primer semestre con un look perfecto.', '2025-06-01', '2025-06-30', 24.0, 4, 6, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
+CALL sp_crear_promocion('Día del Padre', 'Promoción especial para consentir a papá.', '2025-06-15', '2025-06-20', 12.0, 3, 1, 'Porcentaje', NULL, NULL, NULL, NULL, NULL, NULL);
 
 INSERT INTO COMPRA_PRODUCTO (cop_fecha_compra, cop_total_compra, cop_metodo_pago, prov_id, gas_id) VALUES
 ('2025-06-16', 191890.7, 'Efectivo', 4, 1),
@@ -262,6 +323,10 @@ INSERT INTO DETALLE_COMPRA (com_id, prod_id, dec_cantidad, dec_precio_unitario)
 (13, 1, 6, 12801.62),
 (14, 3, 4, 30183.87),
 (15, 4, 4, 14420.62);
+
+-- Compras históricas: se registraron después de recibir la mercancía
+UPDATE DETALLE_COMPRA SET dec_cantidad_recibida = dec_cantidad;
+UPDATE COMPRA_PRODUCTO SET cop_estado = 'Cerrada';
 -- INSERT INTO INVENTARIO (inv_fecha_actualizacion, prod_id, inv_cantidad_actual, inv_observaciones) VALUES
 -- ('2025-06-16', 1, 18, 'Comprar más producto'),
 -- ('2025-06-16', 2, 6, 'Sin observaciones'),
@@ -274,21 +339,26 @@ INSERT INTO DETALLE_COMPRA (com_id, prod_id, dec_cantidad, dec_precio_unitario)
 -- ('2025-06-16', 9, 13, 'Sin observaciones'),
 
 INSERT INTO HORARIO_EMPLEADO (hor_dia_semana, hor_hora_entrada, hor_hora_salida, emp_id) VALUES
-('Jueves', '13:26:48', '06:05:58', 8),
-('Jueves', '08:08:11', '16:23:05', 4),
-('Martes', '21:15:40', '09:22:18', 11),
-('Sabado', '05:20:19', '01:08:13', 1),
-('Lunes', '02:25:04', '13:02:10', 13),
-('Jueves', '22:43:11', '20:20:46', 4),
-('Martes', '00:25:37', '19:02:08', 13),
-('Sabado', '15:39:12', '04:59:01', 9),
-('Jueves', '11:17:00', '17:23:55', 1),
-('Viernes', '20:12:14', '23:14:46', 4),
-('Lunes', '14:53:09', '13:36:19', 8),
-('Martes', '22:11:49', '00:12:26', 13),
-('Jueves', '06:17:54', '02:41:44', 11),
-('Viernes', '19:14:30', '17:21:05', 9),
-('Viernes', '12:58:57', '08:00:41', 6);
+('Lunes', '08:00:00', '17:00:00', 1),
+('Martes', '08:00:00', '17:00:00', 1),
+('Jueves', '08:00:00', '17:00:00', 1),
+('Sabado', '09:00:00', '14:00:00', 1),
+('Lunes', '09:00:00', '18:00:00', 4),
+('Miercoles', '09:00:00', '18:00:00', 4),
+('Jueves', '09:00:00', '18:00:00', 4),
+('Viernes', '09:00:00', '18:00:00', 4),
+('Martes', '10:00:00', '19:00:00', 7),
+('Miercoles', '10:00:00', '19:00:00', 7),
+('Viernes', '10:00:00', '19:00:00', 7),
+('Sabado', '08:00:00', '14:00:00', 7),
+('Lunes', '08:00:00', '16:00:00', 10),
+('Martes', '08:00:00', '16:00:00', 10),
+('Viernes', '08:00:00', '16:00:00', 10);
+INSERT INTO HORARIO_EXCEPCION (hex_fecha, hex_tipo, hex_hora_entrada, hex_hora_salida, hex_motivo, emp_id) VALUES
+('2025-08-07', 'Festivo', NULL, NULL, 'Batalla de Boyacá', 1),
+('2025-08-07', 'Festivo', NULL, NULL, 'Batalla de Boyacá', 4),
+('2025-07-25', 'Descanso', NULL, NULL, 'Cita médica', 7),
+('2025-07-26', 'Horario Especial', '10:00:00', '13:00:00', 'Capacitación en la tarde', 7);
 INSERT INTO HISTORIAL_CITA (his_observaciones, cit_id) VALUES
 ('Sin observaciones posteriores', 13),
 ('Sin observaciones posteriores', 15),
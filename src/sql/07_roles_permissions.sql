@@ -29,6 +29,69 @@ GRANT EXECUTE ON PROCEDURE salondb.sp_update_empleado TO 'rol_empleado';
 GRANT EXECUTE ON PROCEDURE salondb.sp_update_usuario TO 'rol_empleado';
 GRANT EXECUTE ON PROCEDURE salondb.sp_ver_citas_empleado TO 'rol_empleado';
 GRANT EXECUTE ON PROCEDURE salondb.sp_ver_historial_empleado TO 'rol_empleado';
+GRANT SELECT ON salondb.HORARIO_EMPLEADO TO 'rol_empleado';
+GRANT SELECT ON salondb.HORARIO_EXCEPCION TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_horarios_empleado TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_excepciones_empleado TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_horarios_por_dia TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_excepciones_por_fecha TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_citas_por_fecha TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_buscar_conflicto_cita TO 'rol_empleado';
+GRANT SELECT ON salondb.HISTORIAL_ESTADO_CITA TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_cambiar_estado_cita TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_historial_estado_cita TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_bloquear_cita TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_insertar_factura_cliente TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_insertar_detalle_factura TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_vincular_cita_factura TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_lineas_factura TO 'rol_empleado';
+GRANT SELECT ON salondb.PROMOCION TO 'rol_empleado';
+GRANT SELECT ON salondb.PRODUCTO_USADO TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_productos_servicio TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_descontar_productos_cita TO 'rol_empleado';
+GRANT SELECT ON salondb.PROMOCION_SERVICIO TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_insertar_detalle_factura_promocion TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_promociones_vigentes TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_buscar_promocion_por_codigo TO 'rol_empleado';
+GRANT SELECT ON salondb.COMPRA_PRODUCTO TO 'rol_empleado';
+GRANT SELECT ON salondb.DETALLE_COMPRA TO 'rol_empleado';
+GRANT SELECT ON salondb.HISTORIAL_ESTADO_COMPRA TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_insertar_compra TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_actualizar_compra TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_buscar_compra_por_id TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_compras TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_recalcular_total_compra TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_insertar_detalle_compra TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_actualizar_detalle_compra TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_eliminar_detalle_compra TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_detalles_compra TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_cambiar_estado_compra TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_historial_estado_compra TO 'rol_empleado';
+GRANT SELECT ON salondb.MOVIMIENTO_INVENTARIO TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_registrar_movimiento_inventario TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_movimientos_producto TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_buscar_producto_por_id TO 'rol_empleado';
+GRANT SELECT ON salondb.vw_productos_reorden TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_get_productos_reorden TO 'rol_empleado';
+GRANT SELECT ON salondb.CONTEO_INVENTARIO TO 'rol_empleado';
+GRANT SELECT ON salondb.DETALLE_CONTEO TO 'rol_empleado';
+GRANT SELECT ON salondb.REGISTRO_CONTEO TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_abrir_conteo_inventario TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_conteos_inventario TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_buscar_conteo_inventario TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_registrar_conteo_producto TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_diferencias_conteo TO 'rol_empleado';
+GRANT SELECT ON salondb.DETALLE_FACTURA_PRODUCTO TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_insertar_detalle_factura_producto TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_productos_factura TO 'rol_empleado';
+GRANT SELECT ON salondb.PAGO_CLIENTE TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_pagos_factura TO 'rol_empleado';
+GRANT SELECT ON salondb.NOTA_CREDITO TO 'rol_empleado';
+GRANT SELECT ON salondb.DETALLE_NOTA_CREDITO TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_notas_credito_factura TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_lineas_notas_credito_factura TO 'rol_empleado';
+GRANT SELECT ON salondb.RESOLUCION_FACTURACION TO 'rol_empleado';
+GRANT EXECUTE ON PROCEDURE salondb.sp_resumen_impuestos_factura TO 'rol_empleado';
 
 
 -- Permisos Cliente
@@ -41,6 +104,9 @@ GRANT SELECT ON salondb.SERVICIO TO 'rol_cliente';
 GRANT SELECT ON salondb.PROMOCION TO 'rol_cliente';
 GRANT SELECT ON salondb.FACTURA_SERVICIO TO 'rol_cliente';
 GRANT SELECT ON salondb.DETALLE_FACTURA_SERVICIO TO 'rol_cliente';
+GRANT SELECT ON salondb.DETALLE_FACTURA_PRODUCTO TO 'rol_cliente';
+GRANT SELECT ON salondb.PAGO_CLIENTE TO 'rol_cliente';
+GRANT SELECT ON salondb.NOTA_CREDITO TO 'rol_cliente';
 GRANT SELECT ON salondb.HISTORIAL_CITA TO 'rol_cliente';
 GRANT SELECT ON salondb.USUARIO_SISTEMA TO 'rol_cliente';
 GRANT SELECT ON salondb.EMPLEADO TO 'rol_cliente';
@@ -50,6 +116,14 @@ GRANT UPDATE ON salondb.USUARIO_SISTEMA TO 'rol_cliente';
 GRANT DELETE ON salondb.CITA TO 'rol_cliente';
 GRANT EXECUTE ON PROCEDURE salondb.sp_update_cliente TO 'rol_cliente';
 GRANT EXECUTE ON PROCEDURE salondb.sp_update_usuario TO 'rol_cliente';
+GRANT SELECT ON salondb.HORARIO_EMPLEADO TO 'rol_cliente';
+GRANT SELECT ON salondb.HORARIO_EXCEPCION TO 'rol_cliente';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_horarios_por_dia TO 'rol_cliente';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_excepciones_por_fecha TO 'rol_cliente';
+GRANT EXECUTE ON PROCEDURE salondb.sp_listar_citas_por_fecha TO 'rol_cliente';
+GRANT EXECUTE ON PROCEDURE salondb.sp_buscar_conflicto_cita TO 'rol_cliente';
+GRANT SELECT, INSERT ON salondb.HISTORIAL_ESTADO_CITA TO 'rol_cliente';
+GRANT EXECUTE ON PROCEDURE salondb.sp_cancelar_cita_cliente TO 'rol_cliente';
 
 CREATE USER IF NOT EXISTS 'salon_user'@'%' IDENTIFIED BY 'salon_password_456';
 GRANT 'rol_admin'@'%' TO 'salon_user'@'%';